@@ -0,0 +1,446 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package governance
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aungmawjj/juria-blockchain/core"
+	"github.com/aungmawjj/juria-blockchain/execution/chaincode"
+)
+
+// EpochDelay is the number of blocks after the approving block
+// for the new validator set to take effect.
+// It must be larger than the commit delay of consensus
+// so that all validators know the new set before the start height.
+const EpochDelay = 20
+
+// proposal actions
+const (
+	ActionAdd      = "add"
+	ActionRemove   = "remove"
+	ActionSetPower = "setPower"
+)
+
+// EventValidatorSetChanged is emitted with Epoch json data when a proposal is executed
+const EventValidatorSetChanged = "validatorSetChanged"
+
+type Input struct {
+	Method     string  `json:"method"`
+	Action     string  `json:"action,omitempty"`
+	Validator  []byte  `json:"validator,omitempty"`
+	ProposalID uint64  `json:"proposalID,omitempty"`
+	BLSKey     *BLSKey `json:"blsKey,omitempty"`
+	Power      uint64  `json:"power,omitempty"` // voting power for add and setPower actions, 1 if not given
+}
+
+// BLSKey is the bls public key of a validator with the proof of possession.
+// The proof is verified by the nodes, a validator with invalid proof is treated as having no bls key.
+type BLSKey struct {
+	Key   []byte `json:"key"`
+	Proof []byte `json:"proof"`
+}
+
+// Proposal to add or remove a validator, or to change its voting power
+type Proposal struct {
+	ID        uint64   `json:"id"`
+	Action    string   `json:"action"`
+	Validator []byte   `json:"validator"`
+	Power     uint64   `json:"power,omitempty"`
+	Approvals [][]byte `json:"approvals"`
+	Executed  bool     `json:"executed"`
+}
+
+// Epoch is a validator set effective from the start height
+type Epoch struct {
+	StartHeight uint64    `json:"startHeight"`
+	Validators  [][]byte  `json:"validators"`
+	BLSKeys     []*BLSKey `json:"blsKeys,omitempty"` // same order as validators, nil for validators without key
+	Powers      []uint64  `json:"powers,omitempty"`  // same order as validators, every validator has power 1 if nil
+}
+
+// power gives the voting power of the validator at idx
+func (e *Epoch) power(idx int) uint64 {
+	if idx < len(e.Powers) {
+		return e.Powers[idx]
+	}
+	return 1
+}
+
+func (e *Epoch) totalPower() uint64 {
+	var total uint64
+	for i := range e.Validators {
+		total += e.power(i)
+	}
+	return total
+}
+
+var (
+	keyEpochs        = []byte("epochs")
+	keyProposalCount = []byte("proposalCount")
+	keyProposal      = []byte("proposal")
+	keyBLSKey        = []byte("blsKey")
+)
+
+// Governance chaincode manages validator set changes.
+// Validators propose to add or remove a member or to change its voting power,
+// and the change is executed when the majority power of the latest validator set approves it.
+//
+// Accounts register their bls keys to sign votes with,
+// the registered keys take effect with the next validator set change.
+type Governance struct {
+	// genesis validators, used until the first change is executed
+	Genesis [][]byte
+
+	// optional bls keys of genesis validators, same order as Genesis
+	GenesisBLSKeys []*BLSKey
+
+	// optional voting powers of genesis validators, same order as Genesis
+	GenesisPowers []uint64
+}
+
+var _ chaincode.Chaincode = (*Governance)(nil)
+
+func (gov *Governance) Init(ctx chaincode.CallContext) error {
+	return nil
+}
+
+func (gov *Governance) Invoke(ctx chaincode.CallContext) error {
+	input, err := parseInput(ctx.Input())
+	if err != nil {
+		return err
+	}
+	switch input.Method {
+
+	case "propose":
+		return gov.invokePropose(ctx, input)
+
+	case "approve":
+		return gov.invokeApprove(ctx, input)
+
+	case "registerBLSKey":
+		return gov.invokeRegisterBLSKey(ctx, input)
+
+	default:
+		return errors.New("method not found")
+	}
+}
+
+func (gov *Governance) Query(ctx chaincode.CallContext) ([]byte, error) {
+	input, err := parseInput(ctx.Input())
+	if err != nil {
+		return nil, err
+	}
+	switch input.Method {
+
+	case "validators":
+		epochs := gov.getEpochs(ctx)
+		return json.Marshal(epochs[len(epochs)-1].Validators)
+
+	case "epochs":
+		return json.Marshal(gov.getEpochs(ctx))
+
+	case "blsKey":
+		return json.Marshal(getBLSKey(ctx, input.Validator))
+
+	case "proposal":
+		proposal := getProposal(ctx, input.ProposalID)
+		if proposal == nil {
+			return nil, errors.New("proposal not found")
+		}
+		return json.Marshal(proposal)
+
+	default:
+		return nil, errors.New("method not found")
+	}
+}
+
+func (gov *Governance) invokePropose(ctx chaincode.CallContext, input *Input) error {
+	validators := gov.latestValidators(ctx)
+	if !containsKey(validators, ctx.Sender()) {
+		return errors.New("sender must be validator")
+	}
+	if len(input.Validator) == 0 {
+		return errors.New("empty validator")
+	}
+	power := input.Power
+	switch input.Action {
+	case ActionAdd:
+		if _, err := core.NewPublicKey(input.Validator); err != nil {
+			return fmt.Errorf("invalid validator, %w", err)
+		}
+		if containsKey(validators, input.Validator) {
+			return errors.New("already validator")
+		}
+	case ActionRemove:
+		if !containsKey(validators, input.Validator) {
+			return errors.New("not validator")
+		}
+		power = 0
+	case ActionSetPower:
+		if !containsKey(validators, input.Validator) {
+			return errors.New("not validator")
+		}
+	default:
+		return errors.New("unknown action")
+	}
+	if input.Action != ActionRemove && power == 0 {
+		power = 1
+	}
+	proposal := &Proposal{
+		ID:        decodeUint64(ctx.GetState(keyProposalCount)) + 1,
+		Action:    input.Action,
+		Validator: input.Validator,
+		Power:     power,
+		Approvals: [][]byte{ctx.Sender()},
+	}
+	ctx.SetState(keyProposalCount, encodeUint64(proposal.ID))
+	return gov.executeIfApproved(ctx, proposal)
+}
+
+func (gov *Governance) invokeApprove(ctx chaincode.CallContext, input *Input) error {
+	if !containsKey(gov.latestValidators(ctx), ctx.Sender()) {
+		return errors.New("sender must be validator")
+	}
+	proposal := getProposal(ctx, input.ProposalID)
+	if proposal == nil {
+		return errors.New("proposal not found")
+	}
+	if proposal.Executed {
+		return errors.New("proposal already executed")
+	}
+	if containsKey(proposal.Approvals, ctx.Sender()) {
+		return errors.New("already approved")
+	}
+	proposal.Approvals = append(proposal.Approvals, ctx.Sender())
+	return gov.executeIfApproved(ctx, proposal)
+}
+
+func (gov *Governance) invokeRegisterBLSKey(ctx chaincode.CallContext, input *Input) error {
+	if input.BLSKey == nil || len(input.BLSKey.Key) == 0 || len(input.BLSKey.Proof) == 0 {
+		return errors.New("empty bls key")
+	}
+	b, err := json.Marshal(input.BLSKey)
+	if err != nil {
+		return err
+	}
+	ctx.SetState(blsKeyKey(ctx.Sender()), b)
+	return nil
+}
+
+// executeIfApproved applies the change if the majority power of the latest set approved,
+// approvals from removed validators are not counted
+func (gov *Governance) executeIfApproved(ctx chaincode.CallContext, proposal *Proposal) error {
+	latest := gov.latestEpoch(ctx)
+	var power uint64
+	for i, v := range latest.Validators {
+		if containsKey(proposal.Approvals, v) {
+			power += latest.power(i)
+		}
+	}
+	if power >= majorityPower(latest.totalPower()) {
+		if err := gov.applyProposal(ctx, proposal, latest); err != nil {
+			return err
+		}
+		proposal.Executed = true
+	}
+	return setProposal(ctx, proposal)
+}
+
+func (gov *Governance) applyProposal(
+	ctx chaincode.CallContext, proposal *Proposal, latest *Epoch,
+) error {
+	validators := latest.Validators
+	next := make([][]byte, 0, len(validators)+1)
+	powers := make([]uint64, 0, len(validators)+1)
+	found := false
+	for i, v := range validators {
+		if !bytes.Equal(v, proposal.Validator) {
+			next = append(next, v)
+			powers = append(powers, latest.power(i))
+			continue
+		}
+		found = true
+		if proposal.Action == ActionSetPower {
+			next = append(next, v) // keep the position of the validator
+			powers = append(powers, proposal.Power)
+		}
+	}
+	if proposal.Action == ActionAdd {
+		if found {
+			return errors.New("already validator")
+		}
+		next = append(next, proposal.Validator)
+		powers = append(powers, proposal.Power)
+	} else if !found {
+		return errors.New("not validator")
+	}
+	if len(next) == 0 {
+		return errors.New("cannot remove last validator")
+	}
+	var total uint64
+	for _, p := range powers {
+		if total+p < total {
+			return errors.New("total power overflow")
+		}
+		total += p
+	}
+	epoch := &Epoch{
+		StartHeight: ctx.BlockHeight() + EpochDelay,
+		Validators:  next,
+		BLSKeys:     gov.epochBLSKeys(ctx, next),
+		Powers:      epochPowers(powers),
+	}
+	epochs := gov.getEpochs(ctx)
+	if epochs[len(epochs)-1].StartHeight == epoch.StartHeight {
+		epochs[len(epochs)-1] = epoch // another change in the same block
+	} else {
+		epochs = append(epochs, epoch)
+	}
+	b, err := json.Marshal(epochs)
+	if err != nil {
+		return err
+	}
+	ctx.SetState(keyEpochs, b)
+	b, _ = json.Marshal(epoch)
+	ctx.EmitEvent(EventValidatorSetChanged, b)
+	return nil
+}
+
+func (gov *Governance) getEpochs(ctx chaincode.CallContext) []*Epoch {
+	var epochs []*Epoch
+	if b := ctx.GetState(keyEpochs); b != nil {
+		json.Unmarshal(b, &epochs)
+	}
+	if len(epochs) == 0 {
+		epochs = []*Epoch{{0, gov.Genesis, gov.GenesisBLSKeys, gov.GenesisPowers}}
+	}
+	return epochs
+}
+
+// epochBLSKeys gives the bls keys of the validators, registered keys replace the genesis keys
+func (gov *Governance) epochBLSKeys(ctx chaincode.CallContext, validators [][]byte) []*BLSKey {
+	keys := make([]*BLSKey, len(validators))
+	found := false
+	for i, v := range validators {
+		keys[i] = getBLSKey(ctx, v)
+		if keys[i] == nil {
+			keys[i] = gov.genesisBLSKey(v)
+		}
+		found = found || keys[i] != nil
+	}
+	if !found {
+		return nil
+	}
+	return keys
+}
+
+func (gov *Governance) genesisBLSKey(validator []byte) *BLSKey {
+	for i, v := range gov.Genesis {
+		if bytes.Equal(v, validator) && i < len(gov.GenesisBLSKeys) {
+			return gov.GenesisBLSKeys[i]
+		}
+	}
+	return nil
+}
+
+// epochPowers gives nil if every validator has power 1, to keep the epochs of equal power compact
+func epochPowers(powers []uint64) []uint64 {
+	for _, p := range powers {
+		if p != 1 {
+			return powers
+		}
+	}
+	return nil
+}
+
+func (gov *Governance) latestEpoch(ctx chaincode.CallContext) *Epoch {
+	epochs := gov.getEpochs(ctx)
+	return epochs[len(epochs)-1]
+}
+
+func (gov *Governance) latestValidators(ctx chaincode.CallContext) [][]byte {
+	return gov.latestEpoch(ctx).Validators
+}
+
+func getProposal(ctx chaincode.CallContext, id uint64) *Proposal {
+	b := ctx.GetState(proposalKey(id))
+	if b == nil {
+		return nil
+	}
+	proposal := new(Proposal)
+	if err := json.Unmarshal(b, proposal); err != nil {
+		return nil
+	}
+	return proposal
+}
+
+func setProposal(ctx chaincode.CallContext, proposal *Proposal) error {
+	b, err := json.Marshal(proposal)
+	if err != nil {
+		return err
+	}
+	ctx.SetState(proposalKey(proposal.ID), b)
+	return nil
+}
+
+func getBLSKey(ctx chaincode.CallContext, validator []byte) *BLSKey {
+	b := ctx.GetState(blsKeyKey(validator))
+	if b == nil {
+		return nil
+	}
+	key := new(BLSKey)
+	if err := json.Unmarshal(b, key); err != nil {
+		return nil
+	}
+	return key
+}
+
+func blsKeyKey(validator []byte) []byte {
+	return append(append([]byte{}, keyBLSKey...), validator...)
+}
+
+func proposalKey(id uint64) []byte {
+	return append(append([]byte{}, keyProposal...), encodeUint64(id)...)
+}
+
+func containsKey(keys [][]byte, key []byte) bool {
+	for _, k := range keys {
+		if bytes.Equal(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// same as core.MajorityPower, chaincodes don't depend on core
+func majorityPower(totalPower uint64) uint64 {
+	return totalPower/3*2 + totalPower%3*2/3 + 1
+}
+
+func decodeUint64(b []byte) uint64 {
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+func encodeUint64(value uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, value)
+	return b
+}
+
+func parseInput(b []byte) (*Input, error) {
+	input := new(Input)
+	err := json.Unmarshal(b, input)
+	if err != nil {
+		return nil, errors.New("failed to parse input: " + err.Error())
+	}
+	return input, nil
+}
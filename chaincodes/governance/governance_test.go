@@ -0,0 +1,213 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package governance
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aungmawjj/juria-blockchain/core"
+	"github.com/aungmawjj/juria-blockchain/execution/chaincode"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestKey() []byte {
+	return core.GenerateKey(nil).PublicKey().Bytes()
+}
+
+func makeInput(input *Input) []byte {
+	b, _ := json.Marshal(input)
+	return b
+}
+
+func queryEpochs(gov *Governance, ctx *chaincode.MockCallContext) []*Epoch {
+	ctx.MockInput = makeInput(&Input{Method: "epochs"})
+	b, _ := gov.Query(ctx)
+	var epochs []*Epoch
+	json.Unmarshal(b, &epochs)
+	return epochs
+}
+
+func TestGovernance_AddValidator(t *testing.T) {
+	assert := assert.New(t)
+
+	v1, v2, v3, v4 := newTestKey(), newTestKey(), newTestKey(), newTestKey()
+	gov := &Governance{Genesis: [][]byte{v1, v2, v3}}
+	ctx := new(chaincode.MockCallContext)
+	ctx.MockState = chaincode.NewMockState()
+	ctx.MockBlockHeight = 5
+
+	epochs := queryEpochs(gov, ctx)
+	if assert.Equal(1, len(epochs)) {
+		assert.EqualValues(0, epochs[0].StartHeight)
+		assert.Equal(gov.Genesis, epochs[0].Validators)
+	}
+
+	ctx.MockSender = v4
+	ctx.MockInput = makeInput(&Input{Method: "propose", Action: ActionAdd, Validator: v4})
+	assert.Error(gov.Invoke(ctx), "sender not validator")
+
+	ctx.MockSender = v1
+	ctx.MockInput = makeInput(&Input{Method: "propose", Action: ActionAdd, Validator: v2})
+	assert.Error(gov.Invoke(ctx), "already validator")
+
+	ctx.MockInput = makeInput(&Input{Method: "propose", Action: ActionAdd, Validator: []byte{4}})
+	assert.Error(gov.Invoke(ctx), "invalid public key")
+
+	ctx.MockInput = makeInput(&Input{Method: "propose", Action: ActionAdd, Validator: v4})
+	assert.NoError(gov.Invoke(ctx))
+
+	ctx.MockInput = makeInput(&Input{Method: "approve", ProposalID: 1})
+	assert.Error(gov.Invoke(ctx), "already approved")
+
+	ctx.MockSender = v2
+	assert.NoError(gov.Invoke(ctx))
+	assert.Equal(1, len(queryEpochs(gov, ctx)), "not enough approvals")
+
+	ctx.MockSender = v3
+	ctx.MockInput = makeInput(&Input{Method: "approve", ProposalID: 1})
+	assert.NoError(gov.Invoke(ctx))
+	assert.Equal(EventValidatorSetChanged, ctx.MockEvents[0].Name)
+
+	epochs = queryEpochs(gov, ctx)
+	if assert.Equal(2, len(epochs)) {
+		assert.EqualValues(5+EpochDelay, epochs[1].StartHeight)
+		assert.Equal([][]byte{v1, v2, v3, v4}, epochs[1].Validators)
+	}
+
+	ctx.MockInput = makeInput(&Input{Method: "proposal", ProposalID: 1})
+	b, err := gov.Query(ctx)
+	assert.NoError(err)
+	proposal := new(Proposal)
+	json.Unmarshal(b, proposal)
+	assert.True(proposal.Executed)
+
+	ctx.MockSender = v4
+	ctx.MockInput = makeInput(&Input{Method: "approve", ProposalID: 1})
+	assert.Error(gov.Invoke(ctx), "already executed")
+}
+
+func TestGovernance_RemoveValidator(t *testing.T) {
+	assert := assert.New(t)
+
+	v1, v2 := newTestKey(), newTestKey()
+	gov := &Governance{Genesis: [][]byte{v1, v2}}
+	ctx := new(chaincode.MockCallContext)
+	ctx.MockState = chaincode.NewMockState()
+	ctx.MockBlockHeight = 10
+
+	ctx.MockSender = v1
+	ctx.MockInput = makeInput(&Input{Method: "propose", Action: ActionRemove, Validator: v2})
+	assert.NoError(gov.Invoke(ctx))
+
+	ctx.MockSender = v2
+	ctx.MockInput = makeInput(&Input{Method: "approve", ProposalID: 1})
+	assert.NoError(gov.Invoke(ctx))
+
+	ctx.MockInput = makeInput(&Input{Method: "validators"})
+	b, err := gov.Query(ctx)
+	assert.NoError(err)
+	var validators [][]byte
+	json.Unmarshal(b, &validators)
+	assert.Equal([][]byte{v1}, validators)
+
+	// single validator approves alone
+	ctx.MockSender = v1
+	ctx.MockInput = makeInput(&Input{Method: "propose", Action: ActionRemove, Validator: v1})
+	assert.Error(gov.Invoke(ctx), "cannot remove last validator")
+}
+
+func TestGovernance_BLSKey(t *testing.T) {
+	assert := assert.New(t)
+
+	v1, v2 := newTestKey(), newTestKey()
+	k1 := &BLSKey{Key: []byte{11}, Proof: []byte{12}}
+	gov := &Governance{
+		Genesis:        [][]byte{v1},
+		GenesisBLSKeys: []*BLSKey{k1},
+	}
+	ctx := new(chaincode.MockCallContext)
+	ctx.MockState = chaincode.NewMockState()
+	ctx.MockBlockHeight = 5
+
+	epochs := queryEpochs(gov, ctx)
+	assert.Equal([]*BLSKey{k1}, epochs[0].BLSKeys)
+
+	ctx.MockSender = v2
+	ctx.MockInput = makeInput(&Input{Method: "registerBLSKey"})
+	assert.Error(gov.Invoke(ctx), "empty bls key")
+
+	k2 := &BLSKey{Key: []byte{21}, Proof: []byte{22}}
+	ctx.MockInput = makeInput(&Input{Method: "registerBLSKey", BLSKey: k2})
+	assert.NoError(gov.Invoke(ctx))
+
+	ctx.MockInput = makeInput(&Input{Method: "blsKey", Validator: v2})
+	b, err := gov.Query(ctx)
+	assert.NoError(err)
+	key := new(BLSKey)
+	json.Unmarshal(b, key)
+	assert.Equal(k2, key)
+
+	ctx.MockSender = v1
+	ctx.MockInput = makeInput(&Input{Method: "propose", Action: ActionAdd, Validator: v2})
+	assert.NoError(gov.Invoke(ctx))
+
+	epochs = queryEpochs(gov, ctx)
+	if assert.Equal(2, len(epochs)) {
+		assert.Equal([]*BLSKey{k1, k2}, epochs[1].BLSKeys, "genesis key and registered key")
+	}
+}
+
+func TestGovernance_Power(t *testing.T) {
+	assert := assert.New(t)
+
+	v1, v2, v3, v4 := newTestKey(), newTestKey(), newTestKey(), newTestKey()
+	gov := &Governance{
+		Genesis:       [][]byte{v1, v2, v3},
+		GenesisPowers: []uint64{5, 1, 1},
+	}
+	ctx := new(chaincode.MockCallContext)
+	ctx.MockState = chaincode.NewMockState()
+	ctx.MockBlockHeight = 5
+
+	epochs := queryEpochs(gov, ctx)
+	assert.Equal([]uint64{5, 1, 1}, epochs[0].Powers)
+
+	// v2 and v3 don't have the majority power
+	ctx.MockSender = v2
+	ctx.MockInput = makeInput(&Input{Method: "propose", Action: ActionAdd, Validator: v4, Power: 3})
+	assert.NoError(gov.Invoke(ctx))
+	ctx.MockSender = v3
+	ctx.MockInput = makeInput(&Input{Method: "approve", ProposalID: 1})
+	assert.NoError(gov.Invoke(ctx))
+	assert.Equal(1, len(queryEpochs(gov, ctx)), "not enough power")
+
+	// v1 and v2 have the majority power
+	ctx.MockSender = v1
+	ctx.MockInput = makeInput(&Input{Method: "approve", ProposalID: 1})
+	assert.NoError(gov.Invoke(ctx))
+	epochs = queryEpochs(gov, ctx)
+	if assert.Equal(2, len(epochs)) {
+		assert.Equal([][]byte{v1, v2, v3, v4}, epochs[1].Validators)
+		assert.Equal([]uint64{5, 1, 1, 3}, epochs[1].Powers)
+	}
+
+	ctx.MockInput = makeInput(&Input{Method: "propose", Action: ActionSetPower, Validator: []byte{5}})
+	assert.Error(gov.Invoke(ctx), "not validator")
+
+	// v1 alone doesn't have the majority power of the latest set
+	ctx.MockBlockHeight = 6
+	ctx.MockInput = makeInput(&Input{Method: "propose", Action: ActionSetPower, Validator: v1, Power: 1})
+	assert.NoError(gov.Invoke(ctx))
+	assert.Equal(2, len(queryEpochs(gov, ctx)))
+
+	ctx.MockSender = v4
+	ctx.MockInput = makeInput(&Input{Method: "approve", ProposalID: 2})
+	assert.NoError(gov.Invoke(ctx))
+	epochs = queryEpochs(gov, ctx)
+	if assert.Equal(3, len(epochs)) {
+		assert.Equal([][]byte{v1, v2, v3, v4}, epochs[2].Validators, "validator keeps its position")
+		assert.Equal([]uint64{1, 1, 1, 3}, epochs[2].Powers)
+	}
+}
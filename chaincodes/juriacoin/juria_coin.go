@@ -18,6 +18,19 @@ type Input struct {
 	Value  int64  `json:"value"`
 }
 
+// TransferEvent is emitted as json data for mint and transfer
+type TransferEvent struct {
+	From  []byte `json:"from"` // nil for mint
+	To    []byte `json:"to"`
+	Value int64  `json:"value"`
+}
+
+// event names
+const (
+	EventMint     = "mint"
+	EventTransfer = "transfer"
+)
+
 var (
 	keyMinter = []byte("minter")
 	keyTotal  = []byte("total")
@@ -97,6 +110,7 @@ func invokeMint(ctx chaincode.CallContext, input *Input) error {
 
 	ctx.SetState(keyTotal, encodeBalance(total))
 	ctx.SetState(input.Dest, encodeBalance(balance))
+	emitTransferEvent(ctx, EventMint, nil, input.Dest, input.Value)
 	return nil
 }
 
@@ -112,9 +126,19 @@ func invokeTransfer(ctx chaincode.CallContext, input *Input) error {
 
 	ctx.SetState(ctx.Sender(), encodeBalance(bsctx))
 	ctx.SetState(input.Dest, encodeBalance(bdes))
+	emitTransferEvent(ctx, EventTransfer, ctx.Sender(), input.Dest, input.Value)
 	return nil
 }
 
+func emitTransferEvent(ctx chaincode.CallContext, name string, from, to []byte, value int64) {
+	b, _ := json.Marshal(&TransferEvent{
+		From:  from,
+		To:    to,
+		Value: value,
+	})
+	ctx.EmitEvent(name, b)
+}
+
 func queryTotal(ctx chaincode.CallContext) ([]byte, error) {
 	return json.Marshal(decodeBalance(ctx.GetState(keyTotal)))
 }
@@ -94,10 +94,20 @@ func TestJuriaCoin_Mint(t *testing.T) {
 	err := jctx.Invoke(ctx)
 	assert.Error(err, "sender not minter error")
 
+	assert.Empty(ctx.MockEvents, "no event for failed mint")
+
 	ctx.MockSender = []byte{1, 1, 1}
 	err = jctx.Invoke(ctx)
 
 	assert.NoError(err)
+	if assert.Equal(1, len(ctx.MockEvents)) {
+		assert.Equal(EventMint, ctx.MockEvents[0].Name)
+		evt := new(TransferEvent)
+		json.Unmarshal(ctx.MockEvents[0].Data, evt)
+		assert.Nil(evt.From)
+		assert.Equal([]byte{2, 2, 2}, evt.To)
+		assert.EqualValues(100, evt.Value)
+	}
 
 	input = &Input{
 		Method: "total",
@@ -165,9 +175,18 @@ func TestJuriaCoin_Transfer(t *testing.T) {
 	input.Value = 100
 	b, _ = json.Marshal(input)
 	ctx.MockInput = b
+	ctx.MockEvents = nil
 	err = jctx.Invoke(ctx)
 
 	assert.NoError(err)
+	if assert.Equal(1, len(ctx.MockEvents)) {
+		assert.Equal(EventTransfer, ctx.MockEvents[0].Name)
+		evt := new(TransferEvent)
+		json.Unmarshal(ctx.MockEvents[0].Data, evt)
+		assert.Equal([]byte{2, 2, 2}, evt.From)
+		assert.Equal([]byte{3, 3, 3}, evt.To)
+		assert.EqualValues(100, evt.Value)
+	}
 
 	input.Method = "total"
 	b, _ = json.Marshal(input)
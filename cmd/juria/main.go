@@ -5,6 +5,7 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"github.com/aungmawjj/juria-blockchain/node"
 	"github.com/spf13/cobra"
@@ -19,23 +20,39 @@ const (
 
 	// storage
 	FlagMerkleBranchFactor = "storage-merkleBranchFactor"
+	FlagRetainBlocks       = "storage-retainBlocks"
+	FlagPruneInterval      = "storage-pruneInterval"
 
 	// execution
 	FlagTxExecTimeout       = "execution-txExecTimeout"
 	FlagExecConcurrentLimit = "execution-concurrentLimit"
+	FlagBinccPoolSize       = "execution-binccPoolSize"
 
 	// consensus
 	FlagChainID       = "chainid"
 	FlagBlockTxLimit  = "consensus-blockTxLimit"
+	FlagBlockGasLimit = "consensus-blockGasLimit"
 	FlagTxWaitTime    = "consensus-txWaitTime"
 	FlagBeatTimeout   = "consensus-beatTimeout"
 	FlagBlockDelay    = "consensus-blockDelay"
 	FlagViewWidth     = "consensus-viewWidth"
 	FlagLeaderTimeout = "consensus-leaderTimeout"
+
+	FlagLeaderReputationWindow = "consensus-leaderReputationWindow"
+
+	FlagStateSync          = "consensus-stateSync"
+	FlagStateSyncThreshold = "consensus-stateSyncThreshold"
+	FlagStateChunkSize     = "consensus-stateChunkSize"
+
+	FlagObserver = "observer"
+
+	FlagExportStart = "start"
 )
 
 var nodeConfig = node.DefaultConfig
 
+var exportStart uint64
+
 var rootCmd = &cobra.Command{
 	Use:   "juria",
 	Short: "Juria blockchain",
@@ -44,6 +61,43 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Database tools",
+}
+
+var dbVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check the integrity of the blockchain database offline",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := node.VerifyDB(nodeConfig, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export <file>",
+	Short: "Export the commited chain to an archive file",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := node.ExportChain(nodeConfig, args[0], exportStart, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import the chain from an archive file by executing the blocks again",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := node.ImportChain(nodeConfig, args[0], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
 func main() {
 	err := rootCmd.Execute()
 	if err != nil {
@@ -69,6 +123,14 @@ func init() {
 		FlagMerkleBranchFactor, nodeConfig.StorageConfig.MerkleBranchFactor,
 		"merkle tree branching factor")
 
+	rootCmd.Flags().Uint64Var(&nodeConfig.StorageConfig.RetainBlocks,
+		FlagRetainBlocks, nodeConfig.StorageConfig.RetainBlocks,
+		"number of latest blocks to keep txs and commits, 0 keeps all")
+
+	rootCmd.Flags().DurationVar(&nodeConfig.StorageConfig.PruneInterval,
+		FlagPruneInterval, nodeConfig.StorageConfig.PruneInterval,
+		"interval to prune old blocks")
+
 	rootCmd.Flags().DurationVar(&nodeConfig.ExecutionConfig.TxExecTimeout,
 		FlagTxExecTimeout, nodeConfig.ExecutionConfig.TxExecTimeout,
 		"tx execution timeout")
@@ -77,6 +139,10 @@ func init() {
 		FlagExecConcurrentLimit, nodeConfig.ExecutionConfig.ConcurrentLimit,
 		"concurrent tx execution limit")
 
+	rootCmd.Flags().IntVar(&nodeConfig.ExecutionConfig.BinccPoolSize,
+		FlagBinccPoolSize, nodeConfig.ExecutionConfig.BinccPoolSize,
+		"running process limit per binary chaincode")
+
 	rootCmd.Flags().Int64Var(&nodeConfig.ConsensusConfig.ChainID,
 		FlagChainID, nodeConfig.ConsensusConfig.ChainID,
 		"chainid is used to create genesis block")
@@ -85,6 +151,10 @@ func init() {
 		FlagBlockTxLimit, nodeConfig.ConsensusConfig.BlockTxLimit,
 		"maximum tx count in a block")
 
+	rootCmd.Flags().Uint64Var(&nodeConfig.ConsensusConfig.BlockGasLimit,
+		FlagBlockGasLimit, nodeConfig.ConsensusConfig.BlockGasLimit,
+		"maximum total gas limit of txs in a block")
+
 	rootCmd.Flags().DurationVar(&nodeConfig.ConsensusConfig.TxWaitTime,
 		FlagTxWaitTime, nodeConfig.ConsensusConfig.TxWaitTime,
 		"block creation delay if no transactions in the pool")
@@ -104,4 +174,43 @@ func init() {
 	rootCmd.Flags().DurationVar(&nodeConfig.ConsensusConfig.LeaderTimeout,
 		FlagLeaderTimeout, nodeConfig.ConsensusConfig.LeaderTimeout,
 		"leader must create next qc in this duration")
+
+	rootCmd.Flags().Uint64Var(&nodeConfig.ConsensusConfig.LeaderReputationWindow,
+		FlagLeaderReputationWindow, nodeConfig.ConsensusConfig.LeaderReputationWindow,
+		"skip leaders without votes in this number of recent blocks, 0 for round robin")
+
+	rootCmd.Flags().BoolVar(&nodeConfig.ConsensusConfig.StateSync,
+		FlagStateSync, nodeConfig.ConsensusConfig.StateSync,
+		"download state snapshot from validators on startup")
+
+	rootCmd.Flags().Uint64Var(&nodeConfig.ConsensusConfig.StateSyncThreshold,
+		FlagStateSyncThreshold, nodeConfig.ConsensusConfig.StateSyncThreshold,
+		"minimum blocks behind to use state sync")
+
+	rootCmd.Flags().IntVar(&nodeConfig.ConsensusConfig.StateChunkSize,
+		FlagStateChunkSize, nodeConfig.ConsensusConfig.StateChunkSize,
+		"number of states per state chunk request")
+
+	rootCmd.Flags().BoolVar(&nodeConfig.ConsensusConfig.Observer,
+		FlagObserver, nodeConfig.ConsensusConfig.Observer,
+		"follow the chain without proposing or voting (validators must list the node in peers)")
+
+	dbVerifyCmd.Flags().Uint8Var(&nodeConfig.StorageConfig.MerkleBranchFactor,
+		FlagMerkleBranchFactor, nodeConfig.StorageConfig.MerkleBranchFactor,
+		"merkle tree branching factor used by the node")
+
+	dbCmd.AddCommand(dbVerifyCmd)
+	rootCmd.AddCommand(dbCmd)
+
+	exportCmd.Flags().Uint64Var(&exportStart,
+		FlagExportStart, 0, "block height to start export")
+	rootCmd.AddCommand(exportCmd)
+
+	importCmd.Flags().Uint8Var(&nodeConfig.StorageConfig.MerkleBranchFactor,
+		FlagMerkleBranchFactor, nodeConfig.StorageConfig.MerkleBranchFactor,
+		"merkle tree branching factor used by the node")
+	importCmd.Flags().DurationVar(&nodeConfig.ExecutionConfig.TxExecTimeout,
+		FlagTxExecTimeout, nodeConfig.ExecutionConfig.TxExecTimeout,
+		"tx execution timeout")
+	rootCmd.AddCommand(importCmd)
 }
@@ -3,7 +3,11 @@
 
 package consensus
 
-import "time"
+import (
+	"time"
+
+	"github.com/aungmawjj/juria-blockchain/core"
+)
 
 type Config struct {
 	ChainID int64
@@ -11,6 +15,12 @@ type Config struct {
 	// maximum tx count in a block
 	BlockTxLimit int
 
+	// maximum total gas limit of txs in a block, zero means no limit
+	BlockGasLimit uint64
+
+	// maximum evidence count in a block
+	BlockEvidenceLimit int
+
 	// block creation delay if no transactions in the pool
 	TxWaitTime time.Duration
 
@@ -25,13 +35,34 @@ type Config struct {
 
 	// leader must create next qc within this duration
 	LeaderTimeout time.Duration
+
+	// download state snapshot from validators on startup instead of replaying all blocks
+	StateSync bool
+
+	// state sync is skipped if the snapshot is not higher than local height by this threshold
+	StateSyncThreshold uint64
+
+	// number of states in a state chunk request
+	StateChunkSize int
+
+	// number of recent commited blocks to find the active validators for leader election,
+	// zero means round robin leader rotation
+	LeaderReputationWindow uint64
+
+	// follow the chain without proposing or voting, the node doesn't need to be a validator
+	Observer bool
 }
 
 var DefaultConfig = Config{
-	BlockTxLimit:  400,
-	TxWaitTime:    1 * time.Second,
-	BeatTimeout:   500 * time.Millisecond,
-	BlockDelay:    40 * time.Millisecond, // maximum block rate = 25 blk per sec
-	ViewWidth:     30 * time.Second,
-	LeaderTimeout: 10 * time.Second,
+	BlockTxLimit:       400,
+	BlockGasLimit:      400 * core.DefaultTxGasLimit,
+	BlockEvidenceLimit: 10,
+	TxWaitTime:         1 * time.Second,
+	BeatTimeout:        500 * time.Millisecond,
+	BlockDelay:         40 * time.Millisecond, // maximum block rate = 25 blk per sec
+	ViewWidth:          30 * time.Second,
+	LeaderTimeout:      10 * time.Second,
+
+	StateSyncThreshold: 100,
+	StateChunkSize:     1000,
 }
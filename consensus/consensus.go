@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/aungmawjj/juria-blockchain/core"
+	"github.com/aungmawjj/juria-blockchain/emitter"
 	"github.com/aungmawjj/juria-blockchain/hotstuff"
 	"github.com/aungmawjj/juria-blockchain/logger"
 )
@@ -23,12 +24,19 @@ type Consensus struct {
 	validator *validator
 	pacemaker *pacemaker
 	rotator   *rotator
+
+	commitEmitter *emitter.Emitter
+	statusEmitter *emitter.Emitter
+
+	stopCh chan struct{}
 }
 
 func New(resources *Resources, config Config) *Consensus {
 	cons := &Consensus{
-		resources: resources,
-		config:    config,
+		resources:     resources,
+		config:        config,
+		commitEmitter: emitter.New(),
+		statusEmitter: emitter.New(),
 	}
 	return cons
 }
@@ -49,19 +57,40 @@ func (cons *Consensus) GetBlock(hash []byte) *core.Block {
 	return cons.state.getBlock(hash)
 }
 
+// SubscribeCommit emits *storage.CommitData for each commited block
+func (cons *Consensus) SubscribeCommit(buffer int) *emitter.Subscription {
+	return cons.commitEmitter.Subscribe(buffer)
+}
+
+// SubscribeStatus emits Status when a block is commited or a new qc is created
+func (cons *Consensus) SubscribeStatus(buffer int) *emitter.Subscription {
+	return cons.statusEmitter.Subscribe(buffer)
+}
+
 func (cons *Consensus) start() {
 	cons.startTime = time.Now().UnixNano()
+	if cons.config.StateSync {
+		cons.syncState()
+	}
+	loadValidatorEpochs(cons.resources)
 	b0, q0 := cons.getInitialBlockAndQC()
 	cons.setupState(b0)
 	cons.setupHsDriver()
 	cons.setupHotstuff(b0, q0)
+	cons.setupRotator(b0)
 	cons.setupValidator()
 	cons.setupPacemaker()
-	cons.setupRotator()
 
 	cons.validator.start()
-	cons.pacemaker.start()
+	if cons.config.Observer {
+		logger.I().Info("running as observer, not proposing or voting")
+	} else {
+		cons.pacemaker.start()
+	}
 	cons.rotator.start()
+
+	cons.stopCh = make(chan struct{})
+	go cons.emitStatusOnChange()
 }
 
 func (cons *Consensus) stop() {
@@ -71,12 +100,41 @@ func (cons *Consensus) stop() {
 	cons.pacemaker.stop()
 	cons.rotator.stop()
 	cons.validator.stop()
+	close(cons.stopCh)
+}
+
+func (cons *Consensus) emitStatusOnChange() {
+	subCommit := cons.commitEmitter.Subscribe(10)
+	defer subCommit.Unsubscribe()
+
+	subQC := cons.hotstuff.SubscribeNewQCHigh()
+	defer subQC.Unsubscribe()
+
+	for {
+		select {
+		case <-cons.stopCh:
+			return
+		case <-subCommit.Events():
+		case <-subQC.Events():
+		}
+		cons.statusEmitter.Emit(cons.getStatus())
+	}
 }
 
 func (cons *Consensus) setupState(b0 *core.Block) {
 	cons.state = newState(cons.resources)
 	cons.state.setBlock(b0)
-	cons.state.setLeaderIndex(cons.resources.VldStore.GetValidatorIndex(b0.Proposer()))
+}
+
+func (cons *Consensus) syncState() {
+	syncer := &stateSyncer{
+		resources:       cons.resources,
+		config:          cons.config,
+		anchorScanLimit: 20,
+		retryDelay:      1 * time.Second,
+		retryCount:      10,
+	}
+	syncer.run()
 }
 
 func (cons *Consensus) getInitialBlockAndQC() (*core.Block, *core.QuorumCert) {
@@ -92,16 +150,18 @@ func (cons *Consensus) getInitialBlockAndQC() (*core.Block, *core.QuorumCert) {
 	genesis := &genesis{
 		resources: cons.resources,
 		chainID:   cons.config.ChainID,
+		observer:  cons.config.Observer,
 	}
 	return genesis.run()
 }
 
 func (cons *Consensus) setupHsDriver() {
 	cons.hsDriver = &hsDriver{
-		resources:    cons.resources,
-		config:       cons.config,
-		checkTxDelay: 10 * time.Millisecond,
-		state:        cons.state,
+		resources:     cons.resources,
+		config:        cons.config,
+		checkTxDelay:  10 * time.Millisecond,
+		state:         cons.state,
+		commitEmitter: cons.commitEmitter,
 	}
 }
 
@@ -116,8 +176,10 @@ func (cons *Consensus) setupHotstuff(b0 *core.Block, q0 *core.QuorumCert) {
 func (cons *Consensus) setupValidator() {
 	cons.validator = &validator{
 		resources: cons.resources,
+		config:    cons.config,
 		state:     cons.state,
 		hotstuff:  cons.hotstuff,
+		rotator:   cons.rotator,
 	}
 }
 
@@ -130,13 +192,45 @@ func (cons *Consensus) setupPacemaker() {
 	}
 }
 
-func (cons *Consensus) setupRotator() {
+// setupRotator restores the view of the last commited block,
+// later views are caught up with the timeout certs of the proposals and timeouts
+func (cons *Consensus) setupRotator(b0 *core.Block) {
+	if cons.resources.LeaderElection == nil {
+		cons.resources.LeaderElection = NewLeaderElection(cons.resources, cons.config)
+	}
 	cons.rotator = &rotator{
 		resources: cons.resources,
 		config:    cons.config,
 		state:     cons.state,
 		hotstuff:  cons.hotstuff,
+		timeouts:  newTimeoutPool(),
+		viewCh:    make(chan struct{}, 1),
+	}
+	if blk := cons.firstBlockOfView(b0); blk != nil {
+		cons.state.moveView(blk.TimeoutCert(), cons.rotator.leaderOfView(blk.TimeoutCert(), blk.Height()))
+	} else {
+		cons.state.setLeaderIndex(cons.rotator.leaderOfView(nil, b0.Height()+1))
+	}
+}
+
+// firstBlockOfView gives the commited block carrying the timeout cert of the view of the block,
+// nil in the first view or if the block is pruned
+func (cons *Consensus) firstBlockOfView(blk *core.Block) *core.Block {
+	for blk.View() > 0 {
+		if blk.TimeoutCert() != nil {
+			return blk
+		}
+		if blk.IsGenesis() {
+			return nil
+		}
+		parent, err := cons.resources.Storage.GetBlockByHeight(blk.Height() - 1)
+		if err != nil {
+			logger.I().Warnf("cannot restore view %d, %+v", blk.View(), err)
+			return nil
+		}
+		blk = parent
 	}
+	return nil
 }
 
 func (cons *Consensus) getStatus() (status Status) {
@@ -147,6 +241,8 @@ func (cons *Consensus) getStatus() (status Status) {
 	status.CommitedTxCount = cons.state.getCommitedTxCount()
 	status.BlockPoolSize = cons.state.getBlockPoolSize()
 	status.QCPoolSize = cons.state.getQCPoolSize()
+	status.EvidencePoolSize = cons.state.evidence.getPendingCount()
+	status.View = cons.state.getView()
 	status.LeaderIndex = cons.state.getLeaderIndex()
 	status.ViewStart = cons.rotator.getViewStart()
 	status.PendingViewChange = cons.rotator.getPendingViewChange()
@@ -0,0 +1,28 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package consensus
+
+import (
+	"github.com/aungmawjj/juria-blockchain/core"
+	"github.com/aungmawjj/juria-blockchain/logger"
+)
+
+// loadValidatorEpochs adds the validator sets scheduled by governance chaincode to validator store.
+// It's called on startup and after each commit, before the new sets take effect.
+func loadValidatorEpochs(resources *Resources) {
+	epochs, err := resources.Execution.GetValidatorEpochs()
+	if err != nil {
+		logger.I().Errorf("get validator epochs failed, %+v", err)
+		return
+	}
+	for _, e := range epochs {
+		err := resources.VldStore.AddEpoch(e)
+		if err == nil {
+			logger.I().Infow("added validator epoch",
+				"start", e.StartHeight, "validators", len(e.Validators))
+		} else if err != core.ErrEpochExists {
+			logger.I().Errorf("add validator epoch failed, %+v", err)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package consensus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+	"sync"
+
+	"github.com/aungmawjj/juria-blockchain/core"
+)
+
+// signed proposals and votes are kept for this number of heights below the commited height
+const evidenceWindow = 20
+
+// evidencePool keeps the signed proposals and votes of recent heights to detect equivocation,
+// and the evidence to be included in the next proposals
+type evidencePool struct {
+	resources *Resources
+
+	window uint64
+
+	proposals map[string]*core.Block // by proposer, height and view
+	votes     map[string]*core.Vote  // by voter, height and view
+	pending   map[string]*core.Evidence
+	mtx       sync.Mutex
+}
+
+func newEvidencePool(resources *Resources, window uint64) *evidencePool {
+	return &evidencePool{
+		resources: resources,
+		window:    window,
+		proposals: make(map[string]*core.Block),
+		votes:     make(map[string]*core.Vote),
+		pending:   make(map[string]*core.Evidence),
+	}
+}
+
+// signedMsgKey identifies the signing slot, honest validators sign again at the same height
+// after the view is changed
+func signedMsgKey(signer *core.PublicKey, height, view uint64) string {
+	buf := bytes.NewBuffer(nil)
+	buf.Write(signer.Bytes())
+	binary.Write(buf, binary.BigEndian, height)
+	binary.Write(buf, binary.BigEndian, view)
+	return buf.String()
+}
+
+// addProposal returns the evidence if the proposer already proposed another block at the height and view
+func (pool *evidencePool) addProposal(blk *core.Block) *core.Evidence {
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+
+	key := signedMsgKey(blk.Proposer(), blk.Height(), blk.View())
+	prev, found := pool.proposals[key]
+	if !found {
+		pool.proposals[key] = blk
+		return nil
+	}
+	if bytes.Equal(prev.Hash(), blk.Hash()) {
+		return nil
+	}
+	return core.NewProposalEvidence(prev, blk)
+}
+
+// checkVote returns the previous vote if the voter already voted another block at the height and view
+func (pool *evidencePool) checkVote(vote *core.Vote) *core.Vote {
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+
+	key := signedMsgKey(vote.Voter(), vote.BlockHeight(), vote.View())
+	prev, found := pool.votes[key]
+	if !found {
+		pool.votes[key] = vote
+		return nil
+	}
+	if bytes.Equal(prev.BlockHash(), vote.BlockHash()) {
+		return nil
+	}
+	return prev
+}
+
+// addEvidence returns false if the evidence is already known
+func (pool *evidencePool) addEvidence(ev *core.Evidence) bool {
+	if pool.resources.Storage.HasEvidence(ev.ID()) {
+		return false
+	}
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+
+	if _, found := pool.pending[string(ev.ID())]; found {
+		return false
+	}
+	pool.pending[string(ev.ID())] = ev
+	return true
+}
+
+// pendingEvidence gives the evidence to be included in the next proposal, lower heights first
+func (pool *evidencePool) pendingEvidence(limit int) []*core.Evidence {
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+
+	ret := make([]*core.Evidence, 0, len(pool.pending))
+	for _, ev := range pool.pending {
+		ret = append(ret, ev)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Height() != ret[j].Height() {
+			return ret[i].Height() < ret[j].Height()
+		}
+		return bytes.Compare(ret[i].ID(), ret[j].ID()) < 0
+	})
+	if len(ret) > limit {
+		ret = ret[:limit]
+	}
+	return ret
+}
+
+func (pool *evidencePool) getPendingCount() int {
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+	return len(pool.pending)
+}
+
+// onCommit removes the commited evidence and the signed messages older than the window
+func (pool *evidencePool) onCommit(blk *core.Block) {
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+
+	for _, ev := range blk.Evidence() {
+		delete(pool.pending, string(ev.ID()))
+	}
+	if blk.Height() < pool.window {
+		return
+	}
+	height := blk.Height() - pool.window
+	for key, prop := range pool.proposals {
+		if prop.Height() < height {
+			delete(pool.proposals, key)
+		}
+	}
+	for key, vote := range pool.votes {
+		if vote.BlockHeight() < height {
+			delete(pool.votes, key)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package consensus
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aungmawjj/juria-blockchain/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// newTestProposal creates a block with the qc signed by the proposer and the given voters
+func newTestProposal(height uint64, ts int64, priv core.Signer, voters ...core.Signer) *core.Block {
+	parent := core.NewBlock().SetHeight(height - 1).Sign(priv)
+	votes := []*core.Vote{parent.Vote(priv)}
+	for _, voter := range voters {
+		votes = append(votes, parent.Vote(voter))
+	}
+	qc := core.NewQuorumCert().Build(votes)
+	return core.NewBlock().SetHeight(height).SetTimestamp(ts).SetQuorumCert(qc).Sign(priv)
+}
+
+func TestEvidencePool(t *testing.T) {
+	assert := assert.New(t)
+
+	priv0, priv1 := core.GenerateKey(nil), core.GenerateKey(nil)
+	mStrg := new(MockStorage)
+	mStrg.On("HasEvidence", mock.Anything).Return(false)
+	pool := newEvidencePool(&Resources{Storage: mStrg}, 2)
+
+	blk5 := newTestProposal(5, 1, priv0)
+	assert.Nil(pool.addProposal(blk5))
+	assert.Nil(pool.addProposal(blk5), "same proposal")
+	assert.Nil(pool.addProposal(newTestProposal(5, 1, priv1)), "different proposer")
+	tc := core.NewTimeoutCert().Build([]*core.Timeout{core.NewTimeout().SetView(0).Sign(priv1)})
+	blk5v1 := newTestProposal(5, 2, priv0)
+	blk5v1.SetTimeoutCert(tc).Sign(priv0)
+	assert.Nil(pool.addProposal(blk5v1), "different view")
+	evProp := pool.addProposal(newTestProposal(5, 2, priv0))
+	if assert.NotNil(evProp) {
+		assert.Equal(priv0.PublicKey(), evProp.Accused())
+		assert.EqualValues(5, evProp.Height())
+	}
+
+	blk5b := newTestProposal(5, 2, priv0)
+	assert.Nil(pool.checkVote(blk5.Vote(priv1)))
+	assert.Nil(pool.checkVote(blk5.Vote(priv1)), "same vote")
+	assert.Nil(pool.checkVote(blk5v1.Vote(priv1)), "vote in different view")
+	assert.Equal(blk5.Hash(), pool.checkVote(blk5b.Vote(priv1)).BlockHash())
+
+	blk3 := newTestProposal(3, 1, priv1)
+	evVote := core.NewVoteEvidence(blk3.Vote(priv0), newTestProposal(3, 2, priv1).Vote(priv0),
+		blk3, newTestProposal(3, 2, priv1))
+
+	assert.True(pool.addEvidence(evProp))
+	assert.False(pool.addEvidence(evProp), "already added")
+	assert.True(pool.addEvidence(evVote))
+	assert.Equal(2, pool.getPendingCount())
+
+	evList := pool.pendingEvidence(10)
+	if assert.Equal(2, len(evList)) {
+		assert.Equal(evVote, evList[0], "lower height first")
+	}
+	assert.Equal(1, len(pool.pendingEvidence(1)))
+
+	bcm := core.NewBlock().SetHeight(8).SetEvidence([]*core.Evidence{evVote}).Sign(priv0)
+	pool.onCommit(bcm)
+	assert.Equal([]*core.Evidence{evProp}, pool.pendingEvidence(10), "should remove commited evidence")
+	assert.Nil(pool.checkVote(blk5b.Vote(priv1)), "should remove old votes")
+	assert.Nil(pool.addProposal(newTestProposal(5, 3, priv0)), "should remove old proposals")
+
+	mStrg = new(MockStorage)
+	mStrg.On("HasEvidence", evProp.ID()).Return(true)
+	pool = newEvidencePool(&Resources{Storage: mStrg}, 2)
+	assert.False(pool.addEvidence(evProp), "already commited")
+}
+
+func TestValidator_addEvidence(t *testing.T) {
+	assert := assert.New(t)
+
+	priv0, priv1 := core.GenerateKey(nil), core.GenerateKey(nil)
+	mStrg := new(MockStorage)
+	mStrg.On("HasEvidence", mock.Anything).Return(false)
+	mMsgSvc := new(MockMsgService)
+	resources := &Resources{
+		VldStore: core.NewValidatorStore([]*core.PublicKey{
+			priv0.PublicKey(), priv1.PublicKey(),
+		}),
+		Storage: mStrg,
+		MsgSvc:  mMsgSvc,
+	}
+	vld := &validator{resources: resources, state: newState(resources)}
+
+	ev := core.NewProposalEvidence(newTestProposal(5, 1, priv0), newTestProposal(5, 2, priv0))
+	mMsgSvc.On("BroadcastEvidence", ev).Return(nil).Once()
+
+	assert.NoError(vld.addEvidence(ev))
+	assert.NoError(vld.addEvidence(ev), "should not broadcast known evidence")
+	mMsgSvc.AssertExpectations(t)
+	assert.Equal(1, vld.state.evidence.getPendingCount())
+
+	invalid := core.NewProposalEvidence(newTestProposal(5, 1, priv0), newTestProposal(5, 1, priv0))
+	assert.Error(vld.addEvidence(invalid))
+
+	priv2 := core.GenerateKey(nil)
+	notValidator := core.NewProposalEvidence(newTestProposal(5, 1, priv2), newTestProposal(5, 2, priv2))
+	assert.Error(vld.addEvidence(notValidator))
+	assert.Equal(1, vld.state.evidence.getPendingCount())
+}
+
+func TestValidator_onDoubleVote(t *testing.T) {
+	assert := assert.New(t)
+
+	priv0, priv1 := core.GenerateKey(nil), core.GenerateKey(nil)
+	mStrg := new(MockStorage)
+	mStrg.On("HasEvidence", mock.Anything).Return(false)
+	mMsgSvc := new(MockMsgService)
+	resources := &Resources{
+		VldStore: core.NewValidatorStore([]*core.PublicKey{
+			priv0.PublicKey(), priv1.PublicKey(),
+		}),
+		Storage: mStrg,
+		MsgSvc:  mMsgSvc,
+	}
+	vld := &validator{resources: resources, state: newState(resources)}
+
+	blkA := newTestProposal(5, 1, priv0, priv1)
+	blkB := newTestProposal(5, 2, priv0, priv1)
+	vld.state.setBlock(blkA)
+	mStrg.On("GetBlock", blkB.Hash()).Return(nil, errors.New("not found"))
+	// voted block which is not in the state is requested from the voter
+	mMsgSvc.On("RequestBlock", priv1.PublicKey(), blkB.Hash()).Return(blkB, nil)
+	mMsgSvc.On("BroadcastEvidence", mock.Anything).Return(nil).Once()
+
+	assert.NoError(vld.onDoubleVote(blkA.Vote(priv1), blkB.Vote(priv1)))
+	mMsgSvc.AssertExpectations(t)
+
+	evList := vld.state.evidence.pendingEvidence(10)
+	if assert.Equal(1, len(evList)) {
+		assert.True(evList[0].IsVote())
+		assert.Equal(priv1.PublicKey(), evList[0].Accused())
+	}
+}
@@ -20,6 +20,7 @@ import (
 type genesis struct {
 	resources *Resources
 	chainID   int64
+	observer  bool
 
 	done chan struct{}
 
@@ -68,7 +69,7 @@ func (gns *genesis) propose() {
 	if !gns.isLeader(gns.resources.Signer.PublicKey()) {
 		return
 	}
-	gns.votes = make(map[string]*core.Vote, gns.resources.VldStore.MajorityCount())
+	gns.votes = make(map[string]*core.Vote, gns.resources.VldStore.AtHeight(0).MajorityCount())
 	b0 := gns.createGenesisBlock()
 	gns.setB0(b0)
 	logger.I().Infow("created genesis block, broadcasting...")
@@ -101,10 +102,11 @@ func (gns *genesis) broadcastProposalLoop() {
 }
 
 func (gns *genesis) isLeader(pubKey *core.PublicKey) bool {
-	if !gns.resources.VldStore.IsValidator(pubKey) {
+	vset := gns.resources.VldStore.AtHeight(0)
+	if !vset.IsValidator(pubKey) {
 		return false
 	}
-	return gns.resources.VldStore.GetValidatorIndex(pubKey) == 0
+	return vset.GetValidatorIndex(pubKey) == 0
 }
 
 func hashChainID(chainID int64) []byte {
@@ -182,6 +184,10 @@ func (gns *genesis) onReceiveProposal(proposal *core.Block) error {
 		return fmt.Errorf("genesis block with txs")
 	}
 	gns.setB0(proposal)
+	if gns.observer {
+		logger.I().Infow("got genesis block, waiting qc...")
+		return nil
+	}
 	logger.I().Infow("got genesis block, voting...")
 	return gns.resources.MsgSvc.SendVote(proposal.Proposer(), proposal.Vote(gns.resources.Signer))
 }
@@ -234,14 +240,19 @@ func (gns *genesis) acceptVote(vote *core.Vote) {
 	defer gns.mtxVote.Unlock()
 
 	gns.votes[vote.Voter().String()] = vote
-	if len(gns.votes) < gns.resources.VldStore.MajorityCount() {
+	vset := gns.resources.VldStore.AtHeight(0)
+	var power uint64
+	for _, v := range gns.votes {
+		power += votingPower(vset, v.Voter())
+	}
+	if power < vset.MajorityPower() {
 		return
 	}
 	vlist := make([]*core.Vote, 0, len(gns.votes))
 	for _, vote := range gns.votes {
 		vlist = append(vlist, vote)
 	}
-	gns.setQ0(core.NewQuorumCert().Build(vlist))
+	gns.setQ0(buildQC(vlist, vset))
 	logger.I().Infow("created qc, broadcasting...")
 	gns.broadcastQC()
 }
@@ -286,7 +297,7 @@ func (gns *genesis) acceptQC(qc *core.QuorumCert) {
 	}
 	b0 := gns.getB0()
 	gns.setQ0(qc)
-	if !gns.isLeader(gns.resources.Signer.PublicKey()) {
+	if !gns.observer && !gns.isLeader(gns.resources.Signer.PublicKey()) {
 		gns.resources.MsgSvc.SendNewView(b0.Proposer(), qc)
 	}
 	close(gns.done) // when qc is accepted, genesis creation is done
@@ -4,9 +4,11 @@
 package consensus
 
 import (
+	"sync/atomic"
 	"time"
 
 	"github.com/aungmawjj/juria-blockchain/core"
+	"github.com/aungmawjj/juria-blockchain/emitter"
 	"github.com/aungmawjj/juria-blockchain/hotstuff"
 	"github.com/aungmawjj/juria-blockchain/logger"
 	"github.com/aungmawjj/juria-blockchain/storage"
@@ -19,12 +21,34 @@ type hsDriver struct {
 	checkTxDelay time.Duration
 
 	state *state
+
+	// height of the last proposal created by this node
+	proposalHeight uint64
+
+	// emits *storage.CommitData after a block is commited to storage
+	commitEmitter *emitter.Emitter
 }
 
 var _ hotstuff.Driver = (*hsDriver)(nil)
 
-func (hsd *hsDriver) MajorityCount() int {
-	return hsd.resources.VldStore.MajorityCount()
+// MajorityPower gives the voting power required for a qc in the validator set of the current proposal
+func (hsd *hsDriver) MajorityPower() uint64 {
+	height := atomic.LoadUint64(&hsd.proposalHeight)
+	return hsd.state.getValidators(height).MajorityPower()
+}
+
+// VotePower gives the voting power of the voter in the validator set of the current proposal
+func (hsd *hsDriver) VotePower(hsv hotstuff.Vote) uint64 {
+	height := atomic.LoadUint64(&hsd.proposalHeight)
+	return votingPower(hsd.state.getValidators(height), hsv.(*hsVote).vote.Voter())
+}
+
+// votingPower gives zero if the voter is not a validator
+func votingPower(vset core.ValidatorSet, voter *core.PublicKey) uint64 {
+	if !vset.IsValidator(voter) {
+		return 0
+	}
+	return vset.GetPower(vset.GetValidatorIndex(voter))
 }
 
 func (hsd *hsDriver) CreateLeaf(parent hotstuff.Block, qc hotstuff.QC, height uint64) hotstuff.Block {
@@ -32,12 +56,22 @@ func (hsd *hsDriver) CreateLeaf(parent hotstuff.Block, qc hotstuff.QC, height ui
 		SetParentHash(parent.(*hsBlock).block.Hash()).
 		SetQuorumCert(qc.(*hsQC).qc).
 		SetHeight(height).
-		SetTransactions(hsd.resources.TxPool.PopTxsFromQueue(hsd.config.BlockTxLimit)).
+		SetTransactions(hsd.resources.TxPool.PopTxsFromQueue(
+			hsd.config.BlockTxLimit, hsd.config.BlockGasLimit)).
 		SetExecHeight(hsd.resources.Storage.GetBlockHeight()).
 		SetMerkleRoot(hsd.resources.Storage.GetMerkleRoot()).
 		SetTimestamp(time.Now().UnixNano()).
-		Sign(hsd.resources.Signer)
+		SetEvidence(hsd.state.evidence.pendingEvidence(hsd.config.BlockEvidenceLimit))
+	view, tc := hsd.state.getViewTC()
+	blk.SetView(view)
+	if tc != nil && parent.(*hsBlock).block.View() != view {
+		// proof of the view for the validators who missed the timeouts,
+		// only the first block of the view carries it and the later ones are linked to it
+		blk.SetTimeoutCert(tc)
+	}
+	blk.Sign(hsd.resources.Signer)
 
+	atomic.StoreUint64(&hsd.proposalHeight, height)
 	hsd.state.setBlock(blk)
 	return newHsBlock(blk, hsd.state)
 }
@@ -47,10 +81,19 @@ func (hsd *hsDriver) CreateQC(hsVotes []hotstuff.Vote) hotstuff.QC {
 	for i, hsv := range hsVotes {
 		votes[i] = hsv.(*hsVote).vote
 	}
-	qc := core.NewQuorumCert().Build(votes)
+	qc := buildQC(votes, hsd.state.getValidators(votes[0].BlockHeight()))
 	return newHsQC(qc, hsd.state)
 }
 
+// buildQC aggregates the bls signatures of the votes if all voters have bls keys,
+// otherwise the qc keeps the signatures of the votes
+func buildQC(votes []*core.Vote, vset core.ValidatorSet) *core.QuorumCert {
+	if qc, err := core.NewQuorumCert().BuildAggregate(votes, vset); err == nil {
+		return qc
+	}
+	return core.NewQuorumCert().Build(votes)
+}
+
 func (hsd *hsDriver) BroadcastProposal(hsBlk hotstuff.Block) {
 	blk := hsBlk.(*hsBlock).block
 	hsd.resources.MsgSvc.BroadcastProposal(blk)
@@ -61,8 +104,8 @@ func (hsd *hsDriver) VoteBlock(hsBlk hotstuff.Block) {
 	vote := blk.Vote(hsd.resources.Signer)
 	hsd.resources.TxPool.SetTxsPending(blk.Transactions())
 	hsd.delayVoteWhenNoTxs()
-	proposer := hsd.resources.VldStore.GetValidatorIndex(blk.Proposer())
-	if proposer != hsd.state.getLeaderIndex() {
+	proposer := hsd.state.getValidators(blk.Height()).GetValidatorIndex(blk.Proposer())
+	if proposer != hsd.state.getLeaderIndex() || blk.View() != hsd.state.getView() {
 		return // view changed happened
 	}
 	hsd.resources.MsgSvc.SendVote(blk.Proposer(), vote)
@@ -103,12 +146,21 @@ func (hsd *hsDriver) Commit(hsBlk hotstuff.Block) {
 	if err != nil {
 		logger.I().Fatalf("commit storage error: %+v", err)
 	}
+	loadValidatorEpochs(hsd.resources)
 	hsd.state.addCommitedTxCount(len(txs))
 	hsd.cleanStateOnCommited(bexe)
+	metricBlockCommit.Observe(time.Since(start).Seconds())
+	metricBlockExec.Observe(bcm.ElapsedExec())
+	metricBlockMerkle.Observe(bcm.ElapsedMerkle())
+	metricCommitedBlocks.Inc()
+	metricCommitedTxs.Add(float64(len(txs)))
 	logger.I().Debugw("commited bock",
 		"height", bexe.Height(),
 		"txs", len(txs),
 		"elapsed", time.Since(start))
+	if hsd.commitEmitter != nil {
+		hsd.commitEmitter.Emit(data)
+	}
 }
 
 func (hsd *hsDriver) cleanStateOnCommited(bexec *core.Block) {
@@ -116,6 +168,7 @@ func (hsd *hsDriver) cleanStateOnCommited(bexec *core.Block) {
 	hsd.state.deleteQC(bexec.Hash())
 	hsd.resources.TxPool.RemoveTxs(bexec.Transactions())
 	hsd.state.setCommitedBlock(bexec)
+	hsd.state.evidence.onCommit(bexec)
 
 	folks := hsd.state.getUncommitedOlderBlocks(bexec)
 	for _, blk := range folks {
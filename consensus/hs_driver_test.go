@@ -8,10 +8,12 @@ import (
 	"time"
 
 	"github.com/aungmawjj/juria-blockchain/core"
+	"github.com/aungmawjj/juria-blockchain/emitter"
 	"github.com/aungmawjj/juria-blockchain/hotstuff"
 	"github.com/aungmawjj/juria-blockchain/storage"
 	"github.com/aungmawjj/juria-blockchain/txpool"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func setupTestHsDriver() *hsDriver {
@@ -26,7 +28,7 @@ func setupTestHsDriver() *hsDriver {
 	}
 }
 
-func TestHsDriver_TestMajorityCount(t *testing.T) {
+func TestHsDriver_TestMajorityPower(t *testing.T) {
 	hsd := setupTestHsDriver()
 	hsd.resources.VldStore = core.NewValidatorStore([]*core.PublicKey{
 		core.GenerateKey(nil).PublicKey(),
@@ -35,10 +37,40 @@ func TestHsDriver_TestMajorityCount(t *testing.T) {
 		core.GenerateKey(nil).PublicKey(),
 	})
 
-	res := hsd.MajorityCount()
+	res := hsd.MajorityPower()
 
 	assert := assert.New(t)
-	assert.Equal(hsd.resources.VldStore.MajorityCount(), res)
+	assert.Equal(hsd.resources.VldStore.MajorityPower(), res)
+
+	// majority of validator set at the proposal height
+	hsd.resources.VldStore.AddEpoch(&core.ValidatorEpoch{
+		StartHeight: 10,
+		Validators:  []*core.PublicKey{core.GenerateKey(nil).PublicKey()},
+		Powers:      []uint64{10},
+	})
+	assert.EqualValues(3, hsd.MajorityPower())
+	hsd.proposalHeight = 10
+	assert.EqualValues(7, hsd.MajorityPower())
+}
+
+func TestHsDriver_VotePower(t *testing.T) {
+	hsd := setupTestHsDriver()
+	priv0 := core.GenerateKey(nil)
+	priv1 := core.GenerateKey(nil)
+	vs, err := core.NewGenesisValidatorStore(&core.ValidatorEpoch{
+		Validators: []*core.PublicKey{priv0.PublicKey(), priv1.PublicKey()},
+		Powers:     []uint64{3, 5},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	hsd.resources.VldStore = vs
+	blk := core.NewBlock().Sign(priv0)
+
+	assert := assert.New(t)
+	assert.EqualValues(3, hsd.VotePower(newHsVote(blk.Vote(priv0), hsd.state)))
+	assert.EqualValues(5, hsd.VotePower(newHsVote(blk.Vote(priv1), hsd.state)))
+	assert.EqualValues(0, hsd.VotePower(newHsVote(blk.Vote(core.GenerateKey(nil)), hsd.state)))
 }
 
 func TestHsDriver_CreateLeaf(t *testing.T) {
@@ -50,7 +82,7 @@ func TestHsDriver_CreateLeaf(t *testing.T) {
 
 	txsInQ := [][]byte{[]byte("tx1"), []byte("tx2")}
 	txPool := new(MockTxPool)
-	txPool.On("PopTxsFromQueue", hsd.config.BlockTxLimit).Return(txsInQ)
+	txPool.On("PopTxsFromQueue", hsd.config.BlockTxLimit, hsd.config.BlockGasLimit).Return(txsInQ)
 	hsd.resources.TxPool = txPool
 
 	storage := new(MockStorage)
@@ -78,6 +110,34 @@ func TestHsDriver_CreateLeaf(t *testing.T) {
 	assert.NotNil(hsd.state.getBlock(blk.Hash()), "should store leaf block in state")
 }
 
+func TestHsDriver_CreateLeaf_TimeoutCert(t *testing.T) {
+	assert := assert.New(t)
+
+	hsd := setupTestHsDriver()
+	parent := newHsBlock(core.NewBlock().Sign(hsd.resources.Signer), hsd.state)
+	hsd.state.setBlock(parent.(*hsBlock).block)
+	qc := newHsQC(core.NewQuorumCert(), hsd.state)
+
+	txPool := new(MockTxPool)
+	txPool.On("PopTxsFromQueue", mock.Anything, mock.Anything).Return(nil)
+	hsd.resources.TxPool = txPool
+	storage := new(MockStorage)
+	storage.On("GetBlockHeight").Return(0)
+	storage.On("GetMerkleRoot").Return(nil)
+	hsd.resources.Storage = storage
+
+	tc := newTestTimeoutCert(2, hsd.resources.Signer.(*core.PrivateKey))
+	hsd.state.moveView(tc, 0)
+
+	first := hsd.CreateLeaf(parent, qc, 1)
+	assert.EqualValues(3, first.(*hsBlock).block.View())
+	assert.Equal(tc, first.(*hsBlock).block.TimeoutCert(), "first block of the view carries the timeout cert")
+
+	second := hsd.CreateLeaf(first, qc, 2)
+	assert.EqualValues(3, second.(*hsBlock).block.View())
+	assert.Nil(second.(*hsBlock).block.TimeoutCert(), "later blocks of the view are linked to the first one")
+}
+
 func TestHsDriver_VoteBlock(t *testing.T) {
 	hsd := setupTestHsDriver()
 	hsd.checkTxDelay = time.Millisecond
@@ -149,7 +209,13 @@ func TestHsDriver_Commit(t *testing.T) {
 	txcs := []*core.TxCommit{core.NewTxCommit().SetHash(tx.Hash())}
 	execution := new(MockExecution)
 	execution.On("Execute", bexec, txs).Return(bcm, txcs)
+	// should load new validator sets after commit
+	newValidators := []*core.PublicKey{core.GenerateKey(nil).PublicKey()}
+	execution.On("GetValidatorEpochs").Return([]*core.ValidatorEpoch{
+		{StartHeight: 31, Validators: newValidators},
+	}, nil).Once()
 	hsd.resources.Execution = execution
+	hsd.resources.VldStore = core.NewValidatorStore([]*core.PublicKey{hsd.resources.Signer.PublicKey()})
 
 	cdata := &storage.CommitData{
 		Block:        bexec,
@@ -161,6 +227,10 @@ func TestHsDriver_Commit(t *testing.T) {
 	storage.On("Commit", cdata).Return(nil)
 	hsd.resources.Storage = storage
 
+	hsd.commitEmitter = emitter.New()
+	sub := hsd.commitEmitter.Subscribe(1)
+	defer sub.Unsubscribe()
+
 	hsd.Commit(newHsBlock(bexec, hsd.state))
 
 	txPool.AssertExpectations(t)
@@ -170,12 +240,22 @@ func TestHsDriver_Commit(t *testing.T) {
 	assert := assert.New(t)
 	assert.NotNil(hsd.state.getBlockFromState(bexec.Hash()),
 		"should not delete bexec from state")
+	assert.True(hsd.resources.VldStore.AtHeight(31).IsValidator(newValidators[0]))
+	assert.False(hsd.resources.VldStore.AtHeight(30).IsValidator(newValidators[0]))
 	assert.Nil(hsd.state.getBlockFromState(bfolk.Hash()),
 		"should delete folked block from state")
+
+	select {
+	case e := <-sub.Events():
+		assert.Equal(cdata, e, "should emit commit data")
+	default:
+		assert.Fail("should emit commit data")
+	}
 }
 
 func TestHsDriver_CreateQC(t *testing.T) {
 	hsd := setupTestHsDriver()
+	hsd.resources.VldStore = core.NewValidatorStore([]*core.PublicKey{hsd.resources.Signer.PublicKey()})
 	blk := core.NewBlock().Sign(hsd.resources.Signer)
 	hsd.state.setBlock(blk)
 	votes := []hotstuff.Vote{
@@ -188,6 +268,35 @@ func TestHsDriver_CreateQC(t *testing.T) {
 	assert.Equal(blk, qc.Block().(*hsBlock).block, "should get qc reference block")
 }
 
+func TestHsDriver_CreateQCAggregate(t *testing.T) {
+	hsd := setupTestHsDriver()
+	bls0, bls1 := core.GenerateBLSKey(nil), core.GenerateBLSKey(nil)
+	signer0 := core.NewBLSSigner(core.GenerateKey(nil), bls0)
+	signer1 := core.NewBLSSigner(core.GenerateKey(nil), bls1)
+	hsd.resources.Signer = signer0
+	hsd.resources.VldStore = core.NewBLSValidatorStore(
+		[]*core.PublicKey{signer0.PublicKey(), signer1.PublicKey()},
+		[]*core.BLSPublicKey{bls0.PublicKey(), bls1.PublicKey()},
+	)
+	blk := core.NewBlock().SetHeight(1).Sign(hsd.resources.Signer)
+	hsd.state.setBlock(blk)
+	votes := []hotstuff.Vote{
+		newHsVote(blk.ProposerVote(), hsd.state),
+		newHsVote(blk.Vote(signer1), hsd.state),
+	}
+	qc := hsd.CreateQC(votes).(*hsQC).qc
+
+	assert := assert.New(t)
+	assert.True(qc.IsAggregate())
+	assert.NoError(qc.Validate(hsd.resources.VldStore))
+
+	// voter without bls key
+	votes[1] = newHsVote(blk.Vote(core.GenerateKey(nil)), hsd.state)
+	qc = hsd.CreateQC(votes).(*hsQC).qc
+	assert.False(qc.IsAggregate())
+	assert.Equal(2, len(qc.Signatures()))
+}
+
 func TestHsDriver_BroadcastProposal(t *testing.T) {
 	hsd := setupTestHsDriver()
 	blk := core.NewBlock().Sign(hsd.resources.Signer)
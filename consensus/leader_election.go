@@ -0,0 +1,148 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package consensus
+
+import (
+	"sync"
+
+	"github.com/aungmawjj/juria-blockchain/core"
+)
+
+// LeaderElection selects the leader of the next view when the view changes.
+// The selection must only depend on commited chain data so that all validators agree.
+type LeaderElection interface {
+	// NextLeader gives the index of the leader after the current leader
+	// in the validator set of the given block height.
+	// The commited chain data is read up to the commitHeight agreed by the validators for the view.
+	NextLeader(current int, height, commitHeight uint64) int
+}
+
+// NewLeaderElection creates the leader election for the config,
+// reputation based if LeaderReputationWindow is set, otherwise round robin
+func NewLeaderElection(resources *Resources, config Config) LeaderElection {
+	if config.LeaderReputationWindow > 0 {
+		return newReputationElection(resources, config.LeaderReputationWindow)
+	}
+	return &roundRobinElection{resources}
+}
+
+type roundRobinElection struct {
+	resources *Resources
+}
+
+var _ LeaderElection = (*roundRobinElection)(nil)
+
+func (rr *roundRobinElection) NextLeader(current int, height, commitHeight uint64) int {
+	leaderIdx := current + 1
+	if leaderIdx >= rr.resources.VldStore.AtHeight(height).ValidatorCount() {
+		leaderIdx = 0
+	}
+	return leaderIdx
+}
+
+// reputationElection rotates the leader round robin but skips the validators
+// who neither proposed nor voted any block in the recent commited blocks.
+// The window of blocks ends at the agreed commited height rounded down to the window size.
+// A validator which has not commited the window yet falls back to round robin until it catches up.
+// Skipping is only applied if the active validators have the majority power,
+// then at least one of them is honest and the chain can make progress.
+type reputationElection struct {
+	resources *Resources
+	window    uint64
+
+	anchor uint64              // end height of the window
+	active map[string]struct{} // public keys of active validators in the window
+	mtx    sync.Mutex
+}
+
+var _ LeaderElection = (*reputationElection)(nil)
+
+func newReputationElection(resources *Resources, window uint64) *reputationElection {
+	return &reputationElection{
+		resources: resources,
+		window:    window,
+	}
+}
+
+func (re *reputationElection) NextLeader(current int, height, commitHeight uint64) int {
+	vset := re.resources.VldStore.AtHeight(height)
+	count := vset.ValidatorCount()
+	active := re.getActiveValidators(commitHeight)
+	if activePower(vset, active) < vset.MajorityPower() {
+		return (current + 1) % count // not enough commited blocks or active validators
+	}
+	for i := 1; i < count; i++ {
+		idx := (current + i) % count
+		if _, found := active[vset.GetValidator(idx).String()]; found {
+			return idx
+		}
+	}
+	return current
+}
+
+func activePower(vset core.ValidatorSet, active map[string]struct{}) uint64 {
+	var power uint64
+	for i := 0; i < vset.ValidatorCount(); i++ {
+		if _, found := active[vset.GetValidator(i).String()]; found {
+			power += vset.GetPower(i)
+		}
+	}
+	return power
+}
+
+func (re *reputationElection) getActiveValidators(commitHeight uint64) map[string]struct{} {
+	re.mtx.Lock()
+	defer re.mtx.Unlock()
+
+	anchor := commitHeight / re.window * re.window
+	if anchor == 0 {
+		return nil
+	}
+	if anchor == re.anchor {
+		return re.active
+	}
+	active, err := re.loadActiveValidators(anchor)
+	if err != nil {
+		return nil // not commited yet or not available after state sync, try again on next view change
+	}
+	re.anchor = anchor
+	re.active = active
+	return active
+}
+
+func (re *reputationElection) loadActiveValidators(anchor uint64) (map[string]struct{}, error) {
+	active := make(map[string]struct{})
+	for height := anchor - re.window + 1; height <= anchor; height++ {
+		blk, err := re.resources.Storage.GetBlockByHeight(height)
+		if err != nil {
+			return nil, err
+		}
+		active[blk.Proposer().String()] = struct{}{}
+		for _, signer := range re.qcSigners(blk.QuorumCert()) {
+			active[signer.String()] = struct{}{}
+		}
+	}
+	return active, nil
+}
+
+func (re *reputationElection) qcSigners(qc *core.QuorumCert) []*core.PublicKey {
+	if qc == nil {
+		return nil
+	}
+	if !qc.IsAggregate() {
+		signers := make([]*core.PublicKey, len(qc.Signatures()))
+		for i, sig := range qc.Signatures() {
+			signers[i] = sig.PublicKey()
+		}
+		return signers
+	}
+	vset := re.resources.VldStore.AtHeight(qc.BlockHeight())
+	signers := make([]*core.PublicKey, 0)
+	for _, idx := range qc.SignerIndexes() {
+		if pubKey := vset.GetValidator(idx); pubKey != nil {
+			signers = append(signers, pubKey)
+		}
+	}
+	return signers
+}
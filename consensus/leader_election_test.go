@@ -0,0 +1,116 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package consensus
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aungmawjj/juria-blockchain/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoundRobinElection(t *testing.T) {
+	assert := assert.New(t)
+
+	resources := &Resources{
+		VldStore: core.NewValidatorStore([]*core.PublicKey{
+			core.GenerateKey(nil).PublicKey(),
+			core.GenerateKey(nil).PublicKey(),
+			core.GenerateKey(nil).PublicKey(),
+		}),
+	}
+	election := NewLeaderElection(resources, DefaultConfig)
+	assert.Equal(1, election.NextLeader(0, 1, 0))
+	assert.Equal(0, election.NextLeader(2, 1, 0))
+
+	// leader from the validator set of the height
+	resources.VldStore.AddEpoch(&core.ValidatorEpoch{
+		StartHeight: 10,
+		Validators:  []*core.PublicKey{core.GenerateKey(nil).PublicKey()},
+	})
+	assert.Equal(0, election.NextLeader(0, 10, 0))
+}
+
+func TestReputationElection(t *testing.T) {
+	assert := assert.New(t)
+
+	privs := make([]*core.PrivateKey, 4)
+	vlds := make([]*core.PublicKey, len(privs))
+	for i := range privs {
+		privs[i] = core.GenerateKey(nil)
+		vlds[i] = privs[i].PublicKey()
+	}
+	mStrg := new(MockStorage)
+	resources := &Resources{
+		VldStore: core.NewValidatorStore(vlds),
+		Storage:  mStrg,
+	}
+	config := DefaultConfig
+	config.LeaderReputationWindow = 4
+	election := NewLeaderElection(resources, config)
+
+	// blocks 1 to 4 are proposed by validator 0 and voted by validator 0 and 1
+	for height := uint64(1); height <= 4; height++ {
+		parent := core.NewBlock().SetHeight(height - 1).Sign(privs[0])
+		qc := core.NewQuorumCert().Build(
+			[]*core.Vote{parent.Vote(privs[0]), parent.Vote(privs[1])})
+		blk := core.NewBlock().SetHeight(height).SetQuorumCert(qc).Sign(privs[0])
+		mStrg.On("GetBlockByHeight", height).Return(blk, nil)
+	}
+
+	assert.Equal(2, election.NextLeader(1, 5, 3), "not enough blocks, round robin")
+	assert.Equal(2, election.NextLeader(1, 5, 6), "active validators are not majority, round robin")
+
+	// block 8 is also voted by validator 3
+	for height := uint64(5); height <= 8; height++ {
+		parent := core.NewBlock().SetHeight(height - 1).Sign(privs[0])
+		votes := []*core.Vote{parent.Vote(privs[0]), parent.Vote(privs[1])}
+		if height == 8 {
+			votes = append(votes, parent.Vote(privs[3]))
+		}
+		qc := core.NewQuorumCert().Build(votes)
+		blk := core.NewBlock().SetHeight(height).SetQuorumCert(qc).Sign(privs[0])
+		mStrg.On("GetBlockByHeight", height).Return(blk, nil)
+	}
+	assert.Equal(3, election.NextLeader(1, 10, 9), "should skip inactive validator 2")
+	assert.Equal(0, election.NextLeader(3, 10, 9))
+	assert.Equal(1, election.NextLeader(0, 10, 9))
+	mStrg.AssertNumberOfCalls(t, "GetBlockByHeight", 8)
+
+	mStrg.On("GetBlockByHeight", uint64(9)).Return(nil, errors.New("not found"))
+	assert.Equal(2, election.NextLeader(1, 10, 12), "window is not commited, round robin")
+}
+
+func TestReputationElection_Aggregate(t *testing.T) {
+	assert := assert.New(t)
+
+	privs := make([]*core.PrivateKey, 4)
+	vlds := make([]*core.PublicKey, len(privs))
+	blsPrivs := make([]*core.BLSPrivateKey, len(privs))
+	blsKeys := make([]*core.BLSPublicKey, len(privs))
+	for i := range privs {
+		privs[i] = core.GenerateKey(nil)
+		vlds[i] = privs[i].PublicKey()
+		blsPrivs[i] = core.GenerateBLSKey(nil)
+		blsKeys[i] = blsPrivs[i].PublicKey()
+	}
+	vs := core.NewBLSValidatorStore(vlds, blsKeys)
+	mStrg := new(MockStorage)
+	resources := &Resources{VldStore: vs, Storage: mStrg}
+	election := newReputationElection(resources, 2)
+
+	for height := uint64(1); height <= 2; height++ {
+		parent := core.NewBlock().SetHeight(height - 1).Sign(privs[0])
+		votes := make([]*core.Vote, 0)
+		for _, i := range []int{0, 1, 3} {
+			votes = append(votes, parent.Vote(core.NewBLSSigner(privs[i], blsPrivs[i])))
+		}
+		qc, err := core.NewQuorumCert().BuildAggregate(votes, vs.AtHeight(height-1))
+		assert.NoError(err)
+		blk := core.NewBlock().SetHeight(height).SetQuorumCert(qc).Sign(privs[0])
+		mStrg.On("GetBlockByHeight", height).Return(blk, nil)
+	}
+	assert.Equal(3, election.NextLeader(1, 3, 2))
+}
@@ -0,0 +1,60 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package consensus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	metricBlockCommit = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "juria",
+		Subsystem: "consensus",
+		Name:      "block_commit_seconds",
+		Help:      "Total time to execute and store a commited block.",
+	})
+
+	metricBlockExec = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "juria",
+		Subsystem: "consensus",
+		Name:      "block_exec_seconds",
+		Help:      "Time to execute txs of a commited block.",
+	})
+
+	metricBlockMerkle = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "juria",
+		Subsystem: "consensus",
+		Name:      "block_merkle_seconds",
+		Help:      "Time to compute merkle tree update of a commited block.",
+	})
+
+	metricCommitedBlocks = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "juria",
+		Subsystem: "consensus",
+		Name:      "commited_blocks_total",
+		Help:      "Number of commited blocks.",
+	})
+
+	metricCommitedTxs = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "juria",
+		Subsystem: "consensus",
+		Name:      "commited_txs_total",
+		Help:      "Number of commited txs.",
+	})
+
+	metricViewChanges = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "juria",
+		Subsystem: "consensus",
+		Name:      "view_changes_total",
+		Help:      "Number of view changes.",
+	})
+
+	metricLeaderTimeouts = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "juria",
+		Subsystem: "consensus",
+		Name:      "leader_timeouts_total",
+		Help:      "Number of leader timeouts.",
+	})
+)
@@ -79,7 +79,7 @@ func (pm *pacemaker) onBeat() {
 		return
 	default:
 	}
-	if !pm.state.isThisNodeLeader() {
+	if !pm.state.isThisNodeLeader(pm.hotstuff.GetBLeaf().Height() + 1) {
 		return
 	}
 	pm.propose()
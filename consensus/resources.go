@@ -4,6 +4,8 @@
 package consensus
 
 import (
+	"math/big"
+
 	"github.com/aungmawjj/juria-blockchain/core"
 	"github.com/aungmawjj/juria-blockchain/emitter"
 	"github.com/aungmawjj/juria-blockchain/storage"
@@ -11,7 +13,7 @@ import (
 )
 
 type TxPool interface {
-	PopTxsFromQueue(max int) [][]byte
+	PopTxsFromQueue(max int, gasLimit uint64) [][]byte
 	SetTxsPending(hashes [][]byte)
 	GetTxsToExecute(hashes [][]byte) ([]*core.Transaction, [][]byte)
 	RemoveTxs(hashes [][]byte)
@@ -26,26 +28,44 @@ type Storage interface {
 	Commit(data *storage.CommitData) error
 	GetBlock(hash []byte) (*core.Block, error)
 	GetLastBlock() (*core.Block, error)
+	GetBlockByHeight(height uint64) (*core.Block, error)
 	GetLastQC() (*core.QuorumCert, error)
 	GetBlockHeight() uint64
 	HasTx(hash []byte) bool
+	HasEvidence(id []byte) bool
+	InstallStateSnapshot(
+		blk *core.Block, qc *core.QuorumCert, scList []*core.StateChange,
+		nonces []*core.StateChange, leafCount *big.Int, merkleRoot []byte,
+	) error
 }
 
 type MsgService interface {
 	BroadcastProposal(blk *core.Block) error
 	BroadcastNewView(qc *core.QuorumCert) error
+	BroadcastEvidence(ev *core.Evidence) error
+	BroadcastTimeout(t *core.Timeout) error
 	SendVote(pubKey *core.PublicKey, vote *core.Vote) error
 	RequestBlock(pubKey *core.PublicKey, hash []byte) (*core.Block, error)
 	RequestBlockByHeight(pubKey *core.PublicKey, height uint64) (*core.Block, error)
 	SendNewView(pubKey *core.PublicKey, qc *core.QuorumCert) error
+	RequestStateSnapshot(pubKey *core.PublicKey) (*core.StateSnapshot, error)
+	RequestStateChunk(
+		pubKey *core.PublicKey, height uint64, startKey []byte, limit int,
+	) ([]*core.StateChange, error)
+	RequestNonceChunk(
+		pubKey *core.PublicKey, height uint64, startKey []byte, limit int,
+	) ([]*core.StateChange, error)
 
 	SubscribeProposal(buffer int) *emitter.Subscription
 	SubscribeVote(buffer int) *emitter.Subscription
 	SubscribeNewView(buffer int) *emitter.Subscription
+	SubscribeEvidence(buffer int) *emitter.Subscription
+	SubscribeTimeout(buffer int) *emitter.Subscription
 }
 
 type Execution interface {
 	Execute(blk *core.Block, txs []*core.Transaction) (*core.BlockCommit, []*core.TxCommit)
+	GetValidatorEpochs() ([]*core.ValidatorEpoch, error)
 }
 
 type Resources struct {
@@ -55,4 +75,7 @@ type Resources struct {
 	MsgSvc    MsgService
 	TxPool    TxPool
 	Execution Execution
+
+	// optional, created from the config if not set
+	LeaderElection LeaderElection
 }
@@ -4,6 +4,8 @@
 package consensus
 
 import (
+	"math/big"
+
 	"github.com/aungmawjj/juria-blockchain/core"
 	"github.com/aungmawjj/juria-blockchain/emitter"
 	"github.com/aungmawjj/juria-blockchain/storage"
@@ -17,8 +19,8 @@ type MockTxPool struct {
 
 var _ TxPool = (*MockTxPool)(nil)
 
-func (m *MockTxPool) PopTxsFromQueue(max int) [][]byte {
-	args := m.Called(max)
+func (m *MockTxPool) PopTxsFromQueue(max int, gasLimit uint64) [][]byte {
+	args := m.Called(max, gasLimit)
 	return castBytesBytes(args.Get(0))
 }
 
@@ -75,6 +77,11 @@ func (m *MockStorage) GetBlock(hash []byte) (*core.Block, error) {
 	return castBlock(args.Get(0)), args.Error(1)
 }
 
+func (m *MockStorage) GetBlockByHeight(height uint64) (*core.Block, error) {
+	args := m.Called(height)
+	return castBlock(args.Get(0)), args.Error(1)
+}
+
 func (m *MockStorage) GetLastBlock() (*core.Block, error) {
 	args := m.Called()
 	return castBlock(args.Get(0)), args.Error(1)
@@ -95,6 +102,19 @@ func (m *MockStorage) HasTx(hash []byte) bool {
 	return args.Bool(0)
 }
 
+func (m *MockStorage) HasEvidence(id []byte) bool {
+	args := m.Called(id)
+	return args.Bool(0)
+}
+
+func (m *MockStorage) InstallStateSnapshot(
+	blk *core.Block, qc *core.QuorumCert, scList []*core.StateChange,
+	nonces []*core.StateChange, leafCount *big.Int, merkleRoot []byte,
+) error {
+	args := m.Called(blk, qc, scList, nonces, leafCount, merkleRoot)
+	return args.Error(0)
+}
+
 type MockMsgService struct {
 	mock.Mock
 }
@@ -111,6 +131,16 @@ func (m *MockMsgService) BroadcastNewView(qc *core.QuorumCert) error {
 	return args.Error(0)
 }
 
+func (m *MockMsgService) BroadcastEvidence(ev *core.Evidence) error {
+	args := m.Called(ev)
+	return args.Error(0)
+}
+
+func (m *MockMsgService) BroadcastTimeout(t *core.Timeout) error {
+	args := m.Called(t)
+	return args.Error(0)
+}
+
 func (m *MockMsgService) SendVote(pubKey *core.PublicKey, vote *core.Vote) error {
 	args := m.Called(pubKey, vote)
 	return args.Error(0)
@@ -131,6 +161,25 @@ func (m *MockMsgService) SendNewView(pubKey *core.PublicKey, qc *core.QuorumCert
 	return args.Error(0)
 }
 
+func (m *MockMsgService) RequestStateSnapshot(pubKey *core.PublicKey) (*core.StateSnapshot, error) {
+	args := m.Called(pubKey)
+	return castStateSnapshot(args.Get(0)), args.Error(1)
+}
+
+func (m *MockMsgService) RequestStateChunk(
+	pubKey *core.PublicKey, height uint64, startKey []byte, limit int,
+) ([]*core.StateChange, error) {
+	args := m.Called(pubKey, height, startKey, limit)
+	return castStateChanges(args.Get(0)), args.Error(1)
+}
+
+func (m *MockMsgService) RequestNonceChunk(
+	pubKey *core.PublicKey, height uint64, startKey []byte, limit int,
+) ([]*core.StateChange, error) {
+	args := m.Called(pubKey, height, startKey, limit)
+	return castStateChanges(args.Get(0)), args.Error(1)
+}
+
 func (m *MockMsgService) SubscribeProposal(buffer int) *emitter.Subscription {
 	args := m.Called(buffer)
 	return castSubscription(args.Get(0))
@@ -146,6 +195,16 @@ func (m *MockMsgService) SubscribeNewView(buffer int) *emitter.Subscription {
 	return castSubscription(args.Get(0))
 }
 
+func (m *MockMsgService) SubscribeEvidence(buffer int) *emitter.Subscription {
+	args := m.Called(buffer)
+	return castSubscription(args.Get(0))
+}
+
+func (m *MockMsgService) SubscribeTimeout(buffer int) *emitter.Subscription {
+	args := m.Called(buffer)
+	return castSubscription(args.Get(0))
+}
+
 type MockExecution struct {
 	mock.Mock
 }
@@ -159,6 +218,11 @@ func (m *MockExecution) Execute(
 	return castBlockCommit(args.Get(0)), castTxCommits(args.Get(1))
 }
 
+func (m *MockExecution) GetValidatorEpochs() ([]*core.ValidatorEpoch, error) {
+	args := m.Called()
+	return castValidatorEpochs(args.Get(0)), args.Error(1)
+}
+
 func castBytes(val interface{}) []byte {
 	if val == nil {
 		return nil
@@ -221,3 +285,24 @@ func castTxCommits(val interface{}) []*core.TxCommit {
 	}
 	return val.([]*core.TxCommit)
 }
+
+func castStateSnapshot(val interface{}) *core.StateSnapshot {
+	if val == nil {
+		return nil
+	}
+	return val.(*core.StateSnapshot)
+}
+
+func castStateChanges(val interface{}) []*core.StateChange {
+	if val == nil {
+		return nil
+	}
+	return val.([]*core.StateChange)
+}
+
+func castValidatorEpochs(val interface{}) []*core.ValidatorEpoch {
+	if val == nil {
+		return nil
+	}
+	return val.([]*core.ValidatorEpoch)
+}
@@ -7,10 +7,15 @@ import (
 	"sync"
 	"time"
 
+	"github.com/aungmawjj/juria-blockchain/core"
 	"github.com/aungmawjj/juria-blockchain/hotstuff"
 	"github.com/aungmawjj/juria-blockchain/logger"
 )
 
+// rotator changes the view when the leader fails or the view width is over.
+// A validator broadcasts the signed timeout of its view on local timers,
+// but the view is only changed with the timeout cert of the majority,
+// either created from the received timeouts or carried by the proposal of the next leader.
 type rotator struct {
 	resources *Resources
 	config    Config
@@ -18,6 +23,8 @@ type rotator struct {
 	state    *state
 	hotstuff *hotstuff.Hotstuff
 
+	timeouts *timeoutPool
+
 	leaderTimer *time.Timer
 	viewTimer   *time.Timer
 
@@ -25,11 +32,12 @@ type rotator struct {
 	viewStart int64
 	mtxVS     sync.RWMutex
 
-	// true when view changed before the next leader is approved
+	// true when this node timed out in current view and waits for the timeout cert
 	pendingViewChange bool
 	mtxPVC            sync.RWMutex
 
-	leaderTimeoutCount int
+	mtxView sync.Mutex    // lock for view change
+	viewCh  chan struct{} // notifies the view change to reset the timers
 
 	stopCh chan struct{}
 }
@@ -62,6 +70,9 @@ func (rot *rotator) run() {
 	subQC := rot.hotstuff.SubscribeNewQCHigh()
 	defer subQC.Unsubscribe()
 
+	subTimeout := rot.resources.MsgSvc.SubscribeTimeout(100)
+	defer subTimeout.Unsubscribe()
+
 	rot.viewTimer = time.NewTimer(rot.config.ViewWidth)
 	defer rot.viewTimer.Stop()
 
@@ -81,6 +92,15 @@ func (rot *rotator) run() {
 
 		case e := <-subQC.Events():
 			rot.onNewQCHigh(e.(hotstuff.QC))
+
+		case e := <-subTimeout.Events():
+			if err := rot.onReceiveTimeout(e.(*core.Timeout)); err != nil {
+				logger.I().Warnf("received timeout failed, %+v", err)
+			}
+
+		case <-rot.viewCh:
+			rot.drainResetTimer(rot.leaderTimer, rot.config.LeaderTimeout)
+			rot.drainResetTimer(rot.viewTimer, rot.config.ViewWidth)
 		}
 	}
 }
@@ -102,76 +122,136 @@ func (rot *rotator) drainStopTimer(timer *time.Timer) {
 }
 
 func (rot *rotator) onLeaderTimeout() {
-	logger.I().Warnw("leader timeout", "leader", rot.state.getLeaderIndex())
-	rot.leaderTimeoutCount++
-	rot.changeView()
-	rot.drainStopTimer(rot.viewTimer)
-	if rot.leaderTimeoutCount > rot.state.getFaultyCount() {
-		rot.leaderTimer.Stop()
-		rot.setPendingViewChange(false)
-	} else {
-		rot.leaderTimer.Reset(rot.config.LeaderTimeout)
+	if !rot.getPendingViewChange() {
+		logger.I().Warnw("leader timeout", "leader", rot.state.getLeaderIndex())
+		metricLeaderTimeouts.Inc()
 	}
+	rot.timeout()
+	rot.leaderTimer.Reset(rot.config.LeaderTimeout) // resend the timeout until the view changes
 }
 
 func (rot *rotator) onViewTimeout() {
-	rot.changeView()
+	rot.timeout()
 	rot.drainResetTimer(rot.leaderTimer, rot.config.LeaderTimeout)
 }
 
-func (rot *rotator) changeView() {
-	leaderIdx := rot.nextLeader()
-	rot.state.setLeaderIndex(leaderIdx)
+// timeout gives up current view and broadcasts the signed timeout of the view
+// with the timeout cert of the view
+func (rot *rotator) timeout() {
 	rot.setPendingViewChange(true)
-	rot.setViewStart()
-	leader := rot.resources.VldStore.GetValidator(rot.state.getLeaderIndex())
-	rot.resources.MsgSvc.SendNewView(leader, rot.hotstuff.GetQCHigh().(*hsQC).qc)
-	logger.I().Infow("view changed",
-		"leader", leaderIdx, "qc", qcRefHeight(rot.hotstuff.GetQCHigh()))
-}
-
-func (rot *rotator) nextLeader() int {
-	leaderIdx := rot.state.getLeaderIndex() + 1
-	if leaderIdx >= rot.resources.VldStore.ValidatorCount() {
-		leaderIdx = 0
+	if rot.config.Observer {
+		return
 	}
-	return leaderIdx
+	view, tc := rot.state.getViewTC()
+	t := core.NewTimeout().
+		SetView(view).
+		SetHighTC(tc).
+		SetCommitHeight(rot.hotstuff.GetBExec().Height()).
+		Sign(rot.resources.Signer)
+	rot.resources.MsgSvc.BroadcastTimeout(t)
+	logger.I().Infow("view timeout", "view", view, "leader", rot.state.getLeaderIndex())
+	rot.addTimeout(t)
 }
 
-func (rot *rotator) onNewQCHigh(qc hotstuff.QC) {
-	rot.state.setQC(qc.(*hsQC).qc)
-	proposer := rot.resources.VldStore.GetValidatorIndex(qcRefProposer(qc))
-	logger.I().Debugw("updated qc", "proposer", proposer, "qc", qcRefHeight(qc))
-	var ltreset, vtreset bool
-	if proposer == rot.state.getLeaderIndex() { // if qc is from current leader
-		ltreset = true
+// onReceiveTimeout validates the timeout with the validator set of the next block.
+// The timeout of a later view moves this node to the view with the timeout cert it carries.
+func (rot *rotator) onReceiveTimeout(t *core.Timeout) error {
+	if err := t.Validate(rot.state.getValidators(rot.nextHeight())); err != nil {
+		return err
 	}
-	if rot.isNewViewApproval(proposer) {
-		ltreset = true
-		vtreset = true
-		rot.approveViewLeader(proposer)
+	if tc := t.HighTC(); tc != nil {
+		rot.onTimeoutCert(tc)
 	}
-	if ltreset {
-		rot.drainResetTimer(rot.leaderTimer, rot.config.LeaderTimeout)
+	rot.addTimeout(t)
+	return nil
+}
+
+func (rot *rotator) addTimeout(t *core.Timeout) {
+	if t.View() < rot.state.getView() {
+		return // already changed view
 	}
-	if vtreset {
-		rot.drainResetTimer(rot.viewTimer, rot.config.ViewWidth)
+	if tc := rot.timeouts.addTimeout(t, rot.state.getValidators(rot.nextHeight())); tc != nil {
+		rot.onTimeoutCert(tc)
 	}
 }
 
-func (rot *rotator) isNewViewApproval(proposer int) bool {
-	leaderIdx := rot.state.getLeaderIndex()
-	pending := rot.getPendingViewChange()
-	return (!pending && proposer != leaderIdx) || // node first run or out of sync
-		(pending && proposer == leaderIdx) // expecting leader
+// onTimeoutCert moves to the view after the valid timeout cert and installs the leader of the view.
+// The timeout cert is built from the timeouts received by this node,
+// so the leader is only expected until the first proposal of the view is received.
+func (rot *rotator) onTimeoutCert(tc *core.TimeoutCert) {
+	rot.mtxView.Lock()
+	defer rot.mtxView.Unlock()
+
+	rot.changeView(tc, rot.leaderOfView(tc, rot.nextHeight()))
+}
+
+// onProposalTimeoutCert installs the leader elected by the timeout cert of the proposal.
+// Validators may build the timeout certs of a view from different timeouts which elect different leaders,
+// the leader of the proposal is taken as every validator verifies it with the same timeout cert.
+func (rot *rotator) onProposalTimeoutCert(proposal *core.Block) {
+	rot.mtxView.Lock()
+	defer rot.mtxView.Unlock()
+
+	tc := proposal.TimeoutCert()
+	if tc.View()+1 < rot.state.getView() {
+		return // old view
+	}
+	leaderIdx := rot.leaderOfView(tc, proposal.Height())
+	if rot.state.setViewLeader(tc, leaderIdx) {
+		return
+	}
+	rot.changeView(tc, leaderIdx)
 }
 
-func (rot *rotator) approveViewLeader(proposer int) {
+// changeView must be called with the view lock
+func (rot *rotator) changeView(tc *core.TimeoutCert, leaderIdx int) {
+	view := tc.View() + 1
+	if !rot.state.moveView(tc, leaderIdx) {
+		return
+	}
 	rot.setPendingViewChange(false)
-	rot.state.setLeaderIndex(proposer)
 	rot.setViewStart()
-	logger.I().Infow("approved leader", "leader", rot.state.getLeaderIndex())
-	rot.leaderTimeoutCount = 0
+	metricViewChanges.Inc()
+	select {
+	case rot.viewCh <- struct{}{}:
+	default: // timers are reset already
+	}
+	if !rot.config.Observer {
+		leader := rot.state.getValidators(rot.nextHeight()).GetValidator(leaderIdx)
+		rot.resources.MsgSvc.SendNewView(leader, rot.hotstuff.GetQCHigh().(*hsQC).qc)
+	}
+	logger.I().Infow("view changed",
+		"view", view, "leader", leaderIdx, "qc", qcRefHeight(rot.hotstuff.GetQCHigh()))
+}
+
+// leaderOfView gives the leader of the view after the timeout cert, or the first view if it's nil,
+// in the validator set of the given block height.
+// The leader is elected after the validator at the index of the view before
+// with the commited height of the timeout cert, so the leader only depends on the timeout cert and the height,
+// and the election can skip inactive validators
+func (rot *rotator) leaderOfView(tc *core.TimeoutCert, height uint64) int {
+	var view, commitHeight uint64
+	if tc != nil {
+		view = tc.View() + 1
+		commitHeight = tc.CommitHeight()
+	}
+	count := uint64(rot.state.getValidators(height).ValidatorCount())
+	return rot.resources.LeaderElection.NextLeader(int((view+count-1)%count), height, commitHeight)
+}
+
+// leader is selected from the validator set of the next block
+func (rot *rotator) nextHeight() uint64 {
+	return rot.hotstuff.GetBLeaf().Height() + 1
+}
+
+func (rot *rotator) onNewQCHigh(qc hotstuff.QC) {
+	rot.state.setQC(qc.(*hsQC).qc)
+	proposer := rot.state.getValidators(rot.nextHeight()).GetValidatorIndex(qcRefProposer(qc))
+	logger.I().Debugw("updated qc", "proposer", proposer, "qc", qcRefHeight(qc))
+	// after timeout, keep the leader timer to resend the timeout
+	if proposer == rot.state.getLeaderIndex() && !rot.getPendingViewChange() {
+		rot.drainResetTimer(rot.leaderTimer, rot.config.LeaderTimeout)
+	}
 }
 
 func (rot *rotator) setViewStart() {
@@ -9,18 +9,23 @@ import (
 	"github.com/aungmawjj/juria-blockchain/core"
 	"github.com/aungmawjj/juria-blockchain/hotstuff"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
-func setupRotator() (*rotator, *core.Block) {
-	key1 := core.GenerateKey(nil)
-	key2 := core.GenerateKey(nil)
-	vlds := []*core.PublicKey{
-		key1.PublicKey(),
-		key2.PublicKey(),
+func setupRotator(keys ...*core.PrivateKey) (*rotator, *core.Block) {
+	if len(keys) == 0 {
+		keys = []*core.PrivateKey{core.GenerateKey(nil), core.GenerateKey(nil)}
+	}
+	key1 := keys[0]
+	vlds := make([]*core.PublicKey, len(keys))
+	for i, key := range keys {
+		vlds[i] = key.PublicKey()
 	}
 	resources := &Resources{
+		Signer:   key1,
 		VldStore: core.NewValidatorStore(vlds),
 	}
+	resources.LeaderElection = NewLeaderElection(resources, DefaultConfig)
 
 	b0 := core.NewBlock().Sign(key1)
 	q0 := core.NewQuorumCert().Build([]*core.Vote{b0.ProposerVote()})
@@ -38,61 +43,220 @@ func setupRotator() (*rotator, *core.Block) {
 		config:    DefaultConfig,
 		state:     state,
 		hotstuff:  hotstuff,
+		timeouts:  newTimeoutPool(),
+		viewCh:    make(chan struct{}, 1),
 	}, b0
 }
 
-func TestRotator_changeView(t *testing.T) {
+func newTestTimeoutCert(view uint64, keys ...*core.PrivateKey) *core.TimeoutCert {
+	timeouts := make([]*core.Timeout, len(keys))
+	for i, key := range keys {
+		timeouts[i] = core.NewTimeout().SetView(view).Sign(key)
+	}
+	return core.NewTimeoutCert().Build(timeouts)
+}
+
+func TestRotator_timeout(t *testing.T) {
 	assert := assert.New(t)
 
-	rot, b0 := setupRotator()
-	rot.state.setLeaderIndex(1)
+	keys := []*core.PrivateKey{core.GenerateKey(nil), core.GenerateKey(nil), core.GenerateKey(nil)}
+	rot, b0 := setupRotator(keys...)
 
 	msgSvc := new(MockMsgService)
-	msgSvc.On("SendNewView", rot.resources.VldStore.GetValidator(0), b0.QuorumCert()).Return(nil)
+	msgSvc.On("BroadcastTimeout", mock.Anything).Return(nil).Once()
 	rot.resources.MsgSvc = msgSvc
 
-	rot.changeView()
+	rot.timeout()
 
 	msgSvc.AssertExpectations(t)
+	sent := msgSvc.Calls[0].Arguments.Get(0).(*core.Timeout)
+	assert.EqualValues(0, sent.View())
+	assert.NoError(sent.Validate(rot.state.getValidators(1)))
 	assert.True(rot.getPendingViewChange())
-	assert.EqualValues(rot.state.getLeaderIndex(), 0)
-}
+	assert.EqualValues(0, rot.state.getView(), "view is not changed by local timeout")
 
-func Test_rotator_isNewViewApproval(t *testing.T) {
-	assert := assert.New(t)
+	// view doesn't change until the majority timed out
+	t1 := core.NewTimeout().SetView(0).Sign(keys[1])
+	assert.NoError(rot.onReceiveTimeout(t1))
+	assert.EqualValues(0, rot.state.getView())
 
-	rot1, _ := setupRotator()
-	rot2, _ := setupRotator()
-
-	rot1.setPendingViewChange(true)
-	rot2.setPendingViewChange(false)
-
-	tests := []struct {
-		name        string
-		rot         *rotator
-		proposerIdx int
-		want        bool
-	}{
-		{"pending and same leader", rot1, 0, true},
-		{"not pending and different leader", rot2, 1, true},
-		{"pending and different leader", rot1, 1, false},
-		{"not pending and same leader", rot2, 0, false},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			assert.EqualValues(tt.want, tt.rot.isNewViewApproval(tt.proposerIdx))
-		})
+	msgSvc.On("SendNewView", keys[1].PublicKey(), b0.QuorumCert()).Return(nil)
+
+	t2 := core.NewTimeout().SetView(0).Sign(keys[2])
+	assert.NoError(rot.onReceiveTimeout(t2))
+
+	msgSvc.AssertExpectations(t)
+	assert.EqualValues(1, rot.state.getView())
+	assert.EqualValues(1, rot.state.getLeaderIndex())
+	assert.False(rot.getPendingViewChange())
+	if assert.NotNil(rot.state.getTimeoutCert()) {
+		assert.EqualValues(0, rot.state.getTimeoutCert().View())
+		assert.NoError(rot.state.getTimeoutCert().Validate(rot.state.getValidators(1)))
 	}
+
+	invalid := core.NewTimeout().SetView(1).Sign(core.GenerateKey(nil))
+	assert.Error(rot.onReceiveTimeout(invalid))
 }
 
-func TestRotator_resetViewTimer(t *testing.T) {
+func TestRotator_timeout_Observer(t *testing.T) {
 	assert := assert.New(t)
 
 	rot, _ := setupRotator()
+	rot.config.Observer = true
+
+	msgSvc := new(MockMsgService)
+	rot.resources.MsgSvc = msgSvc
+
+	rot.timeout()
+
+	msgSvc.AssertNotCalled(t, "BroadcastTimeout", mock.Anything)
+	assert.True(rot.getPendingViewChange())
+}
+
+func TestRotator_onTimeoutCert(t *testing.T) {
+	assert := assert.New(t)
+
+	keys := []*core.PrivateKey{core.GenerateKey(nil), core.GenerateKey(nil)}
+	rot, b0 := setupRotator(keys...)
 	rot.setPendingViewChange(true)
 
-	rot.approveViewLeader(1)
+	msgSvc := new(MockMsgService)
+	msgSvc.On("SendNewView", keys[0].PublicKey(), b0.QuorumCert()).Return(nil)
+	rot.resources.MsgSvc = msgSvc
+
+	// validators who missed the timeouts move to the view of the timeout cert
+	rot.onTimeoutCert(newTestTimeoutCert(3, keys...))
 
+	msgSvc.AssertExpectations(t)
+	assert.EqualValues(4, rot.state.getView())
+	assert.EqualValues(0, rot.state.getLeaderIndex())
 	assert.False(rot.getPendingViewChange())
-	assert.EqualValues(rot.state.getLeaderIndex(), 1)
+
+	// older timeout cert is ignored
+	rot.onTimeoutCert(newTestTimeoutCert(2, keys...))
+	assert.EqualValues(4, rot.state.getView())
+	assert.EqualValues(3, rot.state.getTimeoutCert().View())
+	msgSvc.AssertNumberOfCalls(t, "SendNewView", 1)
+
+	// timeout cert of current view is received again with the timeouts of the view
+	rot.onTimeoutCert(newTestTimeoutCert(3, keys...))
+	msgSvc.AssertNumberOfCalls(t, "SendNewView", 1)
+}
+
+func TestRotator_onReceiveTimeout_HighTC(t *testing.T) {
+	assert := assert.New(t)
+
+	keys := []*core.PrivateKey{core.GenerateKey(nil), core.GenerateKey(nil), core.GenerateKey(nil)}
+	rot, b0 := setupRotator(keys...)
+
+	msgSvc := new(MockMsgService)
+	msgSvc.On("SendNewView", mock.Anything, b0.QuorumCert()).Return(nil)
+	msgSvc.On("BroadcastTimeout", mock.Anything).Return(nil)
+	rot.resources.MsgSvc = msgSvc
+
+	// lagging validator catches up the view of the sender with the timeout cert of the timeout
+	highTC := newTestTimeoutCert(2, keys...)
+	t1 := core.NewTimeout().SetView(3).SetHighTC(highTC).Sign(keys[1])
+	assert.NoError(rot.onReceiveTimeout(t1))
+	assert.EqualValues(3, rot.state.getView())
+	assert.EqualValues(rot.leaderOfView(highTC, 1), rot.state.getLeaderIndex())
+
+	// the timeout of this node carries the timeout cert of its view
+	rot.timeout()
+	sent := msgSvc.Calls[len(msgSvc.Calls)-1].Arguments.Get(0).(*core.Timeout)
+	assert.EqualValues(3, sent.View())
+	if assert.NotNil(sent.HighTC()) {
+		assert.EqualValues(2, sent.HighTC().View())
+	}
+	assert.NoError(sent.Validate(rot.state.getValidators(1)))
+
+	noTC := core.NewTimeout().SetView(5).Sign(keys[2])
+	assert.ErrorIs(rot.onReceiveTimeout(noTC), core.ErrInvalidTC)
+	assert.EqualValues(3, rot.state.getView())
+}
+
+func TestRotator_leaderOfView(t *testing.T) {
+	assert := assert.New(t)
+
+	keys := []*core.PrivateKey{core.GenerateKey(nil), core.GenerateKey(nil), core.GenerateKey(nil)}
+	rot, _ := setupRotator(keys...)
+
+	assert.Equal(0, rot.leaderOfView(nil, 1))
+	assert.Equal(1, rot.leaderOfView(newTestTimeoutCert(0, keys...), 1))
+	assert.Equal(2, rot.leaderOfView(newTestTimeoutCert(1, keys...), 1))
+	assert.Equal(0, rot.leaderOfView(newTestTimeoutCert(2, keys...), 1))
+	assert.Equal(1, rot.leaderOfView(newTestTimeoutCert(6, keys...), 1))
+
+	// election reads the commited blocks up to the lowest commited height of the timeout cert
+	election := new(commitHeightElection)
+	rot.resources.LeaderElection = election
+	tc := core.NewTimeoutCert().Build([]*core.Timeout{
+		core.NewTimeout().SetView(2).SetCommitHeight(12).Sign(keys[0]),
+		core.NewTimeout().SetView(2).SetCommitHeight(9).Sign(keys[1]),
+		core.NewTimeout().SetView(2).SetCommitHeight(10).Sign(keys[2]),
+	})
+	rot.leaderOfView(tc, 1)
+	assert.EqualValues(9, election.commitHeight)
+}
+
+func TestRotator_onProposalTimeoutCert_DifferentTimeouts(t *testing.T) {
+	assert := assert.New(t)
+
+	keys := make([]*core.PrivateKey, 4)
+	for i := range keys {
+		keys[i] = core.GenerateKey(nil)
+	}
+	timeouts := make([]*core.Timeout, len(keys))
+	for i, key := range keys {
+		timeouts[i] = core.NewTimeout().SetView(2).SetCommitHeight(uint64(5 + i)).Sign(key)
+	}
+	setupNode := func(timeouts []*core.Timeout) *rotator {
+		rot, _ := setupRotator(keys...)
+		msgSvc := new(MockMsgService)
+		msgSvc.On("SendNewView", mock.Anything, mock.Anything).Return(nil)
+		rot.resources.MsgSvc = msgSvc
+		rot.resources.LeaderElection = &commitHeightElection{count: len(keys)}
+		for _, to := range timeouts {
+			rot.addTimeout(to)
+		}
+		return rot
+	}
+	// the nodes receive the timeouts of different majorities
+	rotA := setupNode(timeouts[:3])
+	rotB := setupNode(timeouts[1:])
+	assert.EqualValues(3, rotA.state.getView())
+	assert.EqualValues(3, rotB.state.getView())
+	assert.NotEqual(rotA.state.getLeaderIndex(), rotB.state.getLeaderIndex(), "local timeout certs elect different leaders")
+
+	leader := keys[rotA.state.getLeaderIndex()]
+	proposal := core.NewBlock().SetHeight(1).
+		SetTimeoutCert(rotA.state.getTimeoutCert()).Sign(leader)
+	rotA.onProposalTimeoutCert(proposal)
+	rotB.onProposalTimeoutCert(proposal)
+
+	assert.Equal(rotA.state.getLeaderIndex(), rotB.state.getLeaderIndex(), "leader of the proposal")
+	assert.True(rotB.state.isLeader(leader.PublicKey(), proposal.Height()))
+	assert.EqualValues(3, rotB.state.getView())
+	assert.EqualValues(5, rotB.state.getTimeoutCert().CommitHeight())
+
+	// proposal of a later view moves the view with its leader
+	rotC := setupNode(nil)
+	rotC.onProposalTimeoutCert(proposal)
+	assert.EqualValues(3, rotC.state.getView())
+	assert.Equal(rotA.state.getLeaderIndex(), rotC.state.getLeaderIndex())
+}
+
+// commitHeightElection records the commited height,
+// it elects the validator at the index of the commited height if count is set
+type commitHeightElection struct {
+	commitHeight uint64
+	count        int
+}
+
+func (e *commitHeightElection) NextLeader(current int, height, commitHeight uint64) int {
+	e.commitHeight = commitHeight
+	if e.count == 0 {
+		return current
+	}
+	return int(commitHeight) % e.count
 }
@@ -24,8 +24,15 @@ type state struct {
 
 	mtxUpdate sync.Mutex // lock for hotstuff update call
 
+	evidence *evidencePool
+
 	leaderIndex int64
 
+	// current view, moved to the next view by the timeout cert of the majority
+	view    uint64
+	tc      *core.TimeoutCert // timeout cert of the previous view, nil in the first view
+	mtxView sync.RWMutex
+
 	// commited block height. on node restart, it's zero until a block is commited
 	commitedHeight uint64
 
@@ -39,6 +46,7 @@ func newState(resources *Resources) *state {
 		blocks:    make(map[string]*core.Block),
 		commited:  make(map[string]struct{}),
 		qcs:       make(map[string]*core.QuorumCert),
+		evidence:  newEvidencePool(resources, evidenceWindow),
 	}
 }
 
@@ -146,15 +154,21 @@ func (state *state) getUncommitedOlderBlocks(bexec *core.Block) []*core.Block {
 	return ret
 }
 
-func (state *state) isThisNodeLeader() bool {
-	return state.isLeader(state.resources.Signer.PublicKey())
+// validator set for the block height
+func (state *state) getValidators(height uint64) core.ValidatorSet {
+	return state.resources.VldStore.AtHeight(height)
+}
+
+func (state *state) isThisNodeLeader(height uint64) bool {
+	return state.isLeader(state.resources.Signer.PublicKey(), height)
 }
 
-func (state *state) isLeader(pubKey *core.PublicKey) bool {
-	if !state.resources.VldStore.IsValidator(pubKey) {
+func (state *state) isLeader(pubKey *core.PublicKey, height uint64) bool {
+	vset := state.getValidators(height)
+	if !vset.IsValidator(pubKey) {
 		return false
 	}
-	return state.getLeaderIndex() == state.resources.VldStore.GetValidatorIndex(pubKey)
+	return state.getLeaderIndex() == vset.GetValidatorIndex(pubKey)
 }
 
 func (state *state) setLeaderIndex(idx int) {
@@ -165,8 +179,50 @@ func (state *state) getLeaderIndex() int {
 	return int(atomic.LoadInt64(&state.leaderIndex))
 }
 
-func (state *state) getFaultyCount() int {
-	return state.resources.VldStore.ValidatorCount() - state.resources.VldStore.MajorityCount()
+// moveView moves to the view after the timeout cert with the leader of the view,
+// returns false if the view is already reached
+func (state *state) moveView(tc *core.TimeoutCert, leaderIdx int) bool {
+	state.mtxView.Lock()
+	defer state.mtxView.Unlock()
+	if tc.View()+1 <= state.view {
+		return false
+	}
+	state.view = tc.View() + 1
+	state.tc = tc
+	state.setLeaderIndex(leaderIdx)
+	return true
+}
+
+// setViewLeader replaces the timeout cert and the leader of the current view,
+// returns false if the timeout cert is not of the current view
+func (state *state) setViewLeader(tc *core.TimeoutCert, leaderIdx int) bool {
+	state.mtxView.Lock()
+	defer state.mtxView.Unlock()
+	if tc.View()+1 != state.view {
+		return false
+	}
+	state.tc = tc
+	state.setLeaderIndex(leaderIdx)
+	return true
+}
+
+func (state *state) getView() uint64 {
+	state.mtxView.RLock()
+	defer state.mtxView.RUnlock()
+	return state.view
+}
+
+func (state *state) getTimeoutCert() *core.TimeoutCert {
+	state.mtxView.RLock()
+	defer state.mtxView.RUnlock()
+	return state.tc
+}
+
+// getViewTC gives the view with its timeout cert
+func (state *state) getViewTC() (uint64, *core.TimeoutCert) {
+	state.mtxView.RLock()
+	defer state.mtxView.RUnlock()
+	return state.view, state.tc
 }
 
 func (state *state) addCommitedTxCount(count int) {
@@ -0,0 +1,233 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package consensus
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/aungmawjj/juria-blockchain/core"
+	"github.com/aungmawjj/juria-blockchain/logger"
+)
+
+// snapshotNonceLimit bounds the senders of a snapshot downloaded from a peer
+const snapshotNonceLimit = 1 << 24
+
+// stateSyncer downloads the state snapshot from a validator on startup.
+// The snapshot states are trusted only if the merkle root matches
+// the one in a certified block which executed the snapshot height.
+type stateSyncer struct {
+	resources *Resources
+	config    Config
+
+	// max blocks to scan after snapshot height to find the certified merkle root
+	anchorScanLimit int
+	retryDelay      time.Duration
+	retryCount      int
+}
+
+func (ss *stateSyncer) run() {
+	peer, snapshot := ss.findSnapshot()
+	if snapshot == nil {
+		return
+	}
+	logger.I().Infow("syncing state snapshot", "height", snapshot.Height())
+	if err := ss.syncSnapshot(peer, snapshot); err != nil {
+		logger.I().Errorf("state sync failed, %+v", err)
+		return
+	}
+	logger.I().Infow("installed state snapshot", "height", snapshot.Height())
+}
+
+func (ss *stateSyncer) findSnapshot() (*core.PublicKey, *core.StateSnapshot) {
+	var peer *core.PublicKey
+	var snapshot *core.StateSnapshot
+	for i := 0; i < ss.resources.VldStore.ValidatorCount(); i++ {
+		pubKey := ss.resources.VldStore.GetValidator(i)
+		if pubKey.Equal(ss.resources.Signer.PublicKey()) {
+			continue
+		}
+		s, err := ss.resources.MsgSvc.RequestStateSnapshot(pubKey)
+		if err != nil {
+			continue
+		}
+		if snapshot == nil || s.Height() > snapshot.Height() {
+			peer, snapshot = pubKey, s
+		}
+	}
+	if snapshot == nil {
+		return nil, nil
+	}
+	if snapshot.Height() <= ss.resources.Storage.GetBlockHeight()+ss.config.StateSyncThreshold {
+		return nil, nil
+	}
+	if snapshot.LeafCount().Sign() == 0 {
+		return nil, nil
+	}
+	return peer, snapshot
+}
+
+func (ss *stateSyncer) syncSnapshot(peer *core.PublicKey, snapshot *core.StateSnapshot) error {
+	blk, qc, err := ss.getSnapshotBlock(peer, snapshot)
+	if err != nil {
+		return err
+	}
+	scList, err := ss.downloadStates(peer, snapshot)
+	if err != nil {
+		return err
+	}
+	nonces, err := ss.downloadNonces(peer, snapshot)
+	if err != nil {
+		return err
+	}
+	merkleRoot, err := ss.getCertifiedMerkleRoot(peer, snapshot.Height())
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(merkleRoot, snapshot.MerkleRoot()) {
+		return errors.New("snapshot merkle root is not certified")
+	}
+	return ss.resources.Storage.InstallStateSnapshot(
+		blk, qc, scList, nonces, snapshot.LeafCount(), merkleRoot)
+}
+
+func (ss *stateSyncer) getSnapshotBlock(
+	peer *core.PublicKey, snapshot *core.StateSnapshot,
+) (*core.Block, *core.QuorumCert, error) {
+	qc := snapshot.LastQC()
+	if qc == nil {
+		return nil, nil, errors.New("snapshot has no qc")
+	}
+	if err := qc.Validate(ss.resources.VldStore); err != nil {
+		return nil, nil, fmt.Errorf("invalid snapshot qc, %w", err)
+	}
+	blk, err := ss.requestBlockByHeight(peer, snapshot.Height())
+	if err != nil {
+		return nil, nil, err
+	}
+	if !bytes.Equal(blk.Hash(), qc.BlockHash()) {
+		return nil, nil, errors.New("snapshot qc does not reference block")
+	}
+	return blk, qc, nil
+}
+
+func (ss *stateSyncer) downloadStates(
+	peer *core.PublicKey, snapshot *core.StateSnapshot,
+) ([]*core.StateChange, error) {
+	scList := make([]*core.StateChange, 0) // leaf count is not trusted yet
+	var startKey []byte
+	for {
+		chunk, err := ss.resources.MsgSvc.RequestStateChunk(
+			peer, snapshot.Height(), startKey, ss.config.StateChunkSize)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get state chunk, %w", err)
+		}
+		if len(chunk) == 0 {
+			return scList, nil
+		}
+		for _, sc := range chunk {
+			if bytes.Compare(sc.Key(), startKey) != 1 {
+				return nil, errors.New("state chunk keys not in order")
+			}
+			startKey = sc.Key()
+		}
+		scList = append(scList, chunk...)
+		if big.NewInt(int64(len(scList))).Cmp(snapshot.LeafCount()) == 1 {
+			return nil, errors.New("too many states in snapshot")
+		}
+	}
+}
+
+// downloadNonces gets the next nonces of the senders at the snapshot height,
+// they are not covered by the merkle root and trusted from the peer
+func (ss *stateSyncer) downloadNonces(
+	peer *core.PublicKey, snapshot *core.StateSnapshot,
+) ([]*core.StateChange, error) {
+	nonces := make([]*core.StateChange, 0)
+	var startKey []byte
+	for {
+		chunk, err := ss.resources.MsgSvc.RequestNonceChunk(
+			peer, snapshot.Height(), startKey, ss.config.StateChunkSize)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get nonce chunk, %w", err)
+		}
+		if len(chunk) == 0 {
+			return nonces, nil
+		}
+		for _, sc := range chunk {
+			if bytes.Compare(sc.Key(), startKey) != 1 {
+				return nil, errors.New("nonce chunk senders not in order")
+			}
+			startKey = sc.Key()
+		}
+		nonces = append(nonces, chunk...)
+		if len(nonces) > snapshotNonceLimit {
+			return nil, errors.New("too many nonces in snapshot")
+		}
+	}
+}
+
+// getCertifiedMerkleRoot finds the block which executed the given height
+// and is certified by the qc in its next block
+func (ss *stateSyncer) getCertifiedMerkleRoot(
+	peer *core.PublicKey, execHeight uint64,
+) ([]byte, error) {
+	height := execHeight + 1
+	blk, err := ss.requestBlockByHeightRetry(peer, height)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < ss.anchorScanLimit; i++ {
+		if blk.ExecHeight() > execHeight {
+			break
+		}
+		next, err := ss.requestBlockByHeightRetry(peer, height+1)
+		if err != nil {
+			return nil, err
+		}
+		if blk.ExecHeight() == execHeight && next.QuorumCert() != nil &&
+			bytes.Equal(next.QuorumCert().BlockHash(), blk.Hash()) {
+			if err := next.QuorumCert().Validate(ss.resources.VldStore); err != nil {
+				return nil, fmt.Errorf("invalid qc, %w", err)
+			}
+			return blk.MerkleRoot(), nil
+		}
+		blk = next
+		height++
+	}
+	return nil, fmt.Errorf("certified block not found for exec height %d", execHeight)
+}
+
+// blocks after snapshot height may not be commited yet on the peer
+func (ss *stateSyncer) requestBlockByHeightRetry(
+	peer *core.PublicKey, height uint64,
+) (blk *core.Block, err error) {
+	for i := 0; i <= ss.retryCount; i++ {
+		if i > 0 {
+			time.Sleep(ss.retryDelay)
+		}
+		blk, err = ss.requestBlockByHeight(peer, height)
+		if err == nil {
+			return blk, nil
+		}
+	}
+	return nil, err
+}
+
+func (ss *stateSyncer) requestBlockByHeight(peer *core.PublicKey, height uint64) (*core.Block, error) {
+	blk, err := ss.resources.MsgSvc.RequestBlockByHeight(peer, height)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get block by height %d, %w", height, err)
+	}
+	if err := blk.Validate(ss.resources.VldStore); err != nil {
+		return nil, fmt.Errorf("validate block error %w", err)
+	}
+	if blk.Height() != height {
+		return nil, fmt.Errorf("invalid block height %d", blk.Height())
+	}
+	return blk, nil
+}
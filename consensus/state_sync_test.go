@@ -0,0 +1,125 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package consensus
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/aungmawjj/juria-blockchain/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestStateSyncer(t *testing.T) {
+	assert := assert.New(t)
+
+	priv0 := core.GenerateKey(nil)
+	priv1 := core.GenerateKey(nil)
+	peer := priv1.PublicKey()
+	mStrg := new(MockStorage)
+	mMsgSvc := new(MockMsgService)
+	resources := &Resources{
+		Signer:   priv0,
+		VldStore: core.NewValidatorStore([]*core.PublicKey{priv0.PublicKey(), peer}),
+		Storage:  mStrg,
+		MsgSvc:   mMsgSvc,
+	}
+	mroot := []byte("merkle-root")
+	b9 := core.NewBlock().SetHeight(9).Sign(priv1)
+	q9 := core.NewQuorumCert().Build([]*core.Vote{b9.Vote(priv0), b9.Vote(priv1)})
+	b10 := core.NewBlock().SetHeight(10).SetQuorumCert(q9).Sign(priv1)
+	q10 := core.NewQuorumCert().Build([]*core.Vote{b10.Vote(priv0), b10.Vote(priv1)})
+	b11 := core.NewBlock().SetHeight(11).SetExecHeight(9).SetQuorumCert(q10).Sign(priv1)
+	q11 := core.NewQuorumCert().Build([]*core.Vote{b11.Vote(priv0), b11.Vote(priv1)})
+	b12 := core.NewBlock().SetHeight(12).SetExecHeight(10).SetMerkleRoot(mroot).
+		SetQuorumCert(q11).Sign(priv1)
+	q12 := core.NewQuorumCert().Build([]*core.Vote{b12.Vote(priv0), b12.Vote(priv1)})
+	b13 := core.NewBlock().SetHeight(13).SetExecHeight(11).SetQuorumCert(q12).Sign(priv1)
+
+	snapshot := core.NewStateSnapshot().
+		SetHeight(10).
+		SetLeafCount(big.NewInt(2)).
+		SetMerkleRoot(mroot).
+		SetLastQC(q10)
+	scList := []*core.StateChange{
+		core.NewStateChange().SetKey([]byte{1}).SetValue([]byte{10}),
+		core.NewStateChange().SetKey([]byte{2}).SetValue([]byte{20}),
+	}
+
+	mStrg.On("GetBlockHeight").Return(0)
+	mMsgSvc.On("RequestStateSnapshot", peer).Return(snapshot, nil)
+	mMsgSvc.On("RequestBlockByHeight", peer, uint64(10)).Return(b10, nil)
+	mMsgSvc.On("RequestBlockByHeight", peer, uint64(11)).Return(b11, nil)
+	mMsgSvc.On("RequestBlockByHeight", peer, uint64(12)).Return(b12, nil)
+	mMsgSvc.On("RequestBlockByHeight", peer, uint64(13)).Return(b13, nil)
+	mMsgSvc.On("RequestStateChunk", peer, uint64(10), []byte(nil), 1).Return(scList[:1], nil)
+	mMsgSvc.On("RequestStateChunk", peer, uint64(10), []byte{1}, 1).Return(scList[1:], nil)
+	mMsgSvc.On("RequestStateChunk", peer, uint64(10), []byte{2}, 1).Return(nil, nil)
+	sender := priv0.PublicKey().Bytes()
+	nonces := []*core.StateChange{
+		core.NewStateChange().SetKey(sender).SetValue([]byte{0, 0, 0, 0, 0, 0, 0, 3}),
+	}
+	mMsgSvc.On("RequestNonceChunk", peer, uint64(10), []byte(nil), 1).Return(nonces, nil)
+	mMsgSvc.On("RequestNonceChunk", peer, uint64(10), sender, 1).Return(nil, nil)
+	mStrg.On("InstallStateSnapshot", b10, q10, scList, nonces, snapshot.LeafCount(), mroot).Return(nil)
+
+	syncer := &stateSyncer{
+		resources:       resources,
+		config:          Config{StateSyncThreshold: 5, StateChunkSize: 1},
+		anchorScanLimit: 5,
+	}
+	syncer.run()
+
+	mMsgSvc.AssertNotCalled(t, "RequestStateSnapshot", priv0.PublicKey())
+	mStrg.AssertExpectations(t)
+
+	// certified merkle root mismatch
+	snapshot.SetMerkleRoot([]byte("fake-root"))
+	err := syncer.syncSnapshot(peer, snapshot)
+	assert.Error(err)
+
+	// snapshot within threshold
+	mStrg = new(MockStorage)
+	mStrg.On("GetBlockHeight").Return(8)
+	resources.Storage = mStrg
+	_, found := syncer.findSnapshot()
+	assert.Nil(found)
+
+	// no snapshot from validators
+	mMsgSvc = new(MockMsgService)
+	mMsgSvc.On("RequestStateSnapshot", mock.Anything).Return(nil, errors.New("not found"))
+	resources.MsgSvc = mMsgSvc
+	_, found = syncer.findSnapshot()
+	assert.Nil(found)
+}
+
+func TestStateSyncer_downloadStates(t *testing.T) {
+	assert := assert.New(t)
+
+	peer := core.GenerateKey(nil).PublicKey()
+	mMsgSvc := new(MockMsgService)
+	syncer := &stateSyncer{
+		resources: &Resources{MsgSvc: mMsgSvc},
+		config:    Config{StateChunkSize: 2},
+	}
+	scList := []*core.StateChange{
+		core.NewStateChange().SetKey([]byte{1}).SetValue([]byte{10}),
+		core.NewStateChange().SetKey([]byte{2}).SetValue([]byte{20}),
+	}
+	mMsgSvc.On("RequestStateChunk", peer, uint64(10), []byte(nil), 2).Return(scList, nil)
+	mMsgSvc.On("RequestStateChunk", peer, uint64(10), []byte{2}, 2).Return(nil, nil)
+
+	// leaf count from the peer is not used to allocate
+	snapshot := core.NewStateSnapshot().SetHeight(10).
+		SetLeafCount(new(big.Int).Lsh(big.NewInt(1), 62))
+	res, err := syncer.downloadStates(peer, snapshot)
+	assert.NoError(err)
+	assert.Equal(scList, res)
+
+	snapshot.SetLeafCount(big.NewInt(1))
+	_, err = syncer.downloadStates(peer, snapshot)
+	assert.Error(err, "too many states")
+}
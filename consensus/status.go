@@ -11,11 +11,17 @@ type Status struct {
 	BlockPoolSize   int
 	QCPoolSize      int
 
+	// evidence waiting to be included in a block
+	EvidencePoolSize int
+
 	// start timestamp of current view
 	ViewStart int64
 
-	// set to true when current view timeout
-	// set to false once the view leader created the first qc
+	// view is changed by the timeout cert of the majority
+	View uint64
+
+	// set to true when this node timed out in current view
+	// set to false once the timeout cert of the view is received
 	PendingViewChange bool
 	LeaderIndex       int
 
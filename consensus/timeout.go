@@ -0,0 +1,48 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package consensus
+
+import (
+	"sync"
+
+	"github.com/aungmawjj/juria-blockchain/core"
+)
+
+// timeoutPool collects the timeouts of validators to create the timeout cert of a view.
+// Only the latest timeout of each validator is kept, the pool is bound by the validator count.
+type timeoutPool struct {
+	timeouts map[string]*core.Timeout // by sender
+	mtx      sync.Mutex
+}
+
+func newTimeoutPool() *timeoutPool {
+	return &timeoutPool{
+		timeouts: make(map[string]*core.Timeout),
+	}
+}
+
+// addTimeout returns the timeout cert once the validators with the majority power
+// of the set timed out in the view of the timeout
+func (pool *timeoutPool) addTimeout(t *core.Timeout, vset core.ValidatorSet) *core.TimeoutCert {
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+
+	if prev, found := pool.timeouts[t.Sender().String()]; found && prev.View() > t.View() {
+		return nil
+	}
+	pool.timeouts[t.Sender().String()] = t
+
+	timeouts := make([]*core.Timeout, 0)
+	var power uint64
+	for _, to := range pool.timeouts {
+		if to.View() == t.View() && vset.IsValidator(to.Sender()) {
+			timeouts = append(timeouts, to)
+			power += votingPower(vset, to.Sender())
+		}
+	}
+	if power < vset.MajorityPower() {
+		return nil
+	}
+	return core.NewTimeoutCert().Build(timeouts)
+}
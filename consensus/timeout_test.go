@@ -0,0 +1,42 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package consensus
+
+import (
+	"testing"
+
+	"github.com/aungmawjj/juria-blockchain/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeoutPool(t *testing.T) {
+	assert := assert.New(t)
+
+	keys := make([]*core.PrivateKey, 4)
+	vlds := make([]*core.PublicKey, len(keys))
+	for i := range keys {
+		keys[i] = core.GenerateKey(nil)
+		vlds[i] = keys[i].PublicKey()
+	}
+	vset := core.NewValidatorStore(vlds)
+	newTimeout := func(view uint64, key *core.PrivateKey) *core.Timeout {
+		return core.NewTimeout().SetView(view).Sign(key)
+	}
+
+	pool := newTimeoutPool()
+	assert.Nil(pool.addTimeout(newTimeout(2, keys[0]), vset))
+	assert.Nil(pool.addTimeout(newTimeout(2, keys[0]), vset), "duplicate")
+	assert.Nil(pool.addTimeout(newTimeout(1, keys[1]), vset), "other view")
+	assert.Nil(pool.addTimeout(newTimeout(2, core.GenerateKey(nil)), vset), "not validator")
+	assert.Nil(pool.addTimeout(newTimeout(2, keys[2]), vset))
+
+	// later timeout replaces the earlier one
+	tc := pool.addTimeout(newTimeout(2, keys[1]), vset)
+	if assert.NotNil(tc) {
+		assert.EqualValues(2, tc.View())
+		assert.Equal(3, len(tc.Signatures()))
+		assert.NoError(tc.Validate(vset))
+	}
+	assert.Nil(pool.addTimeout(newTimeout(1, keys[1]), vset), "older than the latest timeout")
+}
@@ -6,6 +6,7 @@ package consensus
 import (
 	"bytes"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"sync"
 
@@ -16,8 +17,10 @@ import (
 
 type validator struct {
 	resources *Resources
+	config    Config
 	state     *state
 	hotstuff  *hotstuff.Hotstuff
+	rotator   *rotator
 
 	mtxProposal sync.Mutex
 
@@ -32,6 +35,7 @@ func (vld *validator) start() {
 	go vld.proposalLoop()
 	go vld.voteLoop()
 	go vld.newViewLoop()
+	go vld.evidenceLoop()
 	logger.I().Info("started validator")
 }
 
@@ -100,6 +104,23 @@ func (vld *validator) newViewLoop() {
 	}
 }
 
+func (vld *validator) evidenceLoop() {
+	sub := vld.resources.MsgSvc.SubscribeEvidence(100)
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case <-vld.stopCh:
+			return
+
+		case e := <-sub.Events():
+			if err := vld.addEvidence(e.(*core.Evidence)); err != nil {
+				logger.I().Warnf("received evidence failed, %+v", err)
+			}
+		}
+	}
+}
+
 func (vld *validator) onReceiveProposal(proposal *core.Block) error {
 	vld.mtxProposal.Lock()
 	defer vld.mtxProposal.Unlock()
@@ -107,14 +128,28 @@ func (vld *validator) onReceiveProposal(proposal *core.Block) error {
 	if err := proposal.Validate(vld.resources.VldStore); err != nil {
 		return err
 	}
-	pidx := vld.resources.VldStore.GetValidatorIndex(proposal.Proposer())
+	pidx := vld.state.getValidators(proposal.Height()).GetValidatorIndex(proposal.Proposer())
 	logger.I().Debugw("received proposal", "proposer", pidx, "height", proposal.Height())
+	if ev := vld.state.evidence.addProposal(proposal); ev != nil {
+		if err := vld.addEvidence(ev); err != nil {
+			logger.I().Warnf("conflicting proposal evidence failed, %+v", err)
+		}
+	}
+	if tc := proposal.TimeoutCert(); tc != nil {
+		vld.rotator.onProposalTimeoutCert(proposal) // validated with the proposal
+	}
 	parent, err := vld.getParentBlock(proposal)
 	if err != nil {
 		return err
 	}
+	if !proposal.IsGenesis() {
+		qc := proposal.QuorumCert()
+		if err := vld.verifyRefHeight(qc.BlockHash(), qc.BlockHeight()); err != nil {
+			return err
+		}
+	}
 	return vld.verifyWithParentAndUpdateHotstuff(
-		proposal.Proposer(), proposal, parent, true)
+		proposal.Proposer(), proposal, parent, !vld.config.Observer)
 }
 
 func (vld *validator) getParentBlock(proposal *core.Block) (*core.Block, error) {
@@ -189,6 +224,10 @@ func (vld *validator) syncMissingParentBlocksRecursive(
 	if err != nil {
 		return nil, err
 	}
+	if parent.TimeoutCert() != nil {
+		// the first block of the view carries the timeout cert for its later blocks
+		vld.rotator.onProposalTimeoutCert(parent)
+	}
 	err = vld.verifyWithParentAndUpdateHotstuff(peer, parent, grandParent, false)
 	if err != nil {
 		return nil, err
@@ -209,6 +248,9 @@ func (vld *validator) requestBlock(peer *core.PublicKey, hash []byte) (*core.Blo
 
 func (vld *validator) requestBlockByHeight(peer *core.PublicKey, height uint64) (*core.Block, error) {
 	blk, err := vld.resources.MsgSvc.RequestBlockByHeight(peer, height)
+	if errors.Is(err, core.ErrBlockPruned) {
+		blk, err = vld.requestUnprunedBlockByHeight(peer, height)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("cannot get block by height %d, %w", height, err)
 	}
@@ -218,6 +260,24 @@ func (vld *validator) requestBlockByHeight(peer *core.PublicKey, height uint64)
 	return blk, nil
 }
 
+// requestUnprunedBlockByHeight tries the other validators when the peer has pruned the block
+func (vld *validator) requestUnprunedBlockByHeight(
+	pruned *core.PublicKey, height uint64,
+) (blk *core.Block, err error) {
+	err = core.ErrBlockPruned
+	for i := 0; i < vld.resources.VldStore.ValidatorCount(); i++ {
+		pubKey := vld.resources.VldStore.GetValidator(i)
+		if pubKey.Equal(pruned) || pubKey.Equal(vld.resources.Signer.PublicKey()) {
+			continue
+		}
+		blk, err = vld.resources.MsgSvc.RequestBlockByHeight(pubKey, height)
+		if err == nil {
+			return blk, nil
+		}
+	}
+	return nil, err
+}
+
 func (vld *validator) verifyWithParentAndUpdateHotstuff(
 	peer *core.PublicKey, blk, parent *core.Block, voting bool,
 ) error {
@@ -250,8 +310,11 @@ func (vld *validator) updateHotstuff(blk *core.Block, voting bool) error {
 }
 
 func (vld *validator) verifyProposalToVote(proposal *core.Block) error {
-	if !vld.state.isLeader(proposal.Proposer()) {
-		pidx := vld.resources.VldStore.GetValidatorIndex(proposal.Proposer())
+	if proposal.View() != vld.state.getView() {
+		return fmt.Errorf("proposal view %d, current view %d", proposal.View(), vld.state.getView())
+	}
+	if !vld.state.isLeader(proposal.Proposer(), proposal.Height()) {
+		pidx := vld.state.getValidators(proposal.Height()).GetValidatorIndex(proposal.Proposer())
 		return fmt.Errorf("proposer %d is not leader", pidx)
 	}
 	// on node restart, not commited any blocks yet, don't check merkle root
@@ -276,6 +339,7 @@ func (vld *validator) verifyMerkleRoot(proposal *core.Block) error {
 }
 
 func (vld *validator) verifyProposalTxs(proposal *core.Block) error {
+	var gasTotal uint64
 	for _, hash := range proposal.Transactions() {
 		if vld.resources.Storage.HasTx(hash) {
 			return fmt.Errorf("already commited tx: %s", base64String(hash))
@@ -287,6 +351,10 @@ func (vld *validator) verifyProposalTxs(proposal *core.Block) error {
 		if tx.Expiry() != 0 && tx.Expiry() < proposal.Height() {
 			return fmt.Errorf("expired tx: %s", base64String(hash))
 		}
+		gasTotal += tx.GasLimit()
+	}
+	if vld.config.BlockGasLimit > 0 && gasTotal > vld.config.BlockGasLimit {
+		return fmt.Errorf("exceeded block gas limit: %d", gasTotal)
 	}
 	return nil
 }
@@ -295,18 +363,77 @@ func (vld *validator) onReceiveVote(vote *core.Vote) error {
 	if err := vote.Validate(vld.resources.VldStore); err != nil {
 		return err
 	}
+	if err := vld.verifyRefHeight(vote.BlockHash(), vote.BlockHeight()); err != nil {
+		return err
+	}
+	if prev := vld.state.evidence.checkVote(vote); prev != nil {
+		// voted blocks may need to be requested, don't block the vote loop
+		go func() {
+			if err := vld.onDoubleVote(prev, vote); err != nil {
+				logger.I().Warnf("double vote evidence failed, %+v", err)
+			}
+		}()
+	}
 	vld.hotstuff.OnReceiveVote(newHsVote(vote, vld.state))
 	return nil
 }
 
+func (vld *validator) onDoubleVote(voteA, voteB *core.Vote) error {
+	blkA, err := vld.getVotedBlock(voteA)
+	if err != nil {
+		return err
+	}
+	blkB, err := vld.getVotedBlock(voteB)
+	if err != nil {
+		return err
+	}
+	return vld.addEvidence(core.NewVoteEvidence(voteA, voteB, blkA, blkB))
+}
+
+func (vld *validator) getVotedBlock(vote *core.Vote) (*core.Block, error) {
+	if blk := vld.state.getBlock(vote.BlockHash()); blk != nil {
+		return blk, nil
+	}
+	return vld.requestBlock(vote.Voter(), vote.BlockHash())
+}
+
+// addEvidence keeps the valid evidence to be included in a block and gossips it if it's new
+func (vld *validator) addEvidence(ev *core.Evidence) error {
+	if err := ev.Validate(vld.resources.VldStore); err != nil {
+		return err
+	}
+	if !vld.state.evidence.addEvidence(ev) {
+		return nil
+	}
+	logger.I().Warnw("detected equivocation",
+		"validator", vld.state.getValidators(ev.Height()).GetValidatorIndex(ev.Accused()),
+		"height", ev.Height(),
+		"vote", ev.IsVote(),
+	)
+	return vld.resources.MsgSvc.BroadcastEvidence(ev)
+}
+
 func (vld *validator) onReceiveNewView(qc *core.QuorumCert) error {
 	if err := qc.Validate(vld.resources.VldStore); err != nil {
 		return err
 	}
+	if err := vld.verifyRefHeight(qc.BlockHash(), qc.BlockHeight()); err != nil {
+		return err
+	}
 	vld.hotstuff.UpdateQCHigh(newHsQC(qc, vld.state))
 	return nil
 }
 
+// block height in votes and qcs is not signed and
+// must be the same as the referenced block to select the validator set
+func (vld *validator) verifyRefHeight(blkHash []byte, height uint64) error {
+	blk := vld.state.getBlock(blkHash)
+	if blk != nil && blk.Height() != height {
+		return fmt.Errorf("invalid ref height %d, block %d", height, blk.Height())
+	}
+	return nil
+}
+
 func base64String(b []byte) string {
 	return base64.StdEncoding.EncodeToString(b)
 }
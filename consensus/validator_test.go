@@ -41,20 +41,26 @@ func TestValidator_verifyProposalToVote(t *testing.T) {
 	// This should not happen at run time.
 	// Not found tx means sync txs failed. If sync failed, cannot vote already
 	tx5 := core.NewTransaction().SetExpiry(15).Sign(core.GenerateKey(nil))
+	// high gas limit tx
+	tx6 := core.NewTransaction().SetExpiry(15).
+		SetGasLimit(2 * core.DefaultTxGasLimit).Sign(core.GenerateKey(nil))
 
 	mStrg.On("HasTx", tx1.Hash()).Return(false)
 	mStrg.On("HasTx", tx2.Hash()).Return(true)
 	mStrg.On("HasTx", tx3.Hash()).Return(false)
 	mStrg.On("HasTx", tx4.Hash()).Return(false)
 	mStrg.On("HasTx", tx5.Hash()).Return(false)
+	mStrg.On("HasTx", tx6.Hash()).Return(false)
 
 	mTxPool.On("GetTx", tx1.Hash()).Return(tx1)
 	mTxPool.On("GetTx", tx3.Hash()).Return(tx3)
 	mTxPool.On("GetTx", tx4.Hash()).Return(tx4)
 	mTxPool.On("GetTx", tx5.Hash()).Return(nil)
+	mTxPool.On("GetTx", tx6.Hash()).Return(tx6)
 
 	vld := &validator{
 		resources: resources,
+		config:    Config{BlockGasLimit: 3 * core.DefaultTxGasLimit},
 		state:     newState(resources),
 	}
 	vld.state.commitedHeight = mStrg.GetBlockHeight()
@@ -75,6 +81,12 @@ func TestValidator_verifyProposalToVote(t *testing.T) {
 			SetTransactions([][]byte{tx1.Hash(), tx4.Hash()}).
 			Sign(priv0),
 		},
+		{"different view", false, core.NewBlock().
+			SetHeight(14).SetExecHeight(10).SetMerkleRoot(mRoot).
+			SetTransactions([][]byte{tx1.Hash(), tx4.Hash()}).
+			SetTimeoutCert(newTestTimeoutCert(0, priv0, priv1)).
+			Sign(priv1),
+		},
 		{"different exec height", false, core.NewBlock().
 			SetHeight(14).SetExecHeight(9).SetMerkleRoot(mRoot).
 			SetTransactions([][]byte{tx1.Hash(), tx4.Hash()}).
@@ -100,6 +112,16 @@ func TestValidator_verifyProposalToVote(t *testing.T) {
 			SetTransactions([][]byte{tx1.Hash(), tx5.Hash(), tx4.Hash()}).
 			Sign(priv1),
 		},
+		{"within block gas limit", true, core.NewBlock().
+			SetHeight(14).SetExecHeight(10).SetMerkleRoot(mRoot).
+			SetTransactions([][]byte{tx1.Hash(), tx6.Hash()}).
+			Sign(priv1),
+		},
+		{"exceeded block gas limit", false, core.NewBlock().
+			SetHeight(14).SetExecHeight(10).SetMerkleRoot(mRoot).
+			SetTransactions([][]byte{tx1.Hash(), tx6.Hash(), tx4.Hash()}).
+			Sign(priv1),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -112,3 +134,30 @@ func TestValidator_verifyProposalToVote(t *testing.T) {
 		})
 	}
 }
+
+func TestValidator_requestBlockByHeight_Pruned(t *testing.T) {
+	assert := assert.New(t)
+
+	priv0 := core.GenerateKey(nil)
+	priv1 := core.GenerateKey(nil)
+	priv2 := core.GenerateKey(nil)
+	mMsgSvc := new(MockMsgService)
+	resources := &Resources{
+		Signer: priv0,
+		VldStore: core.NewValidatorStore([]*core.PublicKey{
+			priv0.PublicKey(), priv1.PublicKey(), priv2.PublicKey(),
+		}),
+		MsgSvc: mMsgSvc,
+	}
+	vld := &validator{resources: resources}
+
+	b0 := core.NewBlock().SetHeight(0).Sign(priv1)
+	mMsgSvc.On("RequestBlockByHeight", priv1.PublicKey(), uint64(0)).
+		Return(nil, core.ErrBlockPruned)
+	mMsgSvc.On("RequestBlockByHeight", priv2.PublicKey(), uint64(0)).Return(b0, nil)
+
+	blk, err := vld.requestBlockByHeight(priv1.PublicKey(), 0)
+	assert.NoError(err)
+	assert.Equal(b0, blk)
+	mMsgSvc.AssertNotCalled(t, "RequestBlockByHeight", priv0.PublicKey(), uint64(0))
+}
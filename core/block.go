@@ -19,6 +19,8 @@ import (
 var (
 	ErrInvalidBlockHash = errors.New("invalid block hash")
 	ErrNilBlock         = errors.New("nil block")
+	ErrInvalidQCHeight  = errors.New("qc height must be lower than block height")
+	ErrBlockPruned      = errors.New("block txs are pruned")
 )
 
 // Block type
@@ -26,6 +28,8 @@ type Block struct {
 	data       *core_pb.Block
 	proposer   *PublicKey
 	quorumCert *QuorumCert
+	evidence   []*Evidence
+	tc         *TimeoutCert
 }
 
 var _ json.Marshaler = (*Block)(nil)
@@ -52,6 +56,12 @@ func (blk *Block) Sum() []byte {
 	for _, txHash := range blk.data.Transactions {
 		h.Write(txHash)
 	}
+	for _, ev := range blk.evidence {
+		h.Write(ev.Hash())
+	}
+	if blk.data.View > 0 {
+		binary.Write(h, binary.BigEndian, blk.data.View)
+	}
 	return h.Sum(nil)
 }
 
@@ -64,32 +74,54 @@ func (blk *Block) Validate(vs ValidatorStore) error {
 		if err := blk.quorumCert.Validate(vs); err != nil {
 			return err
 		}
+		if blk.quorumCert.BlockHeight() >= blk.Height() {
+			return ErrInvalidQCHeight
+		}
 	}
 	if !bytes.Equal(blk.Sum(), blk.Hash()) {
 		return ErrInvalidBlockHash
 	}
-	sig, err := newSignature(&core_pb.Signature{
-		PubKey: blk.data.Proposer,
-		Value:  blk.data.Signature,
-	})
-	if !vs.IsValidator(sig.PublicKey()) {
-		return ErrInvalidValidator
-	}
+	sig, err := blk.proposerSignature()
 	if err != nil {
 		return err
 	}
-	if !sig.Verify(blk.data.Hash) {
+	if !vs.AtHeight(blk.Height()).IsValidator(sig.PublicKey()) {
+		return ErrInvalidValidator
+	}
+	if !sig.Verify(voteMsg(blk.data.Hash, blk.data.Height)) {
 		return ErrInvalidSig
 	}
+	for _, ev := range blk.evidence {
+		if err := ev.Validate(vs); err != nil {
+			return err
+		}
+	}
+	if blk.tc != nil {
+		if blk.tc.View()+1 != blk.data.View {
+			return ErrInvalidTC
+		}
+		return blk.tc.Validate(vs.AtHeight(blk.Height())) // validator set of the proposal
+	}
 	return nil
 }
 
+func (blk *Block) proposerSignature() (*Signature, error) {
+	return newSignature(&core_pb.Signature{
+		PubKey: blk.data.Proposer,
+		Value:  blk.data.Signature,
+	})
+}
+
 // Vote creates a vote for block
 func (blk *Block) Vote(signer Signer) *Vote {
+	msg := voteMsg(blk.data.Hash, blk.data.Height)
 	vote := NewVote()
 	vote.setData(&core_pb.Vote{
-		BlockHash: blk.data.Hash,
-		Signature: signer.Sign(blk.data.Hash).data,
+		BlockHash:    blk.data.Hash,
+		BlockHeight:  blk.data.Height,
+		View:         blk.View(),
+		Signature:    signer.Sign(msg).data,
+		BlsSignature: signBLS(signer, msg),
 	})
 	return vote
 }
@@ -97,11 +129,14 @@ func (blk *Block) Vote(signer Signer) *Vote {
 func (blk *Block) ProposerVote() *Vote {
 	vote := NewVote()
 	vote.setData(&core_pb.Vote{
-		BlockHash: blk.data.Hash,
+		BlockHash:   blk.data.Hash,
+		BlockHeight: blk.data.Height,
+		View:        blk.View(),
 		Signature: &core_pb.Signature{
 			PubKey: blk.data.Proposer,
 			Value:  blk.data.Signature,
 		},
+		BlsSignature: blk.data.BlsSignature,
 	})
 	return vote
 }
@@ -119,6 +154,17 @@ func (blk *Block) setData(data *core_pb.Block) error {
 		return err
 	}
 	blk.proposer = proposer
+	blk.evidence = make([]*Evidence, len(data.Evidence))
+	for i, evData := range data.Evidence {
+		blk.evidence[i] = NewEvidence()
+		if err := blk.evidence[i].setData(evData); err != nil {
+			return err
+		}
+	}
+	if data.TimeoutCert != nil {
+		blk.tc = NewTimeoutCert()
+		return blk.tc.setData(data.TimeoutCert)
+	}
 	return nil
 }
 
@@ -158,24 +204,56 @@ func (blk *Block) SetTransactions(val [][]byte) *Block {
 	return blk
 }
 
+func (blk *Block) SetEvidence(val []*Evidence) *Block {
+	blk.evidence = val
+	blk.data.Evidence = make([]*core_pb.Evidence, len(val))
+	for i, ev := range val {
+		blk.data.Evidence[i] = ev.data
+	}
+	return blk
+}
+
+// SetView sets the view of the proposer
+func (blk *Block) SetView(val uint64) *Block {
+	blk.data.View = val
+	return blk
+}
+
+// SetTimeoutCert sets the timeout cert of the previous view, which moved to the view of the proposer.
+// It's only carried by the first block of the view, the later blocks of the view are linked to it.
+func (blk *Block) SetTimeoutCert(val *TimeoutCert) *Block {
+	blk.tc = val
+	blk.data.TimeoutCert = val.data
+	blk.data.View = val.View() + 1
+	return blk
+}
+
+// Sign signs the block as the proposer, the signatures are also used as the vote of the proposer
 func (blk *Block) Sign(signer Signer) *Block {
 	blk.proposer = signer.PublicKey()
 	blk.data.Proposer = signer.PublicKey().key
 	blk.data.Hash = blk.Sum()
-	blk.data.Signature = signer.Sign(blk.data.Hash).data.Value
+	msg := voteMsg(blk.data.Hash, blk.data.Height)
+	blk.data.Signature = signer.Sign(msg).data.Value
+	blk.data.BlsSignature = signBLS(signer, msg)
 	return blk
 }
 
-func (blk *Block) Hash() []byte            { return blk.data.Hash }
-func (blk *Block) Height() uint64          { return blk.data.Height }
-func (blk *Block) ParentHash() []byte      { return blk.data.ParentHash }
-func (blk *Block) Proposer() *PublicKey    { return blk.proposer }
-func (blk *Block) QuorumCert() *QuorumCert { return blk.quorumCert }
-func (blk *Block) ExecHeight() uint64      { return blk.data.ExecHeight }
-func (blk *Block) MerkleRoot() []byte      { return blk.data.MerkleRoot }
-func (blk *Block) Timestamp() int64        { return blk.data.Timestamp }
-func (blk *Block) Transactions() [][]byte  { return blk.data.Transactions }
-func (blk *Block) IsGenesis() bool         { return blk.Height() == 0 }
+func (blk *Block) Hash() []byte              { return blk.data.Hash }
+func (blk *Block) Height() uint64            { return blk.data.Height }
+func (blk *Block) ParentHash() []byte        { return blk.data.ParentHash }
+func (blk *Block) Proposer() *PublicKey      { return blk.proposer }
+func (blk *Block) QuorumCert() *QuorumCert   { return blk.quorumCert }
+func (blk *Block) ExecHeight() uint64        { return blk.data.ExecHeight }
+func (blk *Block) MerkleRoot() []byte        { return blk.data.MerkleRoot }
+func (blk *Block) Timestamp() int64          { return blk.data.Timestamp }
+func (blk *Block) Transactions() [][]byte    { return blk.data.Transactions }
+func (blk *Block) Evidence() []*Evidence     { return blk.evidence }
+func (blk *Block) TimeoutCert() *TimeoutCert { return blk.tc }
+func (blk *Block) IsGenesis() bool           { return blk.Height() == 0 }
+
+// View gives the view of the proposer
+func (blk *Block) View() uint64 { return blk.data.View }
 
 // Marshal encodes blk as bytes
 func (blk *Block) Marshal() ([]byte, error) {
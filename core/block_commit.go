@@ -48,6 +48,13 @@ func (bcm *BlockCommit) SetElapsedMerkle(val float64) *BlockCommit {
 	return bcm
 }
 
+// SetPrevNonces sets the next nonces of the senders before the block
+func (bcm *BlockCommit) SetPrevNonces(senders [][]byte, nonces []int64) *BlockCommit {
+	bcm.data.NonceSenders = senders
+	bcm.data.PrevNonces = nonces
+	return bcm
+}
+
 func (bcm *BlockCommit) SetStateChanges(val []*StateChange) *BlockCommit {
 	scpb := make([]*core_pb.StateChange, len(val))
 	for i, sc := range val {
@@ -63,6 +70,8 @@ func (bcm *BlockCommit) LeafCount() []byte      { return bcm.data.LeafCount }
 func (bcm *BlockCommit) MerkleRoot() []byte     { return bcm.data.MerkleRoot }
 func (bcm *BlockCommit) ElapsedExec() float64   { return bcm.data.ElapsedExec }
 func (bcm *BlockCommit) ElapsedMerkle() float64 { return bcm.data.ElapsedMerkle }
+func (bcm *BlockCommit) NonceSenders() [][]byte { return bcm.data.NonceSenders }
+func (bcm *BlockCommit) PrevNonces() []int64    { return bcm.data.PrevNonces }
 
 func (bcm *BlockCommit) StateChanges() []*StateChange {
 	scList := make([]*StateChange, len(bcm.data.StateChanges))
@@ -19,7 +19,7 @@ func TestBlock(t *testing.T) {
 	qc := NewQuorumCert().Build([]*Vote{
 		{data: &core_pb.Vote{
 			BlockHash: []byte{0},
-			Signature: privKey.Sign([]byte{0}).data,
+			Signature: privKey.Sign(voteMsg([]byte{0}, 0)).data,
 		}},
 	})
 
@@ -43,7 +43,9 @@ func TestBlock(t *testing.T) {
 
 	vs := new(MockValidatorStore)
 	vs.On("ValidatorCount").Return(1)
-	vs.On("MajorityCount").Return(1)
+	vs.On("MajorityPower").Return(uint64(1))
+	vs.On("GetValidatorIndex", mock.Anything).Return(0)
+	vs.On("GetPower", mock.Anything).Return(uint64(1))
 	vs.On("IsValidator", privKey.PublicKey()).Return(true)
 	vs.On("IsValidator", mock.Anything).Return(false)
 
@@ -112,3 +114,47 @@ func TestBlock_Vote(t *testing.T) {
 	assert.NoError(err)
 	vs.AssertExpectations(t)
 }
+
+func TestBlock_ValidateQCHeight(t *testing.T) {
+	assert := assert.New(t)
+
+	privKey := GenerateKey(nil)
+	vs := NewValidatorStore([]*PublicKey{privKey.PublicKey()})
+
+	b10 := NewBlock().SetHeight(10).Sign(privKey)
+	qc := NewQuorumCert().Build([]*Vote{b10.Vote(privKey)})
+	assert.EqualValues(10, b10.Vote(privKey).BlockHeight())
+	assert.EqualValues(10, qc.BlockHeight())
+
+	blk := NewBlock().SetHeight(11).SetQuorumCert(qc).Sign(privKey)
+	assert.NoError(blk.Validate(vs))
+
+	blk = NewBlock().SetHeight(10).SetQuorumCert(qc).Sign(privKey)
+	assert.Equal(ErrInvalidQCHeight, blk.Validate(vs))
+
+	// proposer is not in the validator set at block height
+	vs.AddEpoch(&ValidatorEpoch{
+		StartHeight: 11,
+		Validators:  []*PublicKey{GenerateKey(nil).PublicKey()},
+	})
+	blk = NewBlock().SetHeight(11).SetQuorumCert(qc).Sign(privKey)
+	assert.Equal(ErrInvalidValidator, blk.Validate(vs))
+}
+
+func TestBlock_VoteHeightSigned(t *testing.T) {
+	assert := assert.New(t)
+
+	privKey := GenerateKey(nil)
+	vs := NewValidatorStore([]*PublicKey{privKey.PublicKey()})
+
+	b10 := NewBlock().SetHeight(10).Sign(privKey)
+	vote := b10.Vote(privKey)
+	assert.NoError(vote.Validate(vs))
+	vote.data.BlockHeight = 9
+	assert.Equal(ErrInvalidSig, vote.Validate(vs), "vote height is signed")
+
+	qc := NewQuorumCert().Build([]*Vote{b10.ProposerVote()})
+	assert.NoError(qc.Validate(vs))
+	qc.data.BlockHeight = 9
+	assert.Equal(ErrInvalidSig, qc.Validate(vs), "qc height is signed")
+}
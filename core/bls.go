@@ -0,0 +1,220 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package core
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"math/big"
+
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+// BLS signatures on BLS12-381 curve, public keys are G1 points and signatures are G2 points.
+// Signatures of the same message can be aggregated into one signature,
+// which is verified against the sum of the public keys.
+// Rogue key attacks are prevented by the proof of possession given with each public key.
+
+// bls key sizes
+const (
+	BLSPrivateKeySize = 32
+	BLSPublicKeySize  = 48
+	BLSSignatureSize  = 96
+)
+
+// errors
+var (
+	ErrInvalidBLSKey        = errors.New("invalid bls key")
+	ErrInvalidBLSPossession = errors.New("invalid bls proof of possession")
+)
+
+var (
+	blsSigDomain = []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
+	blsPopDomain = []byte("BLS_POP_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
+)
+
+// BLSPublicKey type
+type BLSPublicKey struct {
+	point  *bls12381.PointG1
+	raw    []byte
+	keyStr string
+}
+
+// NewBLSPublicKey creates BLSPublicKey from compressed bytes
+func NewBLSPublicKey(b []byte) (*BLSPublicKey, error) {
+	if len(b) != BLSPublicKeySize {
+		return nil, ErrInvalidKeySize
+	}
+	g1 := bls12381.NewG1()
+	point, err := g1.FromCompressed(b) // checks the subgroup
+	if err != nil || g1.IsZero(point) {
+		return nil, ErrInvalidBLSKey
+	}
+	return &BLSPublicKey{
+		point:  point,
+		raw:    append([]byte{}, b...),
+		keyStr: base64.StdEncoding.EncodeToString(b),
+	}, nil
+}
+
+// Equal checks whether pub and x has the same value
+func (pub *BLSPublicKey) Equal(x *BLSPublicKey) bool {
+	return bls12381.NewG1().Equal(pub.point, x.point)
+}
+
+// Bytes return compressed bytes
+func (pub *BLSPublicKey) Bytes() []byte {
+	return pub.raw
+}
+
+func (pub *BLSPublicKey) String() string {
+	return pub.keyStr
+}
+
+// Verify verifies the signature of the message
+func (pub *BLSPublicKey) Verify(msg, sig []byte) bool {
+	return blsVerify(pub.point, msg, sig, blsSigDomain)
+}
+
+// VerifyPossession verifies the proof that the key owner has the private key
+func (pub *BLSPublicKey) VerifyPossession(proof []byte) bool {
+	return blsVerify(pub.point, pub.raw, proof, blsPopDomain)
+}
+
+// BLSPrivateKey type
+type BLSPrivateKey struct {
+	key    *big.Int
+	pubKey *BLSPublicKey
+}
+
+// NewBLSPrivateKey creates BLSPrivateKey from bytes
+func NewBLSPrivateKey(b []byte) (*BLSPrivateKey, error) {
+	if len(b) != BLSPrivateKeySize {
+		return nil, ErrInvalidKeySize
+	}
+	g1 := bls12381.NewG1()
+	key := new(big.Int).SetBytes(b)
+	if key.Sign() == 0 || key.Cmp(g1.Q()) >= 0 {
+		return nil, ErrInvalidBLSKey
+	}
+	point := g1.MulScalarBig(g1.New(), g1.One(), key)
+	raw := g1.ToCompressed(point)
+	return &BLSPrivateKey{
+		key: key,
+		pubKey: &BLSPublicKey{
+			point:  point,
+			raw:    raw,
+			keyStr: base64.StdEncoding.EncodeToString(raw),
+		},
+	}, nil
+}
+
+// GenerateBLSKey generates a bls private key, crypto/rand is used if rand is nil
+func GenerateBLSKey(random io.Reader) *BLSPrivateKey {
+	if random == nil {
+		random = rand.Reader
+	}
+	q := bls12381.NewG1().Q()
+	for {
+		key, err := rand.Int(random, q)
+		if err != nil {
+			panic(err)
+		}
+		if key.Sign() == 0 {
+			continue
+		}
+		b := make([]byte, BLSPrivateKeySize)
+		priv, _ := NewBLSPrivateKey(key.FillBytes(b))
+		return priv
+	}
+}
+
+// Bytes return raw bytes
+func (priv *BLSPrivateKey) Bytes() []byte {
+	return priv.key.FillBytes(make([]byte, BLSPrivateKeySize))
+}
+
+// PublicKey returns corresponding public key
+func (priv *BLSPrivateKey) PublicKey() *BLSPublicKey {
+	return priv.pubKey
+}
+
+// Sign signs the message
+func (priv *BLSPrivateKey) Sign(msg []byte) []byte {
+	return blsSign(priv.key, msg, blsSigDomain)
+}
+
+// ProvePossession signs the public key to be registered with it
+func (priv *BLSPrivateKey) ProvePossession() []byte {
+	return blsSign(priv.key, priv.pubKey.raw, blsPopDomain)
+}
+
+func blsSign(key *big.Int, msg, domain []byte) []byte {
+	g2 := bls12381.NewG2()
+	point, err := g2.HashToCurve(msg, domain)
+	if err != nil {
+		panic(err)
+	}
+	return g2.ToCompressed(g2.MulScalarBig(point, point, key))
+}
+
+func blsVerify(pubKey *bls12381.PointG1, msg, sig, domain []byte) bool {
+	sigPoint, err := decodeBLSSignature(sig)
+	if err != nil {
+		return false
+	}
+	msgPoint, err := bls12381.NewG2().HashToCurve(msg, domain)
+	if err != nil {
+		return false
+	}
+	// e(pubKey, H(msg)) == e(g1, sig)
+	engine := bls12381.NewEngine()
+	engine.AddPair(pubKey, msgPoint)
+	engine.AddPairInv(engine.G1.One(), sigPoint)
+	return engine.Check()
+}
+
+func decodeBLSSignature(sig []byte) (*bls12381.PointG2, error) {
+	if len(sig) != BLSSignatureSize {
+		return nil, ErrInvalidSig
+	}
+	g2 := bls12381.NewG2()
+	point, err := g2.FromCompressed(sig)
+	if err != nil || g2.IsZero(point) {
+		return nil, ErrInvalidSig
+	}
+	return point, nil
+}
+
+// AggregateBLSSignatures combines the signatures into one
+func AggregateBLSSignatures(sigs [][]byte) ([]byte, error) {
+	g2 := bls12381.NewG2()
+	agg := g2.Zero()
+	for _, sig := range sigs {
+		point, err := decodeBLSSignature(sig)
+		if err != nil {
+			return nil, err
+		}
+		g2.Add(agg, agg, point)
+	}
+	return g2.ToCompressed(agg), nil
+}
+
+// VerifyBLSAggregate verifies the aggregated signature of the same message signed by all keys
+func VerifyBLSAggregate(pubKeys []*BLSPublicKey, msg, sig []byte) bool {
+	if len(pubKeys) == 0 {
+		return false
+	}
+	g1 := bls12381.NewG1()
+	agg := g1.Zero()
+	for _, pubKey := range pubKeys {
+		g1.Add(agg, agg, pubKey.point)
+	}
+	if g1.IsZero(agg) {
+		return false
+	}
+	return blsVerify(agg, msg, sig, blsSigDomain)
+}
@@ -0,0 +1,72 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBLSSignVerify(t *testing.T) {
+	assert := assert.New(t)
+
+	privKey := GenerateBLSKey(nil)
+	msg := []byte("message to be signed")
+	sig := privKey.Sign(msg)
+	assert.Equal(BLSSignatureSize, len(sig))
+
+	assert.True(privKey.PublicKey().Verify(msg, sig))
+	assert.False(privKey.PublicKey().Verify([]byte("tampered message"), sig))
+	assert.False(GenerateBLSKey(nil).PublicKey().Verify(msg, sig))
+
+	privKey1, err := NewBLSPrivateKey(privKey.Bytes())
+	assert.NoError(err)
+	assert.True(privKey.PublicKey().Equal(privKey1.PublicKey()))
+
+	pubKey, err := NewBLSPublicKey(privKey.PublicKey().Bytes())
+	assert.NoError(err)
+	assert.True(pubKey.Verify(msg, sig))
+
+	_, err = NewBLSPrivateKey(make([]byte, BLSPrivateKeySize))
+	assert.Equal(ErrInvalidBLSKey, err)
+	_, err = NewBLSPublicKey(make([]byte, BLSPublicKeySize))
+	assert.Equal(ErrInvalidBLSKey, err)
+}
+
+func TestBLSPossession(t *testing.T) {
+	assert := assert.New(t)
+
+	privKey := GenerateBLSKey(nil)
+	proof := privKey.ProvePossession()
+	assert.True(privKey.PublicKey().VerifyPossession(proof))
+	assert.False(GenerateBLSKey(nil).PublicKey().VerifyPossession(proof))
+
+	// signature of the public key bytes is not a valid proof
+	assert.False(privKey.PublicKey().VerifyPossession(privKey.Sign(privKey.PublicKey().Bytes())))
+}
+
+func TestBLSAggregate(t *testing.T) {
+	assert := assert.New(t)
+
+	msg := []byte("block hash")
+	pubKeys := make([]*BLSPublicKey, 3)
+	sigs := make([][]byte, 3)
+	for i := range pubKeys {
+		privKey := GenerateBLSKey(nil)
+		pubKeys[i] = privKey.PublicKey()
+		sigs[i] = privKey.Sign(msg)
+	}
+	aggSig, err := AggregateBLSSignatures(sigs)
+	assert.NoError(err)
+	assert.Equal(BLSSignatureSize, len(aggSig))
+
+	assert.True(VerifyBLSAggregate(pubKeys, msg, aggSig))
+	assert.False(VerifyBLSAggregate(pubKeys[:2], msg, aggSig))
+	assert.False(VerifyBLSAggregate(pubKeys, []byte("other"), aggSig))
+	assert.False(VerifyBLSAggregate(nil, msg, aggSig))
+
+	_, err = AggregateBLSSignatures([][]byte{sigs[0], []byte("invalid")})
+	assert.Error(err)
+}
@@ -22,6 +22,36 @@ type Signer interface {
 	PublicKey() *PublicKey
 }
 
+// BLSSigner is a validator signer which also signs votes with its bls key
+type BLSSigner interface {
+	Signer
+	BLSKey() *BLSPrivateKey
+}
+
+type blsSigner struct {
+	*PrivateKey
+	blsKey *BLSPrivateKey
+}
+
+var _ BLSSigner = (*blsSigner)(nil)
+
+// NewBLSSigner creates a signer with the identity key and the bls key
+func NewBLSSigner(priv *PrivateKey, blsKey *BLSPrivateKey) BLSSigner {
+	return &blsSigner{priv, blsKey}
+}
+
+func (signer *blsSigner) BLSKey() *BLSPrivateKey {
+	return signer.blsKey
+}
+
+// signBLS gives the bls signature if the signer has bls key
+func signBLS(signer Signer, msg []byte) []byte {
+	if blsSigner, ok := signer.(BLSSigner); ok {
+		return blsSigner.BLSKey().Sign(msg)
+	}
+	return nil
+}
+
 // PublicKey type
 type PublicKey struct {
 	key    ed25519.PublicKey
@@ -153,7 +183,7 @@ func (sigs sigList) hasDuplicate() bool {
 	return false
 }
 
-func (sigs sigList) hasInvalidValidator(vs ValidatorStore) bool {
+func (sigs sigList) hasInvalidValidator(vs ValidatorSet) bool {
 	for _, sig := range sigs {
 		if !vs.IsValidator(sig.PublicKey()) {
 			return true
@@ -162,6 +192,15 @@ func (sigs sigList) hasInvalidValidator(vs ValidatorStore) bool {
 	return false
 }
 
+// power gives the total voting power of the signers
+func (sigs sigList) power(vs ValidatorSet) uint64 {
+	var power uint64
+	for _, sig := range sigs {
+		power += vs.GetPower(vs.GetValidatorIndex(sig.PublicKey()))
+	}
+	return power
+}
+
 func (sigs sigList) hasInvalidSig(msg []byte) bool {
 	for _, sig := range sigs {
 		if !sig.Verify(msg) {
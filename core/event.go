@@ -0,0 +1,74 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package core
+
+import (
+	"encoding/json"
+
+	"github.com/aungmawjj/juria-blockchain/core/core_pb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Event is emitted by chaincode during tx execution
+type Event struct {
+	data *core_pb.Event
+}
+
+var _ json.Marshaler = (*Event)(nil)
+var _ json.Unmarshaler = (*Event)(nil)
+
+func NewEvent() *Event {
+	return &Event{
+		data: new(core_pb.Event),
+	}
+}
+
+func (evt *Event) CodeAddr() []byte { return evt.data.CodeAddr }
+func (evt *Event) Name() string     { return evt.data.Name }
+func (evt *Event) Data() []byte     { return evt.data.Data }
+
+func (evt *Event) SetCodeAddr(val []byte) *Event {
+	evt.data.CodeAddr = val
+	return evt
+}
+
+func (evt *Event) SetName(val string) *Event {
+	evt.data.Name = val
+	return evt
+}
+
+func (evt *Event) SetData(val []byte) *Event {
+	evt.data.Data = val
+	return evt
+}
+
+func (evt *Event) setData(data *core_pb.Event) error {
+	evt.data = data
+	return nil
+}
+
+func (evt *Event) Marshal() ([]byte, error) {
+	return proto.Marshal(evt.data)
+}
+
+func (evt *Event) Unmarshal(b []byte) error {
+	data := new(core_pb.Event)
+	if err := proto.Unmarshal(b, data); err != nil {
+		return err
+	}
+	return evt.setData(data)
+}
+
+func (evt *Event) MarshalJSON() ([]byte, error) {
+	return protojson.Marshal(evt.data)
+}
+
+func (evt *Event) UnmarshalJSON(b []byte) error {
+	data := new(core_pb.Event)
+	if err := protojson.Unmarshal(b, data); err != nil {
+		return err
+	}
+	return evt.setData(data)
+}
@@ -0,0 +1,218 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/aungmawjj/juria-blockchain/core/core_pb"
+	"golang.org/x/crypto/sha3"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// errors
+var (
+	ErrNilEvidence     = errors.New("nil evidence")
+	ErrInvalidEvidence = errors.New("invalid evidence")
+)
+
+// Evidence of equivocation, a validator signed two different blocks at the same height and view.
+// Blocks are included because the height and view are signed through the block hash.
+type Evidence struct {
+	data   *core_pb.Evidence
+	blockA *Block
+	blockB *Block
+	voteA  *Vote
+	voteB  *Vote
+}
+
+func NewEvidence() *Evidence {
+	return &Evidence{
+		data: new(core_pb.Evidence),
+	}
+}
+
+// NewProposalEvidence creates the evidence of a proposer with two different blocks
+func NewProposalEvidence(blkA, blkB *Block) *Evidence {
+	return &Evidence{
+		data: &core_pb.Evidence{
+			BlockA: blkA.data,
+			BlockB: blkB.data,
+		},
+		blockA: blkA,
+		blockB: blkB,
+	}
+}
+
+// NewVoteEvidence creates the evidence of a voter with votes for two different blocks
+func NewVoteEvidence(voteA, voteB *Vote, blkA, blkB *Block) *Evidence {
+	ev := NewProposalEvidence(blkA, blkB)
+	ev.data.VoteA = voteA.data
+	ev.data.VoteB = voteB.data
+	ev.voteA = voteA
+	ev.voteB = voteB
+	return ev
+}
+
+// Validate evidence
+func (ev *Evidence) Validate(vs ValidatorStore) error {
+	if ev.data == nil || ev.blockA == nil || ev.blockB == nil {
+		return ErrNilEvidence
+	}
+	for _, blk := range []*Block{ev.blockA, ev.blockB} {
+		if !bytes.Equal(blk.Sum(), blk.Hash()) {
+			return ErrInvalidBlockHash
+		}
+	}
+	if ev.blockA.Height() != ev.blockB.Height() || ev.blockA.View() != ev.blockB.View() {
+		return ErrInvalidEvidence
+	}
+	if bytes.Equal(ev.blockA.Hash(), ev.blockB.Hash()) {
+		return ErrInvalidEvidence
+	}
+	sigA, sigB, err := ev.signatures()
+	if err != nil {
+		return err
+	}
+	if !sigA.PublicKey().Equal(sigB.PublicKey()) {
+		return ErrInvalidEvidence
+	}
+	if !vs.AtHeight(ev.Height()).IsValidator(sigA.PublicKey()) {
+		return ErrInvalidValidator
+	}
+	if !sigA.Verify(voteMsg(ev.blockA.Hash(), ev.Height())) ||
+		!sigB.Verify(voteMsg(ev.blockB.Hash(), ev.Height())) {
+		return ErrInvalidSig
+	}
+	return nil
+}
+
+// signatures gives the signatures of the accused validator for both blocks
+func (ev *Evidence) signatures() (*Signature, *Signature, error) {
+	if !ev.IsVote() {
+		sigA, err := ev.blockA.proposerSignature()
+		if err != nil {
+			return nil, nil, err
+		}
+		sigB, err := ev.blockB.proposerSignature()
+		return sigA, sigB, err
+	}
+	if ev.voteA == nil || ev.voteB == nil {
+		return nil, nil, ErrNilVote
+	}
+	if !bytes.Equal(ev.voteA.BlockHash(), ev.blockA.Hash()) ||
+		!bytes.Equal(ev.voteB.BlockHash(), ev.blockB.Hash()) {
+		return nil, nil, ErrInvalidEvidence
+	}
+	if ev.voteA.View() != ev.blockA.View() || ev.voteB.View() != ev.blockB.View() {
+		return nil, nil, ErrInvalidEvidence
+	}
+	sigA, err := newSignature(ev.voteA.data.Signature)
+	if err != nil {
+		return nil, nil, err
+	}
+	sigB, err := newSignature(ev.voteB.data.Signature)
+	return sigA, sigB, err
+}
+
+func (ev *Evidence) setData(data *core_pb.Evidence) error {
+	ev.data = data
+	if data.BlockA == nil || data.BlockB == nil {
+		return ErrNilEvidence
+	}
+	ev.blockA = NewBlock()
+	if err := ev.blockA.setData(data.BlockA); err != nil {
+		return err
+	}
+	ev.blockB = NewBlock()
+	if err := ev.blockB.setData(data.BlockB); err != nil {
+		return err
+	}
+	if data.VoteA == nil && data.VoteB == nil {
+		return nil
+	}
+	if data.VoteA == nil || data.VoteB == nil {
+		return ErrNilVote
+	}
+	ev.voteA = NewVote()
+	if err := ev.voteA.setData(data.VoteA); err != nil {
+		return err
+	}
+	ev.voteB = NewVote()
+	return ev.voteB.setData(data.VoteB)
+}
+
+// IsVote checks whether the evidence is for double voting, otherwise it's for conflicting proposals
+func (ev *Evidence) IsVote() bool {
+	return ev.data.VoteA != nil
+}
+
+// Accused returns the validator who signed both blocks
+func (ev *Evidence) Accused() *PublicKey {
+	if ev.IsVote() {
+		return ev.voteA.Voter()
+	}
+	return ev.blockA.Proposer()
+}
+
+func (ev *Evidence) Height() uint64 { return ev.blockA.Height() }
+func (ev *Evidence) View() uint64   { return ev.blockA.View() }
+func (ev *Evidence) BlockA() *Block { return ev.blockA }
+func (ev *Evidence) BlockB() *Block { return ev.blockB }
+
+// ID identifies the offence by the accused validator, height, view and kind.
+// Evidences with the same blocks in different order have the same id.
+func (ev *Evidence) ID() []byte {
+	buf := bytes.NewBuffer(nil)
+	buf.Write(ev.Accused().Bytes())
+	binary.Write(buf, binary.BigEndian, ev.Height())
+	binary.Write(buf, binary.BigEndian, ev.View())
+	if ev.IsVote() {
+		buf.WriteByte(1)
+	} else {
+		buf.WriteByte(0)
+	}
+	return buf.Bytes()
+}
+
+// Hash is the hash of the signed contents, used for block hash
+func (ev *Evidence) Hash() []byte {
+	h := sha3.New256()
+	h.Write(ev.blockA.Hash())
+	h.Write(ev.blockB.Hash())
+	if ev.IsVote() {
+		h.Write(ev.voteA.data.Signature.Value)
+		h.Write(ev.voteB.data.Signature.Value)
+	}
+	return h.Sum(nil)
+}
+
+// Marshal encodes evidence as bytes
+func (ev *Evidence) Marshal() ([]byte, error) {
+	return proto.Marshal(ev.data)
+}
+
+// Unmarshal decodes evidence from bytes
+func (ev *Evidence) Unmarshal(b []byte) error {
+	data := new(core_pb.Evidence)
+	if err := proto.Unmarshal(b, data); err != nil {
+		return err
+	}
+	return ev.setData(data)
+}
+
+func (ev *Evidence) MarshalJSON() ([]byte, error) {
+	return protojson.Marshal(ev.data)
+}
+
+func (ev *Evidence) UnmarshalJSON(b []byte) error {
+	data := new(core_pb.Evidence)
+	if err := protojson.Unmarshal(b, data); err != nil {
+		return err
+	}
+	return ev.setData(data)
+}
@@ -0,0 +1,136 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestBlock(height uint64, ts int64, priv *PrivateKey) *Block {
+	parent := NewBlock().SetHeight(height - 1).Sign(priv)
+	qc := NewQuorumCert().Build([]*Vote{parent.Vote(priv)})
+	return NewBlock().SetHeight(height).SetTimestamp(ts).SetQuorumCert(qc).Sign(priv)
+}
+
+func TestEvidence_Proposal(t *testing.T) {
+	assert := assert.New(t)
+
+	priv0, priv1 := GenerateKey(nil), GenerateKey(nil)
+	vs := NewValidatorStore([]*PublicKey{priv0.PublicKey(), priv1.PublicKey()})
+
+	blkA := newTestBlock(5, 1, priv0)
+	blkB := newTestBlock(5, 2, priv0)
+
+	ev := NewProposalEvidence(blkA, blkB)
+	assert.NoError(ev.Validate(vs))
+	assert.False(ev.IsVote())
+	assert.Equal(priv0.PublicKey(), ev.Accused())
+	assert.EqualValues(5, ev.Height())
+	assert.Equal(ev.ID(), NewProposalEvidence(blkB, blkA).ID())
+
+	b, err := ev.Marshal()
+	assert.NoError(err)
+	ev1 := NewEvidence()
+	assert.NoError(ev1.Unmarshal(b))
+	assert.NoError(ev1.Validate(vs))
+	assert.Equal(ev.Hash(), ev1.Hash())
+
+	err = NewProposalEvidence(blkA, blkA).Validate(vs)
+	assert.Equal(ErrInvalidEvidence, err, "same block")
+
+	err = NewProposalEvidence(blkA, newTestBlock(6, 1, priv0)).Validate(vs)
+	assert.Equal(ErrInvalidEvidence, err, "different heights")
+
+	err = NewProposalEvidence(blkA, newTestBlock(5, 1, priv1)).Validate(vs)
+	assert.Equal(ErrInvalidEvidence, err, "different proposers")
+
+	tc := NewTimeoutCert().Build([]*Timeout{NewTimeout().SetView(0).Sign(priv0)})
+	blkView1 := newTestBlock(5, 2, priv0)
+	blkView1.SetTimeoutCert(tc).Sign(priv0)
+	err = NewProposalEvidence(blkA, blkView1).Validate(vs)
+	assert.Equal(ErrInvalidEvidence, err, "different views")
+
+	blkView1b := newTestBlock(5, 3, priv0)
+	blkView1b.SetTimeoutCert(tc).Sign(priv0)
+	evView1 := NewProposalEvidence(blkView1, blkView1b)
+	assert.NoError(evView1.Validate(vs))
+	assert.EqualValues(1, evView1.View())
+	assert.NotEqual(ev.ID(), evView1.ID())
+
+	priv2 := GenerateKey(nil)
+	err = NewProposalEvidence(
+		newTestBlock(5, 1, priv2),
+		newTestBlock(5, 2, priv2),
+	).Validate(vs)
+	assert.Equal(ErrInvalidValidator, err)
+
+	blkC := newTestBlock(5, 3, priv0)
+	blkC.SetTimestamp(4) // hash no longer matches the contents
+	err = NewProposalEvidence(blkA, blkC).Validate(vs)
+	assert.Equal(ErrInvalidBlockHash, err)
+}
+
+func TestEvidence_Vote(t *testing.T) {
+	assert := assert.New(t)
+
+	priv0, priv1 := GenerateKey(nil), GenerateKey(nil)
+	vs := NewValidatorStore([]*PublicKey{priv0.PublicKey(), priv1.PublicKey()})
+
+	blkA := newTestBlock(5, 1, priv0)
+	blkB := newTestBlock(5, 2, priv0)
+
+	ev := NewVoteEvidence(blkA.Vote(priv1), blkB.Vote(priv1), blkA, blkB)
+	assert.NoError(ev.Validate(vs))
+	assert.True(ev.IsVote())
+	assert.Equal(priv1.PublicKey(), ev.Accused())
+	assert.NotEqual(NewProposalEvidence(blkA, blkB).ID(), ev.ID())
+
+	b, err := ev.Marshal()
+	assert.NoError(err)
+	ev1 := NewEvidence()
+	assert.NoError(ev1.Unmarshal(b))
+	assert.NoError(ev1.Validate(vs))
+
+	err = NewVoteEvidence(blkA.Vote(priv1), blkB.Vote(priv0), blkA, blkB).Validate(vs)
+	assert.Equal(ErrInvalidEvidence, err, "different voters")
+
+	err = NewVoteEvidence(blkA.Vote(priv1), blkA.Vote(priv1), blkA, blkB).Validate(vs)
+	assert.Equal(ErrInvalidEvidence, err, "vote is not for the block")
+
+	voteB := blkB.Vote(priv1)
+	voteB.data.View = 1
+	err = NewVoteEvidence(blkA.Vote(priv1), voteB, blkA, blkB).Validate(vs)
+	assert.Equal(ErrInvalidEvidence, err, "vote view is not the block view")
+}
+
+func TestBlock_Evidence(t *testing.T) {
+	assert := assert.New(t)
+
+	priv0 := GenerateKey(nil)
+	vs := NewValidatorStore([]*PublicKey{priv0.PublicKey()})
+
+	blkA := newTestBlock(5, 1, priv0)
+	blkB := newTestBlock(5, 2, priv0)
+	ev := NewProposalEvidence(blkA, blkB)
+
+	qc := NewQuorumCert().Build([]*Vote{blkA.Vote(priv0)})
+	blk := NewBlock().SetHeight(6).SetQuorumCert(qc).Sign(priv0)
+	blkEv := NewBlock().SetHeight(6).SetQuorumCert(qc).SetEvidence([]*Evidence{ev}).Sign(priv0)
+	assert.NotEqual(blk.Hash(), blkEv.Hash(), "evidence is in block hash")
+
+	b, err := blkEv.Marshal()
+	assert.NoError(err)
+	blk = NewBlock()
+	assert.NoError(blk.Unmarshal(b))
+	assert.NoError(blk.Validate(vs))
+	if assert.Equal(1, len(blk.Evidence())) {
+		assert.Equal(ev.ID(), blk.Evidence()[0].ID())
+	}
+
+	blkEv = NewBlock().SetHeight(6).SetQuorumCert(qc).
+		SetEvidence([]*Evidence{NewProposalEvidence(blkA, blkA)}).Sign(priv0)
+	assert.Equal(ErrInvalidEvidence, blkEv.Validate(vs))
+}
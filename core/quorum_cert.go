@@ -7,6 +7,7 @@ import (
 	"errors"
 
 	"github.com/aungmawjj/juria-blockchain/core/core_pb"
+	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -20,7 +21,9 @@ var (
 	ErrInvalidValidator = errors.New("voter is not a validator")
 )
 
-// QuorumCert type
+// QuorumCert type.
+// It holds either the signatures of the voters, or the aggregated bls signature
+// of the voters with the bitmap of their indexes in the validator set.
 type QuorumCert struct {
 	data *core_pb.QuorumCert
 	sigs sigList
@@ -37,16 +40,41 @@ func (qc *QuorumCert) Validate(vs ValidatorStore) error {
 	if qc.data == nil {
 		return ErrNilQC
 	}
-	if len(qc.sigs) < vs.MajorityCount() {
-		return ErrNotEnoughSig
+	vset := vs.AtHeight(qc.data.BlockHeight)
+	if qc.IsAggregate() {
+		return qc.validateAggregate(vset)
 	}
 	if qc.sigs.hasDuplicate() {
 		return ErrDuplicateSig
 	}
-	if qc.sigs.hasInvalidValidator(vs) {
+	if qc.sigs.hasInvalidValidator(vset) {
 		return ErrInvalidValidator
 	}
-	if qc.sigs.hasInvalidSig(qc.data.BlockHash) {
+	if qc.sigs.power(vset) < vset.MajorityPower() {
+		return ErrNotEnoughSig
+	}
+	if qc.sigs.hasInvalidSig(voteMsg(qc.data.BlockHash, qc.data.BlockHeight)) {
+		return ErrInvalidSig
+	}
+	return nil
+}
+
+func (qc *QuorumCert) validateAggregate(vset ValidatorSet) error {
+	signers := qc.SignerIndexes()
+	pubKeys := make([]*BLSPublicKey, len(signers))
+	var power uint64
+	for i, idx := range signers {
+		pubKeys[i] = vset.GetBLSKey(idx)
+		if pubKeys[i] == nil {
+			return ErrInvalidValidator
+		}
+		power += vset.GetPower(idx)
+	}
+	if power < vset.MajorityPower() {
+		return ErrNotEnoughSig
+	}
+	msg := voteMsg(qc.data.BlockHash, qc.data.BlockHeight)
+	if !VerifyBLSAggregate(pubKeys, msg, qc.data.AggregateSignature) {
 		return ErrInvalidSig
 	}
 	return nil
@@ -68,6 +96,7 @@ func (qc *QuorumCert) Build(votes []*Vote) *QuorumCert {
 	for i, vote := range votes {
 		if qc.data.BlockHash == nil {
 			qc.data.BlockHash = vote.data.BlockHash
+			qc.data.BlockHeight = vote.data.BlockHeight
 		}
 		qc.data.Signatures[i] = vote.data.Signature
 		qc.sigs[i] = &Signature{
@@ -78,7 +107,61 @@ func (qc *QuorumCert) Build(votes []*Vote) *QuorumCert {
 	return qc
 }
 
+// BuildAggregate creates the qc with aggregated bls signature of the votes.
+// All voters must have bls keys in the validator set of the block height.
+func (qc *QuorumCert) BuildAggregate(votes []*Vote, vset ValidatorSet) (*QuorumCert, error) {
+	signers := make([]byte, (vset.ValidatorCount()+7)/8)
+	blsSigs := make([][]byte, len(votes))
+	for i, vote := range votes {
+		if vote.data.BlsSignature == nil {
+			return nil, ErrNilSig
+		}
+		if !vset.IsValidator(vote.voter) {
+			return nil, ErrInvalidValidator
+		}
+		idx := vset.GetValidatorIndex(vote.voter)
+		if vset.GetBLSKey(idx) == nil {
+			return nil, ErrInvalidValidator
+		}
+		if signers[idx/8]&(1<<(idx%8)) != 0 {
+			return nil, ErrDuplicateSig
+		}
+		signers[idx/8] |= 1 << (idx % 8)
+		blsSigs[i] = vote.data.BlsSignature
+	}
+	aggSig, err := AggregateBLSSignatures(blsSigs)
+	if err != nil {
+		return nil, err
+	}
+	if len(votes) > 0 {
+		qc.data.BlockHash = votes[0].data.BlockHash
+		qc.data.BlockHeight = votes[0].data.BlockHeight
+	}
+	qc.data.Signers = signers
+	qc.data.AggregateSignature = aggSig
+	return qc, nil
+}
+
+// IsAggregate checks whether the qc has aggregated bls signature
+func (qc *QuorumCert) IsAggregate() bool {
+	return qc.data.AggregateSignature != nil
+}
+
+// SignerIndexes gives the validator indexes of the voters in the aggregated signature
+func (qc *QuorumCert) SignerIndexes() []int {
+	indexes := make([]int, 0)
+	for i, b := range qc.data.Signers {
+		for j := 0; j < 8; j++ {
+			if b&(1<<j) != 0 {
+				indexes = append(indexes, i*8+j)
+			}
+		}
+	}
+	return indexes
+}
+
 func (qc *QuorumCert) BlockHash() []byte        { return qc.data.BlockHash }
+func (qc *QuorumCert) BlockHeight() uint64      { return qc.data.BlockHeight }
 func (qc *QuorumCert) Signatures() []*Signature { return qc.sigs }
 
 // Marshal encodes quorum cert as bytes
@@ -94,3 +177,15 @@ func (qc *QuorumCert) Unmarshal(b []byte) error {
 	}
 	return qc.setData(data)
 }
+
+func (qc *QuorumCert) MarshalJSON() ([]byte, error) {
+	return protojson.Marshal(qc.data)
+}
+
+func (qc *QuorumCert) UnmarshalJSON(b []byte) error {
+	data := new(core_pb.QuorumCert)
+	if err := protojson.Unmarshal(b, data); err != nil {
+		return err
+	}
+	return qc.setData(data)
+}
@@ -17,7 +17,9 @@ func TestQuorumCert(t *testing.T) {
 
 	vs := new(MockValidatorStore)
 	vs.On("ValidatorCount").Return(4)
-	vs.On("MajorityCount").Return(3)
+	vs.On("MajorityPower").Return(uint64(3))
+	vs.On("GetValidatorIndex", mock.Anything).Return(0)
+	vs.On("GetPower", mock.Anything).Return(uint64(1))
 
 	for i := range privKeys {
 		privKeys[i] = GenerateKey(nil)
@@ -33,7 +35,7 @@ func TestQuorumCert(t *testing.T) {
 		vote := NewVote()
 		vote.setData(&core_pb.Vote{
 			BlockHash: blockHash,
-			Signature: priv.Sign(blockHash).data,
+			Signature: priv.Sign(voteMsg(blockHash, 0)).data,
 		})
 		votes[i] = vote
 	}
@@ -108,3 +110,107 @@ func TestQuorumCert(t *testing.T) {
 		})
 	}
 }
+
+func TestQuorumCert_Aggregate(t *testing.T) {
+	assert := assert.New(t)
+
+	signers := make([]Signer, 5)
+	validators := make([]*PublicKey, 4)
+	blsKeys := make([]*BLSPublicKey, 4)
+	for i := range signers {
+		blsKey := GenerateBLSKey(nil)
+		signer := NewBLSSigner(GenerateKey(nil), blsKey)
+		signers[i] = signer
+		if i < 4 {
+			validators[i] = signer.PublicKey()
+			blsKeys[i] = blsKey.PublicKey()
+		}
+	}
+	blsKeys[3] = nil // validator without bls key
+	vs := NewBLSValidatorStore(validators, blsKeys)
+
+	blk := NewBlock().SetHeight(5).Sign(signers[0])
+	votes := make([]*Vote, len(signers))
+	for i, signer := range signers {
+		votes[i] = blk.Vote(signer)
+		assert.NotNil(votes[i].data.BlsSignature)
+	}
+	assert.Equal(votes[0].data.BlsSignature, blk.ProposerVote().data.BlsSignature)
+
+	qc, err := NewQuorumCert().BuildAggregate([]*Vote{votes[2], votes[0], votes[1]}, vs)
+	assert.NoError(err)
+	assert.True(qc.IsAggregate())
+	assert.Equal([]int{0, 1, 2}, qc.SignerIndexes())
+	assert.Equal(blk.Hash(), qc.BlockHash())
+	assert.EqualValues(5, qc.BlockHeight())
+	assert.Empty(qc.Signatures())
+
+	b, err := qc.Marshal()
+	assert.NoError(err)
+	qc = NewQuorumCert()
+	assert.NoError(qc.Unmarshal(b))
+	assert.NoError(qc.Validate(vs))
+
+	_, err = NewQuorumCert().BuildAggregate([]*Vote{votes[0], votes[1], votes[3]}, vs)
+	assert.Equal(ErrInvalidValidator, err, "voter without bls key")
+
+	_, err = NewQuorumCert().BuildAggregate([]*Vote{votes[0], votes[1], votes[4]}, vs)
+	assert.Equal(ErrInvalidValidator, err, "not validator")
+
+	_, err = NewQuorumCert().BuildAggregate([]*Vote{votes[0], votes[1], votes[1]}, vs)
+	assert.Equal(ErrDuplicateSig, err)
+
+	_, err = NewQuorumCert().BuildAggregate([]*Vote{blk.Vote(GenerateKey(nil))}, vs)
+	assert.Equal(ErrNilSig, err, "vote without bls signature")
+
+	qc, _ = NewQuorumCert().BuildAggregate([]*Vote{votes[0], votes[1]}, vs)
+	assert.Equal(ErrNotEnoughSig, qc.Validate(vs))
+
+	qc, _ = NewQuorumCert().BuildAggregate([]*Vote{votes[0], votes[1], votes[2]}, vs)
+	qc.data.Signers = []byte{0x0b} // claims validator 3 instead of 2
+	assert.Equal(ErrInvalidValidator, qc.Validate(vs))
+
+	qc.data.Signers = []byte{0x07, 0x00, 0x01} // out of validator set
+	assert.Equal(ErrInvalidValidator, qc.Validate(vs))
+
+	qc.data.Signers = []byte{0x07}
+	qc.data.BlockHash = []byte{1}
+	assert.Equal(ErrInvalidSig, qc.Validate(vs))
+}
+
+func TestQuorumCert_Power(t *testing.T) {
+	assert := assert.New(t)
+
+	signers := make([]Signer, 3)
+	validators := make([]*PublicKey, len(signers))
+	blsKeys := make([]*BLSPublicKey, len(signers))
+	for i := range signers {
+		blsKey := GenerateBLSKey(nil)
+		signers[i] = NewBLSSigner(GenerateKey(nil), blsKey)
+		validators[i] = signers[i].PublicKey()
+		blsKeys[i] = blsKey.PublicKey()
+	}
+	vs, err := NewGenesisValidatorStore(&ValidatorEpoch{
+		Validators: validators,
+		BLSKeys:    blsKeys,
+		Powers:     []uint64{10, 20, 70},
+	})
+	assert.NoError(err)
+
+	blk := NewBlock().SetHeight(5).Sign(signers[0])
+	votes := make([]*Vote, len(signers))
+	for i, signer := range signers {
+		votes[i] = blk.Vote(signer)
+	}
+
+	assert.NoError(NewQuorumCert().Build(votes[2:]).Validate(vs), "more than two-thirds power")
+	assert.Equal(ErrNotEnoughSig, NewQuorumCert().Build(votes[:2]).Validate(vs))
+
+	qc, err := NewQuorumCert().BuildAggregate(votes[2:], vs)
+	assert.NoError(err)
+	assert.NoError(qc.Validate(vs))
+
+	qc, err = NewQuorumCert().BuildAggregate(votes[:2], vs)
+	assert.NoError(err)
+	assert.Equal(ErrNotEnoughSig, qc.Validate(vs))
+}
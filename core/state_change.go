@@ -5,6 +5,7 @@ package core
 
 import (
 	"github.com/aungmawjj/juria-blockchain/core/core_pb"
+	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -23,6 +24,7 @@ func (sc *StateChange) Value() []byte         { return sc.data.Value }
 func (sc *StateChange) PrevValue() []byte     { return sc.data.PrevValue }
 func (sc *StateChange) TreeIndex() []byte     { return sc.data.TreeIndex }
 func (sc *StateChange) PrevTreeIndex() []byte { return sc.data.PrevTreeIndex }
+func (sc *StateChange) Deleted() bool         { return sc.data.Deleted }
 
 func (sc *StateChange) setData(val *core_pb.StateChange) error {
 	sc.data = val
@@ -54,6 +56,11 @@ func (sc *StateChange) SetPrevTreeIndex(val []byte) *StateChange {
 	return sc
 }
 
+func (sc *StateChange) SetDeleted(val bool) *StateChange {
+	sc.data.Deleted = val
+	return sc
+}
+
 func (sc *StateChange) Marshal() ([]byte, error) {
 	return proto.Marshal(sc.data)
 }
@@ -65,3 +72,15 @@ func (sc *StateChange) Unmarshal(b []byte) error {
 	}
 	return sc.setData(data)
 }
+
+func (sc *StateChange) MarshalJSON() ([]byte, error) {
+	return protojson.Marshal(sc.data)
+}
+
+func (sc *StateChange) UnmarshalJSON(b []byte) error {
+	data := new(core_pb.StateChange)
+	if err := protojson.Unmarshal(b, data); err != nil {
+		return err
+	}
+	return sc.setData(data)
+}
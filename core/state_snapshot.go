@@ -0,0 +1,106 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package core
+
+import (
+	"math/big"
+
+	"github.com/aungmawjj/juria-blockchain/core/core_pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// StateSnapshot describes the state of a node at a commited block height.
+// State values are transfered separately as StateChangeList chunks.
+type StateSnapshot struct {
+	data   *core_pb.StateSnapshot
+	lastQC *QuorumCert
+}
+
+func NewStateSnapshot() *StateSnapshot {
+	return &StateSnapshot{
+		data: new(core_pb.StateSnapshot),
+	}
+}
+
+func (ss *StateSnapshot) Height() uint64      { return ss.data.Height }
+func (ss *StateSnapshot) MerkleRoot() []byte  { return ss.data.MerkleRoot }
+func (ss *StateSnapshot) LastQC() *QuorumCert { return ss.lastQC }
+func (ss *StateSnapshot) LeafCount() *big.Int { return big.NewInt(0).SetBytes(ss.data.LeafCount) }
+
+func (ss *StateSnapshot) SetHeight(val uint64) *StateSnapshot {
+	ss.data.Height = val
+	return ss
+}
+
+func (ss *StateSnapshot) SetLeafCount(val *big.Int) *StateSnapshot {
+	ss.data.LeafCount = val.Bytes()
+	return ss
+}
+
+func (ss *StateSnapshot) SetMerkleRoot(val []byte) *StateSnapshot {
+	ss.data.MerkleRoot = val
+	return ss
+}
+
+func (ss *StateSnapshot) SetLastQC(val *QuorumCert) *StateSnapshot {
+	ss.lastQC = val
+	ss.data.LastQC = val.data
+	return ss
+}
+
+func (ss *StateSnapshot) setData(data *core_pb.StateSnapshot) error {
+	ss.data = data
+	if data.LastQC != nil {
+		ss.lastQC = NewQuorumCert()
+		if err := ss.lastQC.setData(data.LastQC); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ss *StateSnapshot) Marshal() ([]byte, error) {
+	return proto.Marshal(ss.data)
+}
+
+func (ss *StateSnapshot) Unmarshal(b []byte) error {
+	data := new(core_pb.StateSnapshot)
+	if err := proto.Unmarshal(b, data); err != nil {
+		return err
+	}
+	return ss.setData(data)
+}
+
+type StateChangeList []*StateChange
+
+func NewStateChangeList() *StateChangeList {
+	return new(StateChangeList)
+}
+
+// Unmarshal decodes state change list from bytes
+func (scl *StateChangeList) Unmarshal(b []byte) error {
+	data := new(core_pb.StateChangeList)
+	if err := proto.Unmarshal(b, data); err != nil {
+		return err
+	}
+	*scl = make([]*StateChange, len(data.List))
+	for i, scData := range data.List {
+		sc := NewStateChange()
+		if err := sc.setData(scData); err != nil {
+			return err
+		}
+		(*scl)[i] = sc
+	}
+	return nil
+}
+
+// Marshal encodes state change list as bytes
+func (scl *StateChangeList) Marshal() ([]byte, error) {
+	data := new(core_pb.StateChangeList)
+	data.List = make([]*core_pb.StateChange, len(*scl))
+	for i, sc := range *scl {
+		data.List[i] = sc.data
+	}
+	return proto.Marshal(data)
+}
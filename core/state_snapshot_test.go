@@ -0,0 +1,59 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateSnapshot(t *testing.T) {
+	assert := assert.New(t)
+
+	priv := GenerateKey(nil)
+	blk := NewBlock().SetHeight(10).Sign(priv)
+	qc := NewQuorumCert().Build([]*Vote{blk.ProposerVote()})
+
+	ss := NewStateSnapshot().
+		SetHeight(10).
+		SetLeafCount(big.NewInt(300)).
+		SetMerkleRoot([]byte{1}).
+		SetLastQC(qc)
+
+	b, err := ss.Marshal()
+	assert.NoError(err)
+
+	ss = NewStateSnapshot()
+	err = ss.Unmarshal(b)
+	assert.NoError(err)
+
+	assert.EqualValues(10, ss.Height())
+	assert.EqualValues(300, ss.LeafCount().Int64())
+	assert.Equal([]byte{1}, ss.MerkleRoot())
+	assert.Equal(blk.Hash(), ss.LastQC().BlockHash())
+	assert.Equal(1, len(ss.LastQC().Signatures()))
+}
+
+func TestStateChangeList(t *testing.T) {
+	assert := assert.New(t)
+
+	scl := &StateChangeList{
+		NewStateChange().SetKey([]byte{1}).SetValue([]byte{10}).SetTreeIndex([]byte{0}),
+		NewStateChange().SetKey([]byte{2}).SetValue([]byte{20}).SetTreeIndex([]byte{1}),
+	}
+	b, err := scl.Marshal()
+	assert.NoError(err)
+
+	scl = NewStateChangeList()
+	err = scl.Unmarshal(b)
+	assert.NoError(err)
+
+	if assert.Equal(2, len(*scl)) {
+		assert.Equal([]byte{2}, (*scl)[1].Key())
+		assert.Equal([]byte{20}, (*scl)[1].Value())
+		assert.Equal([]byte{1}, (*scl)[1].TreeIndex())
+	}
+}
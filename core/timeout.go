@@ -0,0 +1,232 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package core
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/aungmawjj/juria-blockchain/core/core_pb"
+	"golang.org/x/crypto/sha3"
+	"google.golang.org/protobuf/proto"
+)
+
+// errors
+var (
+	ErrNilTimeout = errors.New("nil timeout")
+	ErrNilTC      = errors.New("nil timeout cert")
+	ErrInvalidTC  = errors.New("invalid timeout cert for the view")
+)
+
+// timeoutMsg is the signed message of a timeout.
+// It is prefixed so that it cannot be taken for a block hash.
+func timeoutMsg(view, commitHeight uint64) []byte {
+	h := sha3.New256()
+	h.Write([]byte("timeout"))
+	binary.Write(h, binary.BigEndian, view)
+	binary.Write(h, binary.BigEndian, commitHeight)
+	return h.Sum(nil)
+}
+
+// Timeout type.
+// The view and the commited height of the sender are signed,
+// the timeout is validated with the validator set of the receiver.
+// The timeout carries the timeout cert of its view for the receivers in earlier views to catch up.
+type Timeout struct {
+	data   *core_pb.Timeout
+	sender *PublicKey
+	highTC *TimeoutCert
+}
+
+func NewTimeout() *Timeout {
+	return &Timeout{
+		data: new(core_pb.Timeout),
+	}
+}
+
+// Validate timeout
+func (t *Timeout) Validate(vset ValidatorSet) error {
+	if t.data == nil {
+		return ErrNilTimeout
+	}
+	sig, err := newSignature(t.data.Signature)
+	if err != nil {
+		return err
+	}
+	if !vset.IsValidator(sig.PublicKey()) {
+		return ErrInvalidValidator
+	}
+	if !sig.Verify(timeoutMsg(t.data.View, t.data.CommitHeight)) {
+		return ErrInvalidSig
+	}
+	return t.validateHighTC(vset)
+}
+
+// validateHighTC checks the timeout cert which moved the sender to the view of the timeout
+func (t *Timeout) validateHighTC(vset ValidatorSet) error {
+	if t.highTC == nil {
+		if t.data.View == 0 {
+			return nil
+		}
+		return ErrInvalidTC
+	}
+	if t.highTC.View()+1 != t.data.View {
+		return ErrInvalidTC
+	}
+	return t.highTC.Validate(vset)
+}
+
+func (t *Timeout) setData(data *core_pb.Timeout) error {
+	t.data = data
+	sig, err := newSignature(t.data.Signature)
+	if err != nil {
+		return err
+	}
+	t.sender = sig.pubKey
+	if t.data.HighTC != nil {
+		t.highTC = NewTimeoutCert()
+		return t.highTC.setData(t.data.HighTC)
+	}
+	return nil
+}
+
+func (t *Timeout) SetView(val uint64) *Timeout {
+	t.data.View = val
+	return t
+}
+
+// SetHighTC sets the timeout cert of the view, it's not signed
+func (t *Timeout) SetHighTC(tc *TimeoutCert) *Timeout {
+	t.highTC = tc
+	t.data.HighTC = nil
+	if tc != nil {
+		t.data.HighTC = tc.data
+	}
+	return t
+}
+
+func (t *Timeout) SetCommitHeight(val uint64) *Timeout {
+	t.data.CommitHeight = val
+	return t
+}
+
+func (t *Timeout) Sign(signer Signer) *Timeout {
+	t.sender = signer.PublicKey()
+	t.data.Signature = signer.Sign(timeoutMsg(t.data.View, t.data.CommitHeight)).data
+	return t
+}
+
+func (t *Timeout) View() uint64         { return t.data.View }
+func (t *Timeout) CommitHeight() uint64 { return t.data.CommitHeight }
+func (t *Timeout) Sender() *PublicKey   { return t.sender }
+func (t *Timeout) HighTC() *TimeoutCert { return t.highTC }
+
+// Marshal encodes timeout as bytes
+func (t *Timeout) Marshal() ([]byte, error) {
+	return proto.Marshal(t.data)
+}
+
+// Unmarshal decodes timeout from bytes
+func (t *Timeout) Unmarshal(b []byte) error {
+	data := new(core_pb.Timeout)
+	if err := proto.Unmarshal(b, data); err != nil {
+		return err
+	}
+	return t.setData(data)
+}
+
+// TimeoutCert type.
+// It holds the timeout signatures of the majority of validators for the view.
+type TimeoutCert struct {
+	data *core_pb.TimeoutCert
+	sigs sigList
+}
+
+func NewTimeoutCert() *TimeoutCert {
+	return &TimeoutCert{
+		data: new(core_pb.TimeoutCert),
+	}
+}
+
+// Validate godoc
+func (tc *TimeoutCert) Validate(vset ValidatorSet) error {
+	if tc.data == nil {
+		return ErrNilTC
+	}
+	if tc.sigs.hasDuplicate() {
+		return ErrDuplicateSig
+	}
+	if tc.sigs.hasInvalidValidator(vset) {
+		return ErrInvalidValidator
+	}
+	if tc.sigs.power(vset) < vset.MajorityPower() {
+		return ErrNotEnoughSig
+	}
+	if len(tc.data.CommitHeights) != len(tc.sigs) {
+		return ErrInvalidTC
+	}
+	for i, sig := range tc.sigs {
+		if !sig.Verify(timeoutMsg(tc.data.View, tc.data.CommitHeights[i])) {
+			return ErrInvalidSig
+		}
+	}
+	return nil
+}
+
+func (tc *TimeoutCert) setData(data *core_pb.TimeoutCert) error {
+	tc.data = data
+	sigs, err := newSigList(tc.data.Signatures)
+	if err != nil {
+		return err
+	}
+	tc.sigs = sigs
+	return nil
+}
+
+// Build creates the timeout cert.
+// The timeouts must be of the same view.
+func (tc *TimeoutCert) Build(timeouts []*Timeout) *TimeoutCert {
+	tc.data.Signatures = make([]*core_pb.Signature, len(timeouts))
+	tc.data.CommitHeights = make([]uint64, len(timeouts))
+	tc.sigs = make(sigList, len(timeouts))
+	for i, t := range timeouts {
+		tc.data.View = t.data.View
+		tc.data.Signatures[i] = t.data.Signature
+		tc.data.CommitHeights[i] = t.data.CommitHeight
+		tc.sigs[i] = &Signature{
+			data:   t.data.Signature,
+			pubKey: t.sender,
+		}
+	}
+	return tc
+}
+
+func (tc *TimeoutCert) View() uint64             { return tc.data.View }
+func (tc *TimeoutCert) Signatures() []*Signature { return tc.sigs }
+
+// CommitHeight gives the lowest commited height of the signers.
+// It's agreed by all validators with the timeout cert, faulty signers can only lower it.
+func (tc *TimeoutCert) CommitHeight() uint64 {
+	var height uint64
+	for i, h := range tc.data.CommitHeights {
+		if i == 0 || h < height {
+			height = h
+		}
+	}
+	return height
+}
+
+// Marshal encodes timeout cert as bytes
+func (tc *TimeoutCert) Marshal() ([]byte, error) {
+	return proto.Marshal(tc.data)
+}
+
+// Unmarshal decodes timeout cert from bytes
+func (tc *TimeoutCert) Unmarshal(b []byte) error {
+	data := new(core_pb.TimeoutCert)
+	if err := proto.Unmarshal(b, data); err != nil {
+		return err
+	}
+	return tc.setData(data)
+}
@@ -0,0 +1,156 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	priv := GenerateKey(nil)
+	vs := NewValidatorStore([]*PublicKey{priv.PublicKey()})
+
+	highTC := NewTimeoutCert().Build([]*Timeout{NewTimeout().SetView(2).Sign(priv)})
+	b, err := NewTimeout().SetView(3).SetHighTC(highTC).Sign(priv).Marshal()
+	assert.NoError(err)
+
+	to := NewTimeout()
+	if !assert.NoError(to.Unmarshal(b)) {
+		return
+	}
+	assert.EqualValues(3, to.View())
+	assert.Equal(priv.PublicKey(), to.Sender())
+	if assert.NotNil(to.HighTC()) {
+		assert.EqualValues(2, to.HighTC().View())
+	}
+	assert.NoError(to.Validate(vs))
+
+	to.data.View = 4
+	assert.ErrorIs(to.Validate(vs), ErrInvalidSig, "view is signed")
+	to.data.View = 3
+	to.data.CommitHeight = 1
+	assert.ErrorIs(to.Validate(vs), ErrInvalidSig, "commit height is signed")
+
+	to = NewTimeout().SetView(3).SetHighTC(highTC).Sign(GenerateKey(nil))
+	assert.ErrorIs(to.Validate(vs), ErrInvalidValidator)
+
+	assert.NoError(NewTimeout().SetView(0).Sign(priv).Validate(vs), "no tc in first view")
+	to = NewTimeout().SetView(3).Sign(priv)
+	assert.ErrorIs(to.Validate(vs), ErrInvalidTC, "no tc")
+	to = NewTimeout().SetView(4).SetHighTC(highTC).Sign(priv)
+	assert.ErrorIs(to.Validate(vs), ErrInvalidTC, "tc of other view")
+
+	invalidTC := NewTimeoutCert().Build([]*Timeout{NewTimeout().SetView(2).Sign(GenerateKey(nil))})
+	to = NewTimeout().SetView(3).SetHighTC(invalidTC).Sign(priv)
+	assert.ErrorIs(to.Validate(vs), ErrInvalidValidator, "invalid tc")
+
+	assert.Error(NewTimeout().Unmarshal(nil), "nil signature")
+}
+
+func TestTimeoutCert(t *testing.T) {
+	privKeys := make([]*PrivateKey, 4)
+	vlds := make([]*PublicKey, len(privKeys))
+	timeouts := make([]*Timeout, len(privKeys))
+	for i := range privKeys {
+		privKeys[i] = GenerateKey(nil)
+		vlds[i] = privKeys[i].PublicKey()
+		timeouts[i] = NewTimeout().SetView(5).SetCommitHeight(uint64(10 + i)).Sign(privKeys[i])
+	}
+	vs := NewValidatorStore(vlds)
+	otherView := NewTimeout().SetView(6).Sign(privKeys[3])
+	nonValidator := NewTimeout().SetView(5).Sign(GenerateKey(nil))
+
+	tests := []struct {
+		name     string
+		timeouts []*Timeout
+		err      error
+	}{
+		{"valid", timeouts[:3], nil},
+		{"valid full", timeouts, nil},
+		{"not enough sig", timeouts[:2], ErrNotEnoughSig},
+		{"duplicate", []*Timeout{timeouts[0], timeouts[1], timeouts[1]}, ErrDuplicateSig},
+		{"invalid validator", []*Timeout{timeouts[0], timeouts[1], nonValidator}, ErrInvalidValidator},
+		{"other view", []*Timeout{timeouts[0], timeouts[1], otherView}, ErrInvalidSig},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			b, err := NewTimeoutCert().Build(tt.timeouts).Marshal()
+			assert.NoError(err)
+
+			tc := NewTimeoutCert()
+			if !assert.NoError(tc.Unmarshal(b)) {
+				return
+			}
+			assert.Equal(len(tt.timeouts), len(tc.Signatures()))
+
+			err = tc.Validate(vs)
+			if tt.err == nil {
+				assert.NoError(err)
+				assert.EqualValues(5, tc.View())
+				assert.EqualValues(10, tc.CommitHeight(), "lowest commit height")
+			} else {
+				assert.ErrorIs(err, tt.err)
+			}
+		})
+	}
+
+	tc := NewTimeoutCert().Build(timeouts)
+	tc.data.CommitHeights[1] = 0
+	assert.ErrorIs(t, tc.Validate(vs), ErrInvalidSig, "commit heights are signed")
+	tc.data.CommitHeights = tc.data.CommitHeights[:2]
+	assert.ErrorIs(t, tc.Validate(vs), ErrInvalidTC, "missing commit heights")
+}
+
+func TestBlock_TimeoutCert(t *testing.T) {
+	assert := assert.New(t)
+
+	priv0 := GenerateKey(nil)
+	priv1 := GenerateKey(nil)
+	vs := NewValidatorStore([]*PublicKey{priv0.PublicKey(), priv1.PublicKey()})
+
+	b0 := NewBlock().Sign(priv0)
+	qc := NewQuorumCert().Build([]*Vote{b0.Vote(priv0), b0.Vote(priv1)})
+	tc := NewTimeoutCert().Build([]*Timeout{
+		NewTimeout().SetView(2).Sign(priv0),
+		NewTimeout().SetView(2).Sign(priv1),
+	})
+
+	blk := NewBlock().SetHeight(1).SetParentHash(b0.Hash()).SetQuorumCert(qc).Sign(priv1)
+	assert.Nil(blk.TimeoutCert())
+	assert.EqualValues(0, blk.View())
+	hash := blk.Hash()
+
+	blk.SetTimeoutCert(tc).Sign(priv1)
+	assert.NotEqual(hash, blk.Hash(), "view is in block hash")
+
+	b, err := blk.Marshal()
+	assert.NoError(err)
+	blk = NewBlock()
+	if !assert.NoError(blk.Unmarshal(b)) {
+		return
+	}
+	assert.NoError(blk.Validate(vs))
+	assert.EqualValues(3, blk.View())
+
+	invalidTC := NewTimeoutCert().Build([]*Timeout{NewTimeout().SetView(2).Sign(priv0)})
+	blk.SetTimeoutCert(invalidTC).Sign(priv1)
+	assert.ErrorIs(blk.Validate(vs), ErrNotEnoughSig)
+
+	blk.SetTimeoutCert(tc).SetView(4).Sign(priv1)
+	assert.ErrorIs(blk.Validate(vs), ErrInvalidTC, "view of other timeout cert")
+
+	next := NewBlock().SetHeight(2).SetParentHash(blk.Hash()).SetQuorumCert(qc).SetView(3).Sign(priv1)
+	assert.Nil(next.TimeoutCert(), "later block of the view")
+	assert.NoError(next.Validate(vs))
+	assert.EqualValues(3, next.View())
+	hash = next.Hash()
+	next.SetView(4).Sign(priv1)
+	assert.NotEqual(hash, next.Hash(), "view is in block hash")
+}
@@ -21,6 +21,9 @@ var (
 	ErrNilTx         = errors.New("nil tx")
 )
 
+// DefaultTxGasLimit is used for transactions without gas limit
+const DefaultTxGasLimit uint64 = 100000
+
 // Transaction type
 type Transaction struct {
 	data   *core_pb.Transaction
@@ -44,6 +47,7 @@ func (tx *Transaction) Sum() []byte {
 	h.Write(tx.data.CodeAddr)
 	h.Write(tx.data.Input)
 	binary.Write(h, binary.BigEndian, tx.data.Expiry)
+	binary.Write(h, binary.BigEndian, tx.data.GasLimit)
 	return h.Sum(nil)
 }
 
@@ -95,6 +99,17 @@ func (tx *Transaction) SetExpiry(val uint64) *Transaction {
 	return tx
 }
 
+func (tx *Transaction) SetGasLimit(val uint64) *Transaction {
+	tx.data.GasLimit = val
+	return tx
+}
+
+// SetHash is used for unsigned txs which are simulated but not submitted
+func (tx *Transaction) SetHash(val []byte) *Transaction {
+	tx.data.Hash = val
+	return tx
+}
+
 func (tx *Transaction) Sign(signer Signer) *Transaction {
 	tx.sender = signer.PublicKey()
 	tx.data.Sender = signer.PublicKey().key
@@ -110,6 +125,15 @@ func (tx *Transaction) CodeAddr() []byte   { return tx.data.CodeAddr }
 func (tx *Transaction) Input() []byte      { return tx.data.Input }
 func (tx *Transaction) Expiry() uint64     { return tx.data.Expiry }
 
+// GasLimit returns the maximum gas the tx can consume,
+// DefaultTxGasLimit if it's not set
+func (tx *Transaction) GasLimit() uint64 {
+	if tx.data.GasLimit == 0 {
+		return DefaultTxGasLimit
+	}
+	return tx.data.GasLimit
+}
+
 // Marshal encodes transaction as bytes
 func (tx *Transaction) Marshal() ([]byte, error) {
 	return proto.Marshal(tx.data)
@@ -154,6 +178,15 @@ func (txc *TxCommit) BlockHash() []byte   { return txc.data.BlockHash }
 func (txc *TxCommit) BlockHeight() uint64 { return txc.data.BlockHeight }
 func (txc *TxCommit) Elapsed() float64    { return txc.data.Elapsed }
 func (txc *TxCommit) Error() string       { return txc.data.Error }
+func (txc *TxCommit) GasUsed() uint64     { return txc.data.GasUsed }
+
+func (txc *TxCommit) Events() []*Event {
+	events := make([]*Event, len(txc.data.Events))
+	for i, data := range txc.data.Events {
+		events[i] = &Event{data: data}
+	}
+	return events
+}
 
 func (txc *TxCommit) SetHash(val []byte) *TxCommit {
 	txc.data.Hash = val
@@ -180,6 +213,19 @@ func (txc *TxCommit) SetError(val string) *TxCommit {
 	return txc
 }
 
+func (txc *TxCommit) SetGasUsed(val uint64) *TxCommit {
+	txc.data.GasUsed = val
+	return txc
+}
+
+func (txc *TxCommit) SetEvents(val []*Event) *TxCommit {
+	txc.data.Events = make([]*core_pb.Event, len(val))
+	for i, evt := range val {
+		txc.data.Events[i] = evt.data
+	}
+	return txc
+}
+
 func (txc *TxCommit) setData(data *core_pb.TxCommit) error {
 	txc.data = data
 	return nil
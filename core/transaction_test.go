@@ -51,6 +51,21 @@ func TestTransaction(t *testing.T) {
 	assert.NoError(tx.Validate())
 }
 
+func TestTransaction_GasLimit(t *testing.T) {
+	privKey := GenerateKey(nil)
+	assert := assert.New(t)
+
+	tx := NewTransaction().Sign(privKey)
+	assert.Equal(DefaultTxGasLimit, tx.GasLimit(), "default gas limit")
+
+	tx1 := NewTransaction().SetGasLimit(500).Sign(privKey)
+	assert.EqualValues(500, tx1.GasLimit())
+	assert.NotEqual(tx.Hash(), tx1.Hash(), "gas limit must be signed")
+
+	tx1.SetGasLimit(1000)
+	assert.Error(tx1.Validate())
+}
+
 func TestTxList(t *testing.T) {
 	privKey := GenerateKey(nil)
 
@@ -80,3 +95,31 @@ func TestTxList(t *testing.T) {
 	assert.Equal(tx1.Sum(), (*txs)[0].Sum())
 	assert.Equal(tx2.Sum(), (*txs)[1].Sum())
 }
+
+func TestTxCommit_Events(t *testing.T) {
+	assert := assert.New(t)
+
+	txc := NewTxCommit().
+		SetHash([]byte{1}).
+		SetEvents([]*Event{
+			NewEvent().SetCodeAddr([]byte{2}).SetName("evt1").SetData([]byte("data1")),
+			NewEvent().SetCodeAddr([]byte{3}).SetName("evt2"),
+		})
+
+	b, err := txc.Marshal()
+	assert.NoError(err)
+
+	txc = NewTxCommit()
+	err = txc.Unmarshal(b)
+	assert.NoError(err)
+
+	events := txc.Events()
+	if assert.Equal(2, len(events)) {
+		assert.Equal([]byte{2}, events[0].CodeAddr())
+		assert.Equal("evt1", events[0].Name())
+		assert.Equal([]byte("data1"), events[0].Data())
+		assert.Equal([]byte{3}, events[1].CodeAddr())
+		assert.Equal("evt2", events[1].Name())
+		assert.Nil(events[1].Data())
+	}
+}
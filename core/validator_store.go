@@ -4,67 +4,272 @@
 package core
 
 import (
+	"errors"
 	"math"
+	"sort"
+	"sync"
 )
 
-// ValidatorStore godoc
-type ValidatorStore interface {
+// errors
+var (
+	ErrEpochExists    = errors.New("epoch already exists")
+	ErrEmptyValidator = errors.New("empty validator set")
+	ErrInvalidPower   = errors.New("invalid voting power")
+)
+
+// ValidatorSet godoc
+type ValidatorSet interface {
 	ValidatorCount() int
 	MajorityCount() int
 	IsValidator(pubKey *PublicKey) bool
 	GetValidator(idx int) *PublicKey
 	GetValidatorIndex(pubKey *PublicKey) int
+
+	// TotalPower is the sum of voting power of the validators
+	TotalPower() uint64
+
+	// MajorityPower is more than two-thirds of the total power, required for a qc
+	MajorityPower() uint64
+
+	// GetPower returns the voting power of the validator, zero if idx is out of range
+	GetPower(idx int) uint64
+
+	// GetBLSKey returns the bls key of the validator, nil if it has no bls key
+	GetBLSKey(idx int) *BLSPublicKey
+}
+
+// ValidatorStore keeps validator sets by epochs.
+// ValidatorSet methods of the store give the latest scheduled set.
+type ValidatorStore interface {
+	ValidatorSet
+
+	// AtHeight returns the validator set effective at the block height
+	AtHeight(height uint64) ValidatorSet
+
+	// AddEpoch schedules a validator set to be effective from the start height.
+	// start height must be higher than the latest epoch
+	AddEpoch(epoch *ValidatorEpoch) error
 }
 
-type simpleValidatorStore struct {
+// ValidatorEpoch is a validator set effective from the start height
+type ValidatorEpoch struct {
+	StartHeight uint64
+	Validators  []*PublicKey
+	BLSKeys     []*BLSPublicKey // optional, same order as validators, nil for validators without bls key
+	Powers      []uint64        // optional, same order as validators, every validator has power 1 if nil
+}
+
+// validate checks the voting power of the validators, the total power must not overflow
+func (epoch *ValidatorEpoch) validate() error {
+	if len(epoch.Validators) == 0 {
+		return ErrEmptyValidator
+	}
+	if epoch.Powers == nil {
+		return nil
+	}
+	if len(epoch.Powers) != len(epoch.Validators) {
+		return ErrInvalidPower
+	}
+	var total uint64
+	for _, power := range epoch.Powers {
+		if power == 0 || total+power < total {
+			return ErrInvalidPower
+		}
+		total += power
+	}
+	return nil
+}
+
+type simpleValidatorSet struct {
 	validators []*PublicKey
+	blsKeys    []*BLSPublicKey
+	powers     []uint64
 	vMap       map[string]int
 
-	majority int
+	majority      int
+	totalPower    uint64
+	majorityPower uint64
 }
 
-var _ ValidatorStore = (*simpleValidatorStore)(nil)
+var _ ValidatorSet = (*simpleValidatorSet)(nil)
 
-func NewValidatorStore(validators []*PublicKey) ValidatorStore {
-	store := &simpleValidatorStore{
-		validators: validators,
+func newValidatorSet(epoch *ValidatorEpoch) *simpleValidatorSet {
+	vset := &simpleValidatorSet{
+		validators: epoch.Validators,
+		blsKeys:    epoch.BLSKeys,
+		powers:     epoch.Powers,
+	}
+	if vset.powers == nil {
+		vset.powers = make([]uint64, len(vset.validators))
+		for i := range vset.powers {
+			vset.powers[i] = 1
+		}
 	}
-	store.vMap = make(map[string]int, len(store.validators))
-	for i, v := range store.validators {
-		store.vMap[v.String()] = i
+	vset.vMap = make(map[string]int, len(vset.validators))
+	for i, v := range vset.validators {
+		vset.vMap[v.String()] = i
+		vset.totalPower += vset.powers[i]
 	}
-	store.majority = MajorityCount(len(validators))
-	return store
+	vset.majority = MajorityCount(len(vset.validators))
+	vset.majorityPower = MajorityPower(vset.totalPower)
+	return vset
 }
 
-func (store *simpleValidatorStore) ValidatorCount() int {
-	return len(store.validators)
+func (vset *simpleValidatorSet) ValidatorCount() int {
+	return len(vset.validators)
 }
 
-func (store *simpleValidatorStore) MajorityCount() int {
-	return store.majority
+func (vset *simpleValidatorSet) MajorityCount() int {
+	return vset.majority
 }
 
-func (store *simpleValidatorStore) IsValidator(pubKey *PublicKey) bool {
+func (vset *simpleValidatorSet) IsValidator(pubKey *PublicKey) bool {
 	if pubKey == nil {
 		return false
 	}
-	_, ok := store.vMap[pubKey.String()]
+	_, ok := vset.vMap[pubKey.String()]
 	return ok
 }
 
-func (store *simpleValidatorStore) GetValidator(idx int) *PublicKey {
-	if idx >= len(store.validators) || idx < 0 {
+func (vset *simpleValidatorSet) GetValidator(idx int) *PublicKey {
+	if idx >= len(vset.validators) || idx < 0 {
 		return nil
 	}
-	return store.validators[idx]
+	return vset.validators[idx]
 }
 
-func (store *simpleValidatorStore) GetValidatorIndex(pubKey *PublicKey) int {
+func (vset *simpleValidatorSet) GetValidatorIndex(pubKey *PublicKey) int {
 	if pubKey == nil {
 		return 0
 	}
-	return store.vMap[pubKey.String()]
+	return vset.vMap[pubKey.String()]
+}
+
+func (vset *simpleValidatorSet) GetBLSKey(idx int) *BLSPublicKey {
+	if idx >= len(vset.blsKeys) || idx < 0 {
+		return nil
+	}
+	return vset.blsKeys[idx]
+}
+
+func (vset *simpleValidatorSet) TotalPower() uint64 {
+	return vset.totalPower
+}
+
+func (vset *simpleValidatorSet) MajorityPower() uint64 {
+	return vset.majorityPower
+}
+
+func (vset *simpleValidatorSet) GetPower(idx int) uint64 {
+	if idx >= len(vset.powers) || idx < 0 {
+		return 0
+	}
+	return vset.powers[idx]
+}
+
+type validatorEpoch struct {
+	startHeight uint64
+	vset        *simpleValidatorSet
+}
+
+type epochValidatorStore struct {
+	epochs []*validatorEpoch // sorted by start height
+	mtx    sync.RWMutex
+}
+
+var _ ValidatorStore = (*epochValidatorStore)(nil)
+
+// NewValidatorStore creates a validator store with the genesis validators as the first epoch
+func NewValidatorStore(validators []*PublicKey) ValidatorStore {
+	return NewBLSValidatorStore(validators, nil)
+}
+
+// NewBLSValidatorStore creates a validator store with the genesis validators and their bls keys
+func NewBLSValidatorStore(validators []*PublicKey, blsKeys []*BLSPublicKey) ValidatorStore {
+	return &epochValidatorStore{
+		epochs: []*validatorEpoch{{0, newValidatorSet(&ValidatorEpoch{
+			Validators: validators,
+			BLSKeys:    blsKeys,
+		})}},
+	}
+}
+
+// NewGenesisValidatorStore creates a validator store with the genesis epoch,
+// the start height of the epoch is ignored
+func NewGenesisValidatorStore(genesis *ValidatorEpoch) (ValidatorStore, error) {
+	if err := genesis.validate(); err != nil {
+		return nil, err
+	}
+	return &epochValidatorStore{
+		epochs: []*validatorEpoch{{0, newValidatorSet(genesis)}},
+	}, nil
+}
+
+func (store *epochValidatorStore) AtHeight(height uint64) ValidatorSet {
+	store.mtx.RLock()
+	defer store.mtx.RUnlock()
+	// first epoch starts at zero, idx is never zero
+	idx := sort.Search(len(store.epochs), func(i int) bool {
+		return store.epochs[i].startHeight > height
+	})
+	return store.epochs[idx-1].vset
+}
+
+func (store *epochValidatorStore) AddEpoch(epoch *ValidatorEpoch) error {
+	if err := epoch.validate(); err != nil {
+		return err
+	}
+	store.mtx.Lock()
+	defer store.mtx.Unlock()
+	if epoch.StartHeight <= store.epochs[len(store.epochs)-1].startHeight {
+		return ErrEpochExists
+	}
+	store.epochs = append(store.epochs, &validatorEpoch{
+		epoch.StartHeight, newValidatorSet(epoch),
+	})
+	return nil
+}
+
+func (store *epochValidatorStore) latest() *simpleValidatorSet {
+	store.mtx.RLock()
+	defer store.mtx.RUnlock()
+	return store.epochs[len(store.epochs)-1].vset
+}
+
+func (store *epochValidatorStore) ValidatorCount() int {
+	return store.latest().ValidatorCount()
+}
+
+func (store *epochValidatorStore) MajorityCount() int {
+	return store.latest().MajorityCount()
+}
+
+func (store *epochValidatorStore) IsValidator(pubKey *PublicKey) bool {
+	return store.latest().IsValidator(pubKey)
+}
+
+func (store *epochValidatorStore) GetValidator(idx int) *PublicKey {
+	return store.latest().GetValidator(idx)
+}
+
+func (store *epochValidatorStore) GetValidatorIndex(pubKey *PublicKey) int {
+	return store.latest().GetValidatorIndex(pubKey)
+}
+
+func (store *epochValidatorStore) GetBLSKey(idx int) *BLSPublicKey {
+	return store.latest().GetBLSKey(idx)
+}
+
+func (store *epochValidatorStore) TotalPower() uint64 {
+	return store.latest().TotalPower()
+}
+
+func (store *epochValidatorStore) MajorityPower() uint64 {
+	return store.latest().MajorityPower()
+}
+
+func (store *epochValidatorStore) GetPower(idx int) uint64 {
+	return store.latest().GetPower(idx)
 }
 
 // MajorityCount returns 2f + 1 members
@@ -72,3 +277,10 @@ func MajorityCount(validatorCount int) int {
 	// n=3f+1 -> f=floor((n-1)3) -> m=n-f -> m=ceil((2n+1)/3)
 	return int(math.Ceil(float64(2*validatorCount+1) / 3))
 }
+
+// MajorityPower returns the power more than two-thirds of the total power.
+// It is the same as MajorityCount when every validator has power 1.
+func MajorityPower(totalPower uint64) uint64 {
+	// floor(2t/3) + 1 without overflow
+	return totalPower/3*2 + totalPower%3*2/3 + 1
+}
@@ -4,6 +4,7 @@
 package core
 
 import (
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/mock"
@@ -16,6 +17,15 @@ type MockValidatorStore struct {
 
 var _ ValidatorStore = (*MockValidatorStore)(nil)
 
+func (m *MockValidatorStore) AtHeight(height uint64) ValidatorSet {
+	return m
+}
+
+func (m *MockValidatorStore) AddEpoch(epoch *ValidatorEpoch) error {
+	args := m.Called(epoch)
+	return args.Error(0)
+}
+
 func (m *MockValidatorStore) ValidatorCount() int {
 	args := m.Called()
 	return args.Int(0)
@@ -45,6 +55,30 @@ func (m *MockValidatorStore) GetValidatorIndex(pubKey *PublicKey) int {
 	return args.Int(0)
 }
 
+func (m *MockValidatorStore) TotalPower() uint64 {
+	args := m.Called()
+	return args.Get(0).(uint64)
+}
+
+func (m *MockValidatorStore) MajorityPower() uint64 {
+	args := m.Called()
+	return args.Get(0).(uint64)
+}
+
+func (m *MockValidatorStore) GetPower(idx int) uint64 {
+	args := m.Called(idx)
+	return args.Get(0).(uint64)
+}
+
+func (m *MockValidatorStore) GetBLSKey(idx int) *BLSPublicKey {
+	args := m.Called(idx)
+	val := args.Get(0)
+	if val == nil {
+		return nil
+	}
+	return val.(*BLSPublicKey)
+}
+
 func TestMajorityCount(t *testing.T) {
 	type args struct {
 		validatorCount int
@@ -67,3 +101,97 @@ func TestMajorityCount(t *testing.T) {
 		})
 	}
 }
+
+func TestValidatorStore_Epochs(t *testing.T) {
+	keys := make([]*PublicKey, 4)
+	for i := range keys {
+		keys[i] = GenerateKey(nil).PublicKey()
+	}
+	store := NewValidatorStore(keys[:3])
+
+	blsKey := GenerateBLSKey(nil).PublicKey()
+
+	assert.Equal(t, ErrEmptyValidator, store.AddEpoch(&ValidatorEpoch{StartHeight: 10}))
+	assert.NilError(t, store.AddEpoch(&ValidatorEpoch{
+		StartHeight: 10,
+		Validators:  keys[1:],
+		BLSKeys:     []*BLSPublicKey{nil, blsKey},
+	}))
+	assert.Equal(t, ErrEpochExists, store.AddEpoch(&ValidatorEpoch{StartHeight: 10, Validators: keys}))
+	assert.Equal(t, ErrEpochExists, store.AddEpoch(&ValidatorEpoch{StartHeight: 5, Validators: keys}))
+
+	assert.Equal(t, true, store.AtHeight(9).IsValidator(keys[0]))
+	assert.Equal(t, false, store.AtHeight(9).IsValidator(keys[3]))
+	assert.Equal(t, false, store.AtHeight(10).IsValidator(keys[0]))
+	assert.Equal(t, true, store.AtHeight(20).IsValidator(keys[3]))
+	assert.Equal(t, 2, store.AtHeight(10).GetValidatorIndex(keys[3]))
+
+	assert.Assert(t, store.AtHeight(9).GetBLSKey(1) == nil)
+	assert.Assert(t, store.AtHeight(10).GetBLSKey(0) == nil)
+	assert.Equal(t, blsKey, store.AtHeight(10).GetBLSKey(1))
+	assert.Assert(t, store.AtHeight(10).GetBLSKey(2) == nil)
+
+	// latest epoch
+	assert.Equal(t, 3, store.ValidatorCount())
+	assert.Equal(t, keys[1], store.GetValidator(0))
+}
+
+func TestMajorityPower(t *testing.T) {
+	tests := []struct {
+		name       string
+		totalPower uint64
+		want       uint64
+	}{
+		{"single", 1, 1},
+		{"same as count", 4, 3},
+		{"same as count", 14, 10},
+		{"weighted", 100, 67},
+		{"exact two-thirds", 90, 61},
+		{"max", math.MaxUint64, math.MaxUint64/3*2 + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, MajorityPower(tt.totalPower))
+		})
+	}
+	for n := 1; n < 100; n++ {
+		assert.Equal(t, uint64(MajorityCount(n)), MajorityPower(uint64(n)))
+	}
+}
+
+func TestValidatorStore_Powers(t *testing.T) {
+	keys := make([]*PublicKey, 3)
+	for i := range keys {
+		keys[i] = GenerateKey(nil).PublicKey()
+	}
+	_, err := NewGenesisValidatorStore(&ValidatorEpoch{
+		Validators: keys, Powers: []uint64{1, 2},
+	})
+	assert.Equal(t, ErrInvalidPower, err)
+	_, err = NewGenesisValidatorStore(&ValidatorEpoch{
+		Validators: keys, Powers: []uint64{1, 0, 2},
+	})
+	assert.Equal(t, ErrInvalidPower, err)
+	_, err = NewGenesisValidatorStore(&ValidatorEpoch{
+		Validators: keys, Powers: []uint64{1, math.MaxUint64, 2},
+	})
+	assert.Equal(t, ErrInvalidPower, err, "overflow")
+
+	store, err := NewGenesisValidatorStore(&ValidatorEpoch{
+		Validators: keys, Powers: []uint64{10, 20, 70},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, uint64(100), store.TotalPower())
+	assert.Equal(t, uint64(67), store.MajorityPower())
+	assert.Equal(t, uint64(70), store.GetPower(2))
+	assert.Equal(t, uint64(0), store.GetPower(3))
+	assert.Equal(t, 3, store.MajorityCount())
+
+	assert.Equal(t, ErrInvalidPower, store.AddEpoch(&ValidatorEpoch{
+		StartHeight: 10, Validators: keys, Powers: []uint64{1},
+	}))
+	assert.NilError(t, store.AddEpoch(&ValidatorEpoch{StartHeight: 10, Validators: keys}))
+	assert.Equal(t, uint64(1), store.AtHeight(10).GetPower(2), "default power")
+	assert.Equal(t, uint64(3), store.AtHeight(10).TotalPower())
+	assert.Equal(t, uint64(70), store.AtHeight(9).GetPower(2))
+}
@@ -4,9 +4,11 @@
 package core
 
 import (
+	"encoding/binary"
 	"errors"
 
 	"github.com/aungmawjj/juria-blockchain/core/core_pb"
+	"golang.org/x/crypto/sha3"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -15,6 +17,16 @@ var (
 	ErrNilVote = errors.New("nil vote")
 )
 
+// voteMsg is the message signed for a block by its proposer and voters.
+// The height is signed with the block hash so that the validator set
+// selected by the height of a vote or qc cannot be forged.
+func voteMsg(blkHash []byte, height uint64) []byte {
+	h := sha3.New256()
+	h.Write(blkHash)
+	binary.Write(h, binary.BigEndian, height)
+	return h.Sum(nil)
+}
+
 // Vote type
 type Vote struct {
 	data  *core_pb.Vote
@@ -36,12 +48,21 @@ func (vote *Vote) Validate(vs ValidatorStore) error {
 	if err != nil {
 		return err
 	}
-	if !vs.IsValidator(sig.PublicKey()) {
+	vset := vs.AtHeight(vote.data.BlockHeight)
+	if !vset.IsValidator(sig.PublicKey()) {
 		return ErrInvalidValidator
 	}
-	if !sig.Verify(vote.data.BlockHash) {
+	msg := voteMsg(vote.data.BlockHash, vote.data.BlockHeight)
+	if !sig.Verify(msg) {
 		return ErrInvalidSig
 	}
+	if vote.data.BlsSignature != nil {
+		// bls signature is not used for qc if the voter has no bls key in the set
+		blsKey := vset.GetBLSKey(vset.GetValidatorIndex(sig.PublicKey()))
+		if blsKey != nil && !blsKey.Verify(msg, vote.data.BlsSignature) {
+			return ErrInvalidSig
+		}
+	}
 	return nil
 }
 
@@ -55,8 +76,10 @@ func (vote *Vote) setData(data *core_pb.Vote) error {
 	return nil
 }
 
-func (vote *Vote) BlockHash() []byte { return vote.data.BlockHash }
-func (vote *Vote) Voter() *PublicKey { return vote.voter }
+func (vote *Vote) BlockHash() []byte   { return vote.data.BlockHash }
+func (vote *Vote) BlockHeight() uint64 { return vote.data.BlockHeight }
+func (vote *Vote) View() uint64        { return vote.data.View }
+func (vote *Vote) Voter() *PublicKey   { return vote.voter }
 
 // Marshal encodes vote as bytes
 func (vote *Vote) Marshal() ([]byte, error) {
@@ -64,3 +64,22 @@ func TestVote(t *testing.T) {
 		})
 	}
 }
+
+func TestVote_ValidateBLS(t *testing.T) {
+	assert := assert.New(t)
+
+	blsKey := GenerateBLSKey(nil)
+	signer := NewBLSSigner(GenerateKey(nil), blsKey)
+	blk := NewBlock().SetHeight(1).Sign(signer)
+
+	vs := NewBLSValidatorStore([]*PublicKey{signer.PublicKey()}, []*BLSPublicKey{blsKey.PublicKey()})
+	assert.NoError(blk.Vote(signer).Validate(vs))
+
+	vote := blk.Vote(signer)
+	vote.data.BlsSignature = blsKey.Sign([]byte("wrong data"))
+	assert.Equal(ErrInvalidSig, vote.Validate(vs))
+
+	// bls signature is ignored when voter has no bls key
+	vs = NewValidatorStore([]*PublicKey{signer.PublicKey()})
+	assert.NoError(vote.Validate(vs))
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/aungmawjj/juria-blockchain/execution/chaincode"
 )
 
+// ChaincodeHardTimeout is the limit of a call, the process exits if a call is not done in time
 const ChaincodeHardTimeout = 10 * time.Second
 
 type Client struct {
@@ -22,20 +23,9 @@ type Client struct {
 
 var _ chaincode.CallContext = (*Client)(nil)
 
+// RunChaincode serves the calls from the node until stdin is closed.
+// The process is kept in the node's process pool and reused for the calls to the chaincode.
 func RunChaincode(cc chaincode.Chaincode) {
-	timeout := time.After(ChaincodeHardTimeout)
-	done := make(chan struct{})
-	go runChaincodeAsync(cc, done)
-	select {
-	case <-timeout:
-		os.Exit(1)
-	case <-done:
-		os.Exit(0)
-	}
-}
-
-func runChaincodeAsync(cc chaincode.Chaincode, done chan<- struct{}) {
-	defer close(done)
 	c := &Client{
 		rw: &readWriter{
 			reader: os.Stdin,
@@ -43,10 +33,25 @@ func runChaincodeAsync(cc chaincode.Chaincode, done chan<- struct{}) {
 		},
 		cc: cc,
 	}
-	if err := c.loadCallData(); err != nil {
-		return
+	for {
+		if err := c.loadCallData(); err != nil {
+			os.Exit(0) // process is recycled by the node
+		}
+		c.runChaincodeWithTimeout()
+	}
+}
+
+func (c *Client) runChaincodeWithTimeout() {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.runChaincode()
+	}()
+	select {
+	case <-time.After(ChaincodeHardTimeout):
+		os.Exit(1)
+	case <-done:
 	}
-	c.runChaincode()
 }
 
 func (c *Client) loadCallData() error {
@@ -100,7 +105,51 @@ func (c *Client) SetState(key, value []byte) {
 	c.request(key, value, UpStreamSetState)
 }
 
+func (c *Client) DeleteState(key []byte) {
+	c.request(key, nil, UpStreamDeleteState)
+}
+
+// RangeState requests the states page by page, until fn returns false or no more states
+func (c *Client) RangeState(start, end []byte, fn func(key, value []byte) bool) {
+	for {
+		down, err := c.requestDownStream(start, end, UpStreamRangeState)
+		if err != nil {
+			return
+		}
+		for i, key := range down.Keys {
+			if !fn(key, down.Values[i]) {
+				return
+			}
+		}
+		if len(down.Keys) < RangePageSize {
+			return
+		}
+		start = append(down.Keys[len(down.Keys)-1], 0) // next key after the last one
+	}
+}
+
+func (c *Client) EmitEvent(name string, data []byte) {
+	c.request([]byte(name), data, UpStreamEmitEvent)
+}
+
+func (c *Client) Invoke(codeAddr, input []byte) error {
+	_, err := c.request(codeAddr, input, UpStreamInvoke)
+	return err
+}
+
+func (c *Client) Query(codeAddr, input []byte) ([]byte, error) {
+	return c.request(codeAddr, input, UpStreamQuery)
+}
+
 func (c *Client) request(key, value []byte, upType UpStreamType) ([]byte, error) {
+	down, err := c.requestDownStream(key, value, upType)
+	if err != nil {
+		return nil, err
+	}
+	return down.Value, nil
+}
+
+func (c *Client) requestDownStream(key, value []byte, upType UpStreamType) (*DownStream, error) {
 	up := new(UpStream)
 	up.Type = upType
 	up.Key = key
@@ -120,13 +169,14 @@ func (c *Client) request(key, value []byte, upType UpStreamType) ([]byte, error)
 	if len(down.Error) > 0 {
 		return nil, fmt.Errorf(down.Error)
 	}
-	return down.Value, nil
+	return down, nil
 }
 
 func (c *Client) sendResult(value []byte, err error) {
 	up := new(UpStream)
 	up.Type = UpStreamResult
 	up.Value = value
+	up.KeepAlive = true
 	if err != nil {
 		up.Error = err.Error()
 	}
@@ -18,17 +18,34 @@ import (
 	"golang.org/x/crypto/sha3"
 )
 
+// DefaultPoolSize is the default limit of running processes per code
+const DefaultPoolSize = 8
+
 type CodeDriver struct {
 	codeDir     string
 	execTimeout time.Duration
 	mtxInstall  sync.Mutex
+
+	poolSize int
+	pools    map[string]*processPool
+	mtxPools sync.Mutex
 }
 
 func NewCodeDriver(codeDir string, timeout time.Duration) *CodeDriver {
 	return &CodeDriver{
 		codeDir:     codeDir,
 		execTimeout: timeout,
+		poolSize:    DefaultPoolSize,
+		pools:       make(map[string]*processPool),
+	}
+}
+
+// SetPoolSize sets the limit of running processes per code
+func (drv *CodeDriver) SetPoolSize(size int) *CodeDriver {
+	if size > 0 {
+		drv.poolSize = size
 	}
+	return drv
 }
 
 func (drv *CodeDriver) Install(codeID, data []byte) error {
@@ -39,11 +56,23 @@ func (drv *CodeDriver) Install(codeID, data []byte) error {
 
 func (drv *CodeDriver) GetInstance(codeID []byte) (chaincode.Chaincode, error) {
 	return &Runner{
-		codePath: path.Join(drv.codeDir, hex.EncodeToString(codeID)),
-		timeout:  drv.execTimeout,
+		pool:    drv.getPool(codeID),
+		timeout: drv.execTimeout,
 	}, nil
 }
 
+func (drv *CodeDriver) getPool(codeID []byte) *processPool {
+	drv.mtxPools.Lock()
+	defer drv.mtxPools.Unlock()
+
+	pool, ok := drv.pools[string(codeID)]
+	if !ok {
+		pool = newProcessPool(path.Join(drv.codeDir, hex.EncodeToString(codeID)), drv.poolSize)
+		drv.pools[string(codeID)] = pool
+	}
+	return pool
+}
+
 func (drv *CodeDriver) downloadCodeIfRequired(codeID, data []byte) error {
 	filepath := path.Join(drv.codeDir, hex.EncodeToString(codeID))
 	if _, err := os.Stat(filepath); err == nil {
@@ -87,6 +87,40 @@ func TestSetState(t *testing.T) {
 	assert.Equal(value, mctx.GetState(key))
 }
 
+func TestEmitEvent(t *testing.T) {
+	r, c := setupRunnerAndClient()
+	mctx := new(chaincode.MockCallContext)
+	r.callContext = mctx
+
+	go r.serveStateAndGetResult()
+	c.EmitEvent("someevent", []byte("somedata"))
+
+	assert := assert.New(t)
+	if assert.Equal(1, len(mctx.MockEvents)) {
+		assert.Equal("someevent", mctx.MockEvents[0].Name)
+		assert.Equal([]byte("somedata"), mctx.MockEvents[0].Data)
+	}
+}
+
+func TestInvokeAndQuery(t *testing.T) {
+	r, c := setupRunnerAndClient()
+	mctx := new(chaincode.MockCallContext)
+	mctx.MockInvokeError = errors.New("invoke error")
+	mctx.MockQueryResult = []byte("result")
+	r.callContext = mctx
+
+	go r.serveStateAndGetResult()
+	err := c.Invoke([]byte("codeAddr"), []byte("input"))
+
+	assert := assert.New(t)
+	assert.Error(err)
+	assert.Equal(mctx.MockInvokeError.Error(), err.Error())
+
+	res, err := c.Query([]byte("codeAddr"), []byte("input"))
+	assert.NoError(err)
+	assert.Equal(mctx.MockQueryResult, res)
+}
+
 func TestResult(t *testing.T) {
 	r, c := setupRunnerAndClient()
 
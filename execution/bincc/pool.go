@@ -0,0 +1,154 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package bincc
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sync/atomic"
+	"time"
+
+	"github.com/aungmawjj/juria-blockchain/logger"
+)
+
+// process is a running chaincode binary which serves calls one after another
+type process struct {
+	cmd    *exec.Cmd
+	rw     *readWriter
+	exited chan struct{}
+	err    error // exit error, set before exited is closed
+	killed int32
+}
+
+func startProcess(codePath string, timeout <-chan time.Time) (*process, error) {
+	proc, err := newProcess(codePath)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		err := proc.cmd.Start()
+		if err == nil {
+			break
+		}
+		logger.I().Warnf("start code error %+v", err)
+		select {
+		case <-timeout:
+			proc.rw.reader.Close()
+			return nil, fmt.Errorf("chaincode start timeout")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	// close the write end in this process so that reads get EOF when the chaincode exits
+	proc.cmd.Stderr.(*os.File).Close()
+	go func() {
+		proc.err = proc.cmd.Wait()
+		close(proc.exited)
+	}()
+	return proc, nil
+}
+
+func newProcess(codePath string) (*process, error) {
+	proc := &process{
+		cmd:    exec.Command(codePath),
+		rw:     new(readWriter),
+		exited: make(chan struct{}),
+	}
+	var err error
+	proc.rw.writer, err = proc.cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	// stderr pipe is not closed by cmd.Wait, results written before exit can still be read
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	proc.rw.reader = r
+	proc.cmd.Stderr = w
+	return proc, nil
+}
+
+func (proc *process) kill() {
+	atomic.StoreInt32(&proc.killed, 1)
+	proc.cmd.Process.Kill()
+}
+
+func (proc *process) isKilled() bool {
+	return atomic.LoadInt32(&proc.killed) == 1
+}
+
+func (proc *process) isExited() bool {
+	select {
+	case <-proc.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+func (proc *process) wait() error {
+	<-proc.exited
+	return proc.err
+}
+
+// processPool keeps the chaincode processes of a code for reuse, up to the pool size
+type processPool struct {
+	codePath string
+	idle     chan *process
+	tokens   chan struct{} // a token is held by each running process
+}
+
+func newProcessPool(codePath string, size int) *processPool {
+	return &processPool{
+		codePath: codePath,
+		idle:     make(chan *process, size),
+		tokens:   make(chan struct{}, size),
+	}
+}
+
+// get gives an idle process or starts a new one if the pool is not full,
+// otherwise it waits for a process to be released
+func (pool *processPool) get(timeout <-chan time.Time) (*process, error) {
+	for {
+		var proc *process
+		select {
+		case proc = <-pool.idle:
+		default:
+			select {
+			case proc = <-pool.idle:
+			case pool.tokens <- struct{}{}:
+				return pool.start(timeout)
+			case <-timeout:
+				return nil, fmt.Errorf("chaincode process pool timeout")
+			}
+		}
+		if !proc.isExited() {
+			return proc, nil
+		}
+		pool.remove(proc) // crashed while idle
+	}
+}
+
+func (pool *processPool) start(timeout <-chan time.Time) (*process, error) {
+	proc, err := startProcess(pool.codePath, timeout)
+	if err != nil {
+		<-pool.tokens
+		return nil, err
+	}
+	return proc, nil
+}
+
+// put releases a healthy process to be reused
+func (pool *processPool) put(proc *process) {
+	pool.idle <- proc
+}
+
+// remove kills the process and frees its place in the pool
+func (pool *processPool) remove(proc *process) {
+	proc.kill()
+	proc.wait()
+	proc.rw.reader.Close()
+	<-pool.tokens
+}
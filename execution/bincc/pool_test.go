@@ -0,0 +1,141 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package bincc
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aungmawjj/juria-blockchain/execution/chaincode"
+	"github.com/stretchr/testify/assert"
+)
+
+const envTestChaincode = "BINCC_TEST_CHAINCODE"
+
+// the test binary runs as the chaincode when started by the runner
+func TestMain(m *testing.M) {
+	if os.Getenv(envTestChaincode) != "" {
+		RunChaincode(new(pidChaincode))
+	}
+	os.Exit(m.Run())
+}
+
+// pidChaincode returns the process id, it exits on "crash" input, sleeps on "sleep" input
+// and reads the state on "state" input
+type pidChaincode struct{}
+
+func (cc *pidChaincode) Init(ctx chaincode.CallContext) error {
+	return nil
+}
+
+func (cc *pidChaincode) Invoke(ctx chaincode.CallContext) error {
+	switch string(ctx.Input()) {
+	case "crash":
+		os.Exit(2)
+	case "sleep":
+		time.Sleep(time.Second)
+	case "state":
+		ctx.GetState([]byte("key"))
+	}
+	return nil
+}
+
+func (cc *pidChaincode) Query(ctx chaincode.CallContext) ([]byte, error) {
+	return []byte(strconv.Itoa(os.Getpid())), nil
+}
+
+// outOfGasContext panics on the gas consumption of upstream calls
+type outOfGasContext struct {
+	*chaincode.MockCallContext
+}
+
+func (ctx *outOfGasContext) ConsumeGas(amount uint64) {
+	panic("out of gas")
+}
+
+func newTestRunner(pool *processPool, timeout time.Duration) *Runner {
+	return &Runner{pool: pool, timeout: timeout}
+}
+
+func queryPid(r *Runner) string {
+	res, _ := r.Query(new(chaincode.MockCallContext))
+	return string(res)
+}
+
+func TestProcessPool(t *testing.T) {
+	os.Setenv(envTestChaincode, "1")
+	defer os.Unsetenv(envTestChaincode)
+
+	assert := assert.New(t)
+	pool := newProcessPool(os.Args[0], 2)
+
+	r := newTestRunner(pool, 5*time.Second)
+	pid := queryPid(r)
+	assert.NotEmpty(pid)
+	assert.Equal(pid, queryPid(r), "process should be reused")
+
+	err := r.Invoke(&chaincode.MockCallContext{MockInput: []byte("crash")})
+	assert.Error(err)
+	pid1 := queryPid(r)
+	assert.NotEmpty(pid1)
+	assert.NotEqual(pid, pid1, "crashed process should be replaced")
+
+	var wg sync.WaitGroup
+	var mtx sync.Mutex
+	pids := make(map[string]struct{})
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pid := queryPid(newTestRunner(pool, 5*time.Second))
+			mtx.Lock()
+			pids[pid] = struct{}{}
+			mtx.Unlock()
+		}()
+	}
+	wg.Wait()
+	assert.NotContains(pids, "")
+	assert.LessOrEqual(len(pids), 2, "running processes should be bound by pool size")
+}
+
+func TestProcessPool_Timeout(t *testing.T) {
+	os.Setenv(envTestChaincode, "1")
+	defer os.Unsetenv(envTestChaincode)
+
+	assert := assert.New(t)
+	pool := newProcessPool(os.Args[0], 1)
+
+	r := newTestRunner(pool, 300*time.Millisecond)
+	pid := queryPid(r)
+	assert.NotEmpty(pid)
+
+	err := r.Invoke(&chaincode.MockCallContext{MockInput: []byte("sleep")})
+	assert.EqualError(err, "chaincode call timeout")
+
+	pid1 := queryPid(r)
+	assert.NotEmpty(pid1, "timed out process should be recycled")
+	assert.NotEqual(pid, pid1)
+}
+
+func TestProcessPool_Panic(t *testing.T) {
+	os.Setenv(envTestChaincode, "1")
+	defer os.Unsetenv(envTestChaincode)
+
+	assert := assert.New(t)
+	pool := newProcessPool(os.Args[0], 1)
+
+	r := newTestRunner(pool, 5*time.Second)
+	pid := queryPid(r)
+	assert.NotEmpty(pid)
+
+	ctx := &outOfGasContext{&chaincode.MockCallContext{MockInput: []byte("state")}}
+	assert.PanicsWithValue("out of gas", func() { r.Invoke(ctx) })
+
+	pid1 := queryPid(r)
+	assert.NotEmpty(pid1, "process should be released from the pool")
+	assert.NotEqual(pid, pid1, "process should be killed")
+}
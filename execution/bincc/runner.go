@@ -6,24 +6,25 @@ package bincc
 import (
 	"encoding/json"
 	"fmt"
-	"os/exec"
 	"time"
 
 	"github.com/aungmawjj/juria-blockchain/execution/chaincode"
-	"github.com/aungmawjj/juria-blockchain/logger"
 )
 
 const MessageSizeLimit = 100 * 1000 * 1000
 
 type Runner struct {
-	codePath string
-	timeout  time.Duration
+	pool    *processPool
+	timeout time.Duration
 
 	callContext chaincode.CallContext
 
-	cmd   *exec.Cmd
 	rw    *readWriter
 	timer *time.Timer
+
+	// set by the result of chaincode built with persistent client,
+	// older chaincodes exit after one call
+	keepAlive bool
 }
 
 var _ chaincode.Chaincode = (*Runner)(nil)
@@ -46,52 +47,53 @@ func (r *Runner) Query(ctx chaincode.CallContext) ([]byte, error) {
 }
 
 func (r *Runner) runCode(callType CallType) ([]byte, error) {
+	start := time.Now()
 	r.timer = time.NewTimer(r.timeout)
 	defer r.timer.Stop()
 
-	if err := r.startCode(CallTypeInit); err != nil {
+	proc, err := r.pool.get(r.timer.C)
+	if err != nil {
 		return nil, err
 	}
-	defer r.cmd.Process.Kill()
-
-	if err := r.sendCallData(callType); err != nil {
-		return nil, err
+	defer func() {
+		// call context panics (e.g. out of gas) in the middle of the call,
+		// the process cannot serve other calls
+		if e := recover(); e != nil {
+			r.pool.remove(proc)
+			panic(e)
+		}
+	}()
+	// blocking pipe io returns when the process is killed
+	killer := time.AfterFunc(r.timeout-time.Since(start), proc.kill)
+	defer killer.Stop()
+
+	r.rw = proc.rw
+	r.keepAlive = false
+	res, err := r.serveCall(callType)
+	if r.keepAlive && killer.Stop() {
+		r.pool.put(proc)
+		return res, err
 	}
-	res, err := r.serveStateAndGetResult()
+	timeout := proc.isKilled()
 	if err != nil {
-		return nil, err
+		proc.kill()
 	}
-	return res, r.cmd.Wait()
-}
-
-func (r *Runner) startCode(callType CallType) error {
-	if err := r.setupCmd(); err != nil {
-		return err
-	}
-	err := r.cmd.Start()
-	if err == nil {
-		return nil
+	exitErr := proc.wait()
+	r.pool.remove(proc)
+	if timeout {
+		return nil, fmt.Errorf("chaincode call timeout")
 	}
-	logger.I().Warnf("start code error %f", err)
-	select {
-	case <-r.timer.C:
-		return fmt.Errorf("chaincode start timeout")
-	default:
+	if err != nil {
+		return nil, err
 	}
-	time.Sleep(5 * time.Millisecond)
-	return r.startCode(callType)
+	return res, exitErr
 }
 
-func (r *Runner) setupCmd() error {
-	r.cmd = exec.Command(r.codePath)
-	var err error
-	r.rw = new(readWriter)
-	r.rw.writer, err = r.cmd.StdinPipe()
-	if err != nil {
-		return err
+func (r *Runner) serveCall(callType CallType) ([]byte, error) {
+	if err := r.sendCallData(callType); err != nil {
+		return nil, err
 	}
-	r.rw.reader, err = r.cmd.StderrPipe()
-	return err
+	return r.serveStateAndGetResult()
 }
 
 func (r *Runner) sendCallData(callType CallType) error {
@@ -122,6 +124,7 @@ func (r *Runner) serveStateAndGetResult() ([]byte, error) {
 			return nil, fmt.Errorf("cannot parse upstream data")
 		}
 		if up.Type == UpStreamResult {
+			r.keepAlive = up.KeepAlive
 			if len(up.Error) > 0 {
 				return nil, fmt.Errorf(up.Error)
 			}
@@ -134,6 +137,9 @@ func (r *Runner) serveStateAndGetResult() ([]byte, error) {
 }
 
 func (r *Runner) serveState(up *UpStream) error {
+	if gm, ok := r.callContext.(chaincode.GasMeter); ok {
+		gm.ConsumeGas(GasPerUpStream)
+	}
 	down := new(DownStream)
 	switch up.Type {
 
@@ -143,6 +149,31 @@ func (r *Runner) serveState(up *UpStream) error {
 
 	case UpStreamSetState:
 		r.callContext.SetState(up.Key, up.Value)
+
+	case UpStreamDeleteState:
+		r.callContext.DeleteState(up.Key)
+
+	case UpStreamRangeState:
+		r.callContext.RangeState(up.Key, up.Value, func(key, value []byte) bool {
+			down.Keys = append(down.Keys, key)
+			down.Values = append(down.Values, value)
+			return len(down.Keys) < RangePageSize
+		})
+
+	case UpStreamEmitEvent:
+		r.callContext.EmitEvent(string(up.Key), up.Value)
+
+	case UpStreamInvoke:
+		if err := r.callContext.Invoke(up.Key, up.Value); err != nil {
+			down.Error = err.Error()
+		}
+
+	case UpStreamQuery:
+		val, err := r.callContext.Query(up.Key, up.Value)
+		if err != nil {
+			down.Error = err.Error()
+		}
+		down.Value = val
 	}
 
 	b, _ := json.Marshal(down)
@@ -21,20 +21,38 @@ type CallData struct {
 
 type UpStreamType int
 
+// GasPerUpStream is charged for each upstream message from chaincode process
+const GasPerUpStream uint64 = 50
+
 const (
 	UpStreamGetState UpStreamType = iota
 	UpStreamSetState
 	UpStreamResult
+	UpStreamEmitEvent
+	UpStreamInvoke
+	UpStreamQuery
+	UpStreamDeleteState
+	UpStreamRangeState // Key is start and Value is end of range
 )
 
+// RangePageSize is the maximum states in a downstream of range state
+const RangePageSize = 100
+
 type UpStream struct {
 	Key   []byte
 	Value []byte
 	Error string
 	Type  UpStreamType
+
+	// set in result when the chaincode process serves the next call
+	KeepAlive bool `json:",omitempty"`
 }
 
 type DownStream struct {
 	Value []byte
 	Error string
+
+	// states of range state in key order
+	Keys   [][]byte `json:",omitempty"`
+	Values [][]byte `json:",omitempty"`
 }
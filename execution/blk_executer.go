@@ -4,6 +4,7 @@
 package execution
 
 import (
+	"errors"
 	"sync/atomic"
 	"time"
 
@@ -12,6 +13,10 @@ import (
 	"github.com/aungmawjj/juria-blockchain/logger"
 )
 
+// ErrStaleNonce is the error of the tx with a nonce lower than the next nonce of the sender,
+// the tx is not executed
+var ErrStaleNonce = errors.New("stale tx nonce")
+
 type blkExecutor struct {
 	txTimeout       time.Duration
 	concurrentLimit int
@@ -21,8 +26,9 @@ type blkExecutor struct {
 	blk          *core.Block
 	txs          []*core.Transaction
 
-	rootTrk   *stateTracker
-	txCommits []*core.TxCommit
+	rootTrk     *stateTracker
+	txCommits   []*core.TxCommit
+	staleNonces []bool
 
 	mergeIdx     int32
 	mergeEmitter *emitter.Emitter
@@ -51,6 +57,7 @@ func (bexe *blkExecutor) execute() (*core.BlockCommit, []*core.TxCommit) {
 	bexe.mergeEmitter = emitter.New()
 	bexe.rootTrk = newStateTracker(bexe.state, nil)
 	bexe.txCommits = make([]*core.TxCommit, len(bexe.txs))
+	bexe.findStaleNonces()
 	bexe.executeConcurrent()
 	elapsed := time.Since(start)
 	bcm := core.NewBlockCommit().
@@ -65,6 +72,25 @@ func (bexe *blkExecutor) execute() (*core.BlockCommit, []*core.TxCommit) {
 	return bcm, bexe.txCommits
 }
 
+// findStaleNonces marks the txs with nonces lower than the next nonce of the sender,
+// the nonces used by the commited txs or the earlier txs in the block, duplicate or out of order.
+func (bexe *blkExecutor) findStaleNonces() {
+	bexe.staleNonces = make([]bool, len(bexe.txs))
+	nonces := make(map[string]int64)
+	for i, tx := range bexe.txs {
+		sender := string(tx.Sender().Bytes())
+		next, found := nonces[sender]
+		if !found {
+			next = bexe.state.GetNextNonce(tx.Sender())
+		}
+		if tx.Nonce() < next {
+			bexe.staleNonces[i] = true
+			continue
+		}
+		nonces[sender] = tx.Nonce() + 1
+	}
+}
+
 func (bexe *blkExecutor) executeConcurrent() {
 	if len(bexe.txs) == 0 {
 		return
@@ -137,7 +163,20 @@ func (bexe *blkExecutor) executeTx(i int) *txExecutor {
 		blk:          bexe.blk,
 		tx:           bexe.txs[i],
 	}
+	if bexe.staleNonces[i] {
+		bexe.txCommits[i] = core.NewTxCommit().
+			SetHash(texe.tx.Hash()).
+			SetBlockHash(bexe.blk.Hash()).
+			SetBlockHeight(bexe.blk.Height()).
+			SetError(ErrStaleNonce.Error())
+		return texe
+	}
 	bexe.txCommits[i] = texe.execute()
+	// observed here rather than in txExecutor, so simulated txs are not counted
+	metricTxExec.Observe(bexe.txCommits[i].Elapsed())
+	if bexe.txCommits[i].Error() != "" {
+		metricTxErrors.Inc()
+	}
 	return texe
 }
 
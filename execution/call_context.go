@@ -4,20 +4,70 @@
 package execution
 
 import (
+	"bytes"
+	"errors"
+
 	"github.com/aungmawjj/juria-blockchain/core"
 	"github.com/aungmawjj/juria-blockchain/execution/chaincode"
 )
 
+// MaxCallDepth is the maximum depth of nested chaincode calls
+const MaxCallDepth = 8
+
+var (
+	ErrMaxCallDepth   = errors.New("exceeded max call depth")
+	ErrReentrantCall  = errors.New("reentrant chaincode call")
+	ErrInvokeInQuery  = errors.New("cannot invoke chaincode in query")
+	ErrNoCodeRegistry = errors.New("chaincode calls not supported")
+)
+
+// callStack holds the addresses of chaincodes in the current call chain
+type callStack [][]byte
+
+func (cs callStack) push(codeAddr []byte) (callStack, error) {
+	if len(cs) > MaxCallDepth {
+		return nil, ErrMaxCallDepth
+	}
+	for _, addr := range cs {
+		if bytes.Equal(addr, codeAddr) {
+			return nil, ErrReentrantCall
+		}
+	}
+	next := make(callStack, len(cs), len(cs)+1)
+	copy(next, cs)
+	return append(next, codeAddr), nil
+}
+
+func (cs callStack) current() []byte {
+	if len(cs) == 0 {
+		return nil
+	}
+	return cs[len(cs)-1]
+}
+
 type callContextTx struct {
-	blk   *core.Block
-	tx    *core.Transaction
-	input []byte
+	blk      *core.Block
+	tx       *core.Transaction
+	input    []byte
+	codeAddr []byte
+	sender   []byte
+	gas      *gasMeter
+	events   *eventList
+
+	codeRegistry *codeRegistry
+	calls        callStack
+	frameTrk     *stateTracker // state of current call frame without key prefix
+
 	*stateTracker
 }
 
 var _ chaincode.CallContext = (*callContextTx)(nil)
+var _ chaincode.GasMeter = (*callContextTx)(nil)
 
 func (ctx *callContextTx) Sender() []byte {
+	if ctx.sender != nil {
+		return ctx.sender
+	}
 	if ctx.tx == nil {
 		return nil
 	}
@@ -45,8 +95,110 @@ func (ctx *callContextTx) Input() []byte {
 	return ctx.input
 }
 
+func (ctx *callContextTx) GetState(key []byte) []byte {
+	ctx.ConsumeGas(GasGetState)
+	value := ctx.stateTracker.GetState(key)
+	ctx.ConsumeGas(gasForState(key, value))
+	return value
+}
+
+func (ctx *callContextTx) SetState(key, value []byte) {
+	ctx.ConsumeGas(GasSetState + gasForState(key, value))
+	ctx.stateTracker.SetState(key, value)
+}
+
+func (ctx *callContextTx) DeleteState(key []byte) {
+	ctx.ConsumeGas(GasDeleteState + gasForState(key, nil))
+	ctx.stateTracker.DeleteState(key)
+}
+
+func (ctx *callContextTx) RangeState(start, end []byte, fn func(key, value []byte) bool) {
+	ctx.ConsumeGas(GasRangeState)
+	ctx.stateTracker.RangeState(start, end, func(key, value []byte) bool {
+		ctx.ConsumeGas(GasRangeEntry + gasForState(key, value))
+		return fn(key, value)
+	})
+}
+
+func (ctx *callContextTx) EmitEvent(name string, data []byte) {
+	ctx.ConsumeGas(GasEmitEvent + uint64(len(name)+len(data))*GasPerEventByte)
+	if ctx.events == nil {
+		return
+	}
+	ctx.events.add(core.NewEvent().
+		SetCodeAddr(ctx.codeAddr).
+		SetName(name).
+		SetData(data))
+}
+
+// Invoke runs the callee in a child call frame.
+// The frame is merged to the caller's frame only if the callee succeeds.
+func (ctx *callContextTx) Invoke(codeAddr, input []byte) error {
+	if ctx.codeRegistry == nil || ctx.frameTrk == nil {
+		return ErrNoCodeRegistry
+	}
+	ctx.ConsumeGas(GasCall + uint64(len(input))*GasPerInputByte)
+	calls, err := ctx.calls.push(codeAddr)
+	if err != nil {
+		return err
+	}
+	frameTrk := ctx.frameTrk.spawn(nil)
+	cc, err := ctx.codeRegistry.getInstance(codeAddr, frameTrk.spawn(codeRegistryAddr))
+	if err != nil {
+		return err
+	}
+	callee := &callContextTx{
+		blk:          ctx.blk,
+		tx:           ctx.tx,
+		input:        input,
+		codeAddr:     codeAddr,
+		sender:       ctx.calls.current(),
+		gas:          ctx.gas,
+		events:       new(eventList),
+		codeRegistry: ctx.codeRegistry,
+		calls:        calls,
+		frameTrk:     frameTrk,
+		stateTracker: frameTrk.spawn(codeAddr),
+	}
+	if err := cc.Invoke(callee); err != nil {
+		return err
+	}
+	frameTrk.merge(callee.stateTracker)
+	ctx.frameTrk.merge(frameTrk)
+	if ctx.events != nil {
+		ctx.events.addList(callee.events.list())
+	}
+	return nil
+}
+
+func (ctx *callContextTx) Query(codeAddr, input []byte) ([]byte, error) {
+	if ctx.codeRegistry == nil || ctx.frameTrk == nil {
+		return nil, ErrNoCodeRegistry
+	}
+	ctx.ConsumeGas(GasCall + uint64(len(input))*GasPerInputByte)
+	query := &callContextQuery{
+		codeRegistry: ctx.codeRegistry,
+		calls:        ctx.calls,
+		rootState:    ctx.frameTrk,
+	}
+	return query.Query(codeAddr, input)
+}
+
+func (ctx *callContextTx) ConsumeGas(amount uint64) {
+	if ctx.gas == nil {
+		return
+	}
+	ctx.gas.ConsumeGas(amount)
+}
+
 type callContextQuery struct {
-	input []byte
+	input  []byte
+	sender []byte
+
+	codeRegistry *codeRegistry
+	calls        callStack
+	rootState    stateGetter // state without key prefix
+
 	stateGetter
 }
 
@@ -57,7 +209,7 @@ func (ctx *callContextQuery) Input() []byte {
 }
 
 func (ctx *callContextQuery) Sender() []byte {
-	return nil
+	return ctx.sender
 }
 
 func (ctx *callContextQuery) BlockHash() []byte {
@@ -71,3 +223,38 @@ func (ctx *callContextQuery) BlockHeight() uint64 {
 func (ctx *callContextQuery) SetState(key, value []byte) {
 	// do nothing
 }
+
+func (ctx *callContextQuery) DeleteState(key []byte) {
+	// do nothing
+}
+
+func (ctx *callContextQuery) EmitEvent(name string, data []byte) {
+	// do nothing
+}
+
+func (ctx *callContextQuery) Invoke(codeAddr, input []byte) error {
+	return ErrInvokeInQuery
+}
+
+func (ctx *callContextQuery) Query(codeAddr, input []byte) ([]byte, error) {
+	if ctx.codeRegistry == nil || ctx.rootState == nil {
+		return nil, ErrNoCodeRegistry
+	}
+	calls, err := ctx.calls.push(codeAddr)
+	if err != nil {
+		return nil, err
+	}
+	cc, err := ctx.codeRegistry.getInstance(
+		codeAddr, newStateTracker(ctx.rootState, codeRegistryAddr))
+	if err != nil {
+		return nil, err
+	}
+	return cc.Query(&callContextQuery{
+		input:        input,
+		sender:       ctx.calls.current(),
+		codeRegistry: ctx.codeRegistry,
+		calls:        calls,
+		rootState:    ctx.rootState,
+		stateGetter:  newStateTracker(ctx.rootState, codeAddr),
+	})
+}
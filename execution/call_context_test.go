@@ -0,0 +1,177 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package execution
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aungmawjj/juria-blockchain/chaincodes/juriacoin"
+	"github.com/aungmawjj/juria-blockchain/core"
+	"github.com/aungmawjj/juria-blockchain/execution/chaincode"
+	"github.com/stretchr/testify/assert"
+)
+
+const driverTypeTestCaller DriverType = 100
+
+type callerInput struct {
+	CodeAddr    []byte
+	Input       []byte
+	IgnoreError bool
+}
+
+// callerCode calls another chaincode given by input
+type callerCode struct{}
+
+func (cc *callerCode) Init(ctx chaincode.CallContext) error {
+	return nil
+}
+
+func (cc *callerCode) Invoke(ctx chaincode.CallContext) error {
+	input := new(callerInput)
+	if err := json.Unmarshal(ctx.Input(), input); err != nil {
+		return err
+	}
+	ctx.SetState([]byte("called"), []byte{1})
+	err := ctx.Invoke(input.CodeAddr, input.Input)
+	if input.IgnoreError {
+		return nil
+	}
+	return err
+}
+
+func (cc *callerCode) Query(ctx chaincode.CallContext) ([]byte, error) {
+	input := new(callerInput)
+	if err := json.Unmarshal(ctx.Input(), input); err != nil {
+		return nil, err
+	}
+	return ctx.Query(input.CodeAddr, input.Input)
+}
+
+type callerCodeDriver struct{}
+
+func (drv *callerCodeDriver) Install(codeID, data []byte) error {
+	return nil
+}
+
+func (drv *callerCodeDriver) GetInstance(codeID []byte) (chaincode.Chaincode, error) {
+	return new(callerCode), nil
+}
+
+func makeCallerInput(codeAddr, input []byte, ignoreError bool) []byte {
+	b, _ := json.Marshal(&callerInput{
+		CodeAddr:    codeAddr,
+		Input:       input,
+		IgnoreError: ignoreError,
+	})
+	return b
+}
+
+func TestCallContext_Invoke(t *testing.T) {
+	assert := assert.New(t)
+
+	priv := core.GenerateKey(nil)
+	blk := core.NewBlock().SetHeight(10).Sign(priv)
+	reg := newCodeRegistry()
+	reg.registerDriver(DriverTypeNative, newNativeCodeDriver())
+	reg.registerDriver(driverTypeTestCaller, new(callerCodeDriver))
+
+	rootTrk := newStateTracker(newMapStateStore(), nil)
+	execute := func(codeAddr, input []byte) *core.TxCommit {
+		texe := &txExecutor{
+			codeRegistry: reg,
+			timeout:      1 * time.Second,
+			txTrk:        rootTrk.spawn(nil),
+			blk:          blk,
+			tx: core.NewTransaction().
+				SetNonce(time.Now().UnixNano()).
+				SetCodeAddr(codeAddr).
+				SetInput(input).
+				SetGasLimit(10 * core.DefaultTxGasLimit).
+				Sign(priv),
+		}
+		txc := texe.execute()
+		if txc.Error() == "" {
+			rootTrk.merge(texe.txTrk)
+		}
+		return txc
+	}
+	deploy := func(driverType DriverType, codeID []byte) []byte {
+		b, _ := json.Marshal(&DeploymentInput{
+			CodeInfo: CodeInfo{DriverType: driverType, CodeID: codeID},
+		})
+		txc := execute(nil, b)
+		assert.Equal("", txc.Error())
+		return txc.Hash()
+	}
+	coinInput := func(method string, dest []byte, value int64) []byte {
+		b, _ := json.Marshal(&juriacoin.Input{Method: method, Dest: dest, Value: value})
+		return b
+	}
+
+	coinAddr := deploy(DriverTypeNative, NativeCodeIDJuriaCoin)
+	query := &callContextQuery{codeRegistry: reg, rootState: rootTrk}
+	queryBalance := func(addr []byte) int64 {
+		b, err := query.Query(coinAddr, coinInput("balance", addr, 0))
+		assert.NoError(err)
+		var balance int64
+		json.Unmarshal(b, &balance)
+		return balance
+	}
+
+	callers := make([][]byte, MaxCallDepth+1)
+	for i := range callers {
+		callers[i] = deploy(driverTypeTestCaller, []byte{1})
+	}
+	callerA, callerB := callers[0], callers[1]
+	dest := []byte{2, 2, 2}
+
+	txc := execute(coinAddr, coinInput("mint", callerA, 100))
+	assert.Equal("", txc.Error())
+
+	// callerA is sender of the transfer
+	txc = execute(callerA, makeCallerInput(coinAddr, coinInput("transfer", dest, 30), false))
+	assert.Equal("", txc.Error())
+	assert.EqualValues(70, queryBalance(callerA))
+	assert.EqualValues(30, queryBalance(dest))
+	if assert.Equal(1, len(txc.Events())) {
+		assert.Equal(coinAddr, txc.Events()[0].CodeAddr())
+		assert.Equal(juriacoin.EventTransfer, txc.Events()[0].Name())
+	}
+
+	txc = execute(callerA, makeCallerInput(coinAddr, coinInput("transfer", dest, 1000), false))
+	assert.NotEqual("", txc.Error(), "not enough balance")
+
+	// callerB's changes are rolled back, callerA ignores the error
+	bInput := makeCallerInput(coinAddr, coinInput("transfer", dest, 10), false)
+	txc = execute(callerA, makeCallerInput(callerB, bInput, true))
+	assert.Equal("", txc.Error())
+	assert.Nil(rootTrk.GetState(concatBytes(callerB, []byte("called"))))
+	assert.NotNil(rootTrk.GetState(concatBytes(callerA, []byte("called"))))
+	assert.Empty(txc.Events())
+
+	// callerA -> callerB -> callerA
+	bInput = makeCallerInput(callerA, makeCallerInput(coinAddr, nil, false), false)
+	txc = execute(callerA, makeCallerInput(callerB, bInput, false))
+	assert.Equal(ErrReentrantCall.Error(), txc.Error())
+
+	// callers[0] -> callers[1] -> ... -> callers[MaxCallDepth] -> coin
+	input := coinInput("transfer", dest, 1)
+	input = makeCallerInput(coinAddr, input, false)
+	for i := len(callers) - 1; i > 0; i-- {
+		input = makeCallerInput(callers[i], input, false)
+	}
+	txc = execute(callers[0], input)
+	assert.Equal(ErrMaxCallDepth.Error(), txc.Error())
+
+	// query through callerA
+	b, err := query.Query(callerA, makeCallerInput(coinAddr, coinInput("balance", dest, 0), false))
+	assert.NoError(err)
+	var balance int64
+	json.Unmarshal(b, &balance)
+	assert.EqualValues(30, balance)
+
+	assert.Equal(ErrInvokeInQuery, query.Invoke(coinAddr, nil))
+}
@@ -11,6 +11,40 @@ type CallContext interface {
 
 	GetState(key []byte) []byte
 	SetState(key, value []byte)
+	DeleteState(key []byte)
+
+	// RangeState calls fn for the states with keys in [start, end) in key order until fn returns false.
+	// empty end means no upper bound, use PrefixEnd to iterate the keys with a prefix
+	RangeState(start, end []byte, fn func(key, value []byte) bool)
+
+	// EmitEvent records an event in the tx commit.
+	// events are discarded if the tx fails
+	EmitEvent(name string, data []byte)
+
+	// Invoke calls another chaincode with the current chaincode address as sender.
+	// state changes of the callee are discarded if it returns error
+	Invoke(codeAddr, input []byte) error
+
+	// Query calls query of another chaincode
+	Query(codeAddr, input []byte) ([]byte, error)
+}
+
+// PrefixEnd gives the smallest key greater than all keys with the prefix,
+// nil if there is no such key
+func PrefixEnd(prefix []byte) []byte {
+	end := append([]byte{}, prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+// GasMeter is implemented by call contexts that charge gas for chaincode execution
+type GasMeter interface {
+	ConsumeGas(amount uint64)
 }
 
 // all chaincodes implements Chaincode interface
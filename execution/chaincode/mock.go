@@ -3,6 +3,8 @@
 
 package chaincode
 
+import "sort"
+
 type MockState struct {
 	StateMap    map[string][]byte
 	VerifyError error
@@ -29,11 +31,39 @@ func (ms *MockState) SetState(key, value []byte) {
 	ms.StateMap[string(key)] = value
 }
 
+func (ms *MockState) DeleteState(key []byte) {
+	delete(ms.StateMap, string(key))
+}
+
+func (ms *MockState) RangeState(start, end []byte, fn func(key, value []byte) bool) {
+	keys := make([]string, 0, len(ms.StateMap))
+	for key := range ms.StateMap {
+		if key >= string(start) && (len(end) == 0 || key < string(end)) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !fn([]byte(key), ms.StateMap[key]) {
+			return
+		}
+	}
+}
+
+type MockEvent struct {
+	Name string
+	Data []byte
+}
+
 type MockCallContext struct {
 	MockSender      []byte
 	MockBlockHeight uint64
 	MockBlockHash   []byte
 	MockInput       []byte
+	MockEvents      []MockEvent
+	MockInvokeError error
+	MockQueryResult []byte
+	MockQueryError  error
 	*MockState
 }
 
@@ -54,3 +84,15 @@ func (wc *MockCallContext) BlockHeight() uint64 {
 func (wc *MockCallContext) Input() []byte {
 	return wc.MockInput
 }
+
+func (wc *MockCallContext) EmitEvent(name string, data []byte) {
+	wc.MockEvents = append(wc.MockEvents, MockEvent{name, data})
+}
+
+func (wc *MockCallContext) Invoke(codeAddr, input []byte) error {
+	return wc.MockInvokeError
+}
+
+func (wc *MockCallContext) Query(codeAddr, input []byte) ([]byte, error) {
+	return wc.MockQueryResult, wc.MockQueryError
+}
@@ -13,6 +13,21 @@ import (
 
 var codeRegistryAddr = bytes.Repeat([]byte{0}, 32)
 
+// UpgradeCodeAddr is the fixed address to send chaincode upgrade txs
+var UpgradeCodeAddr = append(bytes.Repeat([]byte{0}, 31), 2)
+
+// registry keys suffixed to the code address
+var (
+	keySuffixAdmin    = []byte("admin")
+	keySuffixUpgrades = []byte("upgrades")
+)
+
+// errors
+var (
+	ErrNotUpgradable = errors.New("chaincode has no admin to upgrade")
+	ErrNotCodeAdmin  = errors.New("sender is not chaincode admin")
+)
+
 type CodeDriver interface {
 	// Install is called when code deployment transaction is received
 	// Example data field - download url for code binary
@@ -26,6 +41,7 @@ type DriverType uint8
 const (
 	DriverTypeNative DriverType = iota + 1
 	DriverTypeBincc
+	DriverTypeWasm
 )
 
 type CodeInfo struct {
@@ -33,16 +49,46 @@ type CodeInfo struct {
 	CodeID     []byte     `json:"codeID"`
 }
 
+// UpgradeInput replaces the code of a deployed chaincode while keeping its states
+type UpgradeInput struct {
+	CodeAddr    []byte   `json:"codeAddr"`
+	CodeInfo    CodeInfo `json:"codeInfo"`
+	InstallData []byte   `json:"installData"`
+
+	// optional input to invoke the new code after upgrade to migrate the states
+	MigrateInput []byte `json:"migrateInput,omitempty"`
+}
+
+// UpgradeRecord is an entry of the upgrade history of a chaincode
+type UpgradeRecord struct {
+	BlockHeight uint64   `json:"blockHeight"`
+	TxHash      []byte   `json:"txHash"`
+	PrevCode    CodeInfo `json:"prevCode"`
+	NewCode     CodeInfo `json:"newCode"`
+}
+
 type codeRegistry struct {
 	drivers map[DriverType]CodeDriver
+
+	// system chaincodes have fixed addresses and are not deployed
+	systemCodes map[string]chaincode.Chaincode
 }
 
 func newCodeRegistry() *codeRegistry {
 	reg := new(codeRegistry)
 	reg.drivers = make(map[DriverType]CodeDriver)
+	reg.systemCodes = make(map[string]chaincode.Chaincode)
 	return reg
 }
 
+func (reg *codeRegistry) registerSystemCode(codeAddr []byte, cc chaincode.Chaincode) error {
+	if _, found := reg.systemCodes[string(codeAddr)]; found {
+		return errors.New("system code already registered")
+	}
+	reg.systemCodes[string(codeAddr)] = cc
+	return nil
+}
+
 func (reg *codeRegistry) registerDriver(driverType DriverType, driver CodeDriver) error {
 	if _, found := reg.drivers[driverType]; found {
 		return errors.New("driver already registered")
@@ -70,9 +116,55 @@ func (reg *codeRegistry) deploy(
 	return driver.GetInstance(input.CodeInfo.CodeID)
 }
 
+// upgrade replaces the code info of the chaincode if the sender is its admin
+func (reg *codeRegistry) upgrade(
+	sender []byte, input *UpgradeInput, record *UpgradeRecord, st *stateTracker,
+) (chaincode.Chaincode, error) {
+	if _, found := reg.systemCodes[string(input.CodeAddr)]; found {
+		return nil, errors.New("cannot upgrade system chaincode")
+	}
+	prev, err := reg.getCodeInfo(input.CodeAddr, st)
+	if err != nil {
+		return nil, errors.New("chaincode not found")
+	}
+	admin := reg.getCodeAdmin(input.CodeAddr, st)
+	if len(admin) == 0 {
+		return nil, ErrNotUpgradable
+	}
+	if !bytes.Equal(admin, sender) {
+		return nil, ErrNotCodeAdmin
+	}
+	driver, err := reg.getDriver(input.CodeInfo.DriverType)
+	if err != nil {
+		return nil, err
+	}
+	cc, err := driver.GetInstance(input.CodeInfo.CodeID)
+	if err != nil {
+		return nil, err
+	}
+	if err := reg.setCodeInfo(input.CodeAddr, &input.CodeInfo, st); err != nil {
+		return nil, err
+	}
+	record.PrevCode = *prev
+	record.NewCode = input.CodeInfo
+	upgrades, err := reg.getUpgrades(input.CodeAddr, st)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(append(upgrades, record))
+	if err != nil {
+		return nil, err
+	}
+	st.SetState(concatBytes(input.CodeAddr, keySuffixUpgrades), b)
+	return cc, nil
+}
+
 func (reg *codeRegistry) getInstance(
 	codeAddr []byte, state stateGetter,
 ) (chaincode.Chaincode, error) {
+	if cc, found := reg.systemCodes[string(codeAddr)]; found {
+		return cc, nil
+	}
 	info, err := reg.getCodeInfo(codeAddr, state)
 	if err != nil {
 		return nil, err
@@ -112,3 +204,23 @@ func (reg *codeRegistry) getCodeInfo(codeAddr []byte, state stateGetter) (*CodeI
 	}
 	return info, nil
 }
+
+func (reg *codeRegistry) setCodeAdmin(codeAddr, admin []byte, st *stateTracker) {
+	st.SetState(concatBytes(codeAddr, keySuffixAdmin), admin)
+}
+
+func (reg *codeRegistry) getCodeAdmin(codeAddr []byte, state stateGetter) []byte {
+	return state.GetState(concatBytes(codeAddr, keySuffixAdmin))
+}
+
+func (reg *codeRegistry) getUpgrades(codeAddr []byte, state stateGetter) ([]*UpgradeRecord, error) {
+	upgrades := make([]*UpgradeRecord, 0)
+	b := state.GetState(concatBytes(codeAddr, keySuffixUpgrades))
+	if b == nil {
+		return upgrades, nil
+	}
+	if err := json.Unmarshal(b, &upgrades); err != nil {
+		return nil, err
+	}
+	return upgrades, nil
+}
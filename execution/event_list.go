@@ -0,0 +1,36 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package execution
+
+import (
+	"sync"
+
+	"github.com/aungmawjj/juria-blockchain/core"
+)
+
+// eventList collects events emitted during a tx execution
+type eventList struct {
+	events []*core.Event
+	mtx    sync.Mutex
+}
+
+func (el *eventList) add(evt *core.Event) {
+	el.mtx.Lock()
+	defer el.mtx.Unlock()
+	el.events = append(el.events, evt)
+}
+
+func (el *eventList) list() []*core.Event {
+	el.mtx.Lock()
+	defer el.mtx.Unlock()
+	events := make([]*core.Event, len(el.events))
+	copy(events, el.events)
+	return events
+}
+
+func (el *eventList) addList(events []*core.Event) {
+	el.mtx.Lock()
+	defer el.mtx.Unlock()
+	el.events = append(el.events, events...)
+}
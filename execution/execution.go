@@ -4,23 +4,43 @@
 package execution
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"sync"
 	"time"
 
+	"github.com/aungmawjj/juria-blockchain/chaincodes/governance"
 	"github.com/aungmawjj/juria-blockchain/core"
 	"github.com/aungmawjj/juria-blockchain/execution/bincc"
+	"github.com/aungmawjj/juria-blockchain/execution/wasmcc"
+	"github.com/aungmawjj/juria-blockchain/logger"
 )
 
 type Config struct {
 	BinccDir        string
+	WasmccDir       string
 	TxExecTimeout   time.Duration
 	ConcurrentLimit int
+
+	// limit of running processes per binary chaincode
+	BinccPoolSize int
+
+	// genesis validator public keys for governance chaincode
+	GenesisValidators [][]byte
+
+	// optional bls keys of genesis validators
+	GenesisBLSKeys []*governance.BLSKey
+
+	// optional voting powers of genesis validators
+	GenesisPowers []uint64
 }
 
 var DefaultConfig = Config{
 	TxExecTimeout:   10 * time.Second,
 	ConcurrentLimit: 20,
+	BinccPoolSize:   bincc.DefaultPoolSize,
 }
 
 type Execution struct {
@@ -28,22 +48,38 @@ type Execution struct {
 	config     Config
 
 	codeRegistry *codeRegistry
+
+	// bls keys with verified proof of possession, to avoid verifying on each epochs load
+	blsKeys    map[string]*core.BLSPublicKey
+	mtxBLSKeys sync.Mutex
 }
 
 type StateStore interface {
-	VerifyState(key []byte) []byte
+	VerifyState(key []byte) ([]byte, error)
 	GetState(key []byte) []byte
+	GetStateAt(key []byte, height uint64) ([]byte, error)
+	RangeState(start, end []byte, fn func(key, value []byte) bool)
+	GetNextNonce(sender *core.PublicKey) int64
 }
 
 func New(stateStore StateStore, config Config) *Execution {
 	exec := &Execution{
 		stateStore: stateStore,
 		config:     config,
+		blsKeys:    make(map[string]*core.BLSPublicKey),
 	}
 	exec.codeRegistry = newCodeRegistry()
 	exec.codeRegistry.registerDriver(DriverTypeNative, newNativeCodeDriver())
 	exec.codeRegistry.registerDriver(DriverTypeBincc,
-		bincc.NewCodeDriver(exec.config.BinccDir, exec.config.TxExecTimeout))
+		bincc.NewCodeDriver(exec.config.BinccDir, exec.config.TxExecTimeout).
+			SetPoolSize(exec.config.BinccPoolSize))
+	exec.codeRegistry.registerDriver(DriverTypeWasm,
+		wasmcc.NewCodeDriver(exec.config.WasmccDir, exec.config.TxExecTimeout))
+	exec.codeRegistry.registerSystemCode(GovernanceCodeAddr, &governance.Governance{
+		Genesis:        exec.config.GenesisValidators,
+		GenesisBLSKeys: exec.config.GenesisBLSKeys,
+		GenesisPowers:  exec.config.GenesisPowers,
+	})
 	return exec
 }
 
@@ -64,6 +100,9 @@ func (exec *Execution) Execute(blk *core.Block, txs []*core.Transaction) (
 type QueryData struct {
 	CodeAddr []byte
 	Input    []byte
+
+	// optional block height to query the state at, latest state is used if nil
+	Height *uint64 `json:",omitempty"`
 }
 
 func (exec *Execution) Query(query *QueryData) (val []byte, err error) {
@@ -72,18 +111,179 @@ func (exec *Execution) Query(query *QueryData) (val []byte, err error) {
 			err = fmt.Errorf("%v", r)
 		}
 	}()
-	cc, err := exec.codeRegistry.getInstance(
-		query.CodeAddr, newStateVerifier(exec.stateStore, codeRegistryAddr))
+	queryStates := make([]interface{ getError() error }, 0)
+	newState := func(prefix []byte) stateGetter {
+		if query.Height != nil {
+			hs := newHistoricalState(exec.stateStore, *query.Height, prefix)
+			queryStates = append(queryStates, hs)
+			return hs
+		}
+		sv := newStateVerifier(exec.stateStore, prefix)
+		queryStates = append(queryStates, sv)
+		return sv
+	}
+	cc, err := exec.codeRegistry.getInstance(query.CodeAddr, newState(codeRegistryAddr))
+	if err == nil {
+		val, err = cc.Query(&callContextQuery{
+			input:        query.Input,
+			codeRegistry: exec.codeRegistry,
+			calls:        callStack{query.CodeAddr},
+			rootState:    newState(nil),
+			stateGetter:  newState(query.CodeAddr),
+		})
+	}
+	for _, qs := range queryStates {
+		if qsErr := qs.getError(); qsErr != nil {
+			return nil, qsErr
+		}
+	}
+	return val, err
+}
+
+// SimulationResult is the result of a tx executed on the commited state without commiting
+type SimulationResult struct {
+	Error        string              `json:"error,omitempty"`
+	Elapsed      float64             `json:"elapsed"`
+	GasUsed      uint64              `json:"gasUsed"`
+	Events       []*core.Event       `json:"events"`
+	StateChanges []*core.StateChange `json:"stateChanges"`
+}
+
+// Simulate executes the tx in the given block on a throwaway state tracker over the commited state.
+// The state changes are sorted by key with the previous values, and they are empty if the tx failed.
+func (exec *Execution) Simulate(blk *core.Block, tx *core.Transaction) *SimulationResult {
+	texe := &txExecutor{
+		codeRegistry: exec.codeRegistry,
+		timeout:      exec.config.TxExecTimeout,
+		txTrk:        newStateTracker(exec.stateStore, nil),
+		blk:          blk,
+		tx:           tx,
+	}
+	txc := texe.execute()
+	result := &SimulationResult{
+		Error:        txc.Error(),
+		Elapsed:      txc.Elapsed(),
+		GasUsed:      txc.GasUsed(),
+		Events:       txc.Events(),
+		StateChanges: make([]*core.StateChange, 0),
+	}
+	if txc.Error() == "" {
+		result.StateChanges = texe.txTrk.getStateChanges()
+		sort.Slice(result.StateChanges, func(i, j int) bool {
+			return bytes.Compare(result.StateChanges[i].Key(), result.StateChanges[j].Key()) < 0
+		})
+		for _, sc := range result.StateChanges {
+			sc.SetPrevValue(exec.stateStore.GetState(sc.Key()))
+		}
+	}
+	return result
+}
+
+// GetCodeUpgrades gives the upgrade history of the chaincode
+func (exec *Execution) GetCodeUpgrades(codeAddr []byte) (upgrades []*UpgradeRecord, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	sv := newStateVerifier(exec.stateStore, codeRegistryAddr)
+	upgrades, err = exec.codeRegistry.getUpgrades(codeAddr, sv)
+	if svErr := sv.getError(); svErr != nil {
+		return nil, svErr
+	}
+	return upgrades, err
+}
+
+// GetValidatorEpochs gives the validator sets from governance chaincode state
+func (exec *Execution) GetValidatorEpochs() ([]*core.ValidatorEpoch, error) {
+	input, _ := json.Marshal(&governance.Input{Method: "epochs"})
+	b, err := exec.Query(&QueryData{
+		CodeAddr: GovernanceCodeAddr,
+		Input:    input,
+	})
 	if err != nil {
 		return nil, err
 	}
-	return cc.Query(&callContextQuery{
-		input:       query.Input,
-		stateGetter: newStateVerifier(exec.stateStore, query.CodeAddr),
-	})
+	var epochs []*governance.Epoch
+	if err := json.Unmarshal(b, &epochs); err != nil {
+		return nil, err
+	}
+	ret := make([]*core.ValidatorEpoch, len(epochs))
+	for i, e := range epochs {
+		ret[i] = exec.newValidatorEpoch(e)
+	}
+	return ret, nil
+}
+
+// newValidatorEpoch skips the validators with invalid public keys,
+// so that a bad key recorded by the governance cannot stop loading the epochs
+func (exec *Execution) newValidatorEpoch(e *governance.Epoch) *core.ValidatorEpoch {
+	ve := &core.ValidatorEpoch{
+		StartHeight: e.StartHeight,
+		Validators:  make([]*core.PublicKey, 0, len(e.Validators)),
+	}
+	if len(e.Powers) > 0 {
+		ve.Powers = make([]uint64, 0, len(e.Validators))
+	}
+	if len(e.BLSKeys) > 0 {
+		ve.BLSKeys = make([]*core.BLSPublicKey, 0, len(e.Validators))
+	}
+	for j, v := range e.Validators {
+		pubKey, err := core.NewPublicKey(v)
+		if err != nil {
+			logger.I().Warnw("skipped invalid validator key", "start", e.StartHeight, "index", j)
+			continue
+		}
+		ve.Validators = append(ve.Validators, pubKey)
+		if ve.Powers != nil {
+			var power uint64
+			if j < len(e.Powers) {
+				power = e.Powers[j]
+			}
+			ve.Powers = append(ve.Powers, power)
+		}
+		if ve.BLSKeys != nil {
+			var blsKey *core.BLSPublicKey
+			if j < len(e.BLSKeys) {
+				blsKey = exec.verifyBLSKey(e.BLSKeys[j])
+			}
+			ve.BLSKeys = append(ve.BLSKeys, blsKey)
+		}
+	}
+	return ve
+}
+
+// verifyBLSKey gives nil if the key or its proof of possession is invalid
+func (exec *Execution) verifyBLSKey(key *governance.BLSKey) *core.BLSPublicKey {
+	if key == nil {
+		return nil
+	}
+	exec.mtxBLSKeys.Lock()
+	defer exec.mtxBLSKeys.Unlock()
+
+	id := string(key.Key) + string(key.Proof)
+	if pubKey, found := exec.blsKeys[id]; found {
+		return pubKey
+	}
+	pubKey, err := core.NewBLSPublicKey(key.Key)
+	if err != nil || !pubKey.VerifyPossession(key.Proof) {
+		pubKey = nil
+	}
+	exec.blsKeys[id] = pubKey
+	return pubKey
 }
 
 func (exec *Execution) VerifyTx(tx *core.Transaction) error {
+	if bytes.Equal(tx.CodeAddr(), UpgradeCodeAddr) {
+		input := new(UpgradeInput)
+		if err := json.Unmarshal(tx.Input(), input); err != nil {
+			return err
+		}
+		return exec.codeRegistry.install(&DeploymentInput{
+			CodeInfo:    input.CodeInfo,
+			InstallData: input.InstallData,
+		})
+	}
 	if len(tx.CodeAddr()) != 0 { // invoke tx
 		return nil
 	}
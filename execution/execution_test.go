@@ -5,9 +5,11 @@ package execution
 
 import (
 	"encoding/json"
+	"errors"
 	"testing"
 	"time"
 
+	"github.com/aungmawjj/juria-blockchain/chaincodes/governance"
 	"github.com/aungmawjj/juria-blockchain/core"
 	"github.com/stretchr/testify/assert"
 )
@@ -94,3 +96,216 @@ func TestExecution(t *testing.T) {
 	// assert.NoError(err)
 	// assert.Equal(priv.PublicKey().Bytes(), minter)
 }
+
+func TestExecution_StaleNonces(t *testing.T) {
+	assert := assert.New(t)
+
+	priv := core.GenerateKey(nil)
+	state := newMapStateStore()
+	state.nonces[string(priv.PublicKey().Bytes())] = 2
+	execution := New(state, DefaultConfig)
+
+	nonces := []int64{1, 3, 3, 2, 4}
+	txs := make([]*core.Transaction, len(nonces))
+	for i, nonce := range nonces {
+		txs[i] = core.NewTransaction().
+			SetNonce(nonce).
+			SetCodeAddr(GovernanceCodeAddr).
+			SetInput([]byte(`{"method":"epochs"}`)).
+			Sign(priv)
+	}
+	blk := core.NewBlock().SetHeight(10).Sign(priv)
+	_, txcs := execution.Execute(blk, txs)
+
+	stale := []bool{true, false, true, true, false}
+	for i, txc := range txcs {
+		assert.Equal(txs[i].Hash(), txc.Hash())
+		if stale[i] {
+			assert.Equal(ErrStaleNonce.Error(), txc.Error(), "tx %d", i)
+		} else {
+			assert.NotEqual(ErrStaleNonce.Error(), txc.Error(), "tx %d", i)
+		}
+	}
+}
+
+func TestExecution_GetValidatorEpochs(t *testing.T) {
+	assert := assert.New(t)
+
+	priv0 := core.GenerateKey(nil)
+	priv1 := core.GenerateKey(nil)
+	state := newMapStateStore()
+	config := DefaultConfig
+	config.TxExecTimeout = 1 * time.Second
+	config.GenesisValidators = [][]byte{priv0.PublicKey().Bytes()}
+	config.GenesisPowers = []uint64{2}
+	execution := New(state, config)
+
+	epochs, err := execution.GetValidatorEpochs()
+	assert.NoError(err)
+	if assert.Equal(1, len(epochs)) {
+		assert.Equal([]*core.PublicKey{priv0.PublicKey()}, epochs[0].Validators)
+		assert.Equal([]uint64{2}, epochs[0].Powers)
+	}
+
+	input, _ := json.Marshal(&governance.Input{
+		Method:    "propose",
+		Action:    governance.ActionAdd,
+		Validator: priv1.PublicKey().Bytes(),
+		Power:     3,
+	})
+	tx := core.NewTransaction().
+		SetNonce(time.Now().UnixNano()).
+		SetCodeAddr(GovernanceCodeAddr).
+		SetInput(input).
+		Sign(priv0)
+	blk := core.NewBlock().SetHeight(10).Sign(priv0)
+	bcm, txcs := execution.Execute(blk, []*core.Transaction{tx})
+	assert.Equal("", txcs[0].Error())
+	for _, sc := range bcm.StateChanges() {
+		state.SetState(sc.Key(), sc.Value())
+	}
+
+	epochs, err = execution.GetValidatorEpochs()
+	assert.NoError(err)
+	if assert.Equal(2, len(epochs)) {
+		assert.EqualValues(10+governance.EpochDelay, epochs[1].StartHeight)
+		assert.Equal([]*core.PublicKey{priv0.PublicKey(), priv1.PublicKey()}, epochs[1].Validators)
+		assert.Equal([]uint64{2, 3}, epochs[1].Powers)
+	}
+}
+
+func TestExecution_GetValidatorEpochs_InvalidKey(t *testing.T) {
+	assert := assert.New(t)
+
+	priv0 := core.GenerateKey(nil)
+	config := DefaultConfig
+	config.TxExecTimeout = 1 * time.Second
+	config.GenesisValidators = [][]byte{{1}, priv0.PublicKey().Bytes()}
+	config.GenesisPowers = []uint64{5, 2}
+	execution := New(newMapStateStore(), config)
+
+	epochs, err := execution.GetValidatorEpochs()
+	assert.NoError(err, "invalid key should not fail the epochs")
+	if assert.Equal(1, len(epochs)) {
+		assert.Equal([]*core.PublicKey{priv0.PublicKey()}, epochs[0].Validators)
+		assert.Equal([]uint64{2}, epochs[0].Powers)
+	}
+}
+
+// unverifiedStateStore fails to verify all states
+type unverifiedStateStore struct {
+	*mapStateStore
+}
+
+func (store *unverifiedStateStore) VerifyState(key []byte) ([]byte, error) {
+	return nil, errors.New("state is not verified")
+}
+
+func TestExecution_Query_UnverifiedState(t *testing.T) {
+	assert := assert.New(t)
+
+	priv0 := core.GenerateKey(nil)
+	config := DefaultConfig
+	config.GenesisValidators = [][]byte{priv0.PublicKey().Bytes()}
+	execution := New(&unverifiedStateStore{newMapStateStore()}, config)
+
+	_, err := execution.GetValidatorEpochs()
+	assert.EqualError(err, "state is not verified")
+
+	_, err = execution.GetCodeUpgrades(GovernanceCodeAddr)
+	assert.EqualError(err, "state is not verified")
+}
+
+func TestExecution_GetValidatorEpochsBLS(t *testing.T) {
+	assert := assert.New(t)
+
+	priv0 := core.GenerateKey(nil)
+	priv1 := core.GenerateKey(nil)
+	bls0 := core.GenerateBLSKey(nil)
+	bls1 := core.GenerateBLSKey(nil)
+	state := newMapStateStore()
+	config := DefaultConfig
+	config.TxExecTimeout = 1 * time.Second
+	config.GenesisValidators = [][]byte{priv0.PublicKey().Bytes()}
+	config.GenesisBLSKeys = []*governance.BLSKey{{
+		Key:   bls0.PublicKey().Bytes(),
+		Proof: bls0.ProvePossession(),
+	}}
+	execution := New(state, config)
+
+	epochs, err := execution.GetValidatorEpochs()
+	assert.NoError(err)
+	if assert.Equal(1, len(epochs)) {
+		assert.Equal([]*core.BLSPublicKey{bls0.PublicKey()}, epochs[0].BLSKeys)
+	}
+
+	execTx := func(priv *core.PrivateKey, input *governance.Input, height uint64) {
+		b, _ := json.Marshal(input)
+		tx := core.NewTransaction().
+			SetNonce(time.Now().UnixNano()).
+			SetCodeAddr(GovernanceCodeAddr).
+			SetInput(b).
+			Sign(priv)
+		blk := core.NewBlock().SetHeight(height).Sign(priv0)
+		bcm, txcs := execution.Execute(blk, []*core.Transaction{tx})
+		assert.Equal("", txcs[0].Error())
+		for _, sc := range bcm.StateChanges() {
+			state.SetState(sc.Key(), sc.Value())
+		}
+	}
+	// proof of another key
+	execTx(priv1, &governance.Input{
+		Method: "registerBLSKey",
+		BLSKey: &governance.BLSKey{Key: bls1.PublicKey().Bytes(), Proof: bls0.ProvePossession()},
+	}, 10)
+	execTx(priv0, &governance.Input{
+		Method:    "propose",
+		Action:    governance.ActionAdd,
+		Validator: priv1.PublicKey().Bytes(),
+	}, 11)
+
+	epochs, err = execution.GetValidatorEpochs()
+	assert.NoError(err)
+	if assert.Equal(2, len(epochs)) {
+		assert.Equal([]*core.BLSPublicKey{bls0.PublicKey(), nil}, epochs[1].BLSKeys, "invalid proof")
+	}
+}
+
+func TestExecution_Simulate(t *testing.T) {
+	assert := assert.New(t)
+
+	priv0 := core.GenerateKey(nil)
+	priv1 := core.GenerateKey(nil)
+	state := newMapStateStore()
+	config := DefaultConfig
+	config.TxExecTimeout = 1 * time.Second
+	config.GenesisValidators = [][]byte{priv0.PublicKey().Bytes()}
+	execution := New(state, config)
+
+	input, _ := json.Marshal(&governance.Input{
+		Method:    "propose",
+		Action:    governance.ActionAdd,
+		Validator: priv1.PublicKey().Bytes(),
+	})
+	tx := core.NewTransaction().
+		SetCodeAddr(GovernanceCodeAddr).
+		SetInput(input).
+		Sign(priv0)
+	blk := core.NewBlock().SetHeight(10)
+
+	result := execution.Simulate(blk, tx)
+	assert.Equal("", result.Error)
+	assert.NotZero(result.GasUsed)
+	assert.Equal(1, len(result.Events))
+	assert.NotEmpty(result.StateChanges)
+	assert.Empty(state.stateMap, "state is not changed")
+
+	// not validator
+	tx = core.NewTransaction().
+		SetCodeAddr(GovernanceCodeAddr).
+		SetInput(input).
+		Sign(priv1)
+	result = execution.Simulate(blk, tx)
+	assert.NotEqual("", result.Error)
+	assert.Empty(result.StateChanges)
+}
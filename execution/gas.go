@@ -0,0 +1,60 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package execution
+
+import (
+	"errors"
+	"sync"
+)
+
+// gas costs
+const (
+	GasTxBase       uint64 = 1000 // charged for every tx
+	GasPerInputByte uint64 = 2
+	GasGetState     uint64 = 100
+	GasSetState     uint64 = 500
+	GasDeleteState  uint64 = 200
+	GasRangeState   uint64 = 100 // charged for each range call
+	GasRangeEntry   uint64 = 20  // charged for each state given by range call
+	GasPerStateByte uint64 = 1   // charged for key and value bytes of state calls
+	GasEmitEvent    uint64 = 200
+	GasCall         uint64 = 700 // charged for each cross chaincode call
+	GasPerEventByte uint64 = 1   // charged for name and data bytes of events
+)
+
+var ErrOutOfGas = errors.New("out of gas")
+
+// gasMeter counts gas consumed by a tx execution.
+// it panics with ErrOutOfGas when consumed gas exceeds the limit,
+// tx executor recovers it as tx error
+type gasMeter struct {
+	limit uint64
+	used  uint64
+	mtx   sync.Mutex
+}
+
+func newGasMeter(limit uint64) *gasMeter {
+	return &gasMeter{limit: limit}
+}
+
+func (gm *gasMeter) ConsumeGas(amount uint64) {
+	gm.mtx.Lock()
+	defer gm.mtx.Unlock()
+
+	if gm.limit-gm.used < amount {
+		gm.used = gm.limit
+		panic(ErrOutOfGas)
+	}
+	gm.used += amount
+}
+
+func (gm *gasMeter) getUsed() uint64 {
+	gm.mtx.Lock()
+	defer gm.mtx.Unlock()
+	return gm.used
+}
+
+func gasForState(key, value []byte) uint64 {
+	return uint64(len(key)+len(value)) * GasPerStateByte
+}
@@ -0,0 +1,22 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package execution
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGasMeter(t *testing.T) {
+	assert := assert.New(t)
+
+	gm := newGasMeter(1000)
+	gm.ConsumeGas(400)
+	gm.ConsumeGas(600)
+	assert.EqualValues(1000, gm.getUsed())
+
+	assert.PanicsWithValue(ErrOutOfGas, func() { gm.ConsumeGas(1) })
+	assert.EqualValues(1000, gm.getUsed(), "used gas should not exceed limit")
+}
@@ -0,0 +1,25 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package execution
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	metricTxExec = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "juria",
+		Subsystem: "execution",
+		Name:      "tx_exec_seconds",
+		Help:      "Time to execute a tx.",
+	})
+
+	metricTxErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "juria",
+		Subsystem: "execution",
+		Name:      "tx_errors_total",
+		Help:      "Number of txs failed to execute.",
+	})
+)
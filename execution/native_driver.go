@@ -13,6 +13,9 @@ import (
 
 var (
 	NativeCodeIDJuriaCoin = bytes.Repeat([]byte{1}, 32)
+
+	// GovernanceCodeAddr is the fixed address of the governance system chaincode
+	GovernanceCodeAddr = append(bytes.Repeat([]byte{0}, 31), 1)
 )
 
 type nativeCodeDriver struct{}
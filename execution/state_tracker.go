@@ -5,6 +5,7 @@ package execution
 
 import (
 	"bytes"
+	"sort"
 	"sync"
 
 	"github.com/aungmawjj/juria-blockchain/core"
@@ -12,6 +13,26 @@ import (
 
 type stateGetter interface {
 	GetState(key []byte) []byte
+
+	// RangeState calls fn for the states with keys in [start, end) in key order until fn returns false.
+	// empty end means no upper bound
+	RangeState(start, end []byte, fn func(key, value []byte) bool)
+}
+
+// keyRange is a dependency of RangeState calls, empty end means no upper bound
+type keyRange struct {
+	start []byte
+	end   []byte
+}
+
+func (kr keyRange) contains(key []byte) bool {
+	return bytes.Compare(key, kr.start) >= 0 && (len(kr.end) == 0 || bytes.Compare(key, kr.end) < 0)
+}
+
+type stateEntry struct {
+	key     []byte
+	value   []byte
+	deleted bool
 }
 
 // stateTracker tracks state changes in key order
@@ -23,7 +44,9 @@ type stateTracker struct {
 
 	trackDep     bool
 	dependencies map[string]struct{} // getState calls
-	changes      map[string][]byte   // setState calls
+	rangeDeps    []keyRange          // rangeState calls
+	changes      map[string][]byte   // setState and deleteState calls
+	deleted      map[string]struct{} // deleteState calls
 
 	mtxChg sync.RWMutex
 	mtxDep sync.RWMutex
@@ -36,6 +59,7 @@ func newStateTracker(state stateGetter, keyPrefix []byte) *stateTracker {
 
 		dependencies: make(map[string]struct{}),
 		changes:      make(map[string][]byte),
+		deleted:      make(map[string]struct{}),
 	}
 }
 
@@ -51,6 +75,69 @@ func (trk *stateTracker) SetState(key, value []byte) {
 	trk.setState(key, value)
 }
 
+func (trk *stateTracker) DeleteState(key []byte) {
+	trk.mtxChg.Lock()
+	defer trk.mtxChg.Unlock()
+	trk.deleteState(key)
+}
+
+// RangeState merges the changes of the tracker with the states of the base state getter.
+// The lock is not held while calling fn, so fn can change the states.
+func (trk *stateTracker) RangeState(start, end []byte, fn func(key, value []byte) bool) {
+	start, end = prefixRange(trk.keyPrefix, start, end)
+	changes := trk.getChangesInRange(keyRange{start, end})
+
+	var last []byte // last key given to fn
+	stopped := false
+	emit := func(key, value []byte) bool {
+		last = key
+		stopped = !fn(key[len(trk.keyPrefix):], value)
+		return !stopped
+	}
+	i := 0
+	trk.baseState.RangeState(start, end, func(key, value []byte) bool {
+		for ; i < len(changes) && bytes.Compare(changes[i].key, key) < 0; i++ {
+			if !changes[i].deleted && !emit(changes[i].key, changes[i].value) {
+				return false
+			}
+		}
+		if i < len(changes) && bytes.Equal(changes[i].key, key) {
+			i++
+			if changes[i-1].deleted {
+				return true
+			}
+			value = changes[i-1].value
+		}
+		return emit(key, value)
+	})
+	for ; !stopped && i < len(changes); i++ {
+		if !changes[i].deleted {
+			emit(changes[i].key, changes[i].value)
+		}
+	}
+	if stopped { // keys after the last one are not read
+		end = concatBytes(last, []byte{0})
+	}
+	trk.setRangeDependency(keyRange{start, end})
+}
+
+func (trk *stateTracker) getChangesInRange(kr keyRange) []*stateEntry {
+	trk.mtxChg.RLock()
+	defer trk.mtxChg.RUnlock()
+
+	entries := make([]*stateEntry, 0)
+	for key, value := range trk.changes {
+		if kr.contains([]byte(key)) {
+			_, deleted := trk.deleted[key]
+			entries = append(entries, &stateEntry{[]byte(key), value, deleted})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].key, entries[j].key) < 0
+	})
+	return entries
+}
+
 // spawn creates a new tracker with current tracker as base StateGetter
 func (trk *stateTracker) spawn(keyPrefix []byte) *stateTracker {
 	child := newStateTracker(trk, keyPrefix)
@@ -73,6 +160,13 @@ func (trk *stateTracker) hasDependencyChanges(child *stateTracker) bool {
 			return true
 		}
 	}
+	for _, kr := range child.rangeDeps { // ranges of full keys
+		for key := range trk.changes {
+			if kr.contains([]byte(key)) {
+				return true
+			}
+		}
+	}
 	return false
 }
 
@@ -84,7 +178,11 @@ func (trk *stateTracker) merge(child *stateTracker) {
 	defer child.mtxChg.RUnlock()
 
 	for key, value := range child.changes {
-		trk.setState([]byte(key), value)
+		if _, deleted := child.deleted[key]; deleted {
+			trk.deleteState([]byte(key))
+		} else {
+			trk.setState([]byte(key), value)
+		}
 	}
 }
 
@@ -94,7 +192,9 @@ func (trk *stateTracker) getStateChanges() []*core.StateChange {
 
 	scList := make([]*core.StateChange, 0, len(trk.changes))
 	for key, value := range trk.changes {
-		scList = append(scList, core.NewStateChange().SetKey([]byte(key)).SetValue(value))
+		_, deleted := trk.deleted[key]
+		scList = append(scList, core.NewStateChange().
+			SetKey([]byte(key)).SetValue(value).SetDeleted(deleted))
 	}
 	return scList
 }
@@ -117,10 +217,27 @@ func (trk *stateTracker) setDependency(key []byte) {
 	trk.dependencies[string(key)] = struct{}{}
 }
 
+func (trk *stateTracker) setRangeDependency(kr keyRange) {
+	if !trk.trackDep {
+		return
+	}
+	trk.mtxDep.Lock()
+	defer trk.mtxDep.Unlock()
+	trk.rangeDeps = append(trk.rangeDeps, kr)
+}
+
 func (trk *stateTracker) setState(key, value []byte) {
 	key = concatBytes(trk.keyPrefix, key)
 	keyStr := string(key)
 	trk.changes[keyStr] = value
+	delete(trk.deleted, keyStr)
+}
+
+func (trk *stateTracker) deleteState(key []byte) {
+	key = concatBytes(trk.keyPrefix, key)
+	keyStr := string(key)
+	trk.changes[keyStr] = nil
+	trk.deleted[keyStr] = struct{}{}
 }
 
 func concatBytes(srcs ...[]byte) []byte {
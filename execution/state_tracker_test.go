@@ -6,31 +6,56 @@ package execution
 import (
 	"testing"
 
+	"github.com/aungmawjj/juria-blockchain/core"
+	"github.com/aungmawjj/juria-blockchain/execution/chaincode"
 	"github.com/stretchr/testify/assert"
 )
 
 type mapStateStore struct {
 	stateMap map[string][]byte
+	nonces   map[string]int64
 }
 
 func newMapStateStore() *mapStateStore {
 	return &mapStateStore{
 		stateMap: make(map[string][]byte),
+		nonces:   make(map[string]int64),
 	}
 }
 
-func (store *mapStateStore) VerifyState(key []byte) []byte {
-	return store.stateMap[string(key)]
+func (store *mapStateStore) VerifyState(key []byte) ([]byte, error) {
+	return store.stateMap[string(key)], nil
 }
 
 func (store *mapStateStore) GetState(key []byte) []byte {
 	return store.stateMap[string(key)]
 }
 
+func (store *mapStateStore) GetStateAt(key []byte, height uint64) ([]byte, error) {
+	return store.stateMap[string(key)], nil
+}
+
 func (store *mapStateStore) SetState(key, value []byte) {
 	store.stateMap[string(key)] = value
 }
 
+func (store *mapStateStore) GetNextNonce(sender *core.PublicKey) int64 {
+	return store.nonces[string(sender.Bytes())]
+}
+
+func (store *mapStateStore) RangeState(start, end []byte, fn func(key, value []byte) bool) {
+	(&chaincode.MockState{StateMap: store.stateMap}).RangeState(start, end, fn)
+}
+
+func collectRange(getter stateGetter, start, end []byte, limit int) [][]byte {
+	keys := make([][]byte, 0)
+	getter.RangeState(start, end, func(key, value []byte) bool {
+		keys = append(keys, key)
+		return len(keys) < limit
+	})
+	return keys
+}
+
 func TestStateTracker_GetState(t *testing.T) {
 	assert := assert.New(t)
 
@@ -125,3 +150,102 @@ func TestStateTracker_WithPrefix(t *testing.T) {
 	assert.Equal([]byte{10}, trk.GetState([]byte{1, 1}))
 	assert.Equal([]byte{20}, trk.GetState([]byte{1, 2}))
 }
+
+func TestStateTracker_DeleteState(t *testing.T) {
+	assert := assert.New(t)
+
+	ms := newMapStateStore()
+	ms.SetState([]byte{1}, []byte{10})
+	trk := newStateTracker(ms, nil)
+
+	trkChild := trk.spawn(nil)
+	trkChild.DeleteState([]byte{1})
+	assert.Nil(trkChild.GetState([]byte{1}))
+	assert.Equal([]byte{10}, trk.GetState([]byte{1}))
+
+	trk.merge(trkChild)
+	assert.Nil(trk.GetState([]byte{1}))
+
+	scList := trk.getStateChanges()
+	if assert.Equal(1, len(scList)) {
+		assert.True(scList[0].Deleted())
+	}
+
+	trk.SetState([]byte{1}, []byte{20})
+	scList = trk.getStateChanges()
+	if assert.Equal(1, len(scList)) {
+		assert.False(scList[0].Deleted(), "set after delete")
+		assert.Equal([]byte{20}, scList[0].Value())
+	}
+}
+
+func TestStateTracker_RangeState(t *testing.T) {
+	assert := assert.New(t)
+
+	ms := newMapStateStore()
+	ms.SetState([]byte{1, 1}, []byte{11})
+	ms.SetState([]byte{1, 3}, []byte{13})
+	ms.SetState([]byte{1, 5}, []byte{15})
+	ms.SetState([]byte{2, 1}, []byte{21})
+	trk := newStateTracker(ms, nil)
+	trk.SetState([]byte{1, 2}, []byte{12})
+	trk.DeleteState([]byte{1, 3})
+
+	trkChild := trk.spawn([]byte{1})
+	trkChild.SetState([]byte{4}, []byte{14})
+	trkChild.SetState([]byte{5}, []byte{50})
+
+	values := make([][]byte, 0)
+	trkChild.RangeState(nil, nil, func(key, value []byte) bool {
+		values = append(values, value)
+		return true
+	})
+	assert.Equal([][]byte{{11}, {12}, {14}, {50}}, values, "merged changes in key order")
+
+	assert.Equal([][]byte{{2}, {4}}, collectRange(trkChild, []byte{2}, []byte{5}, 10))
+	assert.Equal([][]byte{{1}, {2}}, collectRange(trkChild, nil, nil, 2), "stop iteration")
+	assert.Equal([][]byte{{1, 1}, {1, 2}, {1, 5}, {2, 1}}, collectRange(trk, nil, nil, 10))
+}
+
+func TestStateTracker_RangeDependency(t *testing.T) {
+	assert := assert.New(t)
+
+	ms := newMapStateStore()
+	ms.SetState([]byte{1}, []byte{10})
+	ms.SetState([]byte{5}, []byte{50})
+	trk := newStateTracker(ms, nil)
+
+	trkChild := trk.spawn(nil)
+	collectRange(trkChild, []byte{1}, []byte{4}, 10)
+
+	trk.SetState([]byte{4}, []byte{40})
+	assert.False(trk.hasDependencyChanges(trkChild), "changed key out of range")
+
+	trk.SetState([]byte{3}, []byte{30})
+	assert.True(trk.hasDependencyChanges(trkChild), "new key in range")
+
+	trk = newStateTracker(ms, nil)
+	trkChild = trk.spawn(nil)
+	collectRange(trkChild, nil, nil, 1)
+
+	trk.SetState([]byte{3}, []byte{30})
+	assert.False(trk.hasDependencyChanges(trkChild), "key after stopped iteration")
+
+	trk.DeleteState([]byte{1})
+	assert.True(trk.hasDependencyChanges(trkChild), "deleted key in range")
+}
+
+func TestHistoricalState_RangeState(t *testing.T) {
+	assert := assert.New(t)
+
+	ms := newMapStateStore()
+	ms.SetState([]byte{1}, []byte{200})
+	hs := newHistoricalState(ms, 1, nil)
+	trk := newStateTracker(hs, nil)
+
+	assert.Equal([]byte{200}, trk.GetState([]byte{1}))
+	assert.NoError(hs.getError())
+
+	assert.Empty(collectRange(trk, nil, nil, 10))
+	assert.ErrorIs(hs.getError(), ErrRangeAtHeight)
+}
@@ -3,12 +3,24 @@
 
 package execution
 
+import (
+	"errors"
+	"sync"
+
+	"github.com/aungmawjj/juria-blockchain/execution/chaincode"
+)
+
+var ErrRangeAtHeight = errors.New("state range is not supported at block height")
+
 // stateVerifier is used for state query calls
 // it calls the VerifyState of state store instead of GetState
-// to verify the state value with the merkle root
+// to verify the state value with the merkle root.
+// Unverified states give empty results, and the error is returned by the query with getError.
 type stateVerifier struct {
 	store     StateStore
 	keyPrefix []byte
+
+	stateError
 }
 
 func newStateVerifier(store StateStore, prefix []byte) *stateVerifier {
@@ -19,6 +31,85 @@ func newStateVerifier(store StateStore, prefix []byte) *stateVerifier {
 }
 
 func (sv *stateVerifier) GetState(key []byte) []byte {
-	key = concatBytes(sv.keyPrefix, key)
-	return sv.store.VerifyState(key)
+	return sv.verifyState(concatBytes(sv.keyPrefix, key))
+}
+
+// RangeState verifies the values of the states in range
+func (sv *stateVerifier) RangeState(start, end []byte, fn func(key, value []byte) bool) {
+	start, end = prefixRange(sv.keyPrefix, start, end)
+	sv.store.RangeState(start, end, func(key, value []byte) bool {
+		return fn(key[len(sv.keyPrefix):], sv.verifyState(key))
+	})
+}
+
+func (sv *stateVerifier) verifyState(key []byte) []byte {
+	value, err := sv.store.VerifyState(key)
+	if err != nil {
+		sv.setError(err)
+		return nil
+	}
+	return value
+}
+
+// prefixRange gives the range of full keys, empty end is limited to the keys with prefix
+func prefixRange(prefix, start, end []byte) ([]byte, []byte) {
+	start = concatBytes(prefix, start)
+	if len(end) > 0 {
+		return start, concatBytes(prefix, end)
+	}
+	return start, chaincode.PrefixEnd(prefix)
+}
+
+// historicalState is used for state query calls at a block height
+// it gives the state values after commiting the block at the height.
+// Failed calls give empty results, and the error is returned by the query with getError.
+type historicalState struct {
+	store     StateStore
+	height    uint64
+	keyPrefix []byte
+
+	stateError
+}
+
+func newHistoricalState(store StateStore, height uint64, prefix []byte) *historicalState {
+	return &historicalState{
+		store:     store,
+		height:    height,
+		keyPrefix: prefix,
+	}
+}
+
+func (hs *historicalState) GetState(key []byte) []byte {
+	key = concatBytes(hs.keyPrefix, key)
+	value, err := hs.store.GetStateAt(key, hs.height)
+	if err != nil {
+		hs.setError(err)
+		return nil
+	}
+	return value
+}
+
+func (hs *historicalState) RangeState(start, end []byte, fn func(key, value []byte) bool) {
+	hs.setError(ErrRangeAtHeight)
+}
+
+// stateError keeps the first error of the state calls of a query
+type stateError struct {
+	err    error
+	mtxErr sync.Mutex
+}
+
+// setError keeps the first error, state calls of chaincodes may run in other goroutines
+func (se *stateError) setError(err error) {
+	se.mtxErr.Lock()
+	defer se.mtxErr.Unlock()
+	if se.err == nil {
+		se.err = err
+	}
+}
+
+func (se *stateError) getError() error {
+	se.mtxErr.Lock()
+	defer se.mtxErr.Unlock()
+	return se.err
 }
@@ -4,6 +4,7 @@
 package execution
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -18,6 +19,9 @@ type DeploymentInput struct {
 	CodeInfo    CodeInfo `json:"codeInfo"`
 	InstallData []byte   `json:"installData"`
 	InitInput   []byte   `json:"initInput"`
+
+	// optional public key allowed to upgrade the chaincode, it cannot be upgraded if empty
+	Admin []byte `json:"admin,omitempty"`
 }
 
 type txExecutor struct {
@@ -26,8 +30,10 @@ type txExecutor struct {
 	timeout time.Duration
 	txTrk   *stateTracker
 
-	blk *core.Block
-	tx  *core.Transaction
+	blk    *core.Block
+	tx     *core.Transaction
+	gas    *gasMeter
+	events *eventList
 }
 
 func (txe *txExecutor) execute() *core.TxCommit {
@@ -37,12 +43,17 @@ func (txe *txExecutor) execute() *core.TxCommit {
 		SetBlockHash(txe.blk.Hash()).
 		SetBlockHeight(txe.blk.Height())
 
+	txe.gas = newGasMeter(txe.tx.GasLimit())
+	txe.events = new(eventList)
 	err := txe.executeWithTimeout()
 	if err != nil {
 		logger.I().Warnf("execute tx error %+v", err)
 		txc.SetError(err.Error())
+	} else {
+		txc.SetEvents(txe.events.list())
 	}
 	txc.SetElapsed(time.Since(start).Seconds())
+	txc.SetGasUsed(txe.gas.getUsed())
 	return txc
 }
 
@@ -67,9 +78,13 @@ func (txe *txExecutor) executeChaincode() (err error) {
 			err = fmt.Errorf("%+v", r)
 		}
 	}()
+	txe.gas.ConsumeGas(GasTxBase + uint64(len(txe.tx.Input()))*GasPerInputByte)
 	if len(txe.tx.CodeAddr()) == 0 {
 		return txe.executeDeployment()
 	}
+	if bytes.Equal(txe.tx.CodeAddr(), UpgradeCodeAddr) {
+		return txe.executeUpgrade()
+	}
 	return txe.executeInvoke()
 }
 
@@ -80,14 +95,22 @@ func (txe *txExecutor) executeDeployment() error {
 		return err
 	}
 
+	if len(input.Admin) > 0 {
+		if _, err := core.NewPublicKey(input.Admin); err != nil {
+			return fmt.Errorf("invalid admin, %w", err)
+		}
+	}
 	regTrk := txe.txTrk.spawn(codeRegistryAddr)
 	cc, err := txe.codeRegistry.deploy(txe.tx.Hash(), input, regTrk)
 	if err != nil {
 		return err
 	}
+	if len(input.Admin) > 0 {
+		txe.codeRegistry.setCodeAdmin(txe.tx.Hash(), input.Admin, regTrk)
+	}
 
 	initTrk := txe.txTrk.spawn(txe.tx.Hash())
-	err = cc.Init(txe.makeCallContext(initTrk, input.InitInput))
+	err = cc.Init(txe.makeCallContext(txe.tx.Hash(), initTrk, input.InitInput))
 	if err != nil {
 		return err
 	}
@@ -96,6 +119,34 @@ func (txe *txExecutor) executeDeployment() error {
 	return nil
 }
 
+func (txe *txExecutor) executeUpgrade() error {
+	input := new(UpgradeInput)
+	err := json.Unmarshal(txe.tx.Input(), input)
+	if err != nil {
+		return err
+	}
+	regTrk := txe.txTrk.spawn(codeRegistryAddr)
+	cc, err := txe.codeRegistry.upgrade(txe.tx.Sender().Bytes(), input, &UpgradeRecord{
+		BlockHeight: txe.blk.Height(),
+		TxHash:      txe.tx.Hash(),
+	}, regTrk)
+	if err != nil {
+		return err
+	}
+	// merged before migration so that the new code is used for nested calls,
+	// state changes of failed tx are discarded
+	txe.txTrk.merge(regTrk)
+	if len(input.MigrateInput) > 0 {
+		migrateTrk := txe.txTrk.spawn(input.CodeAddr)
+		err = cc.Invoke(txe.makeCallContext(input.CodeAddr, migrateTrk, input.MigrateInput))
+		if err != nil {
+			return fmt.Errorf("migration failed, %w", err)
+		}
+		txe.txTrk.merge(migrateTrk)
+	}
+	return nil
+}
+
 func (txe *txExecutor) executeInvoke() error {
 	cc, err := txe.codeRegistry.getInstance(
 		txe.tx.CodeAddr(), txe.txTrk.spawn(codeRegistryAddr))
@@ -103,7 +154,7 @@ func (txe *txExecutor) executeInvoke() error {
 		return err
 	}
 	invokeTrk := txe.txTrk.spawn(txe.tx.CodeAddr())
-	err = cc.Invoke(txe.makeCallContext(invokeTrk, txe.tx.Input()))
+	err = cc.Invoke(txe.makeCallContext(txe.tx.CodeAddr(), invokeTrk, txe.tx.Input()))
 	if err != nil {
 		return err
 	}
@@ -111,11 +162,19 @@ func (txe *txExecutor) executeInvoke() error {
 	return nil
 }
 
-func (txe *txExecutor) makeCallContext(st *stateTracker, input []byte) chaincode.CallContext {
+func (txe *txExecutor) makeCallContext(
+	codeAddr []byte, st *stateTracker, input []byte,
+) chaincode.CallContext {
 	return &callContextTx{
 		blk:          txe.blk,
 		tx:           txe.tx,
 		input:        input,
+		codeAddr:     codeAddr,
+		gas:          txe.gas,
+		events:       txe.events,
+		codeRegistry: txe.codeRegistry,
+		calls:        callStack{codeAddr},
+		frameTrk:     txe.txTrk,
 		stateTracker: st,
 	}
 }
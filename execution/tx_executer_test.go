@@ -82,6 +82,18 @@ func TestTxExecuter(t *testing.T) {
 	txc = texe.execute()
 
 	assert.Equal("", txc.Error())
+	if assert.Equal(1, len(txc.Events())) {
+		assert.Equal(txDep.Hash(), txc.Events()[0].CodeAddr())
+		assert.Equal(juriacoin.EventMint, txc.Events()[0].Name())
+	}
+
+	// failed tx must not keep events
+	txInvoke = core.NewTransaction().SetCodeAddr(txDep.Hash()).SetInput(b).Sign(core.GenerateKey(nil))
+	texe.tx = txInvoke
+	txc = texe.execute()
+
+	assert.NotEqual("", txc.Error(), "sender is not minter")
+	assert.Empty(txc.Events())
 
 	ccInput.Method = "balance"
 	ccInput.Value = 0
@@ -98,3 +110,117 @@ func TestTxExecuter(t *testing.T) {
 	assert.NoError(err)
 	assert.EqualValues(100, balance)
 }
+
+func TestTxExecuter_Gas(t *testing.T) {
+	assert := assert.New(t)
+
+	priv := core.GenerateKey(nil)
+	depInput := &DeploymentInput{
+		CodeInfo: CodeInfo{
+			DriverType: DriverTypeNative,
+			CodeID:     []byte(NativeCodeIDJuriaCoin),
+		},
+	}
+	b, _ := json.Marshal(depInput)
+	blk := core.NewBlock().SetHeight(10).Sign(priv)
+	reg := newCodeRegistry()
+	reg.registerDriver(DriverTypeNative, newNativeCodeDriver())
+
+	txDep := core.NewTransaction().SetInput(b).Sign(priv)
+	texe := txExecutor{
+		codeRegistry: reg,
+		timeout:      1 * time.Second,
+		txTrk:        newStateTracker(newMapStateStore(), nil),
+		blk:          blk,
+		tx:           txDep,
+	}
+	txc := texe.execute()
+	assert.Equal("", txc.Error())
+	assert.True(txc.GasUsed() > GasTxBase, "should charge base and state gas")
+
+	// not enough gas for setting minter state
+	gasLimit := GasTxBase + uint64(len(b))*GasPerInputByte + GasSetState
+	txDep = core.NewTransaction().SetInput(b).SetGasLimit(gasLimit).Sign(priv)
+	texe.txTrk = newStateTracker(newMapStateStore(), nil)
+	texe.tx = txDep
+	txc = texe.execute()
+	assert.Equal(ErrOutOfGas.Error(), txc.Error())
+	assert.Equal(txDep.GasLimit(), txc.GasUsed())
+}
+
+func TestTxExecuter_Upgrade(t *testing.T) {
+	assert := assert.New(t)
+
+	admin := core.GenerateKey(nil)
+	other := core.GenerateKey(nil)
+	codeInfo := CodeInfo{
+		DriverType: DriverTypeNative,
+		CodeID:     []byte(NativeCodeIDJuriaCoin),
+	}
+	b, _ := json.Marshal(&DeploymentInput{CodeInfo: codeInfo, Admin: admin.PublicKey().Bytes()})
+	txDep := core.NewTransaction().SetInput(b).Sign(admin)
+	b, _ = json.Marshal(&DeploymentInput{CodeInfo: codeInfo})
+	txDepNoAdmin := core.NewTransaction().SetInput(b).Sign(admin)
+
+	blk := core.NewBlock().SetHeight(10).Sign(admin)
+	reg := newCodeRegistry()
+	reg.registerDriver(DriverTypeNative, newNativeCodeDriver())
+	trk := newStateTracker(newMapStateStore(), nil)
+	texe := txExecutor{
+		codeRegistry: reg,
+		timeout:      1 * time.Second,
+		txTrk:        trk,
+		blk:          blk,
+	}
+	execTx := func(tx *core.Transaction) *core.TxCommit {
+		texe.tx = tx
+		return texe.execute()
+	}
+	assert.Equal("", execTx(txDep).Error())
+	assert.Equal("", execTx(txDepNoAdmin).Error())
+
+	mint, _ := json.Marshal(&juriacoin.Input{
+		Method: "mint",
+		Dest:   admin.PublicKey().Bytes(),
+		Value:  50,
+	})
+	upgrade := &UpgradeInput{
+		CodeAddr:     txDep.Hash(),
+		CodeInfo:     codeInfo,
+		MigrateInput: mint,
+	}
+	b, _ = json.Marshal(upgrade)
+	txc := execTx(core.NewTransaction().SetCodeAddr(UpgradeCodeAddr).SetInput(b).Sign(other))
+	assert.Equal(ErrNotCodeAdmin.Error(), txc.Error())
+
+	txUpgrade := core.NewTransaction().SetCodeAddr(UpgradeCodeAddr).SetInput(b).Sign(admin)
+	assert.Equal("", execTx(txUpgrade).Error())
+	assert.Equal("", execTx(txUpgrade).Error(), "upgrade again")
+
+	upgrades, err := reg.getUpgrades(txDep.Hash(), trk.spawn(codeRegistryAddr))
+	assert.NoError(err)
+	if assert.Equal(2, len(upgrades)) {
+		assert.EqualValues(10, upgrades[0].BlockHeight)
+		assert.Equal(txUpgrade.Hash(), upgrades[0].TxHash)
+		assert.Equal(codeInfo, upgrades[0].NewCode)
+	}
+
+	// states are kept and migration is invoked
+	cc, _ := reg.getInstance(txDep.Hash(), trk.spawn(codeRegistryAddr))
+	b, _ = json.Marshal(&juriacoin.Input{Method: "balance", Dest: admin.PublicKey().Bytes()})
+	b, err = cc.Query(&callContextTx{input: b, stateTracker: trk.spawn(txDep.Hash())})
+	assert.NoError(err)
+	var balance int64
+	json.Unmarshal(b, &balance)
+	assert.EqualValues(100, balance)
+
+	upgrade.CodeAddr = txDepNoAdmin.Hash()
+	b, _ = json.Marshal(upgrade)
+	txc = execTx(core.NewTransaction().SetCodeAddr(UpgradeCodeAddr).SetInput(b).Sign(admin))
+	assert.Equal(ErrNotUpgradable.Error(), txc.Error())
+
+	upgrade.CodeAddr = GovernanceCodeAddr
+	b, _ = json.Marshal(upgrade)
+	txc = execTx(core.NewTransaction().SetCodeAddr(UpgradeCodeAddr).SetInput(b).Sign(admin))
+	assert.NotEqual("", txc.Error(), "system chaincode")
+}
@@ -0,0 +1,144 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package wasmcc
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/aungmawjj/juria-blockchain/execution/bincc"
+	"github.com/aungmawjj/juria-blockchain/execution/chaincode"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// MemoryLimitPages is the maximum linear memory of a chaincode instance in 64 KiB pages
+const MemoryLimitPages = 256
+
+// CodeDriver runs WebAssembly chaincodes in an embedded runtime.
+// Modules are downloaded and verified by code hash the same way as binary chaincodes.
+type CodeDriver struct {
+	codeDir     string
+	execTimeout time.Duration
+	downloader  *bincc.CodeDriver
+	runtime     wazero.Runtime
+
+	modules    map[string]wazero.CompiledModule
+	mtxModules sync.Mutex
+	mtxInstall sync.Mutex
+}
+
+func NewCodeDriver(codeDir string, timeout time.Duration) *CodeDriver {
+	drv := &CodeDriver{
+		codeDir:     codeDir,
+		execTimeout: timeout,
+		downloader:  bincc.NewCodeDriver(codeDir, timeout),
+		modules:     make(map[string]wazero.CompiledModule),
+	}
+	drv.runtime = newRuntime()
+	return drv
+}
+
+// newRuntime gives a runtime with the MVP features only, without WASI or any clock and random source
+func newRuntime() wazero.Runtime {
+	ctx := context.Background()
+	r := wazero.NewRuntimeWithConfig(ctx, newRuntimeConfig().WithCloseOnContextDone(true))
+	if err := instantiateHostModule(ctx, r); err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func newRuntimeConfig() wazero.RuntimeConfig {
+	return wazero.NewRuntimeConfig().
+		WithCoreFeatures(api.CoreFeaturesV1).
+		WithMemoryLimitPages(MemoryLimitPages)
+}
+
+func (drv *CodeDriver) Install(codeID, data []byte) error {
+	drv.mtxInstall.Lock()
+	defer drv.mtxInstall.Unlock()
+	if err := drv.downloader.Install(codeID, data); err != nil {
+		return err
+	}
+	if _, err := drv.loadModule(codeID); err != nil {
+		os.Remove(drv.codePath(codeID))
+		return err
+	}
+	return nil
+}
+
+func (drv *CodeDriver) GetInstance(codeID []byte) (chaincode.Chaincode, error) {
+	module, err := drv.loadModule(codeID)
+	if err != nil {
+		return nil, err
+	}
+	return &Instance{
+		runtime: drv.runtime,
+		module:  module,
+		timeout: drv.execTimeout,
+	}, nil
+}
+
+func (drv *CodeDriver) loadModule(codeID []byte) (wazero.CompiledModule, error) {
+	drv.mtxModules.Lock()
+	defer drv.mtxModules.Unlock()
+
+	if module, ok := drv.modules[string(codeID)]; ok {
+		return module, nil
+	}
+	code, err := ioutil.ReadFile(drv.codePath(codeID))
+	if err != nil {
+		return nil, err
+	}
+	module, err := compileModule(drv.runtime, code)
+	if err != nil {
+		return nil, err
+	}
+	drv.modules[string(codeID)] = module
+	return module, nil
+}
+
+func (drv *CodeDriver) codePath(codeID []byte) string {
+	return path.Join(drv.codeDir, hex.EncodeToString(codeID))
+}
+
+func compileModule(r wazero.Runtime, code []byte) (wazero.CompiledModule, error) {
+	module, err := r.CompileModule(context.Background(), code)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wasm module, %w", err)
+	}
+	err = validateModule(module, code)
+	module.Close(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	metered, err := injectGasMeter(code)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wasm module, %w", err)
+	}
+	return r.CompileModule(context.Background(), metered)
+}
+
+// StoreCode validates the wasm module and stores it in the code dir, it returns the code id
+func StoreCode(codeDir string, r io.Reader) ([]byte, error) {
+	code, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	rt := wazero.NewRuntimeWithConfig(context.Background(), newRuntimeConfig())
+	defer rt.Close(context.Background())
+	if _, err := compileModule(rt, code); err != nil {
+		return nil, err
+	}
+	return bincc.StoreCode(codeDir, bytes.NewReader(code))
+}
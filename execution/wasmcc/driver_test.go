@@ -0,0 +1,226 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package wasmcc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aungmawjj/juria-blockchain/execution/chaincode"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/sha3"
+)
+
+// counterWasm stores the input at key "k" and returns it on query
+//
+//	(import "env" "get_state" (func $get_state (param i32 i32 i32 i32) (result i32)))
+//	(import "env" "set_state" (func $set_state (param i32 i32 i32 i32)))
+//	(import "env" "set_return" (func $set_return (param i32 i32)))
+//	(import "env" "input" (func $input (param i32 i32) (result i32)))
+//	(import "env" "revert" (func $revert (param i32 i32)))
+//	(memory (export "memory") 1)
+//	(data (i32.const 32) "k")
+//	(data (i32.const 100) "empty input")
+//	(func (export "invoke") (local $n i32)
+//	  (local.tee $n (call $input (i32.const 0) (i32.const 16)))
+//	  (if (i32.eqz) (then (call $revert (i32.const 100) (i32.const 11))))
+//	  (call $set_state (i32.const 32) (i32.const 1) (i32.const 0) (local.get $n)))
+//	(func (export "query")
+//	  (call $set_return (i32.const 0)
+//	    (call $get_state (i32.const 32) (i32.const 1) (i32.const 0) (i32.const 16))))
+const counterWasm = "0061736d01000000011e0560047f7f7f7f017f60047f7f7f7f0060027f7f0060027f7f017f6000" +
+	"00024b0503656e76096765745f7374617465000003656e76097365745f7374617465000103656e760a7365745f" +
+	"72657475726e000203656e7605696e707574000303656e7606726576657274000203030204040503010001071b" +
+	"03066d656d6f7279020006696e766f6b65000505717565727900060a34022101017f410041101003220045044041" +
+	"e400410b10040b412041014100200010010b100041004120410141004110100010020b0b18020041200b016b0041" +
+	"e4000b0b656d70747920696e707574"
+
+// (func (export "invoke") (drop (f32.const 1)))
+const floatWasm = "0061736d0100000001040160000003020100070a0106696e766f6b6500000a0a010800430000803f1a0b"
+
+// (func (export "invoke") (loop (br 0)))
+const spinWasm = "0061736d0100000001040160000003020100070a0106696e766f6b6500000a0901070003400c000b0b"
+
+// callWasm reverts with "ok" in a function called by invoke
+//
+//	(import "env" "revert" (func $revert (param i32 i32)))
+//	(memory 1)
+//	(data (i32.const 0) "ok")
+//	(func (export "invoke") (call $fail))
+//	(func $fail (call $revert (i32.const 0) (i32.const 2)))
+const callWasm = "0061736d0100000001090260027f7f00600000020e0103656e760672657665727400000303020101" +
+	"0503010001070a0106696e766f6b6500010a0f02040010020b08004100410210000b0b08010041000b026f6b"
+
+// (import "wasi_snapshot_preview1" "sched_yield" (func)) (func (export "invoke"))
+const wasiWasm = "0061736d0100000001040160000002260116776173695f736e617073686f745f70726576696577310b" +
+	"73636865645f7969656c64000003020100070a0106696e766f6b6500010a040102000b"
+
+var errTestOutOfGas = errors.New("out of gas")
+
+// gasContext charges the gas of the calls up to the limit
+type gasContext struct {
+	*chaincode.MockCallContext
+	limit uint64
+	used  uint64
+}
+
+func newGasContext(limit uint64) *gasContext {
+	return &gasContext{
+		MockCallContext: &chaincode.MockCallContext{MockState: chaincode.NewMockState()},
+		limit:           limit,
+	}
+}
+
+func (ctx *gasContext) ConsumeGas(amount uint64) {
+	if ctx.limit-ctx.used < amount {
+		ctx.used = ctx.limit
+		panic(errTestOutOfGas)
+	}
+	ctx.used += amount
+}
+
+func decodeWasm(code string) []byte {
+	b, _ := hex.DecodeString(code)
+	return b
+}
+
+func newTestDriver(t *testing.T, timeout time.Duration) *CodeDriver {
+	dir, err := ioutil.TempDir("", "wasmcc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return NewCodeDriver(dir, timeout)
+}
+
+func TestCodeDriver(t *testing.T) {
+	assert := assert.New(t)
+
+	drv := newTestDriver(t, time.Second)
+	codeID, err := StoreCode(drv.codeDir, bytes.NewReader(decodeWasm(counterWasm)))
+	if !assert.NoError(err) {
+		return
+	}
+	cc, err := drv.GetInstance(codeID)
+	if !assert.NoError(err) {
+		return
+	}
+	ctx := &chaincode.MockCallContext{MockState: chaincode.NewMockState()}
+	assert.NoError(cc.Init(ctx), "init is optional")
+
+	ctx.MockInput = []byte("hello")
+	assert.NoError(cc.Invoke(ctx))
+	assert.Equal([]byte("hello"), ctx.GetState([]byte("k")))
+
+	ctx.MockInput = nil
+	assert.EqualError(cc.Invoke(ctx), "empty input")
+
+	res, err := cc.Query(ctx)
+	assert.NoError(err)
+	assert.Equal([]byte("hello"), res)
+
+	ctx.MockInput = bytes.Repeat([]byte{1}, 17)
+	assert.NoError(cc.Invoke(ctx))
+	assert.Equal(make([]byte, 17), ctx.GetState([]byte("k")), "input bigger than buffer is not copied")
+}
+
+func TestCodeDriver_Timeout(t *testing.T) {
+	assert := assert.New(t)
+
+	drv := newTestDriver(t, 100*time.Millisecond)
+	codeID, err := StoreCode(drv.codeDir, bytes.NewReader(decodeWasm(spinWasm)))
+	if !assert.NoError(err) {
+		return
+	}
+	cc, err := drv.GetInstance(codeID)
+	if !assert.NoError(err) {
+		return
+	}
+	err = cc.Invoke(&chaincode.MockCallContext{MockState: chaincode.NewMockState()})
+	assert.EqualError(err, "chaincode execution timeout")
+}
+
+func TestCodeDriver_Gas(t *testing.T) {
+	assert := assert.New(t)
+
+	drv := newTestDriver(t, time.Minute)
+	getInstance := func(code string) chaincode.Chaincode {
+		codeID, err := StoreCode(drv.codeDir, bytes.NewReader(decodeWasm(code)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cc, err := drv.GetInstance(codeID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cc
+	}
+
+	cc := getInstance(counterWasm)
+	ctx := newGasContext(1000)
+	ctx.MockInput = []byte("hello")
+	assert.NoError(cc.Invoke(ctx))
+	used := ctx.used
+	assert.NotZero(used)
+	ctx.used = 0
+	assert.NoError(cc.Invoke(ctx))
+	assert.Equal(used, ctx.used, "gas is deterministic")
+
+	ctx = newGasContext(1000)
+	assert.EqualError(getInstance(callWasm).Invoke(ctx), "ok", "function indices are shifted")
+	assert.EqualValues(6, ctx.used, "two instructions of invoke and four of the called function")
+
+	ctx = newGasContext(100000)
+	err := getInstance(spinWasm).Invoke(ctx)
+	assert.ErrorIs(err, errTestOutOfGas, "every loop iteration is charged")
+	assert.EqualValues(100000, ctx.used)
+}
+
+func TestStoreCode_Invalid(t *testing.T) {
+	assert := assert.New(t)
+
+	drv := newTestDriver(t, time.Second)
+
+	_, err := StoreCode(drv.codeDir, bytes.NewReader(decodeWasm(floatWasm)))
+	assert.Equal(ErrFloatInstruction, err)
+
+	_, err = StoreCode(drv.codeDir, bytes.NewReader(decodeWasm(wasiWasm)))
+	assert.Equal(ErrInvalidImport, err)
+
+	_, err = StoreCode(drv.codeDir, bytes.NewReader([]byte("not wasm")))
+	assert.Error(err)
+}
+
+func TestCodeDriver_Install(t *testing.T) {
+	assert := assert.New(t)
+
+	codes := map[string][]byte{
+		"/counter": decodeWasm(counterWasm),
+		"/float":   decodeWasm(floatWasm),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(codes[r.URL.Path])
+	}))
+	defer server.Close()
+
+	drv := newTestDriver(t, time.Second)
+
+	codeID := sha3.Sum256(codes["/float"])
+	err := drv.Install(codeID[:], []byte(server.URL+"/float"))
+	assert.Equal(ErrFloatInstruction, err)
+	_, err = os.Stat(drv.codePath(codeID[:]))
+	assert.True(os.IsNotExist(err), "invalid module should be removed")
+
+	codeID = sha3.Sum256(codes["/counter"])
+	assert.NoError(drv.Install(codeID[:], []byte(server.URL+"/counter")))
+	_, err = drv.GetInstance(codeID[:])
+	assert.NoError(err)
+}
@@ -0,0 +1,136 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package wasmcc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aungmawjj/juria-blockchain/execution/chaincode"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// HostModule is the import module name of the host functions.
+//
+// Byte values are copied to the guest buffer at ptr with capacity bufSize,
+// the functions return the value length and nothing is copied if the buffer is too small.
+//
+//	input(ptr, bufSize i32) i32
+//	sender(ptr, bufSize i32) i32
+//	block_height() i64
+//	get_state(keyPtr, keyLen, ptr, bufSize i32) i32
+//	set_state(keyPtr, keyLen, valuePtr, valueLen i32)
+//	delete_state(keyPtr, keyLen i32)
+//	set_return(ptr, len i32)  // result of query
+//	revert(ptr, len i32)      // fails the call with the message
+//
+// The gas(amount i64) function is imported by the gas meter injected into the modules,
+// it charges the gas meter of the call context.
+const HostModule = "env"
+
+var errMemoryAccess = errors.New("wasm memory access out of range")
+
+// revertError is raised by the revert host function
+type revertError struct {
+	msg string
+}
+
+func (err *revertError) Error() string {
+	return err.msg
+}
+
+type hostCallKey struct{}
+
+// hostCall is the state of a chaincode call, passed to the host functions by context
+type hostCall struct {
+	callContext chaincode.CallContext
+	result      []byte
+}
+
+func getHostCall(ctx context.Context) *hostCall {
+	return ctx.Value(hostCallKey{}).(*hostCall)
+}
+
+func instantiateHostModule(ctx context.Context, r wazero.Runtime) error {
+	_, err := r.NewHostModuleBuilder(HostModule).
+		NewFunctionBuilder().WithFunc(hostInput).Export("input").
+		NewFunctionBuilder().WithFunc(hostSender).Export("sender").
+		NewFunctionBuilder().WithFunc(hostBlockHeight).Export("block_height").
+		NewFunctionBuilder().WithFunc(hostGetState).Export("get_state").
+		NewFunctionBuilder().WithFunc(hostSetState).Export("set_state").
+		NewFunctionBuilder().WithFunc(hostDeleteState).Export("delete_state").
+		NewFunctionBuilder().WithFunc(hostSetReturn).Export("set_return").
+		NewFunctionBuilder().WithFunc(hostRevert).Export("revert").
+		NewFunctionBuilder().WithFunc(hostGas).Export(hostGasFunc).
+		Instantiate(ctx)
+	return err
+}
+
+func hostInput(ctx context.Context, m api.Module, ptr, bufSize uint32) uint32 {
+	return writeValue(m, ptr, bufSize, getHostCall(ctx).callContext.Input())
+}
+
+func hostSender(ctx context.Context, m api.Module, ptr, bufSize uint32) uint32 {
+	return writeValue(m, ptr, bufSize, getHostCall(ctx).callContext.Sender())
+}
+
+func hostBlockHeight(ctx context.Context) uint64 {
+	return getHostCall(ctx).callContext.BlockHeight()
+}
+
+func hostGetState(ctx context.Context, m api.Module, keyPtr, keyLen, ptr, bufSize uint32) uint32 {
+	key := readValue(m, keyPtr, keyLen)
+	return writeValue(m, ptr, bufSize, getHostCall(ctx).callContext.GetState(key))
+}
+
+func hostSetState(ctx context.Context, m api.Module, keyPtr, keyLen, valuePtr, valueLen uint32) {
+	key := readValue(m, keyPtr, keyLen)
+	value := readValue(m, valuePtr, valueLen)
+	getHostCall(ctx).callContext.SetState(key, value)
+}
+
+func hostDeleteState(ctx context.Context, m api.Module, keyPtr, keyLen uint32) {
+	getHostCall(ctx).callContext.DeleteState(readValue(m, keyPtr, keyLen))
+}
+
+func hostSetReturn(ctx context.Context, m api.Module, ptr, size uint32) {
+	getHostCall(ctx).result = readValue(m, ptr, size)
+}
+
+func hostRevert(ctx context.Context, m api.Module, ptr, size uint32) {
+	panic(&revertError{string(readValue(m, ptr, size))})
+}
+
+// hostGas panics with the out of gas error of the call context,
+// the error is returned by the call
+func hostGas(ctx context.Context, amount uint64) {
+	if gm, ok := getHostCall(ctx).callContext.(chaincode.GasMeter); ok {
+		gm.ConsumeGas(amount)
+	}
+}
+
+// readValue copies the bytes from the guest memory
+func readValue(m api.Module, ptr, size uint32) []byte {
+	b, ok := getMemory(m).Read(ptr, size)
+	if !ok {
+		panic(errMemoryAccess)
+	}
+	return append([]byte{}, b...)
+}
+
+func writeValue(m api.Module, ptr, bufSize uint32, value []byte) uint32 {
+	if uint32(len(value)) <= bufSize && !getMemory(m).Write(ptr, value) {
+		panic(errMemoryAccess)
+	}
+	return uint32(len(value))
+}
+
+func getMemory(m api.Module) api.Memory {
+	mem := m.Memory()
+	if mem == nil {
+		panic(errMemoryAccess) // module has no memory
+	}
+	return mem
+}
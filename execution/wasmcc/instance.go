@@ -0,0 +1,87 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package wasmcc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aungmawjj/juria-blockchain/execution/chaincode"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// exported functions of wasm chaincodes, they take no params and return no results
+const (
+	FuncInit   = "init" // optional
+	FuncInvoke = "invoke"
+	FuncQuery  = "query"
+)
+
+// Instance runs each call in a new instance of the compiled module
+type Instance struct {
+	runtime wazero.Runtime
+	module  wazero.CompiledModule
+	timeout time.Duration
+}
+
+var _ chaincode.Chaincode = (*Instance)(nil)
+
+func (inst *Instance) Init(ctx chaincode.CallContext) error {
+	_, err := inst.call(ctx, FuncInit)
+	return err
+}
+
+func (inst *Instance) Invoke(ctx chaincode.CallContext) error {
+	_, err := inst.call(ctx, FuncInvoke)
+	return err
+}
+
+func (inst *Instance) Query(ctx chaincode.CallContext) ([]byte, error) {
+	return inst.call(ctx, FuncQuery)
+}
+
+func (inst *Instance) call(callContext chaincode.CallContext, name string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), inst.timeout)
+	defer cancel()
+	hc := &hostCall{callContext: callContext}
+	ctx = context.WithValue(ctx, hostCallKey{}, hc)
+
+	mod, err := inst.runtime.InstantiateModule(ctx, inst.module,
+		wazero.NewModuleConfig().WithName("").WithStartFunctions())
+	if err != nil {
+		return nil, callError(err)
+	}
+	defer mod.Close(context.Background())
+
+	fn := mod.ExportedFunction(name)
+	if fn == nil {
+		if name == FuncInit {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("function %s not exported", name)
+	}
+	if _, err := fn.Call(ctx); err != nil {
+		return nil, callError(err)
+	}
+	return hc.result, nil
+}
+
+// callError removes the wasm stack trace from the errors recovered by the runtime
+func callError(err error) error {
+	var revert *revertError
+	if errors.As(err, &revert) {
+		return errors.New(revert.msg)
+	}
+	var exit *sys.ExitError
+	if errors.As(err, &exit) && exit.ExitCode() == sys.ExitCodeDeadlineExceeded {
+		return errors.New("chaincode execution timeout")
+	}
+	if inner := errors.Unwrap(err); inner != nil {
+		return inner
+	}
+	return err
+}
@@ -0,0 +1,448 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package wasmcc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// GasPerInstruction is charged for each instruction of the wasm chaincode.
+// The instructions of a function body or a loop body are charged when it's entered,
+// so the gas only depends on the code and the inputs, and every loop iteration is charged.
+const GasPerInstruction uint64 = 1
+
+// wasm binary format constants used by the gas meter
+const (
+	sectionCustom  = 0
+	sectionImport  = 2
+	sectionExport  = 7
+	sectionStart   = 8
+	sectionElement = 9
+
+	externFunc   = 0x00
+	externTable  = 0x01
+	externMemory = 0x02
+	externGlobal = 0x03
+
+	funcType = 0x60
+
+	valueTypeI64 = 0x7e
+
+	opCall     = 0x10
+	opI64Const = 0x42
+)
+
+// hostGasFunc is the host function called by the injected instructions
+const hostGasFunc = "gas"
+
+var errUnsupportedElement = errors.New("unsupported wasm element segment")
+
+// gasMeter injects the gas charging instructions into a module.
+// The gas host function is appended to the imported functions,
+// so the indices of the functions defined by the module are shifted by one.
+type gasMeter struct {
+	gasType uint32 // type index of the gas function
+	gasFunc uint32 // function index of the gas function
+}
+
+// injectGasMeter rewrites the module to call the gas host function
+// at the start of each function body and loop body.
+// Custom sections are removed as the function names are not shifted.
+func injectGasMeter(code []byte) ([]byte, error) {
+	sections, err := readSections(code)
+	if err != nil {
+		return nil, err
+	}
+	m := new(gasMeter)
+	out := append([]byte{}, code[:8]...)
+	for _, s := range withImportSection(sections) {
+		switch s.id {
+		case sectionCustom:
+			continue
+		case sectionType:
+			s.data, err = m.meterTypeSection(s.data)
+		case sectionImport:
+			s.data, err = m.meterImportSection(s.data)
+		case sectionExport:
+			s.data, err = m.meterExportSection(s.data)
+		case sectionStart:
+			s.data, err = m.meterStartSection(s.data)
+		case sectionElement:
+			s.data, err = m.meterElementSection(s.data)
+		case sectionCode:
+			s.data, err = m.meterCodeSection(s.data)
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s.id)
+		out = appendU32(out, uint32(len(s.data)))
+		out = append(out, s.data...)
+	}
+	return out, nil
+}
+
+// withImportSection adds the empty type and import sections if the module has none
+func withImportSection(sections []section) []section {
+	hasType, hasImport := false, false
+	for _, s := range sections {
+		hasType = hasType || s.id == sectionType
+		hasImport = hasImport || s.id == sectionImport
+	}
+	ret := make([]section, 0, len(sections)+2)
+	if !hasType {
+		ret = append(ret, section{sectionType, []byte{0}})
+	}
+	for i, s := range sections {
+		if !hasImport && s.id != sectionCustom && s.id != sectionType {
+			ret = append(ret, section{sectionImport, []byte{0}})
+			ret = append(ret, sections[i:]...)
+			return ret
+		}
+		ret = append(ret, s)
+	}
+	if !hasImport {
+		ret = append(ret, section{sectionImport, []byte{0}})
+	}
+	return ret
+}
+
+// meterTypeSection appends the type of the gas function, (i64) -> ()
+func (m *gasMeter) meterTypeSection(data []byte) ([]byte, error) {
+	r := bytes.NewReader(data)
+	count, err := readU32(r)
+	if err != nil {
+		return nil, err
+	}
+	m.gasType = count
+	out := appendU32(nil, count+1)
+	out = append(out, data[len(data)-r.Len():]...)
+	return append(out, funcType, 1, valueTypeI64, 0), nil
+}
+
+// meterImportSection appends the import of the gas function
+func (m *gasMeter) meterImportSection(data []byte) ([]byte, error) {
+	r := bytes.NewReader(data)
+	count, err := readU32(r)
+	if err != nil {
+		return nil, err
+	}
+	out := appendU32(nil, count+1)
+	out = append(out, data[len(data)-r.Len():]...)
+	for i := uint32(0); i < count; i++ {
+		for j := 0; j < 2; j++ { // module and name
+			if err := skipName(r); err != nil {
+				return nil, err
+			}
+		}
+		kind, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if kind == externFunc {
+			m.gasFunc++
+		}
+		if err := skipImportDesc(r, kind); err != nil {
+			return nil, err
+		}
+	}
+	out = appendName(out, HostModule)
+	out = appendName(out, hostGasFunc)
+	out = append(out, externFunc)
+	return appendU32(out, m.gasType), nil
+}
+
+func (m *gasMeter) meterExportSection(data []byte) ([]byte, error) {
+	r := bytes.NewReader(data)
+	count, err := readU32(r)
+	if err != nil {
+		return nil, err
+	}
+	out := appendU32(nil, count)
+	for i := uint32(0); i < count; i++ {
+		start := len(data) - r.Len()
+		if err := skipName(r); err != nil {
+			return nil, err
+		}
+		kind, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, data[start:len(data)-r.Len()]...)
+		idx, err := readU32(r)
+		if err != nil {
+			return nil, err
+		}
+		if kind == externFunc {
+			idx = m.funcIndex(idx)
+		}
+		out = appendU32(out, idx)
+	}
+	return out, nil
+}
+
+func (m *gasMeter) meterStartSection(data []byte) ([]byte, error) {
+	idx, err := readU32(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return appendU32(nil, m.funcIndex(idx)), nil
+}
+
+func (m *gasMeter) meterElementSection(data []byte) ([]byte, error) {
+	r := bytes.NewReader(data)
+	count, err := readU32(r)
+	if err != nil {
+		return nil, err
+	}
+	out := appendU32(nil, count)
+	for i := uint32(0); i < count; i++ {
+		start := len(data) - r.Len()
+		table, err := readU32(r)
+		if err != nil {
+			return nil, err
+		}
+		if table != 0 {
+			return nil, errUnsupportedElement
+		}
+		if _, err := readInstrs(r); err != nil { // offset
+			return nil, err
+		}
+		out = append(out, data[start:len(data)-r.Len()]...)
+		fnCount, err := readU32(r)
+		if err != nil {
+			return nil, err
+		}
+		out = appendU32(out, fnCount)
+		for j := uint32(0); j < fnCount; j++ {
+			idx, err := readU32(r)
+			if err != nil {
+				return nil, err
+			}
+			out = appendU32(out, m.funcIndex(idx))
+		}
+	}
+	return out, nil
+}
+
+func (m *gasMeter) meterCodeSection(data []byte) ([]byte, error) {
+	r := bytes.NewReader(data)
+	count, err := readU32(r)
+	if err != nil {
+		return nil, err
+	}
+	out := appendU32(nil, count)
+	for i := uint32(0); i < count; i++ {
+		size, err := readU32(r)
+		if err != nil {
+			return nil, err
+		}
+		body := make([]byte, size)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return nil, err
+		}
+		body, err = m.meterBody(body)
+		if err != nil {
+			return nil, err
+		}
+		out = appendU32(out, uint32(len(body)))
+		out = append(out, body...)
+	}
+	return out, nil
+}
+
+// meterBody charges the function body on entry and each loop body at the start of the iteration,
+// the instructions of the nested loops are charged by the nested loops.
+func (m *gasMeter) meterBody(body []byte) ([]byte, error) {
+	r := bytes.NewReader(body)
+	localCount, err := readU32(r)
+	if err != nil {
+		return nil, err
+	}
+	for i := uint32(0); i < localCount; i++ {
+		if _, err := readU32(r); err != nil {
+			return nil, err
+		}
+		if _, err := r.ReadByte(); err != nil {
+			return nil, err
+		}
+	}
+	out := append([]byte{}, body[:len(body)-r.Len()]...)
+	instrs, err := readInstrs(r)
+	if err != nil {
+		return nil, err
+	}
+	fnGas, loopGas := gasOfBody(instrs)
+	out = m.appendCharge(out, fnGas)
+	for i, in := range instrs {
+		if in.op == opCall {
+			idx, err := readU32(bytes.NewReader(in.code[1:]))
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, opCall)
+			out = appendU32(out, m.funcIndex(idx))
+			continue
+		}
+		out = append(out, in.code...)
+		if in.op == opLoop {
+			out = m.appendCharge(out, loopGas[i])
+		}
+	}
+	return out, nil
+}
+
+// gasOfBody gives the gas of the function body and the gas of the loops by instruction index
+func gasOfBody(instrs []instr) (uint64, map[int]uint64) {
+	var fnGas uint64
+	loopGas := make(map[int]uint64)
+	loops := make([]int, 0)   // instruction index of the enclosing loops
+	blocks := make([]bool, 0) // enclosing blocks, true for loops
+	for i, in := range instrs {
+		if len(loops) == 0 {
+			fnGas += GasPerInstruction
+		} else {
+			loopGas[loops[len(loops)-1]] += GasPerInstruction
+		}
+		switch in.op {
+		case opBlock, opIf:
+			blocks = append(blocks, false)
+		case opLoop:
+			blocks = append(blocks, true)
+			loops = append(loops, i)
+		case opEnd:
+			if len(blocks) == 0 { // end of function
+				continue
+			}
+			if blocks[len(blocks)-1] {
+				loops = loops[:len(loops)-1]
+			}
+			blocks = blocks[:len(blocks)-1]
+		}
+	}
+	return fnGas, loopGas
+}
+
+// appendCharge appends the call to the gas function with the amount
+func (m *gasMeter) appendCharge(out []byte, amount uint64) []byte {
+	out = append(out, opI64Const)
+	out = appendS64(out, int64(amount))
+	out = append(out, opCall)
+	return appendU32(out, m.gasFunc)
+}
+
+func (m *gasMeter) funcIndex(idx uint32) uint32 {
+	if idx >= m.gasFunc {
+		return idx + 1
+	}
+	return idx
+}
+
+type instr struct {
+	op   byte
+	code []byte // opcode and immediates
+}
+
+// readInstrs reads the instructions until the end of the expression
+func readInstrs(r *bytes.Reader) ([]instr, error) {
+	instrs := make([]instr, 0)
+	depth := 0
+	for {
+		start := r.Size() - int64(r.Len())
+		op, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if err := skipImmediates(r, op); err != nil {
+			return nil, err
+		}
+		code := make([]byte, r.Size()-int64(r.Len())-start)
+		r.ReadAt(code, start)
+		instrs = append(instrs, instr{op, code})
+		switch op {
+		case opBlock, opLoop, opIf:
+			depth++
+		case opEnd:
+			if depth == 0 {
+				return instrs, nil
+			}
+			depth--
+		}
+	}
+}
+
+func skipName(r *bytes.Reader) error {
+	size, err := readU32(r)
+	if err != nil {
+		return err
+	}
+	_, err = r.Seek(int64(size), io.SeekCurrent)
+	return err
+}
+
+func skipImportDesc(r *bytes.Reader, kind byte) error {
+	var err error
+	switch kind {
+	case externFunc:
+		_, err = readU32(r)
+	case externTable:
+		if _, err = r.ReadByte(); err == nil { // element type
+			err = skipLimits(r)
+		}
+	case externMemory:
+		err = skipLimits(r)
+	case externGlobal:
+		if _, err = r.ReadByte(); err == nil { // value type
+			_, err = r.ReadByte() // mutability
+		}
+	default:
+		err = ErrInvalidImport
+	}
+	return err
+}
+
+func skipLimits(r *bytes.Reader) error {
+	flag, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	if _, err := readU32(r); err != nil { // min
+		return err
+	}
+	if flag == 1 {
+		_, err = readU32(r) // max
+	}
+	return err
+}
+
+func appendName(out []byte, name string) []byte {
+	out = appendU32(out, uint32(len(name)))
+	return append(out, name...)
+}
+
+// appendU32 appends the unsigned LEB128 number
+func appendU32(out []byte, value uint32) []byte {
+	for {
+		b := byte(value & 0x7f)
+		value >>= 7
+		if value == 0 {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
+
+// appendS64 appends the signed LEB128 number
+func appendS64(out []byte, value int64) []byte {
+	for {
+		b := byte(value & 0x7f)
+		value >>= 7
+		if (value == 0 && b&0x40 == 0) || (value == -1 && b&0x40 != 0) {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
@@ -0,0 +1,283 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package wasmcc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/tetratelabs/wazero"
+)
+
+// errors
+var (
+	ErrFloatInstruction = errors.New("floating point is not allowed in wasm chaincode")
+	ErrInvalidImport    = errors.New("wasm chaincode can only import host functions")
+)
+
+// wasm binary format constants
+const (
+	sectionType   = 1
+	sectionGlobal = 6
+	sectionCode   = 10
+
+	valueTypeF32 = 0x7d
+	valueTypeF64 = 0x7c
+
+	opBlock = 0x02
+	opLoop  = 0x03
+	opIf    = 0x04
+	opEnd   = 0x0b
+)
+
+// validateModule rejects modules which may not execute the same on every node.
+// The module is already validated by the runtime, so only the instructions and types are scanned for floats.
+func validateModule(module wazero.CompiledModule, code []byte) error {
+	for _, fn := range module.ImportedFunctions() {
+		if mod, _, _ := fn.Import(); mod != HostModule {
+			return ErrInvalidImport
+		}
+	}
+	if len(module.ImportedMemories()) > 0 {
+		return ErrInvalidImport
+	}
+	return scanFloats(code)
+}
+
+type section struct {
+	id   byte
+	data []byte
+}
+
+func readSections(code []byte) ([]section, error) {
+	sections := make([]section, 0)
+	r := bytes.NewReader(code[8:]) // skip magic and version
+	for r.Len() > 0 {
+		id, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		size, err := readU32(r)
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		sections = append(sections, section{id, data})
+	}
+	return sections, nil
+}
+
+func scanFloats(code []byte) error {
+	sections, err := readSections(code)
+	if err != nil {
+		return err
+	}
+	for _, s := range sections {
+		switch s.id {
+		case sectionType:
+			err = scanTypeSection(bytes.NewReader(s.data))
+		case sectionGlobal:
+			err = scanGlobalSection(bytes.NewReader(s.data))
+		case sectionCode:
+			err = scanCodeSection(bytes.NewReader(s.data))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scanTypeSection(r *bytes.Reader) error {
+	count, err := readU32(r)
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < count; i++ {
+		r.ReadByte() // func type 0x60
+		// params and results
+		for j := 0; j < 2; j++ {
+			if err := scanValueTypes(r); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func scanGlobalSection(r *bytes.Reader) error {
+	count, err := readU32(r)
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < count; i++ {
+		if err := scanValueType(r); err != nil {
+			return err
+		}
+		r.ReadByte() // mutability
+		if err := scanExpr(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scanCodeSection(r *bytes.Reader) error {
+	count, err := readU32(r)
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < count; i++ {
+		if _, err := readU32(r); err != nil { // body size
+			return err
+		}
+		localCount, err := readU32(r)
+		if err != nil {
+			return err
+		}
+		for j := uint32(0); j < localCount; j++ {
+			if _, err := readU32(r); err != nil {
+				return err
+			}
+			if err := scanValueType(r); err != nil {
+				return err
+			}
+		}
+		if err := scanExpr(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scanValueTypes(r *bytes.Reader) error {
+	count, err := readU32(r)
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < count; i++ {
+		if err := scanValueType(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scanValueType(r *bytes.Reader) error {
+	vt, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	if vt == valueTypeF32 || vt == valueTypeF64 {
+		return ErrFloatInstruction
+	}
+	return nil
+}
+
+// scanExpr reads the instructions until the end of the expression
+func scanExpr(r *bytes.Reader) error {
+	depth := 0
+	for {
+		op, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if isFloatOp(op) {
+			return ErrFloatInstruction
+		}
+		if err := skipImmediates(r, op); err != nil {
+			return err
+		}
+		switch op {
+		case opBlock, opLoop, opIf:
+			depth++
+		case opEnd:
+			if depth == 0 {
+				return nil
+			}
+			depth--
+		}
+	}
+}
+
+// skipImmediates reads the immediates of the instruction
+func skipImmediates(r *bytes.Reader, op byte) error {
+	var err error
+	switch {
+	case op == opBlock || op == opLoop || op == opIf:
+		err = scanValueType(r) // block type, 0x40 for empty
+	case op == 0x0c || op == 0x0d || op == 0x10 || (op >= 0x20 && op <= 0x24):
+		// br, br_if, call, local and global instructions
+		_, err = readU32(r)
+	case op == 0x0e: // br_table
+		err = skipBrTable(r)
+	case op == 0x11: // call_indirect
+		if _, err = readU32(r); err == nil {
+			_, err = r.ReadByte()
+		}
+	case op >= 0x28 && op <= 0x3e: // memory load and store
+		if _, err = readU32(r); err == nil {
+			_, err = readU32(r)
+		}
+	case op == 0x3f || op == 0x40: // memory.size, memory.grow
+		_, err = r.ReadByte()
+	case op == 0x41 || op == 0x42: // i32.const, i64.const
+		_, err = readU32(r)
+	case op <= 0x01 || op == 0x05 || op == opEnd || op == 0x0f || op == 0x1a || op == 0x1b || op >= 0x45 && op <= 0xbf:
+		// instructions without immediates
+	default:
+		return fmt.Errorf("unsupported wasm instruction 0x%02x", op)
+	}
+	return err
+}
+
+// isFloatOp checks the MVP instructions operating on f32 and f64
+func isFloatOp(op byte) bool {
+	switch {
+	case op == 0x2a || op == 0x2b || op == 0x38 || op == 0x39: // load and store
+		return true
+	case op == 0x43 || op == 0x44: // const
+		return true
+	case op >= 0x5b && op <= 0x66: // comparison
+		return true
+	case op >= 0x8b && op <= 0xa6: // arithmetic
+		return true
+	case op >= 0xa8 && op <= 0xab, op >= 0xae && op <= 0xbf: // conversion
+		return true
+	}
+	return false
+}
+
+func skipBrTable(r *bytes.Reader) error {
+	count, err := readU32(r)
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i <= count; i++ { // labels and default label
+		if _, err := readU32(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readU32 reads a LEB128 number, it also skips signed numbers
+func readU32(r *bytes.Reader) (uint32, error) {
+	var value uint32
+	for shift := uint(0); ; shift += 7 {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		value |= uint32(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return value, nil
+		}
+	}
+}
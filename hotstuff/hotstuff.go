@@ -37,11 +37,11 @@ func (hs *Hotstuff) OnPropose() Block {
 
 // OnReceiveVote is called when received a vote
 func (hs *Hotstuff) OnReceiveVote(v Vote) {
-	err := hs.addVote(v)
+	err := hs.addVote(v, hs.driver.VotePower(v))
 	if err != nil {
 		return
 	}
-	if hs.GetVoteCount() >= hs.driver.MajorityCount() {
+	if hs.GetVotePower() >= hs.driver.MajorityPower() {
 		votes := hs.GetVotes()
 		hs.endProposal()
 		hs.UpdateQCHigh(hs.driver.CreateQC(votes))
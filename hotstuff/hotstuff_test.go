@@ -92,7 +92,8 @@ func TestHotstuff_SuccessfulPropose(t *testing.T) {
 	assert.Equal(b1, hs.GetBLeaf())
 	assert.True(hs.IsProposing())
 
-	driver.On("MajorityCount").Return(3)
+	driver.On("MajorityPower").Return(uint64(3))
+	driver.On("VotePower", mock.Anything).Return(uint64(1))
 
 	v1 := newMockVote(b1, "r1")
 	hs.OnReceiveVote(v1)
@@ -133,7 +134,8 @@ func TestHotstuff_OnReceiveVote(t *testing.T) {
 	driver.On("CreateLeaf", b0, q0, b0.Height()+1).Once().Return(b1)
 	driver.On("BroadcastProposal", b1).Once()
 	hs.OnPropose()
-	driver.On("MajorityCount").Return(2)
+	driver.On("MajorityPower").Return(uint64(2))
+	driver.On("VotePower", mock.Anything).Return(uint64(1))
 
 	v1 := newMockVote(b1, "r1")
 	hs.OnReceiveVote(v1)
@@ -158,6 +160,44 @@ func TestHotstuff_OnReceiveVote(t *testing.T) {
 	assert.Equal(q1, hs.GetQCHigh())
 }
 
+func TestHotstuff_OnReceiveVotePower(t *testing.T) {
+	q0 := newMockQC(nil)
+	b0 := newMockBlock(10, nil, q0)
+	b1 := newMockBlock(11, b0, q0)
+	q1 := newMockQC(b1)
+
+	assert := assert.New(t)
+
+	driver := new(MockDriver)
+	hs := New(driver, b0, q0)
+
+	driver.On("CreateLeaf", b0, q0, b0.Height()+1).Once().Return(b1)
+	driver.On("BroadcastProposal", b1).Once()
+	hs.OnPropose()
+	driver.On("MajorityPower").Return(uint64(67))
+
+	v1 := newMockVote(b1, "r1")
+	v2 := newMockVote(b1, "r2")
+	v3 := newMockVote(b1, "r3")
+	driver.On("VotePower", v1).Return(uint64(10))
+	driver.On("VotePower", v2).Return(uint64(20))
+	driver.On("VotePower", v3).Return(uint64(70))
+
+	hs.OnReceiveVote(v1)
+	hs.OnReceiveVote(v2)
+
+	driver.AssertNotCalled(t, "CreateQC")
+	assert.Equal(2, hs.GetVoteCount())
+	assert.EqualValues(30, hs.GetVotePower())
+
+	driver.On("CreateQC", mock.Anything).Return(q1)
+	hs.OnReceiveVote(v3)
+
+	driver.AssertExpectations(t)
+	assert.False(hs.IsProposing())
+	assert.Equal(q1, hs.GetQCHigh())
+}
+
 func TestHotstuff_CanVote(t *testing.T) {
 	q0 := newMockQC(nil)
 	b0 := newMockBlock(10, nil, q0) // bLock
@@ -18,9 +18,10 @@ type state struct {
 	qcHigh atomic.Value
 	bLeaf  atomic.Value
 
-	proposal Block
-	votes    map[string]Vote
-	pMtx     sync.RWMutex
+	proposal  Block
+	votes     map[string]Vote
+	votePower uint64
+	pMtx      sync.RWMutex
 
 	qcHighEmitter *emitter.Emitter
 }
@@ -79,6 +80,7 @@ func (s *state) startProposal(b Block) {
 
 	s.proposal = b
 	s.votes = make(map[string]Vote)
+	s.votePower = 0
 }
 
 func (s *state) endProposal() {
@@ -87,9 +89,10 @@ func (s *state) endProposal() {
 
 	s.proposal = nil
 	s.votes = nil
+	s.votePower = 0
 }
 
-func (s *state) addVote(v Vote) error {
+func (s *state) addVote(v Vote, power uint64) error {
 	s.pMtx.Lock()
 	defer s.pMtx.Unlock()
 
@@ -104,6 +107,7 @@ func (s *state) addVote(v Vote) error {
 		return fmt.Errorf("duplicate vote")
 	}
 	s.votes[key] = v
+	s.votePower += power
 	return nil
 }
 
@@ -114,6 +118,14 @@ func (s *state) GetVoteCount() int {
 	return len(s.votes)
 }
 
+// GetVotePower gives the total voting power of the votes for the current proposal
+func (s *state) GetVotePower() uint64 {
+	s.pMtx.RLock()
+	defer s.pMtx.RUnlock()
+
+	return s.votePower
+}
+
 func (s *state) GetVotes() []Vote {
 	s.pMtx.RLock()
 	defer s.pMtx.RUnlock()
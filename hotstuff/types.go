@@ -24,7 +24,9 @@ type Vote interface {
 
 // Driver godoc
 type Driver interface {
-	MajorityCount() int
+	// MajorityPower is the voting power required to create a qc
+	MajorityPower() uint64
+	VotePower(v Vote) uint64
 	CreateLeaf(parent Block, qc QC, height uint64) Block
 	CreateQC(votes []Vote) QC
 	BroadcastProposal(blk Block)
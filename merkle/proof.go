@@ -0,0 +1,102 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package merkle
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"math/big"
+)
+
+// Proof is the merkle inclusion proof of a leaf node.
+//
+// Siblings holds the child nodes of the groups on the path from leaf level to root.
+// Each group has branch-factor slots and the slot of the proven node (or its ancestor) is nil.
+type Proof struct {
+	Hash     crypto.Hash `json:"hash"`
+	Siblings [][][]byte  `json:"siblings"`
+}
+
+// Marshal encodes proof as json bytes
+func (proof *Proof) Marshal() ([]byte, error) {
+	return json.Marshal(proof)
+}
+
+// Unmarshal decodes proof from json bytes
+func (proof *Proof) Unmarshal(b []byte) error {
+	return json.Unmarshal(b, proof)
+}
+
+// Proof creates the inclusion proof for the leaf at the given position
+func (tree *Tree) Proof(p *Position) (*Proof, error) {
+	if p.Level() != 0 {
+		return nil, errors.New("not a leaf position")
+	}
+	rowSize := tree.store.GetLeafCount()
+	if rowSize.Cmp(p.Index()) != 1 {
+		return nil, errors.New("leaf index out of range")
+	}
+	if tree.store.GetNode(p) == nil {
+		return nil, errors.New("leaf not found")
+	}
+	height := tree.store.GetHeight()
+	proof := &Proof{
+		Hash:     tree.config.Hash,
+		Siblings: make([][][]byte, 0, height),
+	}
+	index := p.Index()
+	for level := uint8(0); level+1 < height; level++ {
+		gpos := NewPosition(level+1, tree.calc.GroupOfNode(index))
+		g := NewGroup(tree.config.Hash, tree.calc, tree.store, gpos).Load(rowSize)
+		siblings := make([][]byte, len(g.nodes))
+		slot := tree.calc.NodeIndexInGroup(index)
+		for i, n := range g.nodes {
+			if n != nil && i != slot {
+				siblings[i] = n.Data
+			}
+		}
+		proof.Siblings = append(proof.Siblings, siblings)
+		index = gpos.Index()
+		rowSize = tree.calc.GroupCount(rowSize)
+	}
+	return proof, nil
+}
+
+// VerifyProof verifies the leaf node with the merkle root using the proof
+func VerifyProof(root []byte, leaf *Node, proof *Proof, branchFactor uint8) bool {
+	if leaf == nil || proof == nil || leaf.Position.Level() != 0 {
+		return false
+	}
+	if !proof.Hash.Available() || branchFactor < 2 {
+		return false
+	}
+	tc := NewTreeCalc(branchFactor)
+	data := leaf.Data
+	index := leaf.Position.Index()
+	for _, siblings := range proof.Siblings {
+		if len(siblings) != int(branchFactor) {
+			return false
+		}
+		slot := tc.NodeIndexInGroup(index)
+		if siblings[slot] != nil {
+			return false
+		}
+		h := proof.Hash.New()
+		for i, sibling := range siblings {
+			if i == slot {
+				h.Write(data)
+			} else if sibling != nil {
+				h.Write(sibling)
+			}
+		}
+		data = h.Sum(nil)
+		index = tc.GroupOfNode(index)
+	}
+	if index.Cmp(big.NewInt(0)) != 0 { // must reach root position
+		return false
+	}
+	return bytes.Equal(root, data)
+}
@@ -0,0 +1,71 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package merkle
+
+import (
+	"crypto"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTree_Proof(t *testing.T) {
+	assert := assert.New(t)
+
+	store := NewMapStore()
+	tree := NewTree(store, Config{Hash: crypto.SHA1, BranchFactor: 3})
+
+	leaves := make([]*Node, 7)
+	for i := range leaves {
+		leaves[i] = &Node{NewPosition(0, big.NewInt(int64(i))), []byte{uint8(i)}}
+	}
+	store.CommitUpdate(tree.Update(leaves, big.NewInt(7)))
+	root := tree.Root().Data
+
+	for _, leaf := range leaves {
+		proof, err := tree.Proof(leaf.Position)
+		assert.NoError(err)
+		assert.Equal(2, len(proof.Siblings))
+		assert.True(VerifyProof(root, leaf, proof, 3))
+
+		b, err := proof.Marshal()
+		assert.NoError(err)
+		proof = new(Proof)
+		assert.NoError(proof.Unmarshal(b))
+		assert.True(VerifyProof(root, leaf, proof, 3), "serialized proof")
+	}
+
+	proof, _ := tree.Proof(leaves[4].Position)
+	assert.Equal([][]byte{[]byte{3}, nil, []byte{5}}, proof.Siblings[0])
+
+	fakeLeaf := &Node{leaves[4].Position, []byte{10}}
+	assert.False(VerifyProof(root, fakeLeaf, proof, 3), "wrong leaf data")
+
+	movedLeaf := &Node{leaves[5].Position, leaves[4].Data}
+	assert.False(VerifyProof(root, movedLeaf, proof, 3), "wrong leaf position")
+
+	assert.False(VerifyProof(root, leaves[4], proof, 2), "wrong branch factor")
+
+	_, err := tree.Proof(NewPosition(0, big.NewInt(7)))
+	assert.Error(err, "leaf index out of range")
+
+	_, err = tree.Proof(NewPosition(1, big.NewInt(0)))
+	assert.Error(err, "not a leaf")
+}
+
+func TestTree_Proof_SingleLeaf(t *testing.T) {
+	assert := assert.New(t)
+
+	store := NewMapStore()
+	tree := NewTree(store, Config{Hash: crypto.SHA1, BranchFactor: 2})
+
+	leaf := &Node{NewPosition(0, big.NewInt(0)), []byte{1}}
+	store.CommitUpdate(tree.Update([]*Node{leaf}, big.NewInt(1)))
+
+	proof, err := tree.Proof(leaf.Position)
+	assert.NoError(err)
+	assert.Empty(proof.Siblings)
+	assert.True(VerifyProof(tree.Root().Data, leaf, proof, 2))
+}
@@ -37,6 +37,11 @@ func NewTree(store Store, config Config) *Tree {
 	return tree
 }
 
+// BranchFactor returns the branch factor of the tree
+func (tree *Tree) BranchFactor() uint8 {
+	return tree.config.BranchFactor
+}
+
 // Root returns the root node of the tree
 func (tree *Tree) Root() *Node {
 	p := NewPosition(tree.store.GetHeight()-1, big.NewInt(0))
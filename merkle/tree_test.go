@@ -126,6 +126,36 @@ func TestTree_Update(t *testing.T) {
 	assert.Equal(n20, store.GetNode(NewPosition(2, big.NewInt(0))))
 }
 
+func TestTree_UpdateEmptiedLeaves(t *testing.T) {
+	assert := assert.New(t)
+
+	store := NewMapStore()
+	tree := NewTree(store, Config{Hash: crypto.SHA1, BranchFactor: 2})
+
+	leaves := make([]*Node, 4)
+	for i := range leaves {
+		leaves[i] = &Node{NewPosition(0, big.NewInt(int64(i))), []byte{uint8(i)}}
+	}
+	store.CommitUpdate(tree.Update(leaves, big.NewInt(4)))
+
+	// empty the leaves of a whole group
+	res := tree.Update([]*Node{
+		{NewPosition(0, big.NewInt(2)), nil},
+		{NewPosition(0, big.NewInt(3)), nil},
+	}, big.NewInt(4))
+	store.CommitUpdate(res)
+
+	assert.Nil(store.GetNode(NewPosition(1, big.NewInt(1))), "parent of emptied leaves")
+
+	// rebuilt without the emptied leaves
+	store2 := NewMapStore()
+	tree2 := NewTree(store2, Config{Hash: crypto.SHA1, BranchFactor: 2})
+	res2 := tree2.Update(leaves[:2], big.NewInt(4))
+
+	assert.Equal(res2.Root.Data, res.Root.Data)
+	assert.Equal(sha1Sum(sha1Sum([]byte{0, 1})), res.Root.Data)
+}
+
 func TestTree_Verify(t *testing.T) {
 	store := NewMapStore()
 	tree := NewTree(store, Config{Hash: crypto.SHA1, BranchFactor: 3})
@@ -154,23 +154,29 @@ func (b *Group) Sum() []byte {
 	}
 	h := b.hashFunc.New()
 	for _, n := range b.nodes {
-		if n != nil {
+		if !n.isEmpty() {
 			h.Write(n.Data)
 		}
 	}
 	return h.Sum(nil)
 }
 
-// IsEmpty checks whether all the child nodes are nil
+// IsEmpty checks whether all the child nodes are nil or emptied,
+// the parent of an empty group is empty as well
 func (b *Group) IsEmpty() bool {
 	for _, n := range b.nodes {
-		if n != nil {
+		if !n.isEmpty() {
 			return false
 		}
 	}
 	return true
 }
 
+// isEmpty checks whether the node is absent or emptied, such as the leaf of a deleted state
+func (n *Node) isEmpty() bool {
+	return n == nil || len(n.Data) == 0
+}
+
 // UpdateResult type
 type UpdateResult struct {
 	LeafCount *big.Int
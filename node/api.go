@@ -9,18 +9,35 @@ import (
 	"io"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/aungmawjj/juria-blockchain/core"
 	"github.com/aungmawjj/juria-blockchain/execution"
 	"github.com/aungmawjj/juria-blockchain/execution/bincc"
+	"github.com/aungmawjj/juria-blockchain/execution/wasmcc"
 	"github.com/aungmawjj/juria-blockchain/logger"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 type nodeAPI struct {
 	node *Node
 }
 
+// StateProofRequest is used to get the merkle proof of a chaincode state
+type StateProofRequest struct {
+	CodeAddr []byte
+	Key      []byte
+}
+
+// Misbehavior is the commited evidence of a validator signing two blocks at the same height
+type Misbehavior struct {
+	Validator []byte
+	Height    uint64
+	Vote      bool // double voting, otherwise conflicting proposals
+	Evidence  *core.Evidence
+}
+
 func serveNodeAPI(node *Node) {
 	api := &nodeAPI{node}
 
@@ -29,20 +46,30 @@ func serveNodeAPI(node *Node) {
 	r := gin.New()
 	r.Use(gin.Recovery())
 
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
 	r.GET("/consensus", api.getConsensusStatus)
+	r.GET("/events", api.streamEvents)
 
 	r.GET("/txpool", api.getTxPoolStatus)
 	r.POST("/transactions", api.submitTX)
+	r.POST("/transactions/simulate", api.simulateTx)
 	r.GET("/transactions/:hash/status", api.getTxStatus)
 	r.GET("/transactions/:hash/commit", api.getTxCommit)
+	r.GET("/transactions/:hash/events", api.getTxEvents)
+	r.GET("/accounts/:sender/nonce", api.getNextNonce)
 
 	r.GET("/blocks/:hash", api.getBlock)
 	r.GET("/blocksbyh/:height", api.getBlockByHeight)
+	r.GET("/evidence", api.getMisbehaviors)
 
 	r.POST("/querystate", api.queryState)
+	r.GET("/chaincodes/:addr/upgrades", api.getCodeUpgrades)
+	r.POST("/stateproof", api.getStateProof)
 
 	r.POST("/bincc", api.uploadBinChainCode)
 	r.Static("/bincc", node.config.ExecutionConfig.BinccDir)
+	r.POST("/wasmcc", api.uploadWasmChainCode)
+	r.Static("/wasmcc", node.config.ExecutionConfig.WasmccDir)
 
 	go func() {
 		err := r.Run(fmt.Sprintf(":%d", node.config.APIPort))
@@ -74,6 +101,26 @@ func (api *nodeAPI) submitTX(c *gin.Context) {
 	c.String(http.StatusOK, "transaction accepted")
 }
 
+// simulateTx executes the tx on the commited state without submitting to txpool,
+// tx without hash is simulated as unsigned tx
+func (api *nodeAPI) simulateTx(c *gin.Context) {
+	tx := core.NewTransaction()
+	if err := c.ShouldBind(tx); err != nil {
+		c.String(http.StatusBadRequest, "cannot parse tx")
+		return
+	}
+	if len(tx.Hash()) == 0 {
+		tx.SetHash(tx.Sum())
+	} else if err := tx.Validate(); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	blk := core.NewBlock().
+		SetHeight(api.node.storage.GetBlockHeight() + 1).
+		SetTimestamp(time.Now().UnixNano())
+	c.JSON(http.StatusOK, api.node.execution.Simulate(blk, tx))
+}
+
 func (api *nodeAPI) queryState(c *gin.Context) {
 	query := new(execution.QueryData)
 	if err := c.ShouldBind(query); err != nil {
@@ -88,6 +135,37 @@ func (api *nodeAPI) queryState(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+func (api *nodeAPI) getCodeUpgrades(c *gin.Context) {
+	addr, err := hex.DecodeString(c.Param("addr"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "cannot parse code address")
+		return
+	}
+	upgrades, err := api.node.execution.GetCodeUpgrades(addr)
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, upgrades)
+}
+
+func (api *nodeAPI) getStateProof(c *gin.Context) {
+	req := new(StateProofRequest)
+	if err := c.ShouldBind(req); err != nil {
+		c.String(http.StatusBadRequest, "cannot parse request")
+		return
+	}
+	key := make([]byte, 0, len(req.CodeAddr)+len(req.Key))
+	key = append(key, req.CodeAddr...)
+	key = append(key, req.Key...)
+	sp, err := api.node.storage.GetStateProof(key)
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, sp)
+}
+
 func (api *nodeAPI) getTxStatus(c *gin.Context) {
 	hash, err := api.getHash(c)
 	if err != nil {
@@ -112,6 +190,35 @@ func (api *nodeAPI) getTxCommit(c *gin.Context) {
 	c.JSON(http.StatusOK, txc)
 }
 
+func (api *nodeAPI) getTxEvents(c *gin.Context) {
+	hash, err := api.getHash(c)
+	if err != nil {
+		c.String(http.StatusBadRequest, "cannot parse hash")
+		return
+	}
+	txc, err := api.node.storage.GetTxCommit(hash)
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, txc.Events())
+}
+
+// getNextNonce gives the nonce expected for the next tx of the sender by the commited txs
+func (api *nodeAPI) getNextNonce(c *gin.Context) {
+	b, err := hex.DecodeString(c.Param("sender"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "cannot parse sender")
+		return
+	}
+	sender, err := core.NewPublicKey(b)
+	if err != nil {
+		c.String(http.StatusBadRequest, "invalid sender public key")
+		return
+	}
+	c.JSON(http.StatusOK, api.node.storage.GetNextNonce(sender))
+}
+
 func (api *nodeAPI) getBlock(c *gin.Context) {
 	hash, err := api.getHash(c)
 	if err != nil {
@@ -146,7 +253,38 @@ func (api *nodeAPI) getBlockByHeight(c *gin.Context) {
 	c.JSON(http.StatusOK, blk)
 }
 
+func (api *nodeAPI) getMisbehaviors(c *gin.Context) {
+	evList, err := api.node.storage.GetEvidenceList()
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	ret := make([]*Misbehavior, len(evList))
+	for i, ev := range evList {
+		ret[i] = &Misbehavior{
+			Validator: ev.Accused().Bytes(),
+			Height:    ev.Height(),
+			Vote:      ev.IsVote(),
+			Evidence:  ev,
+		}
+	}
+	c.JSON(http.StatusOK, ret)
+}
+
 func (api *nodeAPI) uploadBinChainCode(c *gin.Context) {
+	api.uploadChainCode(c, func(r io.Reader) ([]byte, error) {
+		return bincc.StoreCode(api.node.config.ExecutionConfig.BinccDir, r)
+	})
+}
+
+// uploadWasmChainCode stores the wasm module if it's valid for chaincode
+func (api *nodeAPI) uploadWasmChainCode(c *gin.Context) {
+	api.uploadChainCode(c, func(r io.Reader) ([]byte, error) {
+		return wasmcc.StoreCode(api.node.config.ExecutionConfig.WasmccDir, r)
+	})
+}
+
+func (api *nodeAPI) uploadChainCode(c *gin.Context, storeCode func(r io.Reader) ([]byte, error)) {
 	fh, err := c.FormFile("file")
 	if err != nil {
 		c.String(http.StatusBadRequest, "cannot get uploaded file")
@@ -158,7 +296,7 @@ func (api *nodeAPI) uploadBinChainCode(c *gin.Context) {
 		return
 	}
 	defer f.Close()
-	codeID, err := bincc.StoreCode(api.node.config.ExecutionConfig.BinccDir, f)
+	codeID, err := storeCode(f)
 	if err != nil {
 		c.String(http.StatusInternalServerError, err.Error())
 		return
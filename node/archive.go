@@ -0,0 +1,241 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package node
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/aungmawjj/juria-blockchain/core"
+	"github.com/aungmawjj/juria-blockchain/execution"
+	"github.com/aungmawjj/juria-blockchain/storage"
+)
+
+// ExportChain writes the commited blocks from the start height to the archive file
+func ExportChain(config Config, file string, start uint64, w io.Writer) error {
+	db, err := storage.NewDB(path.Join(config.Datadir, "db"))
+	if err != nil {
+		return fmt.Errorf("open db failed, %w", err)
+	}
+	defer db.Close()
+	strg := storage.New(db, config.StorageConfig)
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	header, err := strg.ExportChain(f, start)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "exported blocks: %d - %d\n", header.StartHeight, header.EndHeight)
+	return f.Close()
+}
+
+// ImportChain replays the blocks in the archive file on the node database.
+// The blocks are validated and executed again, and the merkle roots must match the ones in the blocks.
+// The database must be empty or commited up to the height before the archive.
+func ImportChain(config Config, file string, w io.Writer) error {
+	genesis, err := readGenesis(config.Datadir)
+	if err != nil {
+		return fmt.Errorf("read genesis failed, %w", err)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	reader, err := storage.NewArchiveReader(f)
+	if err != nil {
+		return err
+	}
+	db, err := storage.NewDB(path.Join(config.Datadir, "db"))
+	if err != nil {
+		return fmt.Errorf("open db failed, %w", err)
+	}
+	defer db.Close()
+
+	strg := storage.New(db, config.StorageConfig)
+	if err := strg.CheckVersion(); err != nil {
+		return err
+	}
+	imp, err := newChainImporter(strg, genesis, config)
+	if err != nil {
+		return err
+	}
+	if err := imp.checkStartHeight(reader.Header().StartHeight); err != nil {
+		return err
+	}
+	for {
+		entry, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := imp.importBlock(entry); err != nil {
+			return fmt.Errorf("import block %d failed, %w", entry.Block.Height(), err)
+		}
+	}
+	fmt.Fprintf(w, "imported blocks: %d - %d\n",
+		reader.Header().StartHeight, reader.Header().EndHeight)
+	fmt.Fprintf(w, "merkle root: %x\n", imp.strg.GetMerkleRoot())
+	return nil
+}
+
+type chainImporter struct {
+	strg     *storage.Storage
+	exec     *execution.Execution
+	vldStore core.ValidatorStore
+
+	last  *core.Block       // last commited block, nil for empty db
+	roots map[uint64][]byte // merkle roots after commiting the heights
+}
+
+func newChainImporter(strg *storage.Storage, genesis *Genesis, config Config) (*chainImporter, error) {
+	vldStore, err := loadValidatorStore(strg, genesis, config.ExecutionConfig)
+	if err != nil {
+		return nil, err
+	}
+	execConfig := config.ExecutionConfig
+	setGenesisConfig(&execConfig, genesis)
+	execConfig.BinccDir = path.Join(config.Datadir, "bincc")
+	os.Mkdir(execConfig.BinccDir, 0755)
+	execConfig.WasmccDir = path.Join(config.Datadir, "wasmcc")
+	os.Mkdir(execConfig.WasmccDir, 0755)
+
+	imp := &chainImporter{
+		strg:     strg,
+		exec:     execution.New(strg, execConfig),
+		vldStore: vldStore,
+		roots:    make(map[uint64][]byte),
+	}
+	if last, err := strg.GetLastBlock(); err == nil {
+		imp.last = last
+		imp.roots[last.Height()] = strg.GetMerkleRoot()
+	}
+	return imp, nil
+}
+
+func (imp *chainImporter) checkStartHeight(start uint64) error {
+	if imp.last == nil {
+		if start != 0 {
+			return fmt.Errorf("archive must start from genesis block for empty db")
+		}
+		return nil
+	}
+	if start != imp.last.Height()+1 {
+		return fmt.Errorf("archive must start from height %d", imp.last.Height()+1)
+	}
+	return nil
+}
+
+func (imp *chainImporter) importBlock(entry *storage.ArchiveEntry) error {
+	blk := entry.Block
+	if err := imp.verifyBlock(entry); err != nil {
+		return err
+	}
+	txs, old, err := imp.getTxsToExecute(entry)
+	if err != nil {
+		return err
+	}
+	for _, tx := range txs {
+		if err := imp.exec.VerifyTx(tx); err != nil {
+			return fmt.Errorf("verify tx %x failed, %w", tx.Hash(), err)
+		}
+	}
+	bcm, txcs := imp.exec.Execute(blk, txs)
+	bcm.SetOldBlockTxs(old)
+	if err := verifyTxCommits(txcs, entry.TxCommits); err != nil {
+		return err
+	}
+	err = imp.strg.Commit(&storage.CommitData{
+		Block:        blk,
+		QC:           entry.QC,
+		Transactions: txs,
+		BlockCommit:  bcm,
+		TxCommits:    txcs,
+	})
+	if err != nil {
+		return err
+	}
+	imp.last = blk
+	imp.roots[blk.Height()] = imp.strg.GetMerkleRoot()
+	for h := range imp.roots {
+		if h < blk.ExecHeight() { // exec height of next blocks is not lower
+			delete(imp.roots, h)
+		}
+	}
+	return addValidatorEpochs(imp.exec, imp.vldStore)
+}
+
+func (imp *chainImporter) verifyBlock(entry *storage.ArchiveEntry) error {
+	blk := entry.Block
+	if err := blk.Validate(imp.vldStore); err != nil {
+		return fmt.Errorf("invalid block, %w", err)
+	}
+	if err := entry.QC.Validate(imp.vldStore); err != nil {
+		return fmt.Errorf("invalid qc, %w", err)
+	}
+	if !bytes.Equal(entry.QC.BlockHash(), blk.Hash()) {
+		return errors.New("qc does not reference block")
+	}
+	if imp.last != nil && !bytes.Equal(imp.last.Hash(), blk.ParentHash()) {
+		return errors.New("parent hash mismatch")
+	}
+	// block has the merkle root after commiting its exec height
+	if root, ok := imp.roots[blk.ExecHeight()]; ok && !bytes.Equal(root, blk.MerkleRoot()) {
+		return fmt.Errorf("merkle root mismatch at exec height %d", blk.ExecHeight())
+	}
+	return nil
+}
+
+// getTxsToExecute gives the archived txs in block order, txs already commited in older blocks are old
+func (imp *chainImporter) getTxsToExecute(
+	entry *storage.ArchiveEntry,
+) ([]*core.Transaction, [][]byte, error) {
+	archived := make(map[string]*core.Transaction, len(entry.Transactions))
+	for _, tx := range entry.Transactions {
+		if err := tx.Validate(); err != nil {
+			return nil, nil, fmt.Errorf("invalid tx, %w", err)
+		}
+		archived[string(tx.Hash())] = tx
+	}
+	txs := make([]*core.Transaction, 0, len(entry.Transactions))
+	old := make([][]byte, 0)
+	for _, hash := range entry.Block.Transactions() {
+		if imp.strg.HasTx(hash) {
+			old = append(old, hash)
+			continue
+		}
+		tx, found := archived[string(hash)]
+		if !found {
+			return nil, nil, fmt.Errorf("tx %x not found in archive", hash)
+		}
+		txs = append(txs, tx)
+	}
+	if len(txs) != len(entry.Transactions) {
+		return nil, nil, errors.New("archive has txs not executed in block")
+	}
+	return txs, old, nil
+}
+
+// verifyTxCommits compares the results of executed txs with the archived tx commits
+func verifyTxCommits(txcs, archived []*core.TxCommit) error {
+	if len(txcs) != len(archived) {
+		return fmt.Errorf("tx commit count mismatch")
+	}
+	for i, txc := range txcs {
+		if !bytes.Equal(txc.Hash(), archived[i].Hash()) || txc.Error() != archived[i].Error() {
+			return fmt.Errorf("tx %x result mismatch", txc.Hash())
+		}
+	}
+	return nil
+}
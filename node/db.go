@@ -0,0 +1,138 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package node
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/aungmawjj/juria-blockchain/core"
+	"github.com/aungmawjj/juria-blockchain/execution"
+	"github.com/aungmawjj/juria-blockchain/storage"
+)
+
+// VerifyDB checks the integrity of the node database offline and writes the report
+func VerifyDB(config Config, w io.Writer) error {
+	genesis, err := readGenesis(config.Datadir)
+	if err != nil {
+		return fmt.Errorf("read genesis failed, %w", err)
+	}
+	db, err := storage.NewDB(path.Join(config.Datadir, "db"))
+	if err != nil {
+		return fmt.Errorf("open db failed, %w", err)
+	}
+	defer db.Close()
+	strg := storage.New(db, config.StorageConfig)
+	if err := strg.CheckVersion(); err != nil {
+		return err
+	}
+
+	vldStore, err := loadValidatorStore(strg, genesis, config.ExecutionConfig)
+	if err != nil {
+		return err
+	}
+	report, err := strg.VerifyDB(vldStore)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "blocks: %d - %d\n", report.StartHeight, report.Height)
+	fmt.Fprintf(w, "pruned below: %d\n", report.PrunedHeight)
+	fmt.Fprintf(w, "txs: %d\n", report.TxCount)
+	fmt.Fprintf(w, "states: %d\n", report.StateCount)
+	fmt.Fprintf(w, "merkle root: %x\n", report.MerkleRoot)
+	for _, p := range report.Problems {
+		fmt.Fprintln(w, p)
+	}
+	if len(report.Problems) > 0 {
+		return fmt.Errorf("found %d problems", len(report.Problems))
+	}
+	fmt.Fprintln(w, "ok")
+	return nil
+}
+
+// loadValidatorStore gives the validator store with all epochs from the commited governance state
+func loadValidatorStore(
+	strg *storage.Storage, genesis *Genesis, config execution.Config,
+) (core.ValidatorStore, error) {
+	vldStore, err := newGenesisValidatorStore(genesis)
+	if err != nil {
+		return nil, err
+	}
+	setGenesisConfig(&config, genesis)
+	if err := addValidatorEpochs(execution.New(strg, config), vldStore); err != nil {
+		return nil, err
+	}
+	return vldStore, nil
+}
+
+func newGenesisValidatorStore(genesis *Genesis) (core.ValidatorStore, error) {
+	validators := make([]*core.PublicKey, len(genesis.Validators))
+	for i, v := range genesis.Validators {
+		pubKey, err := core.NewPublicKey(v)
+		if err != nil {
+			return nil, fmt.Errorf("parse validator failed, %w", err)
+		}
+		validators[i] = pubKey
+	}
+	blsKeys, err := parseGenesisBLSKeys(genesis)
+	if err != nil {
+		return nil, err
+	}
+	vldStore, err := core.NewGenesisValidatorStore(&core.ValidatorEpoch{
+		Validators: validators,
+		BLSKeys:    blsKeys,
+		Powers:     genesis.Powers,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid genesis validators, %w", err)
+	}
+	return vldStore, nil
+}
+
+func parseGenesisBLSKeys(genesis *Genesis) ([]*core.BLSPublicKey, error) {
+	if len(genesis.BLSKeys) == 0 {
+		return nil, nil
+	}
+	if len(genesis.BLSKeys) != len(genesis.Validators) {
+		return nil, fmt.Errorf("bls keys count must be the same as validators")
+	}
+	blsKeys := make([]*core.BLSPublicKey, len(genesis.Validators))
+	for i, k := range genesis.BLSKeys {
+		if k == nil {
+			continue
+		}
+		pubKey, err := core.NewBLSPublicKey(k.Key)
+		if err != nil {
+			return nil, fmt.Errorf("parse bls key failed, %w", err)
+		}
+		if !pubKey.VerifyPossession(k.Proof) {
+			return nil, fmt.Errorf("validator %d, %w", i, core.ErrInvalidBLSPossession)
+		}
+		blsKeys[i] = pubKey
+	}
+	return blsKeys, nil
+}
+
+func setGenesisConfig(config *execution.Config, genesis *Genesis) {
+	config.GenesisValidators = genesis.Validators
+	config.GenesisBLSKeys = genesis.BLSKeys
+	config.GenesisPowers = genesis.Powers
+}
+
+// addValidatorEpochs adds the epochs from governance state which are not in the store yet
+func addValidatorEpochs(exec *execution.Execution, vldStore core.ValidatorStore) error {
+	epochs, err := exec.GetValidatorEpochs()
+	if err != nil {
+		return fmt.Errorf("load validator epochs failed, %w", err)
+	}
+	for _, epoch := range epochs {
+		err := vldStore.AddEpoch(epoch)
+		if err != nil && !errors.Is(err, core.ErrEpochExists) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package node
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aungmawjj/juria-blockchain/core"
+	"github.com/aungmawjj/juria-blockchain/logger"
+	"github.com/aungmawjj/juria-blockchain/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// stream message types
+const (
+	StreamTypeBlock    = "block"
+	StreamTypeTxCommit = "txCommit"
+	StreamTypeStatus   = "status"
+)
+
+const streamWriteTimeout = 10 * time.Second
+
+// StreamMessage is sent to websocket clients as json
+type StreamMessage struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// streamFilter is parsed from the query params of websocket request
+// eg. /events?types=block,txCommit&sender=<hex>&codeAddr=<hex>
+// all types are streamed if types is empty
+type streamFilter struct {
+	types    map[string]bool
+	sender   []byte
+	codeAddr []byte
+}
+
+func parseStreamFilter(c *gin.Context) (*streamFilter, error) {
+	f := &streamFilter{types: make(map[string]bool)}
+	for _, t := range strings.Split(c.Query("types"), ",") {
+		if t != "" {
+			f.types[t] = true
+		}
+	}
+	var err error
+	if f.sender, err = hex.DecodeString(c.Query("sender")); err != nil {
+		return nil, err
+	}
+	if f.codeAddr, err = hex.DecodeString(c.Query("codeAddr")); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *streamFilter) hasType(t string) bool {
+	return len(f.types) == 0 || f.types[t]
+}
+
+func (f *streamFilter) matchTx(tx *core.Transaction) bool {
+	if len(f.sender) > 0 {
+		if tx.Sender() == nil || !bytes.Equal(f.sender, tx.Sender().Bytes()) {
+			return false
+		}
+	}
+	if len(f.codeAddr) > 0 {
+		codeAddr := tx.CodeAddr()
+		if len(codeAddr) == 0 {
+			codeAddr = tx.Hash() // deployment tx
+		}
+		if !bytes.Equal(f.codeAddr, codeAddr) {
+			return false
+		}
+	}
+	return true
+}
+
+func (api *nodeAPI) streamEvents(c *gin.Context) {
+	filter, err := parseStreamFilter(c)
+	if err != nil {
+		c.String(http.StatusBadRequest, "cannot parse filter")
+		return
+	}
+	conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		logger.I().Warnf("websocket upgrade failed %+v", err)
+		return
+	}
+	defer conn.Close()
+
+	subCommit := api.node.consensus.SubscribeCommit(20)
+	defer subCommit.Unsubscribe()
+
+	subStatus := api.node.consensus.SubscribeStatus(20)
+	defer subStatus.Unsubscribe()
+
+	closed := make(chan struct{})
+	go readUntilClosed(conn, closed)
+
+	for {
+		var msgs []*StreamMessage
+		select {
+		case <-closed:
+			return
+
+		case e := <-subCommit.Events():
+			msgs = filter.commitMessages(e.(*storage.CommitData))
+
+		case e := <-subStatus.Events():
+			if filter.hasType(StreamTypeStatus) {
+				msgs = append(msgs, &StreamMessage{StreamTypeStatus, e})
+			}
+		}
+		for _, msg := range msgs {
+			conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			if err := conn.WriteJSON(msg); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (f *streamFilter) commitMessages(data *storage.CommitData) []*StreamMessage {
+	msgs := make([]*StreamMessage, 0)
+	if f.hasType(StreamTypeBlock) {
+		msgs = append(msgs, &StreamMessage{StreamTypeBlock, data.Block})
+	}
+	if !f.hasType(StreamTypeTxCommit) {
+		return msgs
+	}
+	for i, txc := range data.TxCommits {
+		if i < len(data.Transactions) && f.matchTx(data.Transactions[i]) {
+			msgs = append(msgs, &StreamMessage{StreamTypeTxCommit, txc})
+		}
+	}
+	return msgs
+}
+
+// readUntilClosed discards client messages and handles control frames
+func readUntilClosed(conn *websocket.Conn, closed chan<- struct{}) {
+	defer close(closed)
+	for {
+		if _, _, err := conn.NextReader(); err != nil {
+			return
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"os"
 	"path"
 
+	"github.com/aungmawjj/juria-blockchain/chaincodes/governance"
 	"github.com/aungmawjj/juria-blockchain/core"
 	"github.com/aungmawjj/juria-blockchain/p2p"
 	"github.com/multiformats/go-multiaddr"
@@ -22,10 +23,17 @@ type Peer struct {
 
 type Genesis struct {
 	Validators [][]byte
+
+	// optional, same order as validators, votes are aggregated into bls signatures when given
+	BLSKeys []*governance.BLSKey `json:",omitempty"`
+
+	// optional, same order as validators, every validator has voting power 1 if not given
+	Powers []uint64 `json:",omitempty"`
 }
 
 const (
 	NodekeyFile = "nodekey"
+	BLSKeyFile  = "blskey" // optional
 	GenesisFile = "genesis.json"
 	PeersFile   = "peers.json"
 )
@@ -38,6 +46,18 @@ func readNodeKey(datadir string) (*core.PrivateKey, error) {
 	return core.NewPrivateKey(b)
 }
 
+// readBLSKey gives nil if the node has no bls key file
+func readBLSKey(datadir string) (*core.BLSPrivateKey, error) {
+	b, err := ioutil.ReadFile(path.Join(datadir, BLSKeyFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s, %w", BLSKeyFile, err)
+	}
+	return core.NewBLSPrivateKey(b)
+}
+
 func readGenesis(datadir string) (*Genesis, error) {
 	f, err := os.Open(path.Join(datadir, GenesisFile))
 	if err != nil {
@@ -0,0 +1,42 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package node
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// registerMetrics exposes the txpool and db sizes of the node
+func registerMetrics(node *Node) {
+	txpoolGauge := func(name, help string, value func() int) {
+		promauto.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "juria",
+			Subsystem: "txpool",
+			Name:      name,
+			Help:      help,
+		}, func() float64 { return float64(value()) })
+	}
+	txpoolGauge("total", "Number of txs in pool.",
+		func() int { return node.txpool.GetStatus().Total })
+	txpoolGauge("queue", "Number of txs in queue.",
+		func() int { return node.txpool.GetStatus().Queue })
+	txpoolGauge("pending", "Number of pending txs.",
+		func() int { return node.txpool.GetStatus().Pending })
+	txpoolGauge("gapped", "Number of txs waiting for lower nonces.",
+		func() int { return node.txpool.GetStatus().Gapped })
+
+	dbGauge := func(name, help string, value func(lsm, vlog int64) int64) {
+		promauto.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "juria",
+			Subsystem: "db",
+			Name:      name,
+			Help:      help,
+		}, func() float64 { return float64(value(node.storage.DBSize())) })
+	}
+	dbGauge("lsm_size_bytes", "Size of badger LSM tree files.",
+		func(lsm, vlog int64) int64 { return lsm })
+	dbGauge("vlog_size_bytes", "Size of badger value log files.",
+		func(lsm, vlog int64) int64 { return vlog })
+}
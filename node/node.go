@@ -27,6 +27,7 @@ type Node struct {
 	config Config
 
 	privKey *core.PrivateKey
+	blsKey  *core.BLSPrivateKey // nil if the node has no bls key
 	peers   []*p2p.Peer
 	genesis *Genesis
 
@@ -42,7 +43,7 @@ type Node struct {
 func Run(config Config) {
 	node := new(Node)
 	node.config = config
-	node.setupBinccDir()
+	node.setupCodeDirs()
 	node.setupLogger()
 	node.readFiles()
 	node.setupComponents()
@@ -50,7 +51,7 @@ func Run(config Config) {
 	node.consensus.Start()
 	status := node.consensus.GetStatus()
 	logger.I().Infow("started consensus",
-		"leader", status.LeaderIndex, "bLeaf", status.BLeaf, "qc", status.QCHigh)
+		"view", status.View, "leader", status.LeaderIndex, "bLeaf", status.BLeaf, "qc", status.QCHigh)
 
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
@@ -73,9 +74,11 @@ func (node *Node) setupLogger() {
 	logger.Set(inst.Sugar())
 }
 
-func (node *Node) setupBinccDir() {
+func (node *Node) setupCodeDirs() {
 	node.config.ExecutionConfig.BinccDir = path.Join(node.config.Datadir, "bincc")
 	os.Mkdir(node.config.ExecutionConfig.BinccDir, 0755)
+	node.config.ExecutionConfig.WasmccDir = path.Join(node.config.Datadir, "wasmcc")
+	os.Mkdir(node.config.ExecutionConfig.WasmccDir, 0755)
 }
 
 func (node *Node) readFiles() {
@@ -86,6 +89,14 @@ func (node *Node) readFiles() {
 	}
 	logger.I().Infow("read nodekey", "pubkey", node.privKey.PublicKey())
 
+	node.blsKey, err = readBLSKey(node.config.Datadir)
+	if err != nil {
+		logger.I().Fatalw("read bls key failed", "error", err)
+	}
+	if node.blsKey != nil {
+		logger.I().Infow("read bls key", "pubkey", node.blsKey.PublicKey())
+	}
+
 	node.genesis, err = readGenesis(node.config.Datadir)
 	if err != nil {
 		logger.I().Fatalw("read genesis failed", "error", err)
@@ -108,19 +119,17 @@ func (node *Node) setupComponents() {
 	node.txpool = txpool.New(node.storage, node.execution, node.msgSvc)
 	node.setupConsensus()
 	node.setReqHandlers()
+	registerMetrics(node)
 	serveNodeAPI(node)
 }
 
 func (node *Node) setupValidatorStore() {
-	validators := make([]*core.PublicKey, len(node.genesis.Validators))
-	for i, v := range node.genesis.Validators {
-		pubKey, err := core.NewPublicKey(v)
-		if err != nil {
-			logger.I().Fatalw("parse validator failed", "error", err)
-		}
-		validators[i] = pubKey
+	vldStore, err := newGenesisValidatorStore(node.genesis)
+	if err != nil {
+		logger.I().Fatalw("setup validator store failed", "error", err)
 	}
-	node.vldStore = core.NewValidatorStore(validators)
+	node.vldStore = vldStore
+	setGenesisConfig(&node.config.ExecutionConfig, node.genesis)
 }
 
 func (node *Node) setupStorage() {
@@ -129,6 +138,13 @@ func (node *Node) setupStorage() {
 		logger.I().Fatalw("setup storage failed", "error", err)
 	}
 	node.storage = storage.New(db, node.config.StorageConfig)
+	if err := node.storage.CheckVersion(); err != nil {
+		logger.I().Fatalw("setup storage failed", "error", err)
+	}
+	if err := node.storage.Recover(); err != nil {
+		logger.I().Fatalw("storage recovery failed", "error", err)
+	}
+	node.storage.StartPruner()
 }
 
 func (node *Node) setupHost() {
@@ -152,7 +168,7 @@ func (node *Node) setupHost() {
 
 func (node *Node) setupConsensus() {
 	node.consensus = consensus.New(&consensus.Resources{
-		Signer:    node.privKey,
+		Signer:    node.signer(),
 		VldStore:  node.vldStore,
 		Storage:   node.storage,
 		MsgSvc:    node.msgSvc,
@@ -162,16 +178,32 @@ func (node *Node) setupConsensus() {
 
 }
 
+// signer signs votes with bls key if the node has one
+func (node *Node) signer() core.Signer {
+	if node.blsKey != nil {
+		return core.NewBLSSigner(node.privKey, node.blsKey)
+	}
+	return node.privKey
+}
+
 func (node *Node) setReqHandlers() {
 	node.msgSvc.SetReqHandler(&p2p.BlockReqHandler{
 		GetBlock: node.GetBlock,
 	})
 	node.msgSvc.SetReqHandler(&p2p.BlockByHeightReqHandler{
 		GetBlockByHeight: node.storage.GetBlockByHeight,
+		GetPrunedHeight:  node.storage.GetPrunedHeight,
 	})
 	node.msgSvc.SetReqHandler(&p2p.TxListReqHandler{
 		GetTxList: node.GetTxList,
 	})
+	node.msgSvc.SetReqHandler(&p2p.StateSnapshotReqHandler{
+		GetStateSnapshot: node.storage.GetStateSnapshot,
+	})
+	node.msgSvc.SetReqHandler(&p2p.StateChunkReqHandler{
+		GetStateChunk: node.storage.GetStateChunk,
+		GetNonceChunk: node.storage.GetNonceChunk,
+	})
 }
 
 func (node *Node) GetBlock(hash []byte) (*core.Block, error) {
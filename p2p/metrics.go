@@ -0,0 +1,25 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package p2p
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	metricMsgReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "juria",
+		Subsystem: "p2p",
+		Name:      "msg_received_total",
+		Help:      "Number of messages received by type and peer.",
+	}, []string{"type", "peer"})
+
+	metricMsgSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "juria",
+		Subsystem: "p2p",
+		Name:      "msg_sent_total",
+		Help:      "Number of messages sent by type and peer.",
+	}, []string{"type", "peer"})
+)
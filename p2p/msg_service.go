@@ -27,8 +27,33 @@ const (
 	MsgTypeTxList
 	MsgTypeRequest
 	MsgTypeResponse
+	MsgTypeEvidence
+	MsgTypeTimeout
 )
 
+func (t MsgType) String() string {
+	switch t {
+	case MsgTypeProposal:
+		return "proposal"
+	case MsgTypeVote:
+		return "vote"
+	case MsgTypeNewView:
+		return "newView"
+	case MsgTypeTxList:
+		return "txList"
+	case MsgTypeRequest:
+		return "request"
+	case MsgTypeResponse:
+		return "response"
+	case MsgTypeEvidence:
+		return "evidence"
+	case MsgTypeTimeout:
+		return "timeout"
+	default:
+		return "unknown"
+	}
+}
+
 type msgReceiver func(peer *Peer, data []byte)
 
 type MsgService struct {
@@ -39,6 +64,8 @@ type MsgService struct {
 	voteEmitter     *emitter.Emitter
 	newViewEmitter  *emitter.Emitter
 	txListEmitter   *emitter.Emitter
+	evidenceEmitter *emitter.Emitter
+	timeoutEmitter  *emitter.Emitter
 
 	reqHandlers map[p2p_pb.Request_Type]ReqHandler
 
@@ -74,6 +101,14 @@ func (svc *MsgService) SubscribeTxList(buffer int) *emitter.Subscription {
 	return svc.txListEmitter.Subscribe(buffer)
 }
 
+func (svc *MsgService) SubscribeEvidence(buffer int) *emitter.Subscription {
+	return svc.evidenceEmitter.Subscribe(buffer)
+}
+
+func (svc *MsgService) SubscribeTimeout(buffer int) *emitter.Subscription {
+	return svc.timeoutEmitter.Subscribe(buffer)
+}
+
 func (svc *MsgService) BroadcastProposal(blk *core.Block) error {
 	data, err := blk.Marshal()
 	if err != nil {
@@ -114,6 +149,22 @@ func (svc *MsgService) BroadcastTxList(txList *core.TxList) error {
 	return svc.broadcastData(MsgTypeTxList, data)
 }
 
+func (svc *MsgService) BroadcastEvidence(ev *core.Evidence) error {
+	data, err := ev.Marshal()
+	if err != nil {
+		return err
+	}
+	return svc.broadcastData(MsgTypeEvidence, data)
+}
+
+func (svc *MsgService) BroadcastTimeout(t *core.Timeout) error {
+	data, err := t.Marshal()
+	if err != nil {
+		return err
+	}
+	return svc.broadcastData(MsgTypeTimeout, data)
+}
+
 func (svc *MsgService) RequestBlock(pubKey *core.PublicKey, hash []byte) (*core.Block, error) {
 	respData, err := svc.requestData(pubKey, p2p_pb.Request_Block, hash)
 	if err != nil {
@@ -157,6 +208,51 @@ func (svc *MsgService) RequestTxList(pubKey *core.PublicKey, hashes [][]byte) (*
 	return txList, nil
 }
 
+func (svc *MsgService) RequestStateSnapshot(pubKey *core.PublicKey) (*core.StateSnapshot, error) {
+	respData, err := svc.requestData(pubKey, p2p_pb.Request_StateSnapshot, nil)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := core.NewStateSnapshot()
+	if err := snapshot.Unmarshal(respData); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+func (svc *MsgService) RequestStateChunk(
+	pubKey *core.PublicKey, height uint64, startKey []byte, limit int,
+) ([]*core.StateChange, error) {
+	return svc.requestChunk(pubKey, height, startKey, limit, false)
+}
+
+// RequestNonceChunk requests the next nonces of the senders from the state snapshot of the peer
+func (svc *MsgService) RequestNonceChunk(
+	pubKey *core.PublicKey, height uint64, startKey []byte, limit int,
+) ([]*core.StateChange, error) {
+	return svc.requestChunk(pubKey, height, startKey, limit, true)
+}
+
+func (svc *MsgService) requestChunk(
+	pubKey *core.PublicKey, height uint64, startKey []byte, limit int, nonces bool,
+) ([]*core.StateChange, error) {
+	req := new(p2p_pb.StateChunkReq)
+	req.Height = height
+	req.StartKey = startKey
+	req.Limit = uint32(limit)
+	req.Nonces = nonces
+	reqData, _ := proto.Marshal(req)
+	respData, err := svc.requestData(pubKey, p2p_pb.Request_StateChunk, reqData)
+	if err != nil {
+		return nil, err
+	}
+	scList := core.NewStateChangeList()
+	if err := scList.Unmarshal(respData); err != nil {
+		return nil, err
+	}
+	return *scList, nil
+}
+
 func (svc *MsgService) SetReqHandler(reqHandler ReqHandler) error {
 	if _, found := svc.reqHandlers[reqHandler.Type()]; found {
 		return fmt.Errorf("request handler already set %s", reqHandler.Type())
@@ -170,6 +266,8 @@ func (svc *MsgService) setEmitters() {
 	svc.voteEmitter = emitter.New()
 	svc.newViewEmitter = emitter.New()
 	svc.txListEmitter = emitter.New()
+	svc.evidenceEmitter = emitter.New()
+	svc.timeoutEmitter = emitter.New()
 }
 
 func (svc *MsgService) setMsgReceivers() {
@@ -179,6 +277,8 @@ func (svc *MsgService) setMsgReceivers() {
 	svc.receivers[MsgTypeNewView] = svc.onReceiveNewView
 	svc.receivers[MsgTypeTxList] = svc.onReceiveTxList
 	svc.receivers[MsgTypeRequest] = svc.onReceiveRequest
+	svc.receivers[MsgTypeEvidence] = svc.onReceiveEvidence
+	svc.receivers[MsgTypeTimeout] = svc.onReceiveTimeout
 }
 
 func (svc *MsgService) listenPeer(peer *Peer) {
@@ -188,6 +288,8 @@ func (svc *MsgService) listenPeer(peer *Peer) {
 		if len(msg) < 2 {
 			continue // invalid message
 		}
+		metricMsgReceived.WithLabelValues(
+			MsgType(msg[0]).String(), peer.PublicKey().String()).Inc()
 		if receiver, found := svc.receivers[MsgType(msg[0])]; found {
 			receiver(peer, msg[1:])
 		}
@@ -226,6 +328,22 @@ func (svc *MsgService) onReceiveTxList(peer *Peer, data []byte) {
 	svc.txListEmitter.Emit(txList)
 }
 
+func (svc *MsgService) onReceiveEvidence(peer *Peer, data []byte) {
+	ev := core.NewEvidence()
+	if err := ev.Unmarshal(data); err != nil {
+		return
+	}
+	svc.evidenceEmitter.Emit(ev)
+}
+
+func (svc *MsgService) onReceiveTimeout(peer *Peer, data []byte) {
+	t := core.NewTimeout()
+	if err := t.Unmarshal(data); err != nil {
+		return
+	}
+	svc.timeoutEmitter.Emit(t)
+}
+
 func (svc *MsgService) onReceiveRequest(peer *Peer, data []byte) {
 	req := new(p2p_pb.Request)
 	if err := proto.Unmarshal(data, req); err != nil {
@@ -245,12 +363,12 @@ func (svc *MsgService) onReceiveRequest(peer *Peer, data []byte) {
 		resp.Error = "no handler for request"
 	}
 	b, _ := proto.Marshal(resp)
-	peer.WriteMsg(append([]byte{byte(MsgTypeResponse)}, b...))
+	writeMsg(peer, MsgTypeResponse, b)
 }
 
 func (svc *MsgService) broadcastData(msgType MsgType, data []byte) error {
 	for _, peer := range svc.host.PeerStore().List() {
-		peer.WriteMsg(append([]byte{byte(msgType)}, data...))
+		writeMsg(peer, msgType, data)
 	}
 	return nil
 }
@@ -260,6 +378,11 @@ func (svc *MsgService) sendData(pubKey *core.PublicKey, msgType MsgType, data []
 	if peer == nil {
 		return errors.New("peer not found")
 	}
+	return writeMsg(peer, msgType, data)
+}
+
+func writeMsg(peer *Peer, msgType MsgType, data []byte) error {
+	metricMsgSent.WithLabelValues(msgType.String(), peer.PublicKey().String()).Inc()
 	return peer.WriteMsg(append([]byte{byte(msgType)}, data...))
 }
 
@@ -279,7 +402,7 @@ func (svc *MsgService) requestData(
 	sub := peer.SubscribeMsg()
 	defer sub.Unsubscribe()
 
-	err := peer.WriteMsg(append([]byte{byte(MsgTypeRequest)}, b...))
+	err := writeMsg(peer, MsgTypeRequest, b)
 	if err != nil {
 		return nil, err
 	}
@@ -304,7 +427,7 @@ func (svc *MsgService) waitResponse(sub *emitter.Subscription, seq uint32) ([]by
 				}
 				if resp.Seq == seq {
 					if len(resp.Error) > 0 {
-						return nil, errors.New(resp.Error)
+						return nil, responseError(resp.Error)
 					}
 					return resp.Data, nil
 				}
@@ -312,3 +435,11 @@ func (svc *MsgService) waitResponse(sub *emitter.Subscription, seq uint32) ([]by
 		}
 	}
 }
+
+// responseError restores the known errors from the error message of the response
+func responseError(msg string) error {
+	if msg == core.ErrBlockPruned.Error() {
+		return core.ErrBlockPruned
+	}
+	return errors.New(msg)
+}
@@ -185,6 +185,85 @@ func TestMsgService_BroadcastTxList(t *testing.T) {
 	}
 }
 
+func TestMsgService_BroadcastEvidence(t *testing.T) {
+	assert := assert.New(t)
+
+	svc, raws, _ := setupMsgServiceWithLoopBackPeers()
+	sub := svc.SubscribeEvidence(5)
+	recv := make(chan *core.Evidence, 2)
+	go func() {
+		for e := range sub.Events() {
+			recv <- e.(*core.Evidence)
+		}
+	}()
+
+	priv := core.GenerateKey(nil)
+	qc := core.NewQuorumCert().Build(
+		[]*core.Vote{core.NewBlock().SetHeight(9).Vote(priv)})
+	ev := core.NewProposalEvidence(
+		core.NewBlock().SetHeight(10).SetTimestamp(1).SetQuorumCert(qc).Sign(priv),
+		core.NewBlock().SetHeight(10).SetTimestamp(2).SetQuorumCert(qc).Sign(priv),
+	)
+	err := svc.BroadcastEvidence(ev)
+
+	if !assert.NoError(err) {
+		return
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case recvEv := <-recv:
+			assert.Equal(ev.ID(), recvEv.ID())
+		case <-time.After(time.Second):
+			t.Fatal("evidence not received")
+		}
+	}
+	assertBroadcastRaws(t, raws, MsgTypeEvidence)
+}
+
+// assertBroadcastRaws waits for both loopback peers to receive the same message of the type
+func assertBroadcastRaws(t *testing.T, raws [][]byte, msgType MsgType) {
+	received := assert.Eventually(t, func() bool {
+		return len(raws[0]) > 0 && len(raws[1]) > 0
+	}, time.Second, time.Millisecond)
+	if !received {
+		return
+	}
+	assert.Equal(t, raws[0], raws[1])
+	assert.EqualValues(t, msgType, raws[0][0])
+}
+
+func TestMsgService_BroadcastTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	svc, raws, _ := setupMsgServiceWithLoopBackPeers()
+	sub := svc.SubscribeTimeout(5)
+	recv := make(chan *core.Timeout, 2)
+	go func() {
+		for e := range sub.Events() {
+			recv <- e.(*core.Timeout)
+		}
+	}()
+
+	timeout := core.NewTimeout().SetView(2).Sign(core.GenerateKey(nil))
+	err := svc.BroadcastTimeout(timeout)
+
+	if !assert.NoError(err) {
+		return
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case recvTimeout := <-recv:
+			assert.EqualValues(2, recvTimeout.View())
+			assert.Equal(timeout.Sender(), recvTimeout.Sender())
+		case <-time.After(time.Second):
+			t.Fatal("timeout not received")
+		}
+	}
+	assertBroadcastRaws(t, raws, MsgTypeTimeout)
+}
+
 func TestMsgService_RequestBlock(t *testing.T) {
 	assert := assert.New(t)
 
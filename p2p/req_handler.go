@@ -55,6 +55,8 @@ func (hdlr *BlockReqHandler) HandleReq(sender *core.PublicKey, data []byte) ([]b
 
 type BlockByHeightReqHandler struct {
 	GetBlockByHeight func(height uint64) (*core.Block, error)
+	// blocks below the pruned height don't have txs to sync, optional
+	GetPrunedHeight func() uint64
 }
 
 var _ ReqHandler = (*BlockByHeightReqHandler)(nil)
@@ -69,5 +71,62 @@ func (hdlr *BlockByHeightReqHandler) HandleReq(sender *core.PublicKey, data []by
 	if err != nil {
 		return nil, err
 	}
+	if hdlr.GetPrunedHeight != nil && height < hdlr.GetPrunedHeight() &&
+		len(block.Transactions()) > 0 {
+		return nil, core.ErrBlockPruned
+	}
 	return block.Marshal()
 }
+
+type StateSnapshotReqHandler struct {
+	GetStateSnapshot func() (*core.StateSnapshot, error)
+}
+
+var _ ReqHandler = (*StateSnapshotReqHandler)(nil)
+
+func (hdlr *StateSnapshotReqHandler) Type() p2p_pb.Request_Type {
+	return p2p_pb.Request_StateSnapshot
+}
+
+func (hdlr *StateSnapshotReqHandler) HandleReq(sender *core.PublicKey, data []byte) ([]byte, error) {
+	snapshot, err := hdlr.GetStateSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.Marshal()
+}
+
+// StateChunkSizeLimit bounds the states of a chunk requested by a peer
+const StateChunkSizeLimit = 1000
+
+type StateChunkReqHandler struct {
+	GetStateChunk func(height uint64, startKey []byte, limit int) ([]*core.StateChange, error)
+	GetNonceChunk func(height uint64, startKey []byte, limit int) ([]*core.StateChange, error)
+}
+
+var _ ReqHandler = (*StateChunkReqHandler)(nil)
+
+func (hdlr *StateChunkReqHandler) Type() p2p_pb.Request_Type {
+	return p2p_pb.Request_StateChunk
+}
+
+func (hdlr *StateChunkReqHandler) HandleReq(sender *core.PublicKey, data []byte) ([]byte, error) {
+	req := new(p2p_pb.StateChunkReq)
+	if err := proto.Unmarshal(data, req); err != nil {
+		return nil, err
+	}
+	limit := int(req.Limit)
+	if limit > StateChunkSizeLimit {
+		limit = StateChunkSizeLimit
+	}
+	getChunk := hdlr.GetStateChunk
+	if req.Nonces {
+		getChunk = hdlr.GetNonceChunk
+	}
+	scList, err := getChunk(req.Height, req.StartKey, limit)
+	if err != nil {
+		return nil, err
+	}
+	chunk := core.StateChangeList(scList)
+	return chunk.Marshal()
+}
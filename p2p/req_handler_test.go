@@ -0,0 +1,62 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package p2p
+
+import (
+	"testing"
+
+	"github.com/aungmawjj/juria-blockchain/core"
+	"github.com/aungmawjj/juria-blockchain/p2p/p2p_pb"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestStateChunkReqHandler_Limit(t *testing.T) {
+	assert := assert.New(t)
+
+	var limit int
+	hdlr := &StateChunkReqHandler{
+		GetStateChunk: func(height uint64, startKey []byte, l int) ([]*core.StateChange, error) {
+			limit = l
+			return nil, nil
+		},
+	}
+	req := func(l uint32) []byte {
+		b, _ := proto.Marshal(&p2p_pb.StateChunkReq{Height: 10, Limit: l})
+		return b
+	}
+
+	_, err := hdlr.HandleReq(nil, req(10))
+	assert.NoError(err)
+	assert.Equal(10, limit)
+
+	_, err = hdlr.HandleReq(nil, req(1<<31))
+	assert.NoError(err)
+	assert.Equal(StateChunkSizeLimit, limit, "limit of peer is capped")
+}
+
+func TestStateChunkReqHandler_Nonces(t *testing.T) {
+	assert := assert.New(t)
+
+	states := []*core.StateChange{core.NewStateChange().SetKey([]byte{1})}
+	nonces := []*core.StateChange{core.NewStateChange().SetKey([]byte{2})}
+	hdlr := &StateChunkReqHandler{
+		GetStateChunk: func(height uint64, startKey []byte, l int) ([]*core.StateChange, error) {
+			return states, nil
+		},
+		GetNonceChunk: func(height uint64, startKey []byte, l int) ([]*core.StateChange, error) {
+			return nonces, nil
+		},
+	}
+	handle := func(nonces bool) []byte {
+		b, _ := proto.Marshal(&p2p_pb.StateChunkReq{Height: 10, Limit: 10, Nonces: nonces})
+		resp, err := hdlr.HandleReq(nil, b)
+		assert.NoError(err)
+		chunk := core.NewStateChangeList()
+		assert.NoError(chunk.Unmarshal(resp))
+		return (*chunk)[0].Key()
+	}
+	assert.Equal([]byte{1}, handle(false))
+	assert.Equal([]byte{2}, handle(true))
+}
@@ -0,0 +1,235 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aungmawjj/juria-blockchain/core"
+	"github.com/aungmawjj/juria-blockchain/storage/storage_pb"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	archiveVersion     = 1
+	archiveRecordLimit = 64 * 1024 * 1024
+)
+
+// errors
+var (
+	ErrArchiveVersion   = errors.New("unsupported archive version")
+	ErrArchiveTruncated = errors.New("archive is truncated")
+)
+
+// ArchiveEntry is a commited block with the txs executed in the block
+type ArchiveEntry struct {
+	Block        *core.Block
+	QC           *core.QuorumCert // qc for the block
+	Transactions []*core.Transaction
+	TxCommits    []*core.TxCommit
+}
+
+// ExportChain writes the commited blocks from the start height to the archive.
+// Each record of the archive is a protobuf message prefixed with its size in 4 bytes big endian.
+// The first record is the header and the others are the blocks in height order.
+func (strg *Storage) ExportChain(w io.Writer, start uint64) (*storage_pb.ArchiveHeader, error) {
+	height, err := strg.chainStore.getBlockHeight()
+	if err != nil {
+		return nil, fmt.Errorf("no commited block, %w", err)
+	}
+	if start > height {
+		return nil, fmt.Errorf("start height %d is higher than block height %d", start, height)
+	}
+	if start < strg.GetPrunedHeight() || start < strg.findChainStartHeight(height) {
+		return nil, fmt.Errorf("start height %d, %w", start, core.ErrBlockPruned)
+	}
+	header := &storage_pb.ArchiveHeader{
+		Version:     archiveVersion,
+		StartHeight: start,
+		EndHeight:   height,
+	}
+	bw := bufio.NewWriter(w)
+	if err := writeArchiveRecord(bw, header); err != nil {
+		return nil, err
+	}
+	for h := start; h <= height; h++ {
+		entry, err := strg.getArchiveEntry(h, height)
+		if err != nil {
+			return nil, fmt.Errorf("block %d, %w", h, err)
+		}
+		if err := writeArchiveRecord(bw, entry); err != nil {
+			return nil, err
+		}
+	}
+	return header, bw.Flush()
+}
+
+func (strg *Storage) getArchiveEntry(height, lastHeight uint64) (*storage_pb.ArchiveEntry, error) {
+	blk, err := strg.chainStore.getBlockByHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	qc, err := strg.getCommitedQC(blk, lastHeight)
+	if err != nil {
+		return nil, err
+	}
+	entry := new(storage_pb.ArchiveEntry)
+	if entry.Block, err = blk.Marshal(); err != nil {
+		return nil, err
+	}
+	if entry.QuorumCert, err = qc.Marshal(); err != nil {
+		return nil, err
+	}
+	for _, hash := range blk.Transactions() {
+		txc, err := strg.chainStore.getTxCommit(hash)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(txc.BlockHash(), blk.Hash()) {
+			continue // executed in older block
+		}
+		tx, err := strg.chainStore.getTx(hash)
+		if err != nil {
+			return nil, err
+		}
+		b, err := tx.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		entry.Transactions = append(entry.Transactions, b)
+		if b, err = txc.Marshal(); err != nil {
+			return nil, err
+		}
+		entry.TxCommits = append(entry.TxCommits, b)
+	}
+	return entry, nil
+}
+
+// getCommitedQC gives the last qc for the last block, otherwise the qc in the next block
+func (strg *Storage) getCommitedQC(blk *core.Block, lastHeight uint64) (*core.QuorumCert, error) {
+	if blk.Height() == lastHeight {
+		return strg.chainStore.getLastQC()
+	}
+	next, err := strg.chainStore.getBlockByHeight(blk.Height() + 1)
+	if err != nil {
+		return nil, err
+	}
+	if next.QuorumCert() == nil || !bytes.Equal(next.QuorumCert().BlockHash(), blk.Hash()) {
+		return nil, errors.New("qc not found")
+	}
+	return next.QuorumCert(), nil
+}
+
+// ArchiveReader reads the blocks of a chain archive in height order
+type ArchiveReader struct {
+	reader *bufio.Reader
+	header *storage_pb.ArchiveHeader
+	next   uint64
+}
+
+// NewArchiveReader reads the header of the archive
+func NewArchiveReader(r io.Reader) (*ArchiveReader, error) {
+	ar := &ArchiveReader{
+		reader: bufio.NewReader(r),
+		header: new(storage_pb.ArchiveHeader),
+	}
+	if err := ar.readRecord(ar.header); err != nil {
+		return nil, fmt.Errorf("read archive header failed, %w", err)
+	}
+	if ar.header.Version != archiveVersion {
+		return nil, ErrArchiveVersion
+	}
+	ar.next = ar.header.StartHeight
+	return ar, nil
+}
+
+func (ar *ArchiveReader) Header() *storage_pb.ArchiveHeader {
+	return ar.header
+}
+
+// Next gives the next block in the archive, io.EOF after the end height
+func (ar *ArchiveReader) Next() (*ArchiveEntry, error) {
+	if ar.next > ar.header.EndHeight {
+		return nil, io.EOF
+	}
+	record := new(storage_pb.ArchiveEntry)
+	if err := ar.readRecord(record); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, ErrArchiveTruncated
+		}
+		return nil, err
+	}
+	entry, err := newArchiveEntry(record)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Block.Height() != ar.next {
+		return nil, fmt.Errorf("expected block %d, got %d", ar.next, entry.Block.Height())
+	}
+	ar.next++
+	return entry, nil
+}
+
+func (ar *ArchiveReader) readRecord(msg proto.Message) error {
+	b := make([]byte, 4)
+	if _, err := io.ReadFull(ar.reader, b); err != nil {
+		return err
+	}
+	size := binary.BigEndian.Uint32(b)
+	if size > archiveRecordLimit {
+		return fmt.Errorf("big record size %d", size)
+	}
+	b = make([]byte, size)
+	if _, err := io.ReadFull(ar.reader, b); err != nil {
+		return err
+	}
+	return proto.Unmarshal(b, msg)
+}
+
+func newArchiveEntry(record *storage_pb.ArchiveEntry) (*ArchiveEntry, error) {
+	entry := &ArchiveEntry{
+		Block:        core.NewBlock(),
+		QC:           core.NewQuorumCert(),
+		Transactions: make([]*core.Transaction, len(record.Transactions)),
+		TxCommits:    make([]*core.TxCommit, len(record.TxCommits)),
+	}
+	if err := entry.Block.Unmarshal(record.Block); err != nil {
+		return nil, err
+	}
+	if err := entry.QC.Unmarshal(record.QuorumCert); err != nil {
+		return nil, err
+	}
+	for i, b := range record.Transactions {
+		entry.Transactions[i] = core.NewTransaction()
+		if err := entry.Transactions[i].Unmarshal(b); err != nil {
+			return nil, err
+		}
+	}
+	for i, b := range record.TxCommits {
+		entry.TxCommits[i] = core.NewTxCommit()
+		if err := entry.TxCommits[i].Unmarshal(b); err != nil {
+			return nil, err
+		}
+	}
+	return entry, nil
+}
+
+func writeArchiveRecord(w io.Writer, msg proto.Message) error {
+	b, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	size := make([]byte, 4)
+	binary.BigEndian.PutUint32(size, uint32(len(b)))
+	if _, err := w.Write(size); err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
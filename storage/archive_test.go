@@ -0,0 +1,60 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package storage
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/aungmawjj/juria-blockchain/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorage_ExportChain(t *testing.T) {
+	assert := assert.New(t)
+
+	strg := newTestStorage()
+	blocks, txs := commitTestChain(strg, 4)
+
+	buf := bytes.NewBuffer(nil)
+	header, err := strg.ExportChain(buf, 1)
+	assert.NoError(err)
+	assert.EqualValues(1, header.StartHeight)
+	assert.EqualValues(3, header.EndHeight)
+	archive := buf.Bytes()
+
+	reader, err := NewArchiveReader(bytes.NewReader(archive))
+	assert.NoError(err)
+	assert.EqualValues(1, reader.Header().StartHeight)
+	for i := 1; i < len(blocks); i++ {
+		entry, err := reader.Next()
+		if !assert.NoError(err) {
+			return
+		}
+		assert.Equal(blocks[i].Hash(), entry.Block.Hash())
+		assert.Equal(blocks[i].Hash(), entry.QC.BlockHash())
+		assert.Equal(1, len(entry.Transactions))
+		assert.Equal(txs[i].Hash(), entry.Transactions[0].Hash())
+		assert.Equal(txs[i].Hash(), entry.TxCommits[0].Hash())
+	}
+	_, err = reader.Next()
+	assert.Equal(io.EOF, err)
+
+	reader, _ = NewArchiveReader(bytes.NewReader(archive[:len(archive)-10]))
+	reader.Next()
+	reader.Next()
+	_, err = reader.Next()
+	assert.Equal(ErrArchiveTruncated, err)
+
+	_, err = strg.ExportChain(buf, 4)
+	assert.Error(err, "start height is higher than block height")
+
+	config := DefaultConfig
+	config.RetainBlocks = 2
+	strg.config = config
+	strg.Prune()
+	_, err = strg.ExportChain(buf, 1)
+	assert.ErrorIs(err, core.ErrBlockPruned)
+}
@@ -106,6 +106,19 @@ func (cs *chainStore) getTxCommit(hash []byte) (*core.TxCommit, error) {
 	return txc, nil
 }
 
+// getNextNonce returns zero if the sender has no commited tx
+func (cs *chainStore) getNextNonce(sender []byte) int64 {
+	b, err := cs.getter.Get(concatBytes([]byte{colNextNonceBySender}, sender))
+	if err != nil {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(b))
+}
+
+func (cs *chainStore) hasEvidence(id []byte) bool {
+	return cs.getter.HasKey(concatBytes([]byte{colEvidenceByID}, id))
+}
+
 func (cs *chainStore) setBlockHeight(height uint64) updateFunc {
 	return func(setter setter) error {
 		return setter.Set([]byte{colBlockHeight}, uint64BEBytes(height))
@@ -171,6 +184,35 @@ func (cs *chainStore) setTxs(txs []*core.Transaction) []updateFunc {
 	return ret
 }
 
+// setNextNonces keeps the next nonce of each sender after the highest nonce of its txs
+func (cs *chainStore) setNextNonces(txs []*core.Transaction) []updateFunc {
+	nonces := make(map[string]int64)
+	for _, tx := range txs {
+		sender := string(tx.Sender().Bytes())
+		next, found := nonces[sender]
+		if !found {
+			next = cs.getNextNonce(tx.Sender().Bytes())
+		}
+		if tx.Nonce() >= next {
+			next = tx.Nonce() + 1
+		}
+		nonces[sender] = next
+	}
+	ret := make([]updateFunc, 0, len(nonces))
+	for sender, next := range nonces {
+		ret = append(ret, cs.setNextNonce([]byte(sender), next))
+	}
+	return ret
+}
+
+func (cs *chainStore) setNextNonce(sender []byte, nonce int64) updateFunc {
+	return func(setter setter) error {
+		return setter.Set(
+			concatBytes([]byte{colNextNonceBySender}, sender), uint64BEBytes(uint64(nonce)),
+		)
+	}
+}
+
 func (cs *chainStore) setTxCommits(txCommits []*core.TxCommit) []updateFunc {
 	ret := make([]updateFunc, len(txCommits))
 	for i, txc := range txCommits {
@@ -179,6 +221,26 @@ func (cs *chainStore) setTxCommits(txCommits []*core.TxCommit) []updateFunc {
 	return ret
 }
 
+func (cs *chainStore) setEvidenceList(evList []*core.Evidence) []updateFunc {
+	ret := make([]updateFunc, len(evList))
+	for i, ev := range evList {
+		ret[i] = cs.setEvidence(ev)
+	}
+	return ret
+}
+
+func (cs *chainStore) setEvidence(ev *core.Evidence) updateFunc {
+	return func(setter setter) error {
+		val, err := ev.Marshal()
+		if err != nil {
+			return err
+		}
+		return setter.Set(
+			concatBytes([]byte{colEvidenceByID}, ev.ID()), val,
+		)
+	}
+}
+
 func (cs *chainStore) setTx(tx *core.Transaction) updateFunc {
 	return func(setter setter) error {
 		val, err := tx.Marshal()
@@ -203,6 +265,36 @@ func (cs *chainStore) setTxCommit(txc *core.TxCommit) updateFunc {
 	}
 }
 
+// deleteBlock removes the block with its block commit and the txs commited in the block
+func (cs *chainStore) deleteBlock(blk *core.Block) []updateFunc {
+	ret := make([]updateFunc, 0)
+	ret = append(ret, deleteKey(concatBytes([]byte{colBlockByHash}, blk.Hash())))
+	ret = append(ret, deleteKey(
+		concatBytes([]byte{colBlockHashByHeight}, uint64BEBytes(blk.Height()))))
+	return append(ret, cs.deleteBlockBody(blk)...)
+}
+
+// deleteBlockBody removes the block commit and the txs commited in the block, the block itself is kept
+func (cs *chainStore) deleteBlockBody(blk *core.Block) []updateFunc {
+	ret := make([]updateFunc, 0)
+	ret = append(ret, deleteKey(concatBytes([]byte{colBlockCommitByHash}, blk.Hash())))
+	for _, hash := range blk.Transactions() {
+		txc, err := cs.getTxCommit(hash)
+		if err != nil || !bytes.Equal(txc.BlockHash(), blk.Hash()) {
+			continue // not commited in this block
+		}
+		ret = append(ret, deleteKey(concatBytes([]byte{colTxByHash}, hash)))
+		ret = append(ret, deleteKey(concatBytes([]byte{colTxCommitByHash}, hash)))
+	}
+	return ret
+}
+
+func deleteKey(key []byte) updateFunc {
+	return func(setter setter) error {
+		return setter.Delete(key)
+	}
+}
+
 func uint64BEBytes(val uint64) []byte {
 	buf := bytes.NewBuffer(nil)
 	binary.Write(buf, binary.BigEndian, val)
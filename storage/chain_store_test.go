@@ -80,3 +80,30 @@ func TestChainStore(t *testing.T) {
 	assert.NoError(err)
 	assert.Equal(txc.BlockHash(), txc1.BlockHash())
 }
+
+func TestChainStore_NextNonces(t *testing.T) {
+	assert := assert.New(t)
+	db := createOnMemoryDB()
+	cs := &chainStore{&badgerGetter{db}}
+
+	priv1 := core.GenerateKey(nil)
+	priv2 := core.GenerateKey(nil)
+
+	assert.EqualValues(0, cs.getNextNonce(priv1.PublicKey().Bytes()))
+
+	updateBadgerDB(db, cs.setNextNonces([]*core.Transaction{
+		core.NewTransaction().SetNonce(0).Sign(priv1),
+		core.NewTransaction().SetNonce(2).Sign(priv1),
+		core.NewTransaction().SetNonce(1).Sign(priv1),
+		core.NewTransaction().SetNonce(5).Sign(priv2),
+	}))
+
+	assert.EqualValues(3, cs.getNextNonce(priv1.PublicKey().Bytes()))
+	assert.EqualValues(6, cs.getNextNonce(priv2.PublicKey().Bytes()))
+
+	// lower nonce should not reduce the next nonce
+	updateBadgerDB(db, cs.setNextNonces([]*core.Transaction{
+		core.NewTransaction().SetNonce(1).SetInput([]byte{1}).Sign(priv1),
+	}))
+	assert.EqualValues(3, cs.getNextNonce(priv1.PublicKey().Bytes()))
+}
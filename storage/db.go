@@ -24,6 +24,13 @@ const (
 	colMerkleTreeHeight                      // tree height
 	colMerkleLeafCount                       // tree leaf count
 	colMerkleNodeByPosition                  // tree node value by position
+	colNextNonceBySender                     // next expected tx nonce by sender
+	colStateValueByKeyHeight                 // state value versions by state key and block height
+	colStateHistoryStart                     // lowest block height of state history
+	colPrunedHeight                          // lowest block height with txs and commits
+	colEvidenceByID                          // commited evidence by id
+	colCommitJournal                         // updates of the block commit or state snapshot being written in batches
+	colDBVersion                             // version of the data format
 )
 
 func NewDB(path string) (*badger.DB, error) {
@@ -32,6 +39,7 @@ func NewDB(path string) (*badger.DB, error) {
 
 type setter interface {
 	Set(key, value []byte) error
+	Delete(key []byte) error
 }
 
 type updateFunc func(setter setter) error
@@ -65,6 +73,24 @@ func (bg *badgerGetter) HasKey(key []byte) bool {
 	return err == nil
 }
 
+// txnGetter reads from a badger transaction to get a consistent view of db
+type txnGetter struct {
+	txn *badger.Txn
+}
+
+func (tg *txnGetter) Get(key []byte) ([]byte, error) {
+	item, err := tg.txn.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return item.ValueCopy(nil)
+}
+
+func (tg *txnGetter) HasKey(key []byte) bool {
+	_, err := tg.txn.Get(key)
+	return err == nil
+}
+
 func updateBadgerDB(db *badger.DB, fns []updateFunc) error {
 	return db.Update(func(txn *badger.Txn) error {
 		for _, fn := range fns {
@@ -76,6 +102,19 @@ func updateBadgerDB(db *badger.DB, fns []updateFunc) error {
 	})
 }
 
+// writeBadgerBatch is used for large updates which may not fit in a transaction
+// the updates are not atomic
+func writeBadgerBatch(db *badger.DB, fns []updateFunc) error {
+	wb := db.NewWriteBatch()
+	defer wb.Cancel()
+	for _, fn := range fns {
+		if err := fn(wb); err != nil {
+			return err
+		}
+	}
+	return wb.Flush()
+}
+
 func concatBytes(srcs ...[]byte) []byte {
 	buf := bytes.NewBuffer(nil)
 	size := 0
@@ -0,0 +1,96 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package storage
+
+import (
+	"github.com/aungmawjj/juria-blockchain/storage/storage_pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// journalSetter records the updates instead of writing them
+type journalSetter struct {
+	journal *storage_pb.CommitJournal
+}
+
+func (js *journalSetter) Set(key, value []byte) error {
+	js.journal.Entries = append(js.journal.Entries, &storage_pb.JournalEntry{
+		Key:   key,
+		Value: value,
+	})
+	return nil
+}
+
+func (js *journalSetter) Delete(key []byte) error {
+	js.journal.Entries = append(js.journal.Entries, &storage_pb.JournalEntry{
+		Key:     key,
+		Deleted: true,
+	})
+	return nil
+}
+
+// writeCommitJournal writes the journal of the updates in one transaction,
+// then the updates in batches and removes the journal.
+// The journal is a single large value kept in the value log, so it fits in a transaction.
+// A journal left by a crash is replayed by Recover.
+func (strg *Storage) writeCommitJournal(fns []updateFunc) error {
+	js := &journalSetter{new(storage_pb.CommitJournal)}
+	for _, fn := range fns {
+		if err := fn(js); err != nil {
+			return err
+		}
+	}
+	if err := strg.setCommitJournal(js.journal); err != nil {
+		return err
+	}
+	return strg.applyCommitJournal(js.journal)
+}
+
+func (strg *Storage) setCommitJournal(journal *storage_pb.CommitJournal) error {
+	val, err := proto.Marshal(journal)
+	if err != nil {
+		return err
+	}
+	return updateBadgerDB(strg.db, []updateFunc{
+		func(setter setter) error {
+			return setter.Set([]byte{colCommitJournal}, val)
+		},
+	})
+}
+
+// getCommitJournal returns nil if there is no journal left
+func (strg *Storage) getCommitJournal() (*storage_pb.CommitJournal, error) {
+	if !strg.chainStore.getter.HasKey([]byte{colCommitJournal}) {
+		return nil, nil
+	}
+	val, err := strg.chainStore.getter.Get([]byte{colCommitJournal})
+	if err != nil {
+		return nil, err
+	}
+	journal := new(storage_pb.CommitJournal)
+	if err := proto.Unmarshal(val, journal); err != nil {
+		return nil, err
+	}
+	return journal, nil
+}
+
+// applyCommitJournal writes the updates of the journal, it can be repeated until the journal is removed
+func (strg *Storage) applyCommitJournal(journal *storage_pb.CommitJournal) error {
+	updFns := make([]updateFunc, len(journal.Entries))
+	for i, entry := range journal.Entries {
+		updFns[i] = journalUpdate(entry)
+	}
+	if err := writeBadgerBatch(strg.db, updFns); err != nil {
+		return err
+	}
+	return updateBadgerDB(strg.db, []updateFunc{deleteKey([]byte{colCommitJournal})})
+}
+
+func journalUpdate(entry *storage_pb.JournalEntry) updateFunc {
+	if entry.Deleted {
+		return deleteKey(entry.Key)
+	}
+	return func(setter setter) error {
+		return setter.Set(entry.Key, entry.Value)
+	}
+}
@@ -68,6 +68,9 @@ func (ms *merkleStore) setNodes(nodes []*merkle.Node) []updateFunc {
 }
 
 func (ms *merkleStore) setNode(n *merkle.Node) updateFunc {
+	if len(n.Data) == 0 { // empty leaf of deleted state or its empty parents
+		return deleteKey(concatBytes([]byte{colMerkleNodeByPosition}, n.Position.Bytes()))
+	}
 	return func(setter setter) error {
 		return setter.Set(
 			concatBytes([]byte{colMerkleNodeByPosition}, n.Position.Bytes()), n.Data,
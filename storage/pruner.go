@@ -0,0 +1,132 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+
+	"github.com/aungmawjj/juria-blockchain/logger"
+	"github.com/dgraph-io/badger/v3"
+)
+
+// GetPrunedHeight returns the lowest block height which has its txs and commits.
+// Only the blocks with qcs are kept below the height.
+func (strg *Storage) GetPrunedHeight() uint64 {
+	val, err := strg.chainStore.getter.Get([]byte{colPrunedHeight})
+	if err != nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(val)
+}
+
+// StartPruner runs Prune in background periodically if the retention is configured
+func (strg *Storage) StartPruner() {
+	if strg.config.RetainBlocks == 0 {
+		return
+	}
+	go strg.pruneLoop()
+}
+
+func (strg *Storage) pruneLoop() {
+	ticker := time.NewTicker(strg.config.PruneInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := strg.Prune(); err != nil {
+			logger.I().Errorw("prune storage failed", "error", err)
+		}
+	}
+}
+
+// Prune removes txs, tx commits and block commits of the blocks older than the retained blocks,
+// and the state history before the lowest retained block
+func (strg *Storage) Prune() error {
+	if strg.config.RetainBlocks == 0 {
+		return nil
+	}
+	height, err := strg.chainStore.getBlockHeight()
+	if err != nil || height < strg.config.RetainBlocks {
+		return nil
+	}
+	target := height - strg.config.RetainBlocks + 1
+	start := time.Now()
+	pruned := strg.GetPrunedHeight()
+	for h := pruned; h < target; h++ {
+		if err := strg.pruneBlock(h); err != nil {
+			return err
+		}
+	}
+	if err := strg.pruneStateHistory(target); err != nil {
+		return err
+	}
+	if pruned < target {
+		logger.I().Debugw("pruned storage",
+			"height", target, "blocks", target-pruned, "elapsed", time.Since(start))
+	}
+	return nil
+}
+
+// pruneBlock removes the body of the block and moves the pruned height in one transaction
+func (strg *Storage) pruneBlock(height uint64) error {
+	updFns := make([]updateFunc, 0)
+	if blk, err := strg.chainStore.getBlockByHeight(height); err == nil {
+		updFns = append(updFns, strg.chainStore.deleteBlockBody(blk)...)
+	}
+	updFns = append(updFns, strg.setPrunedHeight(height+1))
+	return updateBadgerDB(strg.db, updFns)
+}
+
+func (strg *Storage) setPrunedHeight(height uint64) updateFunc {
+	return func(setter setter) error {
+		return setter.Set([]byte{colPrunedHeight}, uint64BEBytes(height))
+	}
+}
+
+// pruneStateHistory moves the history start to the height
+// and removes the state versions which are replaced at or before the height
+func (strg *Storage) pruneStateHistory(height uint64) error {
+	start, err := strg.getStateHistoryStart()
+	if err != nil || start >= height {
+		return nil
+	}
+	strg.mtxWriteState.Lock()
+	err = updateBadgerDB(strg.db, []updateFunc{strg.setStateHistoryStart(height)})
+	strg.mtxWriteState.Unlock()
+	if err != nil {
+		return err
+	}
+	keys, err := strg.findReplacedStateVersions(height)
+	if err != nil {
+		return err
+	}
+	updFns := make([]updateFunc, len(keys))
+	for i, key := range keys {
+		updFns[i] = deleteKey(key)
+	}
+	return writeBadgerBatch(strg.db, updFns)
+}
+
+func (strg *Storage) findReplacedStateVersions(height uint64) ([][]byte, error) {
+	keys := make([][]byte, 0)
+	err := strg.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{
+			Prefix: []byte{colStateValueByKeyHeight},
+		})
+		defer it.Close()
+		var prev []byte
+		for it.Rewind(); it.Valid(); it.Next() {
+			key := it.Item().KeyCopy(nil)
+			// versions of a key are sorted by height,
+			// previous version is replaced if the current one is not after the height
+			if prev != nil && bytes.Equal(prev[:len(prev)-8], key[:len(key)-8]) &&
+				binary.BigEndian.Uint64(key[len(key)-8:]) <= height {
+				keys = append(keys, prev)
+			}
+			prev = key
+		}
+		return nil
+	})
+	return keys, err
+}
@@ -0,0 +1,74 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package storage
+
+import (
+	"testing"
+
+	"github.com/aungmawjj/juria-blockchain/core"
+	"github.com/stretchr/testify/assert"
+)
+
+// commitTestChain commits blocks with one tx each, block i sets state key 1 to i
+func commitTestChain(strg *Storage, count int) ([]*core.Block, []*core.Transaction) {
+	priv := core.GenerateKey(nil)
+	blocks := make([]*core.Block, count)
+	txs := make([]*core.Transaction, count)
+	var qc *core.QuorumCert
+	for i := range blocks {
+		txs[i] = core.NewTransaction().SetNonce(int64(i)).Sign(priv)
+		blocks[i] = core.NewBlock().SetHeight(uint64(i))
+		if i > 0 {
+			blocks[i].SetQuorumCert(qc).SetParentHash(blocks[i-1].Hash())
+		}
+		blocks[i].SetTransactions([][]byte{txs[i].Hash()}).Sign(priv)
+		qc = core.NewQuorumCert().Build([]*core.Vote{blocks[i].ProposerVote()})
+		strg.Commit(&CommitData{
+			Block:        blocks[i],
+			QC:           qc,
+			Transactions: []*core.Transaction{txs[i]},
+			TxCommits: []*core.TxCommit{
+				core.NewTxCommit().SetHash(txs[i].Hash()).SetBlockHash(blocks[i].Hash()),
+			},
+			BlockCommit: core.NewBlockCommit().SetHash(blocks[i].Hash()).
+				SetStateChanges([]*core.StateChange{newTestStateChange(1, byte(i))}),
+		})
+	}
+	return blocks, txs
+}
+
+func TestStorage_Prune(t *testing.T) {
+	assert := assert.New(t)
+
+	config := DefaultConfig
+	config.RetainBlocks = 2
+	strg := New(createOnMemoryDB(), config)
+	blocks, txs := commitTestChain(strg, 5)
+
+	assert.EqualValues(0, strg.GetPrunedHeight())
+	assert.NoError(strg.Prune())
+	assert.EqualValues(3, strg.GetPrunedHeight())
+
+	for i, blk := range blocks {
+		_, err := strg.GetBlockByHeight(blk.Height())
+		assert.NoError(err, "blocks are kept")
+		_, err = strg.GetBlockCommit(blk.Hash())
+		assert.Equal(i >= 3, err == nil)
+		assert.Equal(i >= 3, strg.HasTx(txs[i].Hash()))
+		_, err = strg.GetTxCommit(txs[i].Hash())
+		assert.Equal(i >= 3, err == nil)
+	}
+
+	_, err := strg.GetStateAt([]byte{1}, 2)
+	assert.Equal(ErrStateHistoryNotFound, err)
+	value, err := strg.GetStateAt([]byte{1}, 3)
+	assert.NoError(err)
+	assert.Equal([]byte{3}, value)
+	keys, err := strg.findReplacedStateVersions(3)
+	assert.NoError(err)
+	assert.Empty(keys, "replaced versions are removed")
+
+	assert.NoError(strg.Prune(), "nothing to prune")
+	assert.EqualValues(3, strg.GetPrunedHeight())
+}
@@ -0,0 +1,105 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package storage
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/aungmawjj/juria-blockchain/core"
+	"github.com/aungmawjj/juria-blockchain/logger"
+)
+
+// ErrUnrecoverableCommit is returned when a partially commited block can neither be completed nor rolled back
+var ErrUnrecoverableCommit = errors.New("cannot recover partially commited block")
+
+// Recover repairs a block which was written but not commited to the block height.
+// It must be called on startup before reading the last block.
+// Block commits are atomic, or journaled when too large for a transaction,
+// so are state snapshot installs, a journal left by a crash is replayed first.
+// The data written by older versions may be partial,
+// the block is rolled forward if its state changes are already written, otherwise rolled back.
+func (strg *Storage) Recover() error {
+	strg.mtxWriteState.Lock()
+	defer strg.mtxWriteState.Unlock()
+
+	journal, err := strg.getCommitJournal()
+	if err != nil {
+		return err
+	}
+	if journal != nil {
+		logger.I().Warnw("replaying commit journal", "entries", len(journal.Entries))
+		if err := strg.applyCommitJournal(journal); err != nil {
+			return err
+		}
+	}
+	height, err := strg.chainStore.getBlockHeight()
+	if err != nil {
+		return nil // no commited block yet
+	}
+	blk, err := strg.chainStore.getBlockByHeight(height + 1)
+	if err != nil {
+		return nil // no partial block
+	}
+	if strg.isStateCommited(blk) {
+		logger.I().Warnw("rolling forward partially commited block", "height", blk.Height())
+		return updateBadgerDB(strg.db, []updateFunc{strg.chainStore.setBlockHeight(blk.Height())})
+	}
+	logger.I().Warnw("rolling back partially commited block", "height", blk.Height())
+	return strg.rollbackBlock(blk)
+}
+
+// isStateCommited checks whether the merkle tree is updated by the block commit
+func (strg *Storage) isStateCommited(blk *core.Block) bool {
+	bcm, err := strg.chainStore.getBlockCommit(blk.Hash())
+	if err != nil {
+		return false
+	}
+	if len(bcm.StateChanges()) == 0 {
+		return true
+	}
+	return bytes.Equal(bcm.MerkleRoot(), strg.GetMerkleRoot()) &&
+		bytes.Equal(bcm.LeafCount(), strg.merkleStore.getLeafCount().Bytes())
+}
+
+// rollbackBlock removes the block data, restores the last qc to the qc of the commited block
+// and the next nonces of the senders in the block
+func (strg *Storage) rollbackBlock(blk *core.Block) error {
+	lastBlk, err := strg.chainStore.getBlockByHeight(blk.Height() - 1)
+	if err != nil {
+		return err
+	}
+	qc := blk.QuorumCert()
+	if qc == nil || !bytes.Equal(qc.BlockHash(), lastBlk.Hash()) {
+		return ErrUnrecoverableCommit
+	}
+	updFns := strg.restoreNextNonces(blk)
+	updFns = append(updFns, strg.chainStore.deleteBlock(blk)...)
+	updFns = append(updFns, strg.chainStore.setLastQC(qc))
+	return updateBadgerDB(strg.db, updFns)
+}
+
+// restoreNextNonces sets the next nonces of the senders in the block
+// to the previous nonces kept in the block commit.
+// The nonces are left as they are if the block commit was not written.
+func (strg *Storage) restoreNextNonces(blk *core.Block) []updateFunc {
+	bcm, err := strg.chainStore.getBlockCommit(blk.Hash())
+	if err != nil {
+		logger.I().Warnw("next nonces are not restored without block commit", "height", blk.Height())
+		return nil
+	}
+	senders, nonces := bcm.NonceSenders(), bcm.PrevNonces()
+	updFns := make([]updateFunc, 0, len(senders))
+	for i, sender := range senders {
+		if i >= len(nonces) {
+			break
+		}
+		if nonces[i] == 0 {
+			updFns = append(updFns, deleteKey(concatBytes([]byte{colNextNonceBySender}, sender)))
+		} else {
+			updFns = append(updFns, strg.chainStore.setNextNonce(sender, nonces[i]))
+		}
+	}
+	return updFns
+}
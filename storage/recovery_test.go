@@ -0,0 +1,204 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package storage
+
+import (
+	"testing"
+
+	"github.com/aungmawjj/juria-blockchain/core"
+	"github.com/aungmawjj/juria-blockchain/storage/storage_pb"
+	"github.com/dgraph-io/badger/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupPartialCommit commits b0 and writes chain data and block commit of b1 without commiting its height,
+// stateWritten also writes the state and merkle tree of b1
+func setupPartialCommit(stateWritten bool) (*Storage, *core.Block, *core.Block, *core.Transaction) {
+	strg := newTestStorage()
+	priv := core.GenerateKey(nil)
+
+	tx0 := core.NewTransaction().SetNonce(0).Sign(priv)
+	b0 := core.NewBlock().SetHeight(0).SetTransactions([][]byte{tx0.Hash()}).Sign(priv)
+	q0 := core.NewQuorumCert().Build([]*core.Vote{b0.ProposerVote()})
+	strg.Commit(&CommitData{
+		Block:        b0,
+		QC:           q0,
+		Transactions: []*core.Transaction{tx0},
+		TxCommits:    []*core.TxCommit{core.NewTxCommit().SetHash(tx0.Hash()).SetBlockHash(b0.Hash())},
+		BlockCommit: core.NewBlockCommit().SetHash(b0.Hash()).
+			SetStateChanges([]*core.StateChange{
+				core.NewStateChange().SetKey([]byte{1}).SetValue([]byte{10}),
+			}),
+	})
+
+	tx := core.NewTransaction().SetNonce(1).Sign(priv)
+	other := core.NewTransaction().SetNonce(0).Sign(core.GenerateKey(nil))
+	b1 := core.NewBlock().SetHeight(1).SetQuorumCert(q0).
+		SetParentHash(b0.Hash()).SetTransactions([][]byte{tx.Hash(), other.Hash()}).Sign(priv)
+	q1 := core.NewQuorumCert().Build([]*core.Vote{b1.ProposerVote()})
+	data := &CommitData{
+		Block:        b1,
+		QC:           q1,
+		Transactions: []*core.Transaction{tx, other},
+		TxCommits: []*core.TxCommit{
+			core.NewTxCommit().SetHash(tx.Hash()).SetBlockHash(b1.Hash()),
+			core.NewTxCommit().SetHash(other.Hash()).SetBlockHash(b1.Hash()),
+		},
+		BlockCommit: core.NewBlockCommit().SetHash(b1.Hash()).
+			SetStateChanges([]*core.StateChange{
+				core.NewStateChange().SetKey([]byte{1}).SetValue([]byte{20}),
+			}),
+	}
+	strg.computeMerkleUpdate(data)
+	strg.setPrevNonces(data)
+	updFns := strg.chainDataUpdates(data)
+	updFns = append(updFns, strg.chainStore.setBlockCommit(data.BlockCommit))
+	updateBadgerDB(strg.db, updFns)
+	if stateWritten {
+		updateBadgerDB(strg.db, strg.stateMerkleUpdates(data))
+	}
+	return strg, b0, b1, tx
+}
+
+func TestStorage_Recover_Rollback(t *testing.T) {
+	assert := assert.New(t)
+
+	strg, b0, b1, tx := setupPartialCommit(false)
+	other, err := strg.GetTx(b1.Transactions()[1])
+	assert.NoError(err)
+	assert.EqualValues(1, strg.GetNextNonce(other.Sender()))
+	bcm, err := strg.GetBlockCommit(b1.Hash())
+	assert.NoError(err)
+	assert.Equal([][]byte{tx.Sender().Bytes(), other.Sender().Bytes()}, bcm.NonceSenders())
+	assert.Equal([]int64{1, 0}, bcm.PrevNonces(), "next nonces before b1")
+
+	assert.NoError(strg.Recover())
+
+	assert.EqualValues(0, strg.GetBlockHeight())
+	_, err = strg.GetBlock(b1.Hash())
+	assert.Error(err)
+	_, err = strg.GetBlockByHeight(1)
+	assert.Error(err)
+	assert.False(strg.HasTx(tx.Hash()))
+	assert.EqualValues(1, strg.GetNextNonce(tx.Sender()), "next nonce after the tx of b0")
+	assert.EqualValues(0, strg.GetNextNonce(other.Sender()), "sender without commited tx")
+
+	qc, err := strg.GetLastQC()
+	assert.NoError(err)
+	assert.Equal(b0.Hash(), qc.BlockHash())
+	assert.Equal([]byte{10}, strg.GetState([]byte{1}))
+
+	assert.NoError(strg.Recover(), "nothing to recover")
+}
+
+func TestStorage_Recover_RollForward(t *testing.T) {
+	assert := assert.New(t)
+
+	strg, _, b1, tx := setupPartialCommit(true)
+
+	assert.NoError(strg.Recover())
+
+	assert.EqualValues(1, strg.GetBlockHeight())
+	blk, err := strg.GetLastBlock()
+	assert.NoError(err)
+	assert.Equal(b1.Hash(), blk.Hash())
+	assert.True(strg.HasTx(tx.Hash()))
+	assert.EqualValues(2, strg.GetNextNonce(tx.Sender()))
+
+	qc, err := strg.GetLastQC()
+	assert.NoError(err)
+	assert.Equal(b1.Hash(), qc.BlockHash())
+	assert.Equal([]byte{20}, strg.GetState([]byte{1}))
+}
+
+// newSmallTxnStorage creates the storage with transactions limited to about a thousand updates,
+// the db is on disk since large values are not kept in the value log in memory mode
+func newSmallTxnStorage(t *testing.T) *Storage {
+	db, err := badger.Open(badger.DefaultOptions(t.TempDir()).WithMemTableSize(1 << 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return New(db, DefaultConfig)
+}
+
+// newLargeCommitData creates the commit data of the child block of parent, genesis if parent is nil
+func newLargeCommitData(parent *CommitData, count int) *CommitData {
+	priv := core.GenerateKey(nil)
+	blk := core.NewBlock()
+	if parent != nil {
+		blk.SetHeight(parent.Block.Height() + 1).
+			SetParentHash(parent.Block.Hash()).SetQuorumCert(parent.QC)
+	}
+	blk.Sign(priv)
+	height := blk.Height()
+	scList := make([]*core.StateChange, count)
+	for i := range scList {
+		scList[i] = core.NewStateChange().SetKey(uint64BEBytes(uint64(i))).SetValue([]byte{byte(height)})
+	}
+	return &CommitData{
+		Block:       blk,
+		QC:          core.NewQuorumCert().Build([]*core.Vote{blk.ProposerVote()}),
+		BlockCommit: core.NewBlockCommit().SetHash(blk.Hash()).SetStateChanges(scList),
+	}
+}
+
+func TestStorage_Commit_TxnTooBig(t *testing.T) {
+	assert := assert.New(t)
+
+	strg := newSmallTxnStorage(t)
+	data := newLargeCommitData(nil, 3000)
+	err := updateBadgerDB(strg.db, strg.commitDataUpdates(data))
+	assert.ErrorIs(err, badger.ErrTxnTooBig, "commit should not fit in a transaction")
+
+	assert.NoError(strg.Commit(data))
+
+	assert.EqualValues(0, strg.GetBlockHeight())
+	blk, err := strg.GetLastBlock()
+	assert.NoError(err)
+	assert.Equal(data.Block.Hash(), blk.Hash())
+	assert.Equal(data.BlockCommit.MerkleRoot(), strg.GetMerkleRoot())
+	for _, i := range []uint64{0, 1500, 2999} {
+		value, err := strg.VerifyState(uint64BEBytes(i))
+		assert.NoError(err)
+		assert.Equal([]byte{0}, value)
+	}
+	journal, err := strg.getCommitJournal()
+	assert.NoError(err)
+	assert.Nil(journal, "journal should be removed")
+}
+
+func TestStorage_Recover_CommitJournal(t *testing.T) {
+	assert := assert.New(t)
+
+	strg := newSmallTxnStorage(t)
+	d0 := newLargeCommitData(nil, 10)
+	assert.NoError(strg.Commit(d0))
+
+	data := newLargeCommitData(d0, 3000)
+	strg.computeMerkleUpdate(data)
+	js := &journalSetter{new(storage_pb.CommitJournal)}
+	for _, fn := range strg.commitDataUpdates(data) {
+		fn(js)
+	}
+	// crashed after writing the journal
+	assert.NoError(strg.setCommitJournal(js.journal))
+	assert.EqualValues(0, strg.GetBlockHeight())
+
+	assert.NoError(strg.Recover())
+
+	assert.EqualValues(1, strg.GetBlockHeight())
+	blk, err := strg.GetLastBlock()
+	assert.NoError(err)
+	assert.Equal(data.Block.Hash(), blk.Hash())
+	assert.Equal(data.BlockCommit.MerkleRoot(), strg.GetMerkleRoot())
+	value, err := strg.VerifyState(uint64BEBytes(2999))
+	assert.NoError(err)
+	assert.Equal([]byte{1}, value)
+	journal, err := strg.getCommitJournal()
+	assert.NoError(err)
+	assert.Nil(journal)
+
+	assert.NoError(strg.Recover(), "nothing to recover")
+}
@@ -0,0 +1,261 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/aungmawjj/juria-blockchain/core"
+	"github.com/aungmawjj/juria-blockchain/merkle"
+	"github.com/dgraph-io/badger/v3"
+)
+
+// StateSnapshotLifetime is the duration a state snapshot is served
+// before it's replaced with a newer one
+const StateSnapshotLifetime = 10 * time.Minute
+
+var (
+	ErrSnapshotNotFound   = errors.New("state snapshot not found")
+	ErrSnapshotEmpty      = errors.New("empty state snapshot")
+	ErrSnapshotTreeIndex  = errors.New("invalid tree index in state snapshot")
+	ErrSnapshotMerkleRoot = errors.New("state snapshot merkle root mismatch")
+	ErrSnapshotNonce      = errors.New("invalid sender nonce in state snapshot")
+)
+
+// stateSnapshot keeps a read-only db transaction open
+// to serve consistent state chunks at a commited height
+type stateSnapshot struct {
+	info    *core.StateSnapshot
+	txn     *badger.Txn
+	created time.Time
+}
+
+// GetStateSnapshot gives the current state snapshot info,
+// a new snapshot is created at the latest commited height if the current one expired
+func (strg *Storage) GetStateSnapshot() (*core.StateSnapshot, error) {
+	strg.mtxSnapshot.Lock()
+	defer strg.mtxSnapshot.Unlock()
+
+	if strg.snapshot != nil {
+		if time.Since(strg.snapshot.created) < StateSnapshotLifetime {
+			return strg.snapshot.info, nil
+		}
+		strg.snapshot.txn.Discard()
+		strg.snapshot = nil
+	}
+	snapshot, err := strg.newStateSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	strg.snapshot = snapshot
+	return snapshot.info, nil
+}
+
+func (strg *Storage) newStateSnapshot() (*stateSnapshot, error) {
+	strg.mtxWriteState.RLock()
+	txn := strg.db.NewTransaction(false)
+	strg.mtxWriteState.RUnlock()
+
+	getter := &txnGetter{txn}
+	cs := &chainStore{getter}
+	ms := &merkleStore{getter}
+
+	height, err := cs.getBlockHeight()
+	if err != nil {
+		txn.Discard()
+		return nil, err
+	}
+	qc, err := cs.getLastQC()
+	if err != nil {
+		txn.Discard()
+		return nil, err
+	}
+	info := core.NewStateSnapshot().
+		SetHeight(height).
+		SetLeafCount(ms.getLeafCount()).
+		SetLastQC(qc)
+	if root := merkle.NewTree(ms, merkle.Config{}).Root(); root != nil {
+		info.SetMerkleRoot(root.Data)
+	}
+	return &stateSnapshot{
+		info:    info,
+		txn:     txn,
+		created: time.Now(),
+	}, nil
+}
+
+// GetStateChunk gives the state values with tree indexes in key order from the snapshot
+// startKey is exclusive, empty result means no more states
+func (strg *Storage) GetStateChunk(
+	height uint64, startKey []byte, limit int,
+) ([]*core.StateChange, error) {
+	strg.mtxSnapshot.Lock()
+	defer strg.mtxSnapshot.Unlock()
+
+	if strg.snapshot == nil || strg.snapshot.info.Height() != height {
+		return nil, ErrSnapshotNotFound
+	}
+	getter := &txnGetter{strg.snapshot.txn}
+	return strg.snapshot.getChunk(colStateValueByKey, startKey, limit,
+		func(key, value []byte) (*core.StateChange, error) {
+			idx, err := getter.Get(concatBytes([]byte{colMerkleIndexByStateKey}, key))
+			if err != nil {
+				return nil, err
+			}
+			return core.NewStateChange().SetKey(key).SetValue(value).SetTreeIndex(idx), nil
+		})
+}
+
+// GetNonceChunk gives the next nonces of the senders in sender order from the snapshot,
+// the key of a state change is the sender and the value is the big endian nonce.
+// startKey is exclusive, empty result means no more nonces
+func (strg *Storage) GetNonceChunk(
+	height uint64, startKey []byte, limit int,
+) ([]*core.StateChange, error) {
+	strg.mtxSnapshot.Lock()
+	defer strg.mtxSnapshot.Unlock()
+
+	if strg.snapshot == nil || strg.snapshot.info.Height() != height {
+		return nil, ErrSnapshotNotFound
+	}
+	return strg.snapshot.getChunk(colNextNonceBySender, startKey, limit,
+		func(key, value []byte) (*core.StateChange, error) {
+			return core.NewStateChange().SetKey(key).SetValue(value), nil
+		})
+}
+
+// getChunk iterates the keys of the collection after startKey
+func (snapshot *stateSnapshot) getChunk(
+	col byte, startKey []byte, limit int,
+	newEntry func(key, value []byte) (*core.StateChange, error),
+) ([]*core.StateChange, error) {
+	prefix := []byte{col}
+	it := snapshot.txn.NewIterator(badger.IteratorOptions{
+		PrefetchValues: true,
+		PrefetchSize:   100,
+		Prefix:         prefix,
+	})
+	defer it.Close()
+
+	scList := make([]*core.StateChange, 0, limit)
+	for it.Seek(concatBytes(prefix, startKey)); it.Valid() && len(scList) < limit; it.Next() {
+		key := it.Item().KeyCopy(nil)[len(prefix):]
+		if len(startKey) > 0 && bytes.Equal(key, startKey) {
+			continue
+		}
+		value, err := it.Item().ValueCopy(nil)
+		if err != nil {
+			return nil, err
+		}
+		sc, err := newEntry(key, value)
+		if err != nil {
+			return nil, err
+		}
+		scList = append(scList, sc)
+	}
+	return scList, nil
+}
+
+// InstallStateSnapshot replaces the state with the snapshot states at the given block.
+// The merkle tree is rebuilt from the states and its root must match the trusted merkleRoot.
+// Leaf count of the snapshot can be more than the states because the leaves of deleted states are empty.
+// The next nonces of the senders are given by GetNonceChunk, they are not covered by the merkle root.
+// The existing states, merkle tree, state history and nonces are wiped,
+// and the install is written through the commit journal so a crash can't leave mixed state.
+// Chain data before the block is not available after install.
+func (strg *Storage) InstallStateSnapshot(
+	blk *core.Block, qc *core.QuorumCert, scList []*core.StateChange,
+	nonces []*core.StateChange, leafCount *big.Int, merkleRoot []byte,
+) error {
+	if !bytes.Equal(blk.Hash(), qc.BlockHash()) {
+		return errors.New("qc does not reference snapshot block")
+	}
+	if len(scList) == 0 {
+		return ErrSnapshotEmpty
+	}
+	if err := checkSnapshotTreeIndexes(scList, leafCount); err != nil {
+		return err
+	}
+	nonceUpdFns, err := strg.snapshotNonceUpdates(nonces)
+	if err != nil {
+		return err
+	}
+	// the tree is built on an empty store, the nodes of the existing tree must not be mixed in
+	nodes := strg.stateStore.computeUpdatedTreeNodes(scList)
+	upd := merkle.NewTree(merkle.NewMapStore(), strg.merkleConfig()).Update(nodes, leafCount)
+	if !bytes.Equal(upd.Root.Data, merkleRoot) {
+		return ErrSnapshotMerkleRoot
+	}
+
+	strg.mtxWriteState.Lock()
+	defer strg.mtxWriteState.Unlock()
+
+	updFns, err := strg.stateWipeUpdates()
+	if err != nil {
+		return err
+	}
+	updFns = append(updFns, strg.stateStore.commitStateChanges(scList)...)
+	updFns = append(updFns, strg.merkleStore.commitUpdate(upd)...)
+	updFns = append(updFns, strg.snapshotHistoryUpdates(scList, blk.Height())...)
+	updFns = append(updFns, nonceUpdFns...)
+	updFns = append(updFns, strg.chainStore.setBlock(blk)...)
+	updFns = append(updFns, strg.chainStore.setLastQC(qc))
+	updFns = append(updFns, strg.chainStore.setBlockHeight(blk.Height()))
+	// chain data before the snapshot block is not available and its txs are not synced
+	updFns = append(updFns, strg.setPrunedHeight(blk.Height()+1))
+	return strg.writeCommitJournal(updFns)
+}
+
+// stateWipeUpdates deletes the states, merkle nodes, state history and nonces of the db.
+// Tree height, leaf count and history start are overwritten by the install.
+func (strg *Storage) stateWipeUpdates() ([]updateFunc, error) {
+	cols := []byte{
+		colStateValueByKey, colMerkleIndexByStateKey, colMerkleNodeByPosition,
+		colStateValueByKeyHeight, colNextNonceBySender,
+	}
+	updFns := make([]updateFunc, 0)
+	err := strg.db.View(func(txn *badger.Txn) error {
+		for _, col := range cols {
+			it := txn.NewIterator(badger.IteratorOptions{Prefix: []byte{col}})
+			for it.Rewind(); it.Valid(); it.Next() {
+				updFns = append(updFns, deleteKey(it.Item().KeyCopy(nil)))
+			}
+			it.Close()
+		}
+		return nil
+	})
+	return updFns, err
+}
+
+// snapshotNonceUpdates checks the senders and nonces of the snapshot
+func (strg *Storage) snapshotNonceUpdates(nonces []*core.StateChange) ([]updateFunc, error) {
+	updFns := make([]updateFunc, len(nonces))
+	for i, sc := range nonces {
+		if _, err := core.NewPublicKey(sc.Key()); err != nil || len(sc.Value()) != 8 {
+			return nil, ErrSnapshotNonce
+		}
+		updFns[i] = strg.chainStore.setNextNonce(sc.Key(), int64(binary.BigEndian.Uint64(sc.Value())))
+	}
+	return updFns, nil
+}
+
+// tree indexes must be unique and less than leaf count
+func checkSnapshotTreeIndexes(scList []*core.StateChange, leafCount *big.Int) error {
+	indexes := make(map[string]struct{}, len(scList))
+	for _, sc := range scList {
+		idx := big.NewInt(0).SetBytes(sc.TreeIndex())
+		if leafCount.Cmp(idx) != 1 {
+			return ErrSnapshotTreeIndex
+		}
+		if _, found := indexes[idx.String()]; found {
+			return ErrSnapshotTreeIndex
+		}
+		indexes[idx.String()] = struct{}{}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/aungmawjj/juria-blockchain/core"
+	"github.com/dgraph-io/badger/v3"
+)
+
+// errors
+var (
+	ErrHeightNotCommited    = errors.New("block height is not commited yet")
+	ErrStateHistoryNotFound = errors.New("state history is not available at block height")
+)
+
+// GetStateAt gives the state value after commiting the block at the given height.
+// History is kept from the first block commited with history (or the installed snapshot height).
+func (strg *Storage) GetStateAt(key []byte, height uint64) ([]byte, error) {
+	strg.mtxWriteState.RLock()
+	defer strg.mtxWriteState.RUnlock()
+
+	commited, err := strg.chainStore.getBlockHeight()
+	if err != nil || height > commited {
+		return nil, ErrHeightNotCommited
+	}
+	start, err := strg.getStateHistoryStart()
+	if err != nil || height < start {
+		return nil, ErrStateHistoryNotFound
+	}
+	var value []byte
+	prefix := stateHistoryPrefix(key)
+	err = strg.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{
+			Reverse: true,
+			Prefix:  prefix,
+		})
+		defer it.Close()
+		// reverse seek gives the latest version at or before the height
+		it.Seek(concatBytes(prefix, uint64BEBytes(height)))
+		if !it.Valid() {
+			return nil // state didn't exist at the height
+		}
+		var err error
+		value, err = it.Item().ValueCopy(nil)
+		return err
+	})
+	return value, err
+}
+
+// stateHistoryUpdates keeps the new state values as versions at the block height.
+// Deleted states are kept as empty versions.
+// For states commited before history was kept, the previous value is stored at the start height.
+func (strg *Storage) stateHistoryUpdates(data *CommitData) []updateFunc {
+	height := data.Block.Height()
+	start, err := strg.getStateHistoryStart()
+	updFns := make([]updateFunc, 0)
+	if err != nil {
+		start = height
+		updFns = append(updFns, strg.setStateHistoryStart(start))
+	}
+	for _, sc := range data.BlockCommit.StateChanges() {
+		if sc.PrevValue() != nil && start < height && !strg.hasStateHistory(sc.Key()) {
+			updFns = append(updFns, setStateVersion(sc.Key(), sc.PrevValue(), start))
+		}
+		updFns = append(updFns, setStateVersion(sc.Key(), sc.Value(), height))
+	}
+	return updFns
+}
+
+// snapshotHistoryUpdates restarts the history at the snapshot height
+func (strg *Storage) snapshotHistoryUpdates(scList []*core.StateChange, height uint64) []updateFunc {
+	updFns := make([]updateFunc, 0, len(scList)+1)
+	for _, sc := range scList {
+		updFns = append(updFns, setStateVersion(sc.Key(), sc.Value(), height))
+	}
+	return append(updFns, strg.setStateHistoryStart(height))
+}
+
+func (strg *Storage) hasStateHistory(key []byte) bool {
+	found := false
+	strg.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: stateHistoryPrefix(key)})
+		defer it.Close()
+		it.Rewind()
+		found = it.Valid()
+		return nil
+	})
+	return found
+}
+
+func (strg *Storage) getStateHistoryStart() (uint64, error) {
+	val, err := strg.chainStore.getter.Get([]byte{colStateHistoryStart})
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(val), nil
+}
+
+func (strg *Storage) setStateHistoryStart(height uint64) updateFunc {
+	return func(setter setter) error {
+		return setter.Set([]byte{colStateHistoryStart}, uint64BEBytes(height))
+	}
+}
+
+func setStateVersion(key, value []byte, height uint64) updateFunc {
+	return func(setter setter) error {
+		return setter.Set(concatBytes(stateHistoryPrefix(key), uint64BEBytes(height)), value)
+	}
+}
+
+// key length is prefixed so that the versions of a key don't mix with longer keys
+func stateHistoryPrefix(key []byte) []byte {
+	keyLen := make([]byte, 4)
+	binary.BigEndian.PutUint32(keyLen, uint32(len(key)))
+	return concatBytes([]byte{colStateValueByKeyHeight}, keyLen, key)
+}
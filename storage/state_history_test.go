@@ -0,0 +1,89 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package storage
+
+import (
+	"testing"
+
+	"github.com/aungmawjj/juria-blockchain/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func commitTestStates(strg *Storage, priv core.Signer, height uint64, scList ...*core.StateChange) {
+	blk := core.NewBlock().SetHeight(height).Sign(priv)
+	strg.Commit(&CommitData{
+		Block:       blk,
+		QC:          core.NewQuorumCert().Build([]*core.Vote{blk.ProposerVote()}),
+		BlockCommit: core.NewBlockCommit().SetHash(blk.Hash()).SetStateChanges(scList),
+	})
+}
+
+func newTestStateChange(key, value byte) *core.StateChange {
+	return core.NewStateChange().SetKey([]byte{key}).SetValue([]byte{value})
+}
+
+func TestStorage_GetStateAt(t *testing.T) {
+	assert := assert.New(t)
+
+	strg := newTestStorage()
+	priv := core.GenerateKey(nil)
+
+	_, err := strg.GetStateAt([]byte{1}, 0)
+	assert.Equal(ErrHeightNotCommited, err)
+
+	commitTestStates(strg, priv, 0, newTestStateChange(1, 10))
+	commitTestStates(strg, priv, 1, newTestStateChange(2, 20))
+	commitTestStates(strg, priv, 2, newTestStateChange(1, 11))
+	commitTestStates(strg, priv, 3)
+
+	tests := []struct {
+		key    byte
+		height uint64
+		value  []byte
+	}{
+		{1, 0, []byte{10}},
+		{1, 1, []byte{10}},
+		{1, 2, []byte{11}},
+		{1, 3, []byte{11}},
+		{2, 0, nil},
+		{2, 1, []byte{20}},
+		{2, 3, []byte{20}},
+		{3, 3, nil},
+	}
+	for _, tt := range tests {
+		value, err := strg.GetStateAt([]byte{tt.key}, tt.height)
+		assert.NoError(err)
+		assert.Equal(tt.value, value, "key %d at %d", tt.key, tt.height)
+	}
+
+	_, err = strg.GetStateAt([]byte{1}, 4)
+	assert.Equal(ErrHeightNotCommited, err)
+}
+
+func TestStorage_GetStateAt_LateStart(t *testing.T) {
+	assert := assert.New(t)
+
+	strg := newTestStorage()
+	priv := core.GenerateKey(nil)
+
+	commitTestStates(strg, priv, 0, newTestStateChange(1, 10))
+	// history was not kept before height 1
+	updateBadgerDB(strg.db, []updateFunc{
+		deleteKey(concatBytes(stateHistoryPrefix([]byte{1}), uint64BEBytes(0))),
+		deleteKey([]byte{colStateHistoryStart}),
+	})
+	commitTestStates(strg, priv, 1, newTestStateChange(2, 20))
+	commitTestStates(strg, priv, 2, newTestStateChange(1, 11))
+
+	_, err := strg.GetStateAt([]byte{1}, 0)
+	assert.Equal(ErrStateHistoryNotFound, err)
+
+	value, err := strg.GetStateAt([]byte{1}, 1)
+	assert.NoError(err)
+	assert.Equal([]byte{10}, value, "previous value before history start")
+
+	value, err = strg.GetStateAt([]byte{1}, 2)
+	assert.NoError(err)
+	assert.Equal([]byte{11}, value)
+}
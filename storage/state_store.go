@@ -6,6 +6,7 @@ package storage
 import (
 	"bytes"
 	"crypto"
+	"encoding/binary"
 	"math/big"
 	"sort"
 	"sync"
@@ -41,7 +42,7 @@ func (ss *stateStore) setNewTreeIndexes(scList []*core.StateChange, prevLC *big.
 	for i, sc := range scList {
 		if sc.PrevTreeIndex() != nil {
 			sc.SetTreeIndex(sc.PrevTreeIndex())
-		} else {
+		} else if !sc.Deleted() { // deleted state without leaf doesn't need a tree index
 			key := string(sc.Key())
 			newKeys = append(newKeys, key)
 			scByKey[key] = i
@@ -64,6 +65,17 @@ func setLeafIndex(sc *core.StateChange, idx *big.Int) {
 	sc.SetTreeIndex(idxB)
 }
 
+// treeLeafChanges gives the state changes which update the merkle tree leaves
+func treeLeafChanges(scList []*core.StateChange) []*core.StateChange {
+	ret := make([]*core.StateChange, 0, len(scList))
+	for _, sc := range scList {
+		if sc.TreeIndex() != nil {
+			ret = append(ret, sc)
+		}
+	}
+	return ret
+}
+
 func (ss *stateStore) computeUpdatedTreeNodes(scList []*core.StateChange) []*merkle.Node {
 	nodes := make([]*merkle.Node, len(scList))
 	jobs := make(chan int, ss.concurrentLimit)
@@ -89,14 +101,24 @@ func (ss *stateStore) worker(
 		sc := scList[i]
 		nodes[i] = &merkle.Node{
 			Position: merkle.NewPosition(0, big.NewInt(0).SetBytes(sc.TreeIndex())),
-			Data:     ss.sumStateValue(sc.Value()),
+		}
+		if !sc.Deleted() { // leaf of deleted state is emptied
+			nodes[i].Data = ss.sumState(sc.Key(), sc.Value())
 		}
 		wg.Done()
 	}
 }
 
-func (ss *stateStore) sumStateValue(value []byte) []byte {
-	h := ss.hashFunc.New()
+func (ss *stateStore) sumState(key, value []byte) []byte {
+	return sumStateLeaf(ss.hashFunc, key, value)
+}
+
+// sumStateLeaf gives the merkle leaf data of a state.
+// The key is bound to the value so that the proof of a value cannot be used for another key.
+func sumStateLeaf(hashFunc crypto.Hash, key, value []byte) []byte {
+	h := hashFunc.New()
+	binary.Write(h, binary.BigEndian, uint64(len(key)))
+	h.Write(key)
 	h.Write(value)
 	return h.Sum(nil)
 }
@@ -111,6 +133,11 @@ func (ss *stateStore) commitStateChanges(scList []*core.StateChange) []updateFun
 
 func (ss *stateStore) commitStateChange(sc *core.StateChange) []updateFunc {
 	ret := make([]updateFunc, 0)
+	if sc.Deleted() {
+		// tree index of deleted state is not reused, its leaf stays empty
+		ret = append(ret, deleteKey(concatBytes([]byte{colStateValueByKey}, sc.Key())))
+		return append(ret, deleteKey(concatBytes([]byte{colMerkleIndexByStateKey}, sc.Key())))
+	}
 	ret = append(ret, ss.setState(sc.Key(), sc.Value()))
 	if sc.PrevTreeIndex() == nil || !bytes.Equal(sc.PrevTreeIndex(), sc.TreeIndex()) {
 		ret = append(ret, ss.setTreeIndex(sc.Key(), sc.TreeIndex()))
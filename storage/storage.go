@@ -4,7 +4,10 @@
 package storage
 
 import (
+	"bytes"
 	"crypto"
+	"errors"
+	"fmt"
 	"math/big"
 	"sync"
 	"time"
@@ -25,42 +28,109 @@ type CommitData struct {
 	merkleUpdate *merkle.UpdateResult
 }
 
+// errors
+var (
+	ErrStateNotCertified = errors.New("merkle root is not certified by commited blocks yet")
+	ErrInvalidStateProof = errors.New("invalid state proof")
+	ErrStateNotVerified  = errors.New("state is not verified by merkle tree")
+)
+
+// number of latest commited blocks to find the block with the merkle root of a state proof
+const stateProofScanLimit = 10
+
+// StateProof is the merkle inclusion proof of a state value
+// leaf data of the state is the hash of the key and value.
+// The merkle root is certified by the block and its qc,
+// it's the state after executing the block at the exec height of the block.
+type StateProof struct {
+	Key          []byte           `json:"key"`
+	Value        []byte           `json:"value"`
+	TreeIndex    []byte           `json:"treeIndex"`
+	Proof        *merkle.Proof    `json:"proof"`
+	BranchFactor uint8            `json:"branchFactor"`
+	MerkleRoot   []byte           `json:"merkleRoot"`
+	Block        *core.Block      `json:"block"`
+	QC           *core.QuorumCert `json:"qc"`
+}
+
+// Verify checks the state value against the merkle root of the proof,
+// and the merkle root against the block referenced by the qc.
+// The qc signatures are checked by Validate with the validator set.
+func (sp *StateProof) Verify() bool {
+	if sp.Proof == nil || !sp.Proof.Hash.Available() || sp.Block == nil || sp.QC == nil {
+		return false
+	}
+	if !bytes.Equal(sp.Block.Sum(), sp.Block.Hash()) ||
+		!bytes.Equal(sp.Block.MerkleRoot(), sp.MerkleRoot) ||
+		!bytes.Equal(sp.QC.BlockHash(), sp.Block.Hash()) {
+		return false
+	}
+	leaf := &merkle.Node{
+		Data:     sumStateLeaf(sp.Proof.Hash, sp.Key, sp.Value),
+		Position: merkle.NewPosition(0, big.NewInt(0).SetBytes(sp.TreeIndex)),
+	}
+	return merkle.VerifyProof(sp.MerkleRoot, leaf, sp.Proof, sp.BranchFactor)
+}
+
+// Validate verifies the proof and the qc signatures of the validators
+func (sp *StateProof) Validate(vs core.ValidatorStore) error {
+	if !sp.Verify() {
+		return ErrInvalidStateProof
+	}
+	return sp.QC.Validate(vs)
+}
+
 type Config struct {
 	MerkleBranchFactor uint8
 	ConcurrentLimit    int
+
+	// number of latest blocks to keep txs and commits, zero keeps all.
+	// blocks and qcs are always kept
+	RetainBlocks  uint64
+	PruneInterval time.Duration
 }
 
 var DefaultConfig = Config{
 	MerkleBranchFactor: 8,
 	ConcurrentLimit:    20,
+	PruneInterval:      time.Minute,
 }
 
 type Storage struct {
+	config      Config
 	db          *badger.DB
 	chainStore  *chainStore
 	stateStore  *stateStore
 	merkleStore *merkleStore
 	merkleTree  *merkle.Tree
 
-	// for writeStateTree and VerifyState
+	// for writeCommitData, VerifyState, GetStateProof and state snapshots
 	mtxWriteState sync.RWMutex
+
+	snapshot    *stateSnapshot
+	mtxSnapshot sync.Mutex
 }
 
 func New(db *badger.DB, config Config) *Storage {
 	strg := new(Storage)
+	strg.config = config
 	strg.db = db
 	getter := &badgerGetter{db}
 	strg.chainStore = &chainStore{getter}
 	strg.stateStore = &stateStore{getter, crypto.SHA3_256, config.ConcurrentLimit}
 	strg.merkleStore = &merkleStore{getter}
-	strg.merkleTree = merkle.NewTree(strg.merkleStore, merkle.Config{
-		Hash:            crypto.SHA3_256,
-		BranchFactor:    config.MerkleBranchFactor,
-		ConcurrentLimit: config.ConcurrentLimit,
-	})
+	strg.merkleTree = merkle.NewTree(strg.merkleStore, strg.merkleConfig())
 	return strg
 }
 
+func (strg *Storage) merkleConfig() merkle.Config {
+	return merkle.Config{
+		Hash:            crypto.SHA3_256,
+		BranchFactor:    strg.config.MerkleBranchFactor,
+		ConcurrentLimit: strg.config.ConcurrentLimit,
+	}
+}
+
 func (strg *Storage) Commit(data *CommitData) error {
 	return strg.commit(data)
 }
@@ -98,6 +168,46 @@ func (strg *Storage) HasTx(hash []byte) bool {
 	return strg.chainStore.hasTx(hash)
 }
 
+// HasEvidence checks whether the evidence is already commited in a block
+func (strg *Storage) HasEvidence(id []byte) bool {
+	return strg.chainStore.hasEvidence(id)
+}
+
+// GetEvidenceList returns the commited evidence of the misbehaving validators, ordered by id
+func (strg *Storage) GetEvidenceList() ([]*core.Evidence, error) {
+	prefix := []byte{colEvidenceByID}
+	ret := make([]*core.Evidence, 0)
+	err := strg.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{
+			PrefetchValues: true,
+			PrefetchSize:   100,
+			Prefix:         prefix,
+		})
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			val, err := it.Item().ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			ev := core.NewEvidence()
+			if err := ev.Unmarshal(val); err != nil {
+				return err
+			}
+			ret = append(ret, ev)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// GetNextNonce returns the nonce expected for the next tx of the sender
+func (strg *Storage) GetNextNonce(sender *core.PublicKey) int64 {
+	return strg.chainStore.getNextNonce(sender.Bytes())
+}
+
 func (strg *Storage) GetTxCommit(hash []byte) (*core.TxCommit, error) {
 	return strg.chainStore.getTxCommit(hash)
 }
@@ -106,27 +216,129 @@ func (strg *Storage) GetState(key []byte) []byte {
 	return strg.stateStore.getStateNotFoundNil(key)
 }
 
-func (strg *Storage) VerifyState(key []byte) []byte {
+// RangeState calls fn for the states with keys in [start, end) in key order until fn returns false.
+// empty end means no upper bound
+func (strg *Storage) RangeState(start, end []byte, fn func(key, value []byte) bool) {
+	prefix := []byte{colStateValueByKey}
+	strg.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{
+			PrefetchValues: true,
+			PrefetchSize:   100,
+			Prefix:         prefix,
+		})
+		defer it.Close()
+		for it.Seek(concatBytes(prefix, start)); it.Valid(); it.Next() {
+			key := it.Item().KeyCopy(nil)[len(prefix):]
+			if len(end) > 0 && bytes.Compare(key, end) >= 0 {
+				return nil
+			}
+			value, err := it.Item().ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			if !fn(key, value) {
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+// VerifyState gives the state value verified with the merkle tree, nil if the state is not found.
+// It fails with ErrStateNotVerified if the value does not match the merkle tree.
+func (strg *Storage) VerifyState(key []byte) ([]byte, error) {
 	strg.mtxWriteState.RLock()
 	defer strg.mtxWriteState.RUnlock()
 
 	value, err := strg.stateStore.getState(key)
+	if err == badger.ErrKeyNotFound {
+		return nil, nil
+	}
 	if err != nil {
-		// state not found
-		return nil
+		return nil, err
 	}
 	merkleIdx, err := strg.stateStore.getMerkleIndex(key)
 	if err != nil {
-		panic("failed to get state merkle index")
+		return nil, fmt.Errorf("failed to get state merkle index, %w", err)
 	}
 	node := &merkle.Node{
-		Data:     strg.stateStore.sumStateValue(value),
+		Data:     strg.stateStore.sumState(key, value),
 		Position: merkle.NewPosition(0, big.NewInt(0).SetBytes(merkleIdx)),
 	}
 	if !strg.merkleTree.Verify([]*merkle.Node{node}) {
-		panic("merkle verification failed")
+		return nil, ErrStateNotVerified
+	}
+	return value, nil
+}
+
+// GetStateProof gives the state value with merkle proof at the latest commited block.
+// It fails with ErrStateNotCertified until a block with the merkle root is commited.
+func (strg *Storage) GetStateProof(key []byte) (*StateProof, error) {
+	strg.mtxWriteState.RLock()
+	defer strg.mtxWriteState.RUnlock()
+
+	value, err := strg.stateStore.getState(key)
+	if err != nil {
+		return nil, err
+	}
+	merkleIdx, err := strg.stateStore.getMerkleIndex(key)
+	if err != nil {
+		return nil, err
+	}
+	proof, err := strg.merkleTree.Proof(
+		merkle.NewPosition(0, big.NewInt(0).SetBytes(merkleIdx)))
+	if err != nil {
+		return nil, err
+	}
+	mroot := strg.GetMerkleRoot()
+	blk, qc, err := strg.getCertifiedBlock(mroot)
+	if err != nil {
+		return nil, err
+	}
+	return &StateProof{
+		Key:          key,
+		Value:        value,
+		TreeIndex:    merkleIdx,
+		Proof:        proof,
+		BranchFactor: strg.merkleTree.BranchFactor(),
+		MerkleRoot:   mroot,
+		Block:        blk,
+		QC:           qc,
+	}, nil
+}
+
+// getCertifiedBlock finds the latest commited block with the merkle root and its qc.
+// The merkle root of the latest commited block is given by a later block
+// since blocks carry the merkle root of their exec height.
+func (strg *Storage) getCertifiedBlock(mroot []byte) (*core.Block, *core.QuorumCert, error) {
+	height, err := strg.chainStore.getBlockHeight()
+	if err != nil {
+		return nil, nil, err
 	}
-	return value
+	qc, err := strg.chainStore.getLastQC()
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := 0; i < stateProofScanLimit; i++ {
+		blk, err := strg.chainStore.getBlockByHeight(height)
+		if err != nil {
+			return nil, nil, err
+		}
+		if bytes.Equal(blk.MerkleRoot(), mroot) {
+			return blk, qc, nil
+		}
+		if height == 0 {
+			break
+		}
+		qc = blk.QuorumCert() // qc of the parent block
+		height--
+	}
+	return nil, nil, ErrStateNotCertified
+}
+
+// DBSize returns the sizes of the LSM tree and the value log of the db in bytes
+func (strg *Storage) DBSize() (lsm, vlog int64) {
+	return strg.db.Size()
 }
 
 func (strg *Storage) GetMerkleRoot() []byte {
@@ -143,10 +355,13 @@ func (strg *Storage) commit(data *CommitData) error {
 		strg.computeMerkleUpdate(data)
 		elapsed := time.Since(start)
 		data.BlockCommit.SetElapsedMerkle(elapsed.Seconds())
-		logger.I().Debugw("compute merkle update",
-			"leaf nodes", len(data.merkleUpdate.Leaves), "elapsed", elapsed)
+		if data.merkleUpdate != nil {
+			logger.I().Debugw("compute merkle update",
+				"leaf nodes", len(data.merkleUpdate.Leaves), "elapsed", elapsed)
+		}
 	}
 
+	strg.setPrevNonces(data)
 	start := time.Now()
 	if err := strg.writeCommitData(data); err != nil {
 		return err
@@ -156,17 +371,48 @@ func (strg *Storage) commit(data *CommitData) error {
 	return nil
 }
 
+// writeCommitData writes chain data, block commit, state and merkle tree
+// and the block height in one transaction,
+// so a block is either fully commited or not commited at all on crash.
+// The updates too large for a transaction are written through the commit journal.
 func (strg *Storage) writeCommitData(data *CommitData) error {
-	if err := strg.writeChainData(data); err != nil {
-		return err
-	}
-	if err := strg.writeBlockCommit(data); err != nil {
-		return err
+	strg.mtxWriteState.Lock()
+	defer strg.mtxWriteState.Unlock()
+
+	updFns := strg.commitDataUpdates(data)
+	err := updateBadgerDB(strg.db, updFns)
+	if errors.Is(err, badger.ErrTxnTooBig) {
+		logger.I().Warnw("block commit is too large for a transaction, writing with journal",
+			"height", data.Block.Height())
+		return strg.writeCommitJournal(updFns)
 	}
-	if err := strg.writeStateMerkleTree(data); err != nil {
-		return err
+	return err
+}
+
+// setPrevNonces keeps the next nonces of the senders before the block in the block commit,
+// they are restored when the block is rolled back
+func (strg *Storage) setPrevNonces(data *CommitData) {
+	senders := make([][]byte, 0)
+	nonces := make([]int64, 0)
+	found := make(map[string]struct{})
+	for _, tx := range data.Transactions {
+		sender := tx.Sender().Bytes()
+		if _, ok := found[string(sender)]; ok {
+			continue
+		}
+		found[string(sender)] = struct{}{}
+		senders = append(senders, sender)
+		nonces = append(nonces, strg.chainStore.getNextNonce(sender))
 	}
-	return strg.setCommitedBlockHeight(data.Block.Height())
+	data.BlockCommit.SetPrevNonces(senders, nonces)
+}
+
+func (strg *Storage) commitDataUpdates(data *CommitData) []updateFunc {
+	updFns := strg.chainDataUpdates(data)
+	updFns = append(updFns, strg.chainStore.setBlockCommit(data.BlockCommit))
+	updFns = append(updFns, strg.stateMerkleUpdates(data)...)
+	updFns = append(updFns, strg.stateHistoryUpdates(data)...)
+	return append(updFns, strg.chainStore.setBlockHeight(data.Block.Height()))
 }
 
 func (strg *Storage) computeMerkleUpdate(data *CommitData) {
@@ -174,7 +420,12 @@ func (strg *Storage) computeMerkleUpdate(data *CommitData) {
 	strg.stateStore.loadPrevTreeIndexes(data.BlockCommit.StateChanges())
 	prevLeafCount := strg.merkleStore.getLeafCount()
 	leafCount := strg.stateStore.setNewTreeIndexes(data.BlockCommit.StateChanges(), prevLeafCount)
-	nodes := strg.stateStore.computeUpdatedTreeNodes(data.BlockCommit.StateChanges())
+	leafChanges := treeLeafChanges(data.BlockCommit.StateChanges())
+	if len(leafChanges) == 0 { // only deleted states which were not commited
+		data.BlockCommit.SetLeafCount(leafCount.Bytes()).SetMerkleRoot(strg.GetMerkleRoot())
+		return
+	}
+	nodes := strg.stateStore.computeUpdatedTreeNodes(leafChanges)
 	data.merkleUpdate = strg.merkleTree.Update(nodes, leafCount)
 
 	data.BlockCommit.
@@ -182,34 +433,24 @@ func (strg *Storage) computeMerkleUpdate(data *CommitData) {
 		SetMerkleRoot(data.merkleUpdate.Root.Data)
 }
 
-func (strg *Storage) writeChainData(data *CommitData) error {
+func (strg *Storage) chainDataUpdates(data *CommitData) []updateFunc {
 	updFns := make([]updateFunc, 0)
 	updFns = append(updFns, strg.chainStore.setBlock(data.Block)...)
 	updFns = append(updFns, strg.chainStore.setLastQC(data.QC))
 	updFns = append(updFns, strg.chainStore.setTxs(data.Transactions)...)
+	updFns = append(updFns, strg.chainStore.setNextNonces(data.Transactions)...)
 	updFns = append(updFns, strg.chainStore.setTxCommits(data.TxCommits)...)
-	return updateBadgerDB(strg.db, updFns)
+	updFns = append(updFns, strg.chainStore.setEvidenceList(data.Block.Evidence())...)
+	return updFns
 }
 
-func (strg *Storage) writeBlockCommit(data *CommitData) error {
-	updFn := strg.chainStore.setBlockCommit(data.BlockCommit)
-	return updateBadgerDB(strg.db, []updateFunc{updFn})
-}
-
-// commit state values and merkle tree in one transaction
-func (strg *Storage) writeStateMerkleTree(data *CommitData) error {
+func (strg *Storage) stateMerkleUpdates(data *CommitData) []updateFunc {
 	if len(data.BlockCommit.StateChanges()) == 0 {
 		return nil
 	}
-	strg.mtxWriteState.Lock()
-	defer strg.mtxWriteState.Unlock()
-
 	updFns := strg.stateStore.commitStateChanges(data.BlockCommit.StateChanges())
-	updFns = append(updFns, strg.merkleStore.commitUpdate(data.merkleUpdate)...)
-	return updateBadgerDB(strg.db, updFns)
-}
-
-func (strg *Storage) setCommitedBlockHeight(height uint64) error {
-	updFn := strg.chainStore.setBlockHeight(height)
-	return updateBadgerDB(strg.db, []updateFunc{updFn})
+	if data.merkleUpdate == nil {
+		return updFns
+	}
+	return append(updFns, strg.merkleStore.commitUpdate(data.merkleUpdate)...)
 }
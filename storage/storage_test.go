@@ -4,6 +4,7 @@
 package storage
 
 import (
+	"encoding/json"
 	"math/big"
 	"testing"
 
@@ -67,8 +68,8 @@ func TestStorage_Commit(t *testing.T) {
 	assert.Equal(big.NewInt(2).Bytes(), bcm.LeafCount())
 
 	h := hashFunc.New()
-	h.Write(strg.stateStore.sumStateValue([]byte{10}))
-	h.Write(strg.stateStore.sumStateValue([]byte{20}))
+	h.Write(strg.stateStore.sumState([]byte{1}, []byte{10}))
+	h.Write(strg.stateStore.sumState([]byte{2}, []byte{20}))
 	mroot := h.Sum(nil)
 	h.Reset()
 	assert.Equal(mroot, bcm.MerkleRoot())
@@ -137,35 +138,236 @@ func TestStorage_Commit(t *testing.T) {
 	assert.Equal(big.NewInt(2).Bytes(), bcm.StateChanges()[2].TreeIndex())
 	assert.Equal(big.NewInt(4).Bytes(), bcm.LeafCount())
 
-	h.Write(strg.stateStore.sumStateValue([]byte{20}))
-	h.Write(strg.stateStore.sumStateValue([]byte{20}))
-	h.Write(strg.stateStore.sumStateValue([]byte{30}))
-	h.Write(strg.stateStore.sumStateValue([]byte{50}))
+	h.Write(strg.stateStore.sumState([]byte{1}, []byte{20}))
+	h.Write(strg.stateStore.sumState([]byte{2}, []byte{20}))
+	h.Write(strg.stateStore.sumState([]byte{3}, []byte{30}))
+	h.Write(strg.stateStore.sumState([]byte{5}, []byte{50}))
 	mroot = h.Sum(nil)
 	h.Reset()
 	assert.Equal(mroot, bcm.MerkleRoot())
 	assert.Equal(bcm.MerkleRoot(), strg.GetMerkleRoot())
 
 	assert.Equal([]byte{50}, strg.GetState([]byte{5}))
-	var value []byte
-	assert.NotPanics(func() {
-		value = strg.VerifyState([]byte{5})
-	})
+	value, err := strg.VerifyState([]byte{5})
+	assert.NoError(err)
 	assert.Equal([]byte{50}, value)
 
-	assert.NotPanics(func() {
-		// non existing state value
-		value = strg.VerifyState([]byte{10})
-	})
+	// non existing state value
+	value, err = strg.VerifyState([]byte{10})
+	assert.NoError(err)
 	assert.Nil(value)
 
 	// tampering state value
 	updFn := strg.stateStore.setState([]byte{5}, []byte{100})
 	updateBadgerDB(strg.db, []updateFunc{updFn})
 
-	// should panic
-	assert.Panics(func() {
-		value = strg.VerifyState([]byte{5})
+	value, err = strg.VerifyState([]byte{5})
+	assert.ErrorIs(err, ErrStateNotVerified)
+	assert.Nil(value)
+}
+
+func TestStorage_GetStateProof(t *testing.T) {
+	assert := assert.New(t)
+
+	strg := newTestStorage()
+	priv := core.GenerateKey(nil)
+	b0 := core.NewBlock().SetHeight(0).Sign(priv)
+	scList := make([]*core.StateChange, 20)
+	for i := range scList {
+		scList[i] = core.NewStateChange().SetKey([]byte{uint8(i)}).SetValue([]byte{uint8(i + 100)})
+	}
+	q0 := core.NewQuorumCert().Build([]*core.Vote{b0.ProposerVote()})
+	err := strg.Commit(&CommitData{
+		Block:       b0,
+		QC:          q0,
+		BlockCommit: core.NewBlockCommit().SetHash(b0.Hash()).SetStateChanges(scList),
+	})
+	assert.NoError(err)
+
+	_, err = strg.GetStateProof([]byte{5})
+	assert.Equal(ErrStateNotCertified, err, "merkle root is not in a commited block")
+
+	// next blocks carry the merkle root of the exec height
+	b1 := core.NewBlock().SetHeight(1).SetParentHash(b0.Hash()).SetQuorumCert(q0).
+		SetExecHeight(0).SetMerkleRoot(strg.GetMerkleRoot()).Sign(priv)
+	q1 := core.NewQuorumCert().Build([]*core.Vote{b1.ProposerVote()})
+	b2 := core.NewBlock().SetHeight(2).SetParentHash(b1.Hash()).SetQuorumCert(q1).
+		SetExecHeight(1).SetMerkleRoot(strg.GetMerkleRoot()).Sign(priv)
+	q2 := core.NewQuorumCert().Build([]*core.Vote{b2.ProposerVote()})
+	for _, data := range []*CommitData{
+		{Block: b1, QC: q1, BlockCommit: core.NewBlockCommit().SetHash(b1.Hash())},
+		{Block: b2, QC: q2, BlockCommit: core.NewBlockCommit().SetHash(b2.Hash())},
+	} {
+		assert.NoError(strg.Commit(data))
+	}
+
+	sp, err := strg.GetStateProof([]byte{5})
+	assert.NoError(err)
+	assert.Equal([]byte{105}, sp.Value)
+	assert.Equal(strg.GetMerkleRoot(), sp.MerkleRoot)
+	assert.Equal(b2.Hash(), sp.Block.Hash(), "latest block with the merkle root")
+	assert.Equal(q2.BlockHash(), sp.QC.BlockHash())
+	assert.True(sp.Verify())
+	assert.NoError(sp.Validate(core.NewValidatorStore([]*core.PublicKey{priv.PublicKey()})))
+	assert.Error(sp.Validate(core.NewValidatorStore([]*core.PublicKey{core.GenerateKey(nil).PublicKey()})))
+
+	b, err := json.Marshal(sp)
+	assert.NoError(err)
+	sp = new(StateProof)
+	assert.NoError(json.Unmarshal(b, sp))
+	assert.True(sp.Verify())
+
+	sp.Value = []byte{106}
+	assert.False(sp.Verify(), "tampered value")
+
+	sp.Value = []byte{105}
+	sp.Key = []byte{6}
+	assert.False(sp.Verify(), "key is bound to the leaf")
+
+	sp.Key = []byte{5}
+	sp.QC = q1
+	assert.False(sp.Verify(), "qc of other block")
+
+	_, err = strg.GetStateProof([]byte{100})
+	assert.Error(err, "state not found")
+}
+
+func TestStorage_Evidence(t *testing.T) {
+	assert := assert.New(t)
+
+	strg := newTestStorage()
+	priv := core.GenerateKey(nil)
+	qc := core.NewQuorumCert().Build(
+		[]*core.Vote{core.NewBlock().SetHeight(4).Vote(priv)})
+	blkA := core.NewBlock().SetHeight(5).SetTimestamp(1).SetQuorumCert(qc).Sign(priv)
+	blkB := core.NewBlock().SetHeight(5).SetTimestamp(2).SetQuorumCert(qc).Sign(priv)
+	ev := core.NewProposalEvidence(blkA, blkB)
+	assert.False(strg.HasEvidence(ev.ID()))
+
+	b0 := core.NewBlock().SetHeight(0).SetEvidence([]*core.Evidence{ev}).Sign(priv)
+	err := strg.Commit(&CommitData{
+		Block:       b0,
+		QC:          core.NewQuorumCert(),
+		BlockCommit: core.NewBlockCommit().SetHash(b0.Hash()),
+	})
+	assert.NoError(err)
+
+	assert.True(strg.HasEvidence(ev.ID()))
+	evList, err := strg.GetEvidenceList()
+	assert.NoError(err)
+	if assert.Equal(1, len(evList)) {
+		assert.Equal(ev.ID(), evList[0].ID())
+		assert.Equal(priv.PublicKey(), evList[0].Accused())
+	}
+}
+
+func TestStorage_StateSnapshot(t *testing.T) {
+	assert := assert.New(t)
+
+	strg := newTestStorage()
+	priv := core.GenerateKey(nil)
+	b0 := core.NewBlock().SetHeight(0).Sign(priv)
+	scList := make([]*core.StateChange, 25)
+	for i := range scList {
+		scList[i] = core.NewStateChange().SetKey([]byte{uint8(i)}).SetValue([]byte{uint8(i + 100)})
+	}
+	qc := core.NewQuorumCert().Build([]*core.Vote{b0.ProposerVote()})
+	txs := []*core.Transaction{
+		core.NewTransaction().SetNonce(0).Sign(priv),
+		core.NewTransaction().SetNonce(1).Sign(priv),
+		core.NewTransaction().SetNonce(0).Sign(core.GenerateKey(nil)),
+	}
+	err := strg.Commit(&CommitData{
+		Block:        b0,
+		QC:           qc,
+		Transactions: txs,
+		BlockCommit:  core.NewBlockCommit().SetHash(b0.Hash()).SetStateChanges(scList),
 	})
+	assert.NoError(err)
+
+	snapshot, err := strg.GetStateSnapshot()
+	assert.NoError(err)
+	assert.EqualValues(0, snapshot.Height())
+	assert.EqualValues(25, snapshot.LeafCount().Int64())
+	assert.Equal(strg.GetMerkleRoot(), snapshot.MerkleRoot())
+	assert.Equal(b0.Hash(), snapshot.LastQC().BlockHash())
+
+	_, err = strg.GetStateChunk(1, nil, 10)
+	assert.Equal(ErrSnapshotNotFound, err)
+
+	var chunks []*core.StateChange
+	var startKey []byte
+	for {
+		chunk, err := strg.GetStateChunk(0, startKey, 10)
+		assert.NoError(err)
+		if len(chunk) == 0 {
+			break
+		}
+		chunks = append(chunks, chunk...)
+		startKey = chunk[len(chunk)-1].Key()
+	}
+	assert.Equal(25, len(chunks))
+
+	nonces, err := strg.GetNonceChunk(0, nil, 1)
+	assert.NoError(err)
+	assert.Equal(1, len(nonces))
+	more, err := strg.GetNonceChunk(0, nonces[0].Key(), 10)
+	assert.NoError(err)
+	assert.Equal(1, len(more))
+	nonces = append(nonces, more...)
+	more, err = strg.GetNonceChunk(0, nonces[1].Key(), 10)
+	assert.NoError(err)
+	assert.Empty(more)
+
+	// other node has its own states, merkle tree and nonces to be wiped by the install
+	other := newTestStorage()
+	otherPriv := core.GenerateKey(nil)
+	ob0 := core.NewBlock().SetHeight(0).Sign(otherPriv)
+	otherSCList := make([]*core.StateChange, 40)
+	for i := range otherSCList {
+		otherSCList[i] = core.NewStateChange().SetKey([]byte{uint8(i + 100)}).SetValue([]byte{1})
+	}
+	err = other.Commit(&CommitData{
+		Block:        ob0,
+		QC:           core.NewQuorumCert().Build([]*core.Vote{ob0.ProposerVote()}),
+		Transactions: []*core.Transaction{core.NewTransaction().SetNonce(0).Sign(otherPriv)},
+		BlockCommit:  core.NewBlockCommit().SetHash(ob0.Hash()).SetStateChanges(otherSCList),
+	})
+	assert.NoError(err)
+	assert.EqualValues(1, other.GetNextNonce(otherPriv.PublicKey()))
+
+	err = other.InstallStateSnapshot(b0, qc, chunks[:24], nonces, snapshot.LeafCount(), snapshot.MerkleRoot())
+	assert.Error(err, "missing state")
+
+	err = other.InstallStateSnapshot(b0, qc, chunks, nonces, snapshot.LeafCount(), []byte{1})
+	assert.Equal(ErrSnapshotMerkleRoot, err)
+
+	invalid := []*core.StateChange{core.NewStateChange().SetKey([]byte{1}).SetValue([]byte{1})}
+	err = other.InstallStateSnapshot(b0, qc, chunks, invalid, snapshot.LeafCount(), snapshot.MerkleRoot())
+	assert.Equal(ErrSnapshotNonce, err)
+
+	err = other.InstallStateSnapshot(b0, qc, chunks, nonces, snapshot.LeafCount(), snapshot.MerkleRoot())
+	assert.NoError(err)
+	for _, tx := range txs {
+		assert.Equal(strg.GetNextNonce(tx.Sender()), other.GetNextNonce(tx.Sender()),
+			"synced node should keep the next nonces")
+	}
+	assert.EqualValues(2, other.GetNextNonce(priv.PublicKey()))
+	assert.EqualValues(0, other.GetBlockHeight())
+	assert.Equal(snapshot.MerkleRoot(), other.GetMerkleRoot())
+	lastQC, err := other.GetLastQC()
+	assert.NoError(err)
+	assert.Equal(b0.Hash(), lastQC.BlockHash())
+	value, err := other.VerifyState([]byte{5})
+	assert.NoError(err)
+	assert.Equal([]byte{105}, value)
+
+	assert.Nil(other.GetState([]byte{130}), "existing state should be wiped")
+	value, err = other.VerifyState([]byte{130})
+	assert.NoError(err)
 	assert.Nil(value)
+	assert.EqualValues(0, other.GetNextNonce(otherPriv.PublicKey()), "existing nonce should be wiped")
+	journal, err := other.getCommitJournal()
+	assert.NoError(err)
+	assert.Nil(journal, "journal should be removed after install")
 }
@@ -0,0 +1,195 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package storage
+
+import (
+	"bytes"
+	"crypto"
+	"fmt"
+	"math/big"
+
+	"github.com/aungmawjj/juria-blockchain/core"
+	"github.com/aungmawjj/juria-blockchain/merkle"
+	"github.com/dgraph-io/badger/v3"
+)
+
+// DBReport is the result of database integrity check
+type DBReport struct {
+	// chain data is available from start height, it's not zero for state synced db
+	StartHeight uint64
+	// txs and commits are available from pruned height
+	PrunedHeight uint64
+	Height       uint64
+	TxCount      int
+	StateCount   int
+	MerkleRoot   []byte
+	Problems     []string
+}
+
+func (report *DBReport) addProblem(format string, args ...interface{}) {
+	report.Problems = append(report.Problems, fmt.Sprintf(format, args...))
+}
+
+// VerifyDB walks the commited chain and states to check the integrity of the database.
+// It's meant to run offline, while the db is not written by a node.
+func (strg *Storage) VerifyDB(vs core.ValidatorStore) (*DBReport, error) {
+	height, err := strg.chainStore.getBlockHeight()
+	if err != nil {
+		return nil, fmt.Errorf("no commited block, %w", err)
+	}
+	report := &DBReport{
+		Height:       height,
+		StartHeight:  strg.findChainStartHeight(height),
+		PrunedHeight: strg.GetPrunedHeight(),
+	}
+	lastRoot := strg.verifyChain(vs, report)
+	strg.verifyLastQC(vs, report)
+	if err := strg.verifyMerkleRoot(report); err != nil {
+		return nil, err
+	}
+	if lastRoot != nil && !bytes.Equal(lastRoot, report.MerkleRoot) {
+		report.addProblem("merkle root of states does not match last block commit")
+	}
+	return report, nil
+}
+
+// findChainStartHeight returns the lowest height of the continuous blocks to the given height
+func (strg *Storage) findChainStartHeight(height uint64) uint64 {
+	for ; height > 0; height-- {
+		if _, err := strg.chainStore.getBlockHashByHeight(height - 1); err != nil {
+			return height
+		}
+	}
+	return 0
+}
+
+// verifyChain returns the merkle root of the last block commit which has state changes
+func (strg *Storage) verifyChain(vs core.ValidatorStore, report *DBReport) []byte {
+	var parent *core.Block
+	var lastRoot []byte
+	for height := report.StartHeight; height <= report.Height; height++ {
+		blk, err := strg.chainStore.getBlockByHeight(height)
+		if err != nil {
+			report.addProblem("block %d: not found, %v", height, err)
+			parent = nil
+			continue
+		}
+		if blk.Height() != height {
+			report.addProblem("block %d: wrong height %d", height, blk.Height())
+		}
+		if err := blk.Validate(vs); err != nil {
+			report.addProblem("block %d: invalid, %v", height, err)
+		}
+		if parent != nil && !bytes.Equal(parent.Hash(), blk.ParentHash()) {
+			report.addProblem("block %d: parent hash mismatch", height)
+		}
+		parent = blk
+		if height < report.PrunedHeight {
+			continue
+		}
+		strg.verifyBlockTxs(blk, report)
+		bcm, err := strg.chainStore.getBlockCommit(blk.Hash())
+		if err == nil {
+			if len(bcm.MerkleRoot()) > 0 {
+				lastRoot = bcm.MerkleRoot()
+			}
+		} else if height > report.StartHeight || report.StartHeight == 0 {
+			// state synced block is installed without block commit
+			report.addProblem("block %d: block commit not found", height)
+		}
+	}
+	return lastRoot
+}
+
+func (strg *Storage) verifyBlockTxs(blk *core.Block, report *DBReport) {
+	for _, hash := range blk.Transactions() {
+		report.TxCount++
+		if !strg.chainStore.hasTx(hash) {
+			report.addProblem("block %d: tx %x not found", blk.Height(), hash)
+		}
+		if _, err := strg.chainStore.getTxCommit(hash); err != nil {
+			report.addProblem("block %d: tx commit %x not found", blk.Height(), hash)
+		}
+	}
+}
+
+func (strg *Storage) verifyLastQC(vs core.ValidatorStore, report *DBReport) {
+	qc, err := strg.chainStore.getLastQC()
+	if err != nil {
+		report.addProblem("last qc not found, %v", err)
+		return
+	}
+	if err := qc.Validate(vs); err != nil {
+		report.addProblem("last qc invalid, %v", err)
+	}
+	hash, err := strg.chainStore.getBlockHashByHeight(report.Height)
+	if err == nil && !bytes.Equal(hash, qc.BlockHash()) {
+		report.addProblem("last qc does not reference last block")
+	}
+}
+
+// verifyMerkleRoot recomputes the merkle tree from the state values
+func (strg *Storage) verifyMerkleRoot(report *DBReport) error {
+	leaves := make([]*merkle.Node, 0)
+	indexes := make(map[string]struct{})
+	leafCount := strg.merkleStore.getLeafCount()
+	prefix := []byte{colStateValueByKey}
+	err := strg.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{
+			PrefetchValues: true,
+			PrefetchSize:   100,
+			Prefix:         prefix,
+		})
+		defer it.Close()
+		getter := &txnGetter{txn}
+		for it.Rewind(); it.Valid(); it.Next() {
+			key := it.Item().KeyCopy(nil)[len(prefix):]
+			value, err := it.Item().ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			idx, err := getter.Get(concatBytes([]byte{colMerkleIndexByStateKey}, key))
+			if err != nil {
+				report.addProblem("state %x: tree index not found", key)
+				continue
+			}
+			if _, found := indexes[string(idx)]; found {
+				report.addProblem("state %x: duplicate tree index", key)
+				continue
+			}
+			indexes[string(idx)] = struct{}{}
+			position := merkle.NewPosition(0, big.NewInt(0).SetBytes(idx))
+			if leafCount.Cmp(position.Index()) != 1 {
+				report.addProblem("state %x: tree index out of leaf count", key)
+				continue
+			}
+			leaves = append(leaves, &merkle.Node{
+				Data:     strg.stateStore.sumState(key, value),
+				Position: position,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	report.StateCount = len(leaves)
+	// leaves of deleted states are empty, so leaf count can be more than state count
+	if leafCount.Cmp(big.NewInt(int64(len(leaves)))) == -1 {
+		report.addProblem("state count %d is more than merkle leaf count %d",
+			len(leaves), leafCount)
+	}
+	if len(leaves) == 0 {
+		return nil
+	}
+	tree := merkle.NewTree(merkle.NewMapStore(), merkle.Config{
+		Hash:         crypto.SHA3_256,
+		BranchFactor: strg.merkleTree.BranchFactor(),
+	})
+	report.MerkleRoot = tree.Update(leaves, leafCount).Root.Data
+	if !bytes.Equal(report.MerkleRoot, strg.GetMerkleRoot()) {
+		report.addProblem("merkle root of states does not match stored merkle tree")
+	}
+	return nil
+}
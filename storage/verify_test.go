@@ -0,0 +1,67 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package storage
+
+import (
+	"testing"
+
+	"github.com/aungmawjj/juria-blockchain/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorage_VerifyDB(t *testing.T) {
+	assert := assert.New(t)
+
+	strg := newTestStorage()
+	priv := core.GenerateKey(nil)
+	vs := core.NewValidatorStore([]*core.PublicKey{priv.PublicKey()})
+
+	b0 := core.NewBlock().SetHeight(0).Sign(priv)
+	q0 := core.NewQuorumCert().Build([]*core.Vote{b0.ProposerVote()})
+	assert.NoError(strg.Commit(&CommitData{
+		Block:       b0,
+		QC:          q0,
+		BlockCommit: core.NewBlockCommit().SetHash(b0.Hash()),
+	}))
+
+	tx := core.NewTransaction().SetNonce(1).Sign(priv)
+	b1 := core.NewBlock().SetHeight(1).SetQuorumCert(q0).
+		SetParentHash(b0.Hash()).SetTransactions([][]byte{tx.Hash()}).Sign(priv)
+	q1 := core.NewQuorumCert().Build([]*core.Vote{b1.ProposerVote()})
+	assert.NoError(strg.Commit(&CommitData{
+		Block:        b1,
+		QC:           q1,
+		Transactions: []*core.Transaction{tx},
+		TxCommits:    []*core.TxCommit{core.NewTxCommit().SetHash(tx.Hash()).SetBlockHash(b1.Hash())},
+		BlockCommit: core.NewBlockCommit().SetHash(b1.Hash()).
+			SetStateChanges([]*core.StateChange{
+				core.NewStateChange().SetKey([]byte{1}).SetValue([]byte{10}),
+				core.NewStateChange().SetKey([]byte{2}).SetValue([]byte{20}),
+			}),
+	}))
+
+	report, err := strg.VerifyDB(vs)
+	assert.NoError(err)
+	assert.Empty(report.Problems)
+	assert.EqualValues(1, report.Height)
+	assert.Equal(1, report.TxCount)
+	assert.Equal(2, report.StateCount)
+	assert.Equal(strg.GetMerkleRoot(), report.MerkleRoot)
+
+	// tampering state value and removing tx commit
+	updateBadgerDB(strg.db, []updateFunc{
+		strg.stateStore.setState([]byte{1}, []byte{100}),
+		deleteKey(concatBytes([]byte{colTxCommitByHash}, tx.Hash())),
+	})
+
+	report, err = strg.VerifyDB(vs)
+	assert.NoError(err)
+	assert.Equal(3, len(report.Problems), "missing tx commit, stored tree and block commit root")
+
+	// other validator set
+	report, err = strg.VerifyDB(core.NewValidatorStore(
+		[]*core.PublicKey{core.GenerateKey(nil).PublicKey()}))
+	assert.NoError(err)
+	assert.Equal(6, len(report.Problems), "invalid blocks and qc")
+}
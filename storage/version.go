@@ -0,0 +1,45 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// dbVersion is increased when the data written by older versions cannot be used.
+// Version 1 binds the state keys into the merkle leaves,
+// the merkle trees of older dbs do not verify their states.
+const dbVersion uint64 = 1
+
+// ErrDBVersion is returned for the db written by an unsupported version
+var ErrDBVersion = errors.New("unsupported db version, sync the node with an empty data dir")
+
+// CheckVersion fails with ErrDBVersion if the db is written by another version.
+// It must be called on startup before using the storage, an empty db is marked with the current version.
+// Older dbs are not migrated since the merkle roots are certified by the commited blocks.
+func (strg *Storage) CheckVersion() error {
+	getter := strg.chainStore.getter
+	if !getter.HasKey([]byte{colDBVersion}) {
+		if getter.HasKey([]byte{colBlockHeight}) {
+			return fmt.Errorf("%w, db version 0, required %d", ErrDBVersion, dbVersion)
+		}
+		return updateBadgerDB(strg.db, []updateFunc{setDBVersion(dbVersion)})
+	}
+	b, err := getter.Get([]byte{colDBVersion})
+	if err != nil {
+		return err
+	}
+	if version := binary.BigEndian.Uint64(b); version != dbVersion {
+		return fmt.Errorf("%w, db version %d, required %d", ErrDBVersion, version, dbVersion)
+	}
+	return nil
+}
+
+func setDBVersion(version uint64) updateFunc {
+	return func(setter setter) error {
+		return setter.Set([]byte{colDBVersion}, uint64BEBytes(version))
+	}
+}
@@ -0,0 +1,31 @@
+// Copyright (C) 2021 Aung Maw
+// Licensed under the GNU General Public License v3.0
+
+package storage
+
+import (
+	"testing"
+
+	"github.com/aungmawjj/juria-blockchain/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorage_CheckVersion(t *testing.T) {
+	assert := assert.New(t)
+
+	strg := newTestStorage()
+	assert.NoError(strg.CheckVersion(), "empty db")
+	assert.NoError(strg.CheckVersion(), "marked db")
+
+	updateBadgerDB(strg.db, []updateFunc{setDBVersion(dbVersion + 1)})
+	assert.ErrorIs(strg.CheckVersion(), ErrDBVersion, "newer db")
+
+	// db of older version has blocks without version
+	old := newTestStorage()
+	b0 := core.NewBlock().SetHeight(0).Sign(core.GenerateKey(nil))
+	old.Commit(&CommitData{
+		Block:       b0,
+		BlockCommit: core.NewBlockCommit().SetHash(b0.Hash()),
+	})
+	assert.ErrorIs(old.CheckVersion(), ErrDBVersion, "older db")
+}
@@ -13,6 +13,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/aungmawjj/juria-blockchain/chaincodes/governance"
 	"github.com/aungmawjj/juria-blockchain/core"
 	"github.com/aungmawjj/juria-blockchain/node"
 	"github.com/multiformats/go-multiaddr"
@@ -41,6 +42,16 @@ func WriteNodeKey(datadir string, key *core.PrivateKey) error {
 	return err
 }
 
+func WriteBLSKey(datadir string, key *core.BLSPrivateKey) error {
+	f, err := os.Create(path.Join(datadir, node.BLSKeyFile))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(key.Bytes())
+	return err
+}
+
 func WriteGenesisFile(datadir string, genesis *node.Genesis) error {
 	f, err := os.Create(path.Join(datadir, node.GenesisFile))
 	if err != nil {
@@ -93,9 +104,16 @@ func SetupTemplateDir(dir string, keys []*core.PrivateKey, vlds []node.Peer) err
 	}
 	genesis := &node.Genesis{
 		Validators: make([][]byte, len(keys)),
+		BLSKeys:    make([]*governance.BLSKey, len(keys)),
 	}
+	blsKeys := make([]*core.BLSPrivateKey, len(keys))
 	for i, v := range keys {
 		genesis.Validators[i] = v.PublicKey().Bytes()
+		blsKeys[i] = core.GenerateBLSKey(nil)
+		genesis.BLSKeys[i] = &governance.BLSKey{
+			Key:   blsKeys[i].PublicKey().Bytes(),
+			Proof: blsKeys[i].ProvePossession(),
+		}
 	}
 	for i, key := range keys {
 		dir := path.Join(dir, strconv.Itoa(i))
@@ -103,6 +121,9 @@ func SetupTemplateDir(dir string, keys []*core.PrivateKey, vlds []node.Peer) err
 		if err := WriteNodeKey(dir, key); err != nil {
 			return err
 		}
+		if err := WriteBLSKey(dir, blsKeys[i]); err != nil {
+			return err
+		}
 		if err := WriteGenesisFile(dir, genesis); err != nil {
 			return err
 		}
@@ -154,4 +175,7 @@ func AddJuriaFlags(cmd *exec.Cmd, config *node.Config) {
 
 	cmd.Args = append(cmd.Args, "--consensus-leaderTimeout",
 		config.ConsensusConfig.LeaderTimeout.String())
+
+	cmd.Args = append(cmd.Args, "--consensus-leaderReputationWindow",
+		strconv.FormatUint(config.ConsensusConfig.LeaderReputationWindow, 10))
 }
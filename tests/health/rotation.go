@@ -65,13 +65,7 @@ func (hc *checker) hasViewChanged(status, last *consensus.Status) bool {
 	if last == nil {
 		return false // last view is not loaded yet for the first time
 	}
-	if status.PendingViewChange {
-		return false // view change is pending but not confirmed yet
-	}
-	if last.PendingViewChange { // previously pending, now not pending
-		return true // it's confirmed view change
-	}
-	return status.LeaderIndex != last.LeaderIndex
+	return status.View != last.View // view is only changed with the timeout cert
 }
 
 func (hc *checker) shouldEqualLeader(changedView map[int]*consensus.Status) error {
@@ -32,6 +32,10 @@ type JuriaCoinClient struct {
 	codeAddr []byte
 
 	transferCount int64
+
+	// next tx nonce by sender, keys are generated by the client and start from zero
+	nonces   map[string]int64
+	mtxNonce sync.Mutex
 }
 
 var _ LoadClient = (*JuriaCoinClient)(nil)
@@ -44,6 +48,7 @@ func NewJuriaCoinClient(mintCount, destCount int, binccPath string) *JuriaCoinCl
 		minter:    core.GenerateKey(nil),
 		accounts:  make([]*core.PrivateKey, mintCount),
 		dests:     make([]*core.PrivateKey, destCount),
+		nonces:    make(map[string]int64),
 	}
 	client.generateKeyConcurrent(client.accounts)
 	client.generateKeyConcurrent(client.dests)
@@ -85,6 +90,7 @@ func (client *JuriaCoinClient) SubmitTx() (int, *core.Transaction, error) {
 
 func (client *JuriaCoinClient) setupOnCluster(cls *cluster.Cluster) error {
 	client.cluster = cls
+	client.resetNonces() // new chain on each setup
 	if err := client.deploy(); err != nil {
 		return err
 	}
@@ -159,6 +165,21 @@ func (client *JuriaCoinClient) QueryBalance(node cluster.Node, dest *core.Public
 	return balance, json.Unmarshal(result, &balance)
 }
 
+func (client *JuriaCoinClient) resetNonces() {
+	client.mtxNonce.Lock()
+	defer client.mtxNonce.Unlock()
+	client.nonces = make(map[string]int64)
+}
+
+func (client *JuriaCoinClient) nextNonce(sender *core.PrivateKey) int64 {
+	client.mtxNonce.Lock()
+	defer client.mtxNonce.Unlock()
+	key := sender.PublicKey().String()
+	nonce := client.nonces[key]
+	client.nonces[key] = nonce + 1
+	return nonce
+}
+
 func (client *JuriaCoinClient) MakeDeploymentTx(minter *core.PrivateKey) *core.Transaction {
 	input := client.nativeDeploymentInput()
 	if client.binccCodeID != nil {
@@ -166,7 +187,7 @@ func (client *JuriaCoinClient) MakeDeploymentTx(minter *core.PrivateKey) *core.T
 	}
 	b, _ := json.Marshal(input)
 	return core.NewTransaction().
-		SetNonce(time.Now().UnixNano()).
+		SetNonce(client.nextNonce(minter)).
 		SetInput(b).
 		Sign(minter)
 }
@@ -202,7 +223,7 @@ func (client *JuriaCoinClient) MakeMintTx(dest *core.PublicKey, value int64) *co
 	b, _ := json.Marshal(input)
 	return core.NewTransaction().
 		SetCodeAddr(client.codeAddr).
-		SetNonce(time.Now().UnixNano()).
+		SetNonce(client.nextNonce(client.minter)).
 		SetInput(b).
 		Sign(client.minter)
 }
@@ -218,7 +239,7 @@ func (client *JuriaCoinClient) MakeTransferTx(
 	b, _ := json.Marshal(input)
 	return core.NewTransaction().
 		SetCodeAddr(client.codeAddr).
-		SetNonce(time.Now().UnixNano()).
+		SetNonce(client.nextNonce(sender)).
 		SetInput(b).
 		Sign(sender)
 }
@@ -7,6 +7,7 @@ import (
 	"bytes"
 	"encoding/base64"
 	"errors"
+	"time"
 
 	"github.com/aungmawjj/juria-blockchain/core"
 	"github.com/aungmawjj/juria-blockchain/emitter"
@@ -17,10 +18,15 @@ type Status struct {
 	Total   int `json:"total"`
 	Pending int `json:"pending"`
 	Queue   int `json:"queue"`
+	Gapped  int `json:"gapped"`
 }
 
+// ErrStaleNonce is returned when the tx nonce is lower than the commited next nonce of the sender
+var ErrStaleNonce = errors.New("stale tx nonce")
+
 type Storage interface {
 	HasTx(hash []byte) bool
+	GetNextNonce(sender *core.PublicKey) int64
 }
 
 type Execution interface {
@@ -60,6 +66,7 @@ func New(storage Storage, execution Execution, msgSvc MsgService) *TxPool {
 		broadcaster: newBroadcaster(msgSvc),
 	}
 	go pool.subscribeTxs()
+	go pool.dropExpiredTxs()
 	return pool
 }
 
@@ -71,8 +78,8 @@ func (pool *TxPool) SyncTxs(peer *core.PublicKey, hashes [][]byte) error {
 	return pool.syncTxs(peer, hashes)
 }
 
-func (pool *TxPool) PopTxsFromQueue(max int) [][]byte {
-	return pool.store.popTxsFromQueue(max)
+func (pool *TxPool) PopTxsFromQueue(max int, gasLimit uint64) [][]byte {
+	return pool.store.popTxsFromQueue(max, gasLimit)
 }
 
 func (pool *TxPool) PutTxsToQueue(hashes [][]byte) {
@@ -104,30 +111,39 @@ func (pool *TxPool) GetStatus() Status {
 }
 
 func (pool *TxPool) submitTx(tx *core.Transaction) error {
-	if err := pool.addNewTx(tx); err != nil {
+	if err := pool.addNewTx(tx, false); err != nil {
 		return err
 	}
 	pool.broadcaster.queue <- tx
 	return nil
 }
 
+func (pool *TxPool) dropExpiredTxs() {
+	ticker := time.NewTicker(GappedTxExpiry / 10)
+	defer ticker.Stop()
+	for range ticker.C {
+		pool.store.dropExpiredGappedTxs(time.Now().Add(-GappedTxExpiry))
+	}
+}
+
 func (pool *TxPool) subscribeTxs() {
 	sub := pool.msgSvc.SubscribeTxList(100)
 	for e := range sub.Events() {
 		txList := e.(*core.TxList)
-		if err := pool.addTxList(txList); err != nil {
+		if err := pool.addTxList(txList, false); err != nil {
 			logger.I().Warnf("add tx list failed %+v", err)
 		}
 	}
 }
 
-func (pool *TxPool) addTxList(txList *core.TxList) error {
+// synced txs are from proposals and accepted even if their nonces are used by other txs in pool
+func (pool *TxPool) addTxList(txList *core.TxList, synced bool) error {
 	jobCh := make(chan *core.Transaction)
 	defer close(jobCh)
 	out := make(chan error, len(*txList))
 
 	for i := 0; i < 50; i++ {
-		go pool.workerAddNewTx(jobCh, out)
+		go pool.workerAddNewTx(jobCh, out, synced)
 	}
 	for _, tx := range *txList {
 		jobCh <- tx
@@ -141,24 +157,29 @@ func (pool *TxPool) addTxList(txList *core.TxList) error {
 	return nil
 }
 
-func (pool *TxPool) workerAddNewTx(jobCh <-chan *core.Transaction, out chan<- error) {
+func (pool *TxPool) workerAddNewTx(
+	jobCh <-chan *core.Transaction, out chan<- error, synced bool,
+) {
 	for tx := range jobCh {
-		out <- pool.addNewTx(tx)
+		out <- pool.addNewTx(tx, synced)
 	}
 }
 
-func (pool *TxPool) addNewTx(tx *core.Transaction) error {
+func (pool *TxPool) addNewTx(tx *core.Transaction, synced bool) error {
 	if err := tx.Validate(); err != nil {
 		return err
 	}
 	if pool.storage.HasTx(tx.Hash()) {
 		return nil
 	}
+	nextNonce := pool.storage.GetNextNonce(tx.Sender())
+	if tx.Nonce() < nextNonce {
+		return ErrStaleNonce
+	}
 	if err := pool.execution.VerifyTx(tx); err != nil {
 		return err
 	}
-	pool.store.addNewTx(tx)
-	return nil
+	return pool.store.addNewTx(tx, nextNonce, synced)
 }
 
 func (pool *TxPool) syncTxs(peer *core.PublicKey, hashes [][]byte) error {
@@ -175,7 +196,7 @@ func (pool *TxPool) syncTxs(peer *core.PublicKey, hashes [][]byte) error {
 	if err != nil {
 		return err
 	}
-	return pool.addTxList(txList)
+	return pool.addTxList(txList, true)
 }
 
 func (pool *TxPool) requestTxList(peer *core.PublicKey, hashes [][]byte) (*core.TxList, error) {
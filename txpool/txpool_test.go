@@ -26,6 +26,11 @@ func (m *MockStorage) HasTx(hash []byte) bool {
 	return args.Bool(0)
 }
 
+func (m *MockStorage) GetNextNonce(sender *core.PublicKey) int64 {
+	args := m.Called(sender)
+	return args.Get(0).(int64)
+}
+
 type MockExecution struct {
 	mock.Mock
 }
@@ -68,6 +73,7 @@ func TestTxPool_SubmitTx(t *testing.T) {
 	priv := core.GenerateKey(nil)
 
 	storage := new(MockStorage)
+	storage.On("GetNextNonce", priv.PublicKey()).Return(int64(1))
 	execution := new(MockExecution)
 	msgSvc := new(MockMsgService)
 
@@ -123,6 +129,7 @@ func TestTxPool_SubscribeTxList(t *testing.T) {
 	priv := core.GenerateKey(nil)
 
 	storage := new(MockStorage)
+	storage.On("GetNextNonce", priv.PublicKey()).Return(int64(1))
 	execution := new(MockExecution)
 	msgSvc := new(MockMsgService)
 
@@ -150,7 +157,8 @@ func TestTxPool_SubscribeTxList(t *testing.T) {
 
 	time.Sleep(5 * time.Millisecond)
 
-	assert.Equal(2, pool.GetStatus().Queue)
+	assert.Equal(1, pool.GetStatus().Queue)
+	assert.Equal(1, pool.GetStatus().Gapped, "tx3 should wait for tx2 nonce")
 	storage.AssertExpectations(t)
 }
 
@@ -160,6 +168,7 @@ func TestTxPool_Sync(t *testing.T) {
 	priv := core.GenerateKey(nil)
 
 	storage := new(MockStorage)
+	storage.On("GetNextNonce", priv.PublicKey()).Return(int64(1))
 	execution := new(MockExecution)
 	msgSvc := new(MockMsgService)
 
@@ -217,6 +226,7 @@ func TestTxPool_GetTxsToExecute(t *testing.T) {
 	priv := core.GenerateKey(nil)
 
 	storage := new(MockStorage)
+	storage.On("GetNextNonce", priv.PublicKey()).Return(int64(1))
 	execution := new(MockExecution)
 	msgSvc := new(MockMsgService)
 
@@ -5,21 +5,42 @@ package txpool
 
 import (
 	"container/heap"
+	"errors"
 	"sync"
 	"time"
 
 	"github.com/aungmawjj/juria-blockchain/core"
+	"github.com/aungmawjj/juria-blockchain/logger"
+)
+
+// errors
+var (
+	ErrNonceExists   = errors.New("tx with the same nonce exists in pool")
+	ErrNonceTooHigh  = errors.New("tx nonce is too far ahead of the sender's next nonce")
+	ErrTooManyGapped = errors.New("too many gapped txs of the sender in pool")
+)
+
+// limits of the gapped txs, they are held in pool without being proposed
+const (
+	MaxNonceGap        = 64 // gapped tx nonce must be less than the next nonce plus the gap
+	MaxGappedPerSender = 16
+	GappedTxExpiry     = 10 * time.Minute // gapped tx is dropped if the gap is not filled in time
 )
 
 type txItem struct {
 	tx           *core.Transaction
+	sender       string
 	receivedTime int64
 	index        int
+
+	// waiting in the sender's gapped queue for lower nonce txs
+	gapped bool
 }
 
 func newTxItem(tx *core.Transaction) *txItem {
 	return &txItem{
 		tx:           tx,
+		sender:       string(tx.Sender().Bytes()),
 		receivedTime: time.Now().UnixNano(),
 		index:        -1,
 	}
@@ -43,6 +64,9 @@ func (txq txQueue) Len() int {
 }
 
 func (txq txQueue) Less(i, j int) bool {
+	if txq[i].receivedTime == txq[j].receivedTime && txq[i].sender == txq[j].sender {
+		return txq[i].tx.Nonce() < txq[j].tx.Nonce()
+	}
 	return txq[i].receivedTime < txq[j].receivedTime
 }
 
@@ -69,9 +93,53 @@ func (txq *txQueue) Pop() interface{} {
 	return item
 }
 
+// senderTxs keeps the txs of a sender in the pool
+type senderTxs struct {
+	// nonce of the next tx to be put in queue
+	nextNonce int64
+
+	// future nonce txs waiting for the gap to be filled
+	gapped map[int64]*txItem
+
+	items map[string]*txItem
+}
+
+func newSenderTxs(nextNonce int64) *senderTxs {
+	return &senderTxs{
+		nextNonce: nextNonce,
+		gapped:    make(map[int64]*txItem),
+		items:     make(map[string]*txItem),
+	}
+}
+
+func (stxs *senderTxs) hasNonce(nonce int64) bool {
+	if nonce >= stxs.nextNonce {
+		return stxs.gapped[nonce] != nil
+	}
+	for _, item := range stxs.items {
+		if item.tx.Nonce() == nonce {
+			return true
+		}
+	}
+	return false
+}
+
+func (stxs *senderTxs) checkGappedTx(nonce int64) error {
+	if nonce-stxs.nextNonce >= MaxNonceGap {
+		return ErrNonceTooHigh
+	}
+	if len(stxs.gapped) >= MaxGappedPerSender {
+		return ErrTooManyGapped
+	}
+	return nil
+}
+
 type txStore struct {
 	txq     *txQueue
 	txItems map[string]*txItem
+	senders map[string]*senderTxs
+
+	gappedCount int
 
 	mtx sync.RWMutex
 }
@@ -80,22 +148,129 @@ func newTxStore() *txStore {
 	return &txStore{
 		txq:     newTxQueue(),
 		txItems: make(map[string]*txItem),
+		senders: make(map[string]*senderTxs),
 	}
 }
 
-func (store *txStore) addNewTx(tx *core.Transaction) {
+// addNewTx puts the tx in queue if its nonce is the next nonce of the sender,
+// a future nonce tx is held in the gapped queue of the sender until lower nonces are filled.
+// nextNonce is the commited next nonce of the sender.
+// A tx reusing the nonce of another tx in the pool is rejected unless it's synced from a proposal,
+// the synced tx is kept out of queue.
+// The gapped tx limits are not applied to the synced txs, which are bound by the proposals.
+func (store *txStore) addNewTx(tx *core.Transaction, nextNonce int64, synced bool) error {
 	store.mtx.Lock()
 	defer store.mtx.Unlock()
 
 	if store.txItems[string(tx.Hash())] != nil {
-		return
+		return nil
 	}
 	item := newTxItem(tx)
-	heap.Push(store.txq, item)
+	stxs := store.senders[item.sender]
+	if stxs == nil {
+		stxs = newSenderTxs(nextNonce)
+		store.senders[item.sender] = stxs
+	}
+	store.advanceNextNonce(stxs, nextNonce)
+	switch {
+	case stxs.hasNonce(tx.Nonce()):
+		if !synced {
+			return ErrNonceExists
+		}
+	case tx.Nonce() > stxs.nextNonce:
+		if !synced {
+			if err := stxs.checkGappedTx(tx.Nonce()); err != nil {
+				if len(stxs.items) == 0 {
+					delete(store.senders, item.sender)
+				}
+				return err
+			}
+		}
+		item.gapped = true
+		stxs.gapped[tx.Nonce()] = item
+		store.gappedCount++
+	default:
+		heap.Push(store.txq, item)
+		if tx.Nonce() == stxs.nextNonce {
+			stxs.nextNonce++
+			store.releaseGappedTxs(stxs)
+		}
+	}
+	stxs.items[string(tx.Hash())] = item
+	store.senders[item.sender] = stxs
 	store.txItems[string(tx.Hash())] = item
+	return nil
 }
 
-func (store *txStore) popTxsFromQueue(max int) [][]byte {
+// advanceNextNonce drops the gapped txs with stale nonces and releases the next ones
+func (store *txStore) advanceNextNonce(stxs *senderTxs, nextNonce int64) {
+	if nextNonce <= stxs.nextNonce {
+		return
+	}
+	for nonce, item := range stxs.gapped {
+		if nonce < nextNonce {
+			store.deleteItem(item)
+		}
+	}
+	stxs.nextNonce = nextNonce
+	store.releaseGappedTxs(stxs)
+}
+
+// releaseGappedTxs moves the gapped txs to queue while their nonces are continuous.
+// Released txs are timestamped in nonce order to be popped after the lower nonces.
+func (store *txStore) releaseGappedTxs(stxs *senderTxs) {
+	for {
+		item := stxs.gapped[stxs.nextNonce]
+		if item == nil {
+			return
+		}
+		delete(stxs.gapped, stxs.nextNonce)
+		store.gappedCount--
+		item.gapped = false
+		item.receivedTime = time.Now().UnixNano()
+		heap.Push(store.txq, item)
+		stxs.nextNonce++
+	}
+}
+
+// dropExpiredGappedTxs drops the gapped txs received before the given time
+func (store *txStore) dropExpiredGappedTxs(before time.Time) {
+	store.mtx.Lock()
+	defer store.mtx.Unlock()
+
+	for _, stxs := range store.senders {
+		for _, item := range stxs.gapped {
+			if item.receivedTime < before.UnixNano() {
+				store.deleteItem(item)
+			}
+		}
+	}
+}
+
+func (store *txStore) deleteItem(item *txItem) {
+	if item.inQueue() {
+		heap.Remove(store.txq, item.index)
+	}
+	hash := string(item.tx.Hash())
+	delete(store.txItems, hash)
+	stxs := store.senders[item.sender]
+	if stxs == nil {
+		return
+	}
+	if item.gapped && stxs.gapped[item.tx.Nonce()] == item {
+		delete(stxs.gapped, item.tx.Nonce())
+		store.gappedCount--
+		item.gapped = false
+	}
+	delete(stxs.items, hash)
+	if len(stxs.items) == 0 {
+		delete(store.senders, item.sender)
+	}
+}
+
+// popTxsFromQueue pops txs until max count is reached
+// or the total gas limit of txs would exceed the given gasLimit (zero means no limit)
+func (store *txStore) popTxsFromQueue(max int, gasLimit uint64) [][]byte {
 	store.mtx.Lock()
 	defer store.mtx.Unlock()
 
@@ -103,10 +278,26 @@ func (store *txStore) popTxsFromQueue(max int) [][]byte {
 	if count == 0 {
 		return nil
 	}
-	ret := make([][]byte, count)
-	for i := range ret {
+	ret := make([][]byte, 0, count)
+	var gasTotal uint64
+	for len(ret) < count && store.txq.Len() > 0 {
+		head := (*store.txq)[0]
+		if gasLimit > 0 && head.tx.GasLimit() > gasLimit {
+			// tx can never fit in a block, drop it
+			store.deleteItem(head)
+			logger.I().Warnw("dropped tx exceeding block gas limit",
+				"gasLimit", head.tx.GasLimit())
+			continue
+		}
+		if gasLimit > 0 && gasTotal+head.tx.GasLimit() > gasLimit {
+			break
+		}
+		gasTotal += head.tx.GasLimit()
 		item := (heap.Pop(store.txq)).(*txItem)
-		ret[i] = item.tx.Hash()
+		ret = append(ret, item.tx.Hash())
+	}
+	if len(ret) == 0 {
+		return nil
 	}
 	return ret
 }
@@ -124,7 +315,7 @@ func (store *txStore) putTxsToQueue(hashes [][]byte) {
 
 	for _, hash := range hashes {
 		if item, found := store.txItems[string(hash)]; found {
-			if !item.inQueue() {
+			if !item.inQueue() && !item.gapped {
 				heap.Push(store.txq, item)
 			}
 		}
@@ -140,6 +331,12 @@ func (store *txStore) setTxsPending(hashes [][]byte) {
 			if item.inQueue() {
 				heap.Remove(store.txq, item.index)
 			}
+			if item.gapped { // gapped tx is included in a proposal
+				stxs := store.senders[item.sender]
+				delete(stxs.gapped, item.tx.Nonce())
+				store.gappedCount--
+				item.gapped = false
+			}
 		}
 	}
 }
@@ -148,14 +345,31 @@ func (store *txStore) removeTxs(hashes [][]byte) {
 	store.mtx.Lock()
 	defer store.mtx.Unlock()
 
+	nextNonces := make(map[string]int64)
 	for _, hash := range hashes {
 		if item, found := store.txItems[string(hash)]; found {
-			if item.inQueue() {
-				heap.Remove(store.txq, item.index)
+			if item.tx.Nonce() >= nextNonces[item.sender] {
+				nextNonces[item.sender] = item.tx.Nonce() + 1
 			}
-			delete(store.txItems, string(hash))
+			store.deleteItem(item)
+		}
+	}
+	for sender, nextNonce := range nextNonces {
+		if stxs := store.senders[sender]; stxs != nil {
+			store.removeStaleTxs(stxs, nextNonce)
+		}
+	}
+}
+
+// removeStaleTxs drops the txs of the sender below the commited next nonce,
+// except pending ones which can still be in the proposed blocks
+func (store *txStore) removeStaleTxs(stxs *senderTxs, nextNonce int64) {
+	for _, item := range stxs.items {
+		if item.tx.Nonce() < nextNonce && item.inQueue() {
+			store.deleteItem(item)
 		}
 	}
+	store.advanceNextNonce(stxs, nextNonce)
 }
 
 func (store *txStore) getTx(hash []byte) *core.Transaction {
@@ -177,7 +391,7 @@ func (store *txStore) getTxStatus(hash []byte) TxStatus {
 	if item == nil {
 		return TxStatusNotFound
 	}
-	if item.inQueue() {
+	if item.inQueue() || item.gapped {
 		return TxStatusQueue
 	}
 	return TxStatusPending
@@ -189,6 +403,7 @@ func (store *txStore) getStatus() (status Status) {
 
 	status.Total = len(store.txItems)
 	status.Queue = store.txq.Len()
-	status.Pending = status.Total - status.Queue
+	status.Gapped = store.gappedCount
+	status.Pending = status.Total - status.Queue - status.Gapped
 	return status
 }
@@ -16,7 +16,7 @@ func TestTxStore_addNewTx(t *testing.T) {
 
 	tx := core.NewTransaction().Sign(core.GenerateKey(nil))
 	store := newTxStore()
-	store.addNewTx(tx)
+	store.addNewTx(tx, tx.Nonce(), false)
 
 	assert.Equal(1, store.getStatus().Total)
 	assert.Equal(1, store.getStatus().Queue)
@@ -27,7 +27,7 @@ func TestTxStore_addNewTx(t *testing.T) {
 	assert.Equal(0, txItem.index)
 
 	// add the same tx again and should not accept
-	store.addNewTx(tx)
+	store.addNewTx(tx, tx.Nonce(), false)
 
 	assert.Nil(store.getTx([]byte("notexist")))
 	assert.NotNil(store.getTx(tx.Hash()))
@@ -44,23 +44,22 @@ func TestTxStore_addNewTx(t *testing.T) {
 func TestTxStore_popTxsFromQueue(t *testing.T) {
 	assert := assert.New(t)
 
-	priv := core.GenerateKey(nil)
-	tx1 := core.NewTransaction().SetNonce(4).Sign(priv)
-	tx2 := core.NewTransaction().SetNonce(3).Sign(priv)
-	tx3 := core.NewTransaction().SetNonce(6).Sign(priv)
-	tx4 := core.NewTransaction().SetNonce(2).Sign(priv)
+	tx1 := core.NewTransaction().SetNonce(4).Sign(core.GenerateKey(nil))
+	tx2 := core.NewTransaction().SetNonce(3).Sign(core.GenerateKey(nil))
+	tx3 := core.NewTransaction().SetNonce(6).Sign(core.GenerateKey(nil))
+	tx4 := core.NewTransaction().SetNonce(2).Sign(core.GenerateKey(nil))
 
 	store := newTxStore()
 
-	store.addNewTx(tx1)
+	store.addNewTx(tx1, tx1.Nonce(), false)
 	time.Sleep(1 * time.Microsecond)
-	store.addNewTx(tx2)
+	store.addNewTx(tx2, tx2.Nonce(), false)
 	time.Sleep(1 * time.Microsecond)
-	store.addNewTx(tx3)
+	store.addNewTx(tx3, tx3.Nonce(), false)
 	time.Sleep(1 * time.Microsecond)
-	store.addNewTx(tx4)
+	store.addNewTx(tx4, tx4.Nonce(), false)
 
-	hashes := store.popTxsFromQueue(2)
+	hashes := store.popTxsFromQueue(2, 0)
 
 	assert.Equal(2, len(hashes))
 	assert.Equal(tx1.Hash(), hashes[0])
@@ -73,7 +72,7 @@ func TestTxStore_popTxsFromQueue(t *testing.T) {
 	assert.Equal(2, store.getStatus().Queue)
 	assert.Equal(2, store.getStatus().Pending)
 
-	hashes = store.popTxsFromQueue(3)
+	hashes = store.popTxsFromQueue(3, 0)
 
 	assert.False(store.txItems[string(tx3.Hash())].inQueue())
 	assert.False(store.txItems[string(tx4.Hash())].inQueue())
@@ -86,36 +85,69 @@ func TestTxStore_popTxsFromQueue(t *testing.T) {
 	assert.Equal(0, store.getStatus().Queue)
 	assert.Equal(4, store.getStatus().Pending)
 
-	hashes = store.popTxsFromQueue(2)
+	hashes = store.popTxsFromQueue(2, 0)
 	assert.Nil(hashes)
 }
 
-func TestTxStore_putTxsToQueue(t *testing.T) {
+func TestTxStore_popTxsFromQueue_gasLimit(t *testing.T) {
 	assert := assert.New(t)
 
 	priv := core.GenerateKey(nil)
-	tx1 := core.NewTransaction().SetNonce(4).Sign(priv)
-	tx2 := core.NewTransaction().SetNonce(3).Sign(priv)
-	tx3 := core.NewTransaction().SetNonce(6).Sign(priv)
-	tx4 := core.NewTransaction().SetNonce(2).Sign(priv)
+	tx1 := core.NewTransaction().SetNonce(1).SetGasLimit(300).Sign(priv)
+	tx2 := core.NewTransaction().SetNonce(2).SetGasLimit(500).Sign(priv)
+	tx3 := core.NewTransaction().SetNonce(3).SetGasLimit(600).Sign(priv)
+	tx4 := core.NewTransaction().SetNonce(4).SetGasLimit(2000).Sign(priv)
+
+	store := newTxStore()
+
+	store.addNewTx(tx1, tx1.Nonce(), false)
+	time.Sleep(1 * time.Microsecond)
+	store.addNewTx(tx2, tx2.Nonce(), false)
+	time.Sleep(1 * time.Microsecond)
+	store.addNewTx(tx3, tx3.Nonce(), false)
+	time.Sleep(1 * time.Microsecond)
+	store.addNewTx(tx4, tx4.Nonce(), false)
+
+	hashes := store.popTxsFromQueue(4, 1000)
+
+	assert.Equal(2, len(hashes), "tx3 should not fit in the remaining gas")
+	assert.Equal(tx1.Hash(), hashes[0])
+	assert.Equal(tx2.Hash(), hashes[1])
+
+	hashes = store.popTxsFromQueue(4, 1000)
+
+	assert.Equal(1, len(hashes))
+	assert.Equal(tx3.Hash(), hashes[0])
+	assert.Nil(store.getTx(tx4.Hash()), "tx exceeding gas limit should be dropped")
+	assert.Equal(3, store.getStatus().Total)
+	assert.Equal(0, store.getStatus().Queue)
+}
+
+func TestTxStore_putTxsToQueue(t *testing.T) {
+	assert := assert.New(t)
+
+	tx1 := core.NewTransaction().SetNonce(4).Sign(core.GenerateKey(nil))
+	tx2 := core.NewTransaction().SetNonce(3).Sign(core.GenerateKey(nil))
+	tx3 := core.NewTransaction().SetNonce(6).Sign(core.GenerateKey(nil))
+	tx4 := core.NewTransaction().SetNonce(2).Sign(core.GenerateKey(nil))
 
 	store := newTxStore()
 
-	store.addNewTx(tx1)
+	store.addNewTx(tx1, tx1.Nonce(), false)
 	time.Sleep(1 * time.Microsecond)
-	store.addNewTx(tx2)
+	store.addNewTx(tx2, tx2.Nonce(), false)
 	time.Sleep(1 * time.Microsecond)
-	store.addNewTx(tx3)
+	store.addNewTx(tx3, tx3.Nonce(), false)
 	time.Sleep(1 * time.Microsecond)
-	store.addNewTx(tx4)
+	store.addNewTx(tx4, tx4.Nonce(), false)
 
-	store.popTxsFromQueue(3)
+	store.popTxsFromQueue(3, 0)
 
 	store.putTxsToQueue([][]byte{tx2.Hash(), tx3.Hash()})
 
 	assert.Equal(3, store.getStatus().Queue)
 
-	hashes := store.popTxsFromQueue(2)
+	hashes := store.popTxsFromQueue(2, 0)
 
 	assert.Equal(tx2.Hash(), hashes[0])
 	assert.Equal(tx3.Hash(), hashes[1])
@@ -124,7 +156,7 @@ func TestTxStore_putTxsToQueue(t *testing.T) {
 
 	assert.Equal(2, store.getStatus().Queue)
 
-	hashes = store.popTxsFromQueue(2)
+	hashes = store.popTxsFromQueue(2, 0)
 
 	assert.Equal(tx1.Hash(), hashes[0])
 	assert.Equal(tx4.Hash(), hashes[1])
@@ -133,21 +165,20 @@ func TestTxStore_putTxsToQueue(t *testing.T) {
 func TestTxStore_setTxsPending(t *testing.T) {
 	assert := assert.New(t)
 
-	priv := core.GenerateKey(nil)
-	tx1 := core.NewTransaction().SetNonce(4).Sign(priv)
-	tx2 := core.NewTransaction().SetNonce(3).Sign(priv)
-	tx3 := core.NewTransaction().SetNonce(6).Sign(priv)
-	tx4 := core.NewTransaction().SetNonce(2).Sign(priv)
+	tx1 := core.NewTransaction().SetNonce(4).Sign(core.GenerateKey(nil))
+	tx2 := core.NewTransaction().SetNonce(3).Sign(core.GenerateKey(nil))
+	tx3 := core.NewTransaction().SetNonce(6).Sign(core.GenerateKey(nil))
+	tx4 := core.NewTransaction().SetNonce(2).Sign(core.GenerateKey(nil))
 
 	store := newTxStore()
 
-	store.addNewTx(tx1)
+	store.addNewTx(tx1, tx1.Nonce(), false)
 	time.Sleep(1 * time.Microsecond)
-	store.addNewTx(tx2)
+	store.addNewTx(tx2, tx2.Nonce(), false)
 	time.Sleep(1 * time.Microsecond)
-	store.addNewTx(tx3)
+	store.addNewTx(tx3, tx3.Nonce(), false)
 	time.Sleep(1 * time.Microsecond)
-	store.addNewTx(tx4)
+	store.addNewTx(tx4, tx4.Nonce(), false)
 
 	store.setTxsPending([][]byte{tx2.Hash(), tx4.Hash()})
 
@@ -157,7 +188,7 @@ func TestTxStore_setTxsPending(t *testing.T) {
 	assert.False(store.txItems[string(tx2.Hash())].inQueue())
 	assert.False(store.txItems[string(tx4.Hash())].inQueue())
 
-	hashes := store.popTxsFromQueue(3)
+	hashes := store.popTxsFromQueue(3, 0)
 
 	assert.Equal(2, len(hashes))
 	assert.Equal(tx1.Hash(), hashes[0])
@@ -167,23 +198,22 @@ func TestTxStore_setTxsPending(t *testing.T) {
 func TestTxStore_removeTxs(t *testing.T) {
 	assert := assert.New(t)
 
-	priv := core.GenerateKey(nil)
-	tx1 := core.NewTransaction().SetNonce(4).Sign(priv)
-	tx2 := core.NewTransaction().SetNonce(3).Sign(priv)
-	tx3 := core.NewTransaction().SetNonce(6).Sign(priv)
-	tx4 := core.NewTransaction().SetNonce(2).Sign(priv)
+	tx1 := core.NewTransaction().SetNonce(4).Sign(core.GenerateKey(nil))
+	tx2 := core.NewTransaction().SetNonce(3).Sign(core.GenerateKey(nil))
+	tx3 := core.NewTransaction().SetNonce(6).Sign(core.GenerateKey(nil))
+	tx4 := core.NewTransaction().SetNonce(2).Sign(core.GenerateKey(nil))
 
 	store := newTxStore()
 
-	store.addNewTx(tx1)
+	store.addNewTx(tx1, tx1.Nonce(), false)
 	time.Sleep(1 * time.Microsecond)
-	store.addNewTx(tx2)
+	store.addNewTx(tx2, tx2.Nonce(), false)
 	time.Sleep(1 * time.Microsecond)
-	store.addNewTx(tx3)
+	store.addNewTx(tx3, tx3.Nonce(), false)
 	time.Sleep(1 * time.Microsecond)
-	store.addNewTx(tx4)
+	store.addNewTx(tx4, tx4.Nonce(), false)
 
-	store.popTxsFromQueue(2)
+	store.popTxsFromQueue(2, 0)
 
 	store.removeTxs([][]byte{tx2.Hash(), tx4.Hash()})
 
@@ -191,8 +221,112 @@ func TestTxStore_removeTxs(t *testing.T) {
 	assert.Equal(1, store.getStatus().Queue)
 	assert.Equal(1, store.getStatus().Pending)
 
-	hashes := store.popTxsFromQueue(3)
+	hashes := store.popTxsFromQueue(3, 0)
 
 	assert.Equal(1, len(hashes))
 	assert.Equal(tx3.Hash(), hashes[0])
 }
+
+func TestTxStore_nonceOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	priv := core.GenerateKey(nil)
+	tx0 := core.NewTransaction().SetNonce(0).Sign(priv)
+	tx1 := core.NewTransaction().SetNonce(1).Sign(priv)
+	tx2 := core.NewTransaction().SetNonce(2).Sign(priv)
+	tx3 := core.NewTransaction().SetNonce(3).Sign(priv)
+	other := core.NewTransaction().SetNonce(0).Sign(core.GenerateKey(nil))
+
+	store := newTxStore()
+
+	assert.NoError(store.addNewTx(tx2, 0, false))
+	assert.NoError(store.addNewTx(tx1, 0, false))
+	time.Sleep(1 * time.Microsecond)
+	assert.NoError(store.addNewTx(other, 0, false))
+
+	assert.Equal(1, store.getStatus().Queue)
+	assert.Equal(2, store.getStatus().Gapped)
+	assert.Equal(TxStatusQueue, store.getTxStatus(tx1.Hash()))
+
+	hashes := store.popTxsFromQueue(3, 0)
+	assert.Equal([][]byte{other.Hash()}, hashes, "gapped txs should not be popped")
+
+	assert.NoError(store.addNewTx(tx0, 0, false))
+
+	assert.Equal(3, store.getStatus().Queue, "gapped txs should be released")
+	assert.Equal(0, store.getStatus().Gapped)
+
+	dup := core.NewTransaction().SetNonce(1).SetInput([]byte{1}).Sign(priv)
+	assert.Equal(ErrNonceExists, store.addNewTx(dup, 0, false))
+	assert.NoError(store.addNewTx(dup, 0, true), "synced tx with used nonce")
+	assert.Equal(TxStatusPending, store.getTxStatus(dup.Hash()))
+
+	assert.NoError(store.addNewTx(tx3, 0, false))
+
+	hashes = store.popTxsFromQueue(10, 0)
+	assert.Equal([][]byte{tx0.Hash(), tx1.Hash(), tx2.Hash(), tx3.Hash()}, hashes)
+}
+
+func TestTxStore_removeStaleTxs(t *testing.T) {
+	assert := assert.New(t)
+
+	priv := core.GenerateKey(nil)
+	tx0 := core.NewTransaction().SetNonce(0).Sign(priv)
+	tx1 := core.NewTransaction().SetNonce(1).Sign(priv)
+	tx2 := core.NewTransaction().SetNonce(2).Sign(priv)
+	tx4 := core.NewTransaction().SetNonce(4).Sign(priv)
+
+	store := newTxStore()
+	store.addNewTx(tx0, 0, false)
+	store.addNewTx(tx1, 0, false)
+	store.addNewTx(tx4, 0, false)
+
+	// tx2 is commited in a block from another node's proposal
+	store.addNewTx(tx2, 0, true)
+	store.setTxsPending([][]byte{tx2.Hash()})
+	store.removeTxs([][]byte{tx2.Hash()})
+
+	assert.Nil(store.getTx(tx0.Hash()), "stale queued tx should be removed")
+	assert.Nil(store.getTx(tx1.Hash()))
+	assert.NotNil(store.getTx(tx4.Hash()))
+	assert.Equal(1, store.getStatus().Gapped)
+
+	tx3 := core.NewTransaction().SetNonce(3).Sign(priv)
+	store.addNewTx(tx3, 3, false)
+
+	hashes := store.popTxsFromQueue(10, 0)
+	assert.Equal([][]byte{tx3.Hash(), tx4.Hash()}, hashes)
+}
+
+func TestTxStore_gappedLimits(t *testing.T) {
+	assert := assert.New(t)
+
+	priv := core.GenerateKey(nil)
+	store := newTxStore()
+
+	tooHigh := core.NewTransaction().SetNonce(MaxNonceGap).Sign(priv)
+	assert.Equal(ErrNonceTooHigh, store.addNewTx(tooHigh, 0, false))
+	assert.Equal(0, store.getStatus().Total)
+	assert.Empty(store.senders, "rejected sender should not be kept")
+
+	for i := 1; i <= MaxGappedPerSender; i++ {
+		tx := core.NewTransaction().SetNonce(int64(i)).Sign(priv)
+		assert.NoError(store.addNewTx(tx, 0, false))
+	}
+	assert.Equal(MaxGappedPerSender, store.getStatus().Gapped)
+
+	tx := core.NewTransaction().SetNonce(MaxGappedPerSender + 1).Sign(priv)
+	assert.Equal(ErrTooManyGapped, store.addNewTx(tx, 0, false))
+	assert.NoError(store.addNewTx(tx, 0, true), "synced tx is not limited")
+	assert.NoError(store.addNewTx(tooHigh, 0, true))
+	assert.Equal(MaxGappedPerSender+2, store.getStatus().Gapped)
+
+	other := core.NewTransaction().SetNonce(0).Sign(core.GenerateKey(nil))
+	assert.NoError(store.addNewTx(other, 0, false))
+
+	store.dropExpiredGappedTxs(time.Now())
+	assert.Equal(0, store.getStatus().Gapped, "expired gapped txs should be dropped")
+	assert.Equal(1, store.getStatus().Total, "queued txs are not expired")
+	assert.Nil(store.getTx(tx.Hash()))
+	assert.Nil(store.senders[string(priv.PublicKey().Bytes())])
+}